@@ -13,6 +13,7 @@ require (
 	github.com/patrick-higgins/rtreego v0.0.0-20160917152848-00962878767d
 	github.com/qmuntal/gltf v0.23.1
 	github.com/sqweek/dialog v0.0.0-20220809060634-e981b270ebbf
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -22,5 +23,4 @@ require (
 	golang.org/x/image v0.38.0 // indirect
 	golang.org/x/sys v0.25.0 // indirect
 	golang.org/x/text v0.37.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
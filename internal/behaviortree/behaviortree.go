@@ -0,0 +1,113 @@
+package behaviortree
+
+import "time"
+
+type Status string
+
+const (
+	StatusSuccess Status = "SUCCESS"
+	StatusFailure Status = "FAILURE"
+	// StatusRunning nodes haven't finished yet and are ticked again next update
+	StatusRunning Status = "RUNNING"
+)
+
+// Node is a node of a behavior tree. T is the game context nodes are ticked
+// with, e.g. the entity the tree belongs to
+type Node[T any] interface {
+	Tick(ctx T, bb *Blackboard, delta time.Duration) Status
+	// Reset clears the state the node keeps between ticks. it's called once the
+	// node finishes and when a running node is interrupted
+	Reset()
+}
+
+// Tree is an instance of a behavior tree along with its blackboard, every
+// entity running a behavior gets its own tree
+type Tree[T any] struct {
+	root       Node[T]
+	Blackboard *Blackboard
+}
+
+func NewTree[T any](root Node[T]) *Tree[T] {
+	return &Tree[T]{root: root, Blackboard: NewBlackboard()}
+}
+
+// Tick ticks the tree from its root, the tree starts over on the next tick once
+// the root finishes
+func (t *Tree[T]) Tick(ctx T, delta time.Duration) Status {
+	status := t.root.Tick(ctx, t.Blackboard, delta)
+	if status != StatusRunning {
+		t.root.Reset()
+	}
+	return status
+}
+
+// Reset interrupts the running nodes, the blackboard is kept
+func (t *Tree[T]) Reset() {
+	t.root.Reset()
+}
+
+// Blackboard is the memory shared by the nodes of a tree, e.g. a target one
+// node finds and another moves to
+type Blackboard struct {
+	values map[string]any
+}
+
+func NewBlackboard() *Blackboard {
+	return &Blackboard{values: map[string]any{}}
+}
+
+func (b *Blackboard) Set(key string, value any) {
+	b.values[key] = value
+}
+
+func (b *Blackboard) Get(key string) (any, bool) {
+	value, ok := b.values[key]
+	return value, ok
+}
+
+func (b *Blackboard) Delete(key string) {
+	delete(b.values, key)
+}
+
+// Value returns the value of the key if it's set and has the type V
+func Value[V any](b *Blackboard, key string) (V, bool) {
+	value, ok := b.values[key].(V)
+	return value, ok
+}
+
+// Action is a stateless leaf that runs a function every tick
+type Action[T any] struct {
+	run func(ctx T, bb *Blackboard, delta time.Duration) Status
+}
+
+func NewAction[T any](run func(ctx T, bb *Blackboard, delta time.Duration) Status) *Action[T] {
+	return &Action[T]{run: run}
+}
+
+func (n *Action[T]) Tick(ctx T, bb *Blackboard, delta time.Duration) Status {
+	return n.run(ctx, bb, delta)
+}
+
+func (n *Action[T]) Reset() {}
+
+// Wait is a leaf that runs for a duration and then succeeds
+type Wait[T any] struct {
+	duration time.Duration
+	elapsed  time.Duration
+}
+
+func NewWait[T any](duration time.Duration) *Wait[T] {
+	return &Wait[T]{duration: duration}
+}
+
+func (n *Wait[T]) Tick(ctx T, bb *Blackboard, delta time.Duration) Status {
+	n.elapsed += delta
+	if n.elapsed >= n.duration {
+		return StatusSuccess
+	}
+	return StatusRunning
+}
+
+func (n *Wait[T]) Reset() {
+	n.elapsed = 0
+}
@@ -0,0 +1,330 @@
+package behaviortree
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testLeaf returns its statuses in order, repeating the last one, and records
+// its ticks in the log. ticks counts the ticks since it was last reset
+type testLeaf struct {
+	name     string
+	statuses []Status
+	total    int
+	ticks    int
+	log      *[]string
+}
+
+func (n *testLeaf) Tick(ctx int, bb *Blackboard, delta time.Duration) Status {
+	*n.log = append(*n.log, n.name)
+	status := n.statuses[min(n.total, len(n.statuses)-1)]
+	n.total++
+	n.ticks++
+	return status
+}
+
+func (n *testLeaf) Reset() {
+	n.ticks = 0
+}
+
+func newTestLeaves(log *[]string, statuses ...[]Status) []Node[int] {
+	var leaves []Node[int]
+	for i, s := range statuses {
+		leaves = append(leaves, &testLeaf{name: string(rune('a' + i)), statuses: s, log: log})
+	}
+	return leaves
+}
+
+func TestSequence(t *testing.T) {
+	var log []string
+	leaves := newTestLeaves(&log,
+		[]Status{StatusSuccess},
+		[]Status{StatusRunning, StatusSuccess},
+		[]Status{StatusSuccess},
+	)
+	tree := NewTree[int](NewSequence(leaves...))
+
+	if status := tree.Tick(0, 0); status != StatusRunning {
+		t.Fatalf("first tick = %s, want %s", status, StatusRunning)
+	}
+	if status := tree.Tick(0, 0); status != StatusSuccess {
+		t.Fatalf("second tick = %s, want %s", status, StatusSuccess)
+	}
+	// the running child resumes without ticking the one before it again
+	if want := []string{"a", "b", "b", "c"}; !slices.Equal(log, want) {
+		t.Fatalf("ticked %v, want %v", log, want)
+	}
+}
+
+func TestSelector(t *testing.T) {
+	var log []string
+	leaves := newTestLeaves(&log,
+		[]Status{StatusFailure},
+		[]Status{StatusSuccess},
+		[]Status{StatusSuccess},
+	)
+	if status := NewTree[int](NewSelector(leaves...)).Tick(0, 0); status != StatusSuccess {
+		t.Fatalf("status = %s, want %s", status, StatusSuccess)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(log, want) {
+		t.Fatalf("ticked %v, want %v", log, want)
+	}
+
+	log = nil
+	leaves = newTestLeaves(&log, []Status{StatusFailure}, []Status{StatusFailure})
+	if status := NewTree[int](NewSelector(leaves...)).Tick(0, 0); status != StatusFailure {
+		t.Fatalf("status = %s, want %s", status, StatusFailure)
+	}
+}
+
+func TestReactiveSelector(t *testing.T) {
+	var log []string
+	leaves := newTestLeaves(&log,
+		[]Status{StatusFailure, StatusRunning},
+		[]Status{StatusRunning},
+	)
+	tree := NewTree[int](NewReactiveSelector(leaves...))
+
+	tree.Tick(0, 0)
+	if leaves[1].(*testLeaf).ticks != 1 {
+		t.Fatal("expected the second child to be running")
+	}
+
+	// the first child starts running on the second tick, interrupting the second
+	tree.Tick(0, 0)
+	if want := []string{"a", "b", "a"}; !slices.Equal(log, want) {
+		t.Fatalf("ticked %v, want %v", log, want)
+	}
+	if leaves[1].(*testLeaf).ticks != 0 {
+		t.Fatal("expected the second child to be reset")
+	}
+}
+
+func TestParallel(t *testing.T) {
+	var log []string
+	leaves := newTestLeaves(&log,
+		[]Status{StatusSuccess},
+		[]Status{StatusRunning, StatusSuccess},
+	)
+	tree := NewTree[int](NewParallel(true, leaves...))
+	if status := tree.Tick(0, 0); status != StatusRunning {
+		t.Fatalf("first tick = %s, want %s", status, StatusRunning)
+	}
+	if status := tree.Tick(0, 0); status != StatusSuccess {
+		t.Fatalf("second tick = %s, want %s", status, StatusSuccess)
+	}
+	// finished children aren't ticked again
+	if want := []string{"a", "b", "b"}; !slices.Equal(log, want) {
+		t.Fatalf("ticked %v, want %v", log, want)
+	}
+
+	log = nil
+	leaves = newTestLeaves(&log,
+		[]Status{StatusRunning},
+		[]Status{StatusSuccess},
+	)
+	if status := NewTree[int](NewParallel(false, leaves...)).Tick(0, 0); status != StatusSuccess {
+		t.Fatalf("status = %s, want %s", status, StatusSuccess)
+	}
+	if leaves[0].(*testLeaf).ticks != 0 {
+		t.Fatal("expected the running child to be interrupted")
+	}
+}
+
+func TestDecorators(t *testing.T) {
+	var log []string
+	leaves := newTestLeaves(&log, []Status{StatusSuccess}, []Status{StatusFailure}, []Status{StatusRunning})
+
+	if status := NewInverter(leaves[0]).Tick(0, nil, 0); status != StatusFailure {
+		t.Fatalf("inverted success = %s", status)
+	}
+	if status := NewSucceeder(leaves[1]).Tick(0, nil, 0); status != StatusSuccess {
+		t.Fatalf("succeeded failure = %s", status)
+	}
+
+	repeat := NewRepeat(3, leaves[0])
+	for i := range 2 {
+		if status := repeat.Tick(0, nil, 0); status != StatusRunning {
+			t.Fatalf("repeat %d = %s, want %s", i, status, StatusRunning)
+		}
+	}
+	if status := repeat.Tick(0, nil, 0); status != StatusSuccess {
+		t.Fatalf("last repeat = %s, want %s", status, StatusSuccess)
+	}
+
+	timeout := NewTimeout(time.Second, leaves[2])
+	if status := timeout.Tick(0, nil, 600*time.Millisecond); status != StatusRunning {
+		t.Fatalf("timeout before the duration = %s", status)
+	}
+	if status := timeout.Tick(0, nil, 600*time.Millisecond); status != StatusFailure {
+		t.Fatalf("timeout after the duration = %s", status)
+	}
+	if leaves[2].(*testLeaf).ticks != 0 {
+		t.Fatal("expected the timed out child to be interrupted")
+	}
+}
+
+func TestNodeStatuses(t *testing.T) {
+	s, f, r := StatusSuccess, StatusFailure, StatusRunning
+	testCases := []struct {
+		name string
+		node func(leaves []Node[int]) Node[int]
+		// leaves holds the statuses each leaf returns in order
+		leaves [][]Status
+		// want holds the status of the node on each tick
+		want []Status
+	}{
+		{name: "sequence succeeds", node: func(l []Node[int]) Node[int] { return NewSequence(l...) }, leaves: [][]Status{{s}, {s}}, want: []Status{s}},
+		{name: "sequence fails", node: func(l []Node[int]) Node[int] { return NewSequence(l...) }, leaves: [][]Status{{s}, {f}}, want: []Status{f}},
+		{name: "sequence runs", node: func(l []Node[int]) Node[int] { return NewSequence(l...) }, leaves: [][]Status{{r, s}, {s}}, want: []Status{r, s}},
+		{name: "selector succeeds", node: func(l []Node[int]) Node[int] { return NewSelector(l...) }, leaves: [][]Status{{f}, {s}}, want: []Status{s}},
+		{name: "selector fails", node: func(l []Node[int]) Node[int] { return NewSelector(l...) }, leaves: [][]Status{{f}, {f}}, want: []Status{f}},
+		{name: "reactive sequence rechecks", node: func(l []Node[int]) Node[int] { return NewReactiveSequence(l...) }, leaves: [][]Status{{s, f}, {r}}, want: []Status{r, f}},
+		{name: "reactive selector rechecks", node: func(l []Node[int]) Node[int] { return NewReactiveSelector(l...) }, leaves: [][]Status{{f, s}, {r}}, want: []Status{r, s}},
+		{name: "parallel all", node: func(l []Node[int]) Node[int] { return NewParallel(true, l...) }, leaves: [][]Status{{s}, {r, s}}, want: []Status{r, s}},
+		{name: "parallel all fails", node: func(l []Node[int]) Node[int] { return NewParallel(true, l...) }, leaves: [][]Status{{r}, {f}}, want: []Status{f}},
+		{name: "parallel any", node: func(l []Node[int]) Node[int] { return NewParallel(false, l...) }, leaves: [][]Status{{r}, {s}}, want: []Status{s}},
+		{name: "parallel any fails", node: func(l []Node[int]) Node[int] { return NewParallel(false, l...) }, leaves: [][]Status{{f}, {r, f}}, want: []Status{r, f}},
+		{name: "inverter", node: func(l []Node[int]) Node[int] { return NewInverter(l[0]) }, leaves: [][]Status{{r, s, f}}, want: []Status{r, f, s}},
+		{name: "succeeder", node: func(l []Node[int]) Node[int] { return NewSucceeder(l[0]) }, leaves: [][]Status{{r, f, s}}, want: []Status{r, s, s}},
+		{name: "repeat count", node: func(l []Node[int]) Node[int] { return NewRepeat(2, l[0]) }, leaves: [][]Status{{s}}, want: []Status{r, s, r, s}},
+		{name: "repeat fails", node: func(l []Node[int]) Node[int] { return NewRepeat(0, l[0]) }, leaves: [][]Status{{s, r, f}}, want: []Status{r, r, f}},
+		{name: "repeat forever", node: func(l []Node[int]) Node[int] { return NewRepeat(0, l[0]) }, leaves: [][]Status{{s}}, want: []Status{r, r, r, r}},
+		{name: "timeout finishes", node: func(l []Node[int]) Node[int] { return NewTimeout(3*time.Second, l[0]) }, leaves: [][]Status{{r, s}}, want: []Status{r, s}},
+		{name: "timeout expires", node: func(l []Node[int]) Node[int] { return NewTimeout(2*time.Second, l[0]) }, leaves: [][]Status{{r}}, want: []Status{r, f}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var log []string
+			tree := NewTree(tc.node(newTestLeaves(&log, tc.leaves...)))
+			var got []Status
+			for range tc.want {
+				got = append(got, tree.Tick(0, time.Second))
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("statuses = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWait(t *testing.T) {
+	wait := NewWait[int](time.Second)
+	if status := wait.Tick(0, nil, 500*time.Millisecond); status != StatusRunning {
+		t.Fatalf("status = %s, want %s", status, StatusRunning)
+	}
+	if status := wait.Tick(0, nil, 500*time.Millisecond); status != StatusSuccess {
+		t.Fatalf("status = %s, want %s", status, StatusSuccess)
+	}
+}
+
+func TestBlackboard(t *testing.T) {
+	bb := NewBlackboard()
+	bb.Set("target", 5)
+	if target, ok := Value[int](bb, "target"); !ok || target != 5 {
+		t.Fatalf("target = %v, %v, want 5", target, ok)
+	}
+	if _, ok := Value[string](bb, "target"); ok {
+		t.Fatal("got a value with the wrong type")
+	}
+	bb.Delete("target")
+	if _, ok := bb.Get("target"); ok {
+		t.Fatal("expected the target to be deleted")
+	}
+}
+
+func TestBuildFromConfig(t *testing.T) {
+	config := `
+entity_type: worker
+behavior_tree:
+  type: selector
+  children:
+    - type: sequence
+      children:
+        - type: found
+          key: target
+        - type: timeout
+          seconds: 1.5
+          child:
+            type: wait
+            seconds: 3
+    - type: wait
+      seconds: 1
+`
+	cfg, err := LoadConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.EntityType != "worker" {
+		t.Fatalf("entity type = %q, want worker", cfg.EntityType)
+	}
+
+	var keys []string
+	parseLeaf := func(config NodeConfig) (Node[int], error) {
+		if config.Type != "found" {
+			return nil, errors.New("unknown leaf")
+		}
+		key, err := config.String("key", "")
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		return NewAction(func(ctx int, bb *Blackboard, delta time.Duration) Status {
+			if _, ok := bb.Get(key); ok {
+				return StatusSuccess
+			}
+			return StatusFailure
+		}), nil
+	}
+
+	root, err := Build(cfg.BehaviorTree, parseLeaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"target"}) {
+		t.Fatalf("parsed leaves with keys %v", keys)
+	}
+
+	tree := NewTree(root)
+	tree.Blackboard.Set("target", 1)
+	if status := tree.Tick(0, time.Second); status != StatusRunning {
+		t.Fatalf("status = %s, want %s", status, StatusRunning)
+	}
+	// the timeout cuts the three second wait short and the selector falls back to
+	// the one second wait, which finishes on the same tick
+	if status := tree.Tick(0, time.Second); status != StatusSuccess {
+		t.Fatalf("status = %s, want %s", status, StatusSuccess)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config string
+	}{
+		{name: "unknown leaf", config: "behavior_tree: {type: dance}"},
+		{name: "no children", config: "behavior_tree: {type: sequence}"},
+		{name: "no child", config: "behavior_tree: {type: inverter}"},
+		{name: "bad parameter", config: "behavior_tree: {type: wait, seconds: soon}"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := LoadConfig(strings.NewReader(tc.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Build[int](cfg.BehaviorTree, nil); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+	if _, err := LoadConfig(strings.NewReader("entity_type: worker")); err == nil {
+		t.Fatal("expected an error for a config without a tree")
+	}
+}
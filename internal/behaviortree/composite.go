@@ -0,0 +1,143 @@
+package behaviortree
+
+import "time"
+
+// Composite ticks its children in order until one of them finishes with the
+// status the composite stops on. sequences stop on the first child that fails
+// and selectors on the first child that succeeds.
+//
+// by default a composite resumes from its running child on the next tick.
+// reactive composites tick from their first child every tick instead, so an
+// earlier child e.g. a condition can interrupt a later one that's running
+type Composite[T any] struct {
+	children []Node[T]
+	stopOn   Status
+	reactive bool
+	// running is the index of the child that returned StatusRunning last tick,
+	// or -1
+	running int
+}
+
+// NewSequence returns a composite that succeeds once all its children succeed
+// and fails as soon as one of them fails
+func NewSequence[T any](children ...Node[T]) *Composite[T] {
+	return &Composite[T]{children: children, stopOn: StatusFailure, running: -1}
+}
+
+// NewSelector returns a composite that succeeds as soon as one of its children
+// succeeds and fails once all of them fail
+func NewSelector[T any](children ...Node[T]) *Composite[T] {
+	return &Composite[T]{children: children, stopOn: StatusSuccess, running: -1}
+}
+
+func NewReactiveSequence[T any](children ...Node[T]) *Composite[T] {
+	sequence := NewSequence(children...)
+	sequence.reactive = true
+	return sequence
+}
+
+func NewReactiveSelector[T any](children ...Node[T]) *Composite[T] {
+	selector := NewSelector(children...)
+	selector.reactive = true
+	return selector
+}
+
+func (n *Composite[T]) Tick(ctx T, bb *Blackboard, delta time.Duration) Status {
+	start := n.running
+	if n.reactive || start < 0 {
+		start = 0
+	}
+
+	for i := start; i < len(n.children); i++ {
+		child := n.children[i]
+		status := child.Tick(ctx, bb, delta)
+		if status == StatusRunning {
+			if n.running != -1 && n.running != i {
+				n.children[n.running].Reset()
+			}
+			n.running = i
+			return StatusRunning
+		}
+
+		child.Reset()
+		if status == n.stopOn {
+			n.Reset()
+			return status
+		}
+	}
+
+	n.Reset()
+	if n.stopOn == StatusSuccess {
+		return StatusFailure
+	}
+	return StatusSuccess
+}
+
+func (n *Composite[T]) Reset() {
+	for _, child := range n.children {
+		child.Reset()
+	}
+	n.running = -1
+}
+
+// Parallel ticks all its children every tick. it succeeds once enough of them
+// succeed, either all of them or any one, and fails once that's no longer
+// possible. children that are still running when it finishes are interrupted
+type Parallel[T any] struct {
+	children   []Node[T]
+	requireAll bool
+	// statuses holds the status each child finished with, or StatusRunning
+	statuses []Status
+}
+
+// NewParallel returns a parallel that succeeds once all of its children succeed
+// if requireAll is set, otherwise once any of them does
+func NewParallel[T any](requireAll bool, children ...Node[T]) *Parallel[T] {
+	return &Parallel[T]{children: children, requireAll: requireAll, statuses: make([]Status, len(children))}
+}
+
+func (n *Parallel[T]) Tick(ctx T, bb *Blackboard, delta time.Duration) Status {
+	var successes, failures int
+	for i, child := range n.children {
+		if n.statuses[i] == "" || n.statuses[i] == StatusRunning {
+			n.statuses[i] = child.Tick(ctx, bb, delta)
+			if n.statuses[i] != StatusRunning {
+				child.Reset()
+			}
+		}
+
+		switch n.statuses[i] {
+		case StatusSuccess:
+			successes++
+		case StatusFailure:
+			failures++
+		}
+	}
+
+	status := StatusRunning
+	if n.requireAll {
+		if failures > 0 {
+			status = StatusFailure
+		} else if successes == len(n.children) {
+			status = StatusSuccess
+		}
+	} else {
+		if successes > 0 {
+			status = StatusSuccess
+		} else if failures == len(n.children) {
+			status = StatusFailure
+		}
+	}
+
+	if status != StatusRunning {
+		n.Reset()
+	}
+	return status
+}
+
+func (n *Parallel[T]) Reset() {
+	for i, child := range n.children {
+		child.Reset()
+		n.statuses[i] = ""
+	}
+}
@@ -0,0 +1,196 @@
+package behaviortree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is a behavior tree definition as authored in yaml
+type Config struct {
+	EntityType   string     `yaml:"entity_type"`
+	BehaviorTree NodeConfig `yaml:"behavior_tree"`
+}
+
+// NodeConfig configures a node of the tree. composites list their children
+// under children and decorators their child under child, every other key is a
+// parameter of the node e.g. the seconds of a wait
+type NodeConfig struct {
+	Type     string         `yaml:"type"`
+	Children []NodeConfig   `yaml:"children"`
+	Child    *NodeConfig    `yaml:"child"`
+	Params   map[string]any `yaml:",inline"`
+}
+
+// LeafParser builds the game's leaf nodes from their config, it's handed every
+// node type that isn't built into the engine
+type LeafParser[T any] func(config NodeConfig) (Node[T], error)
+
+func LoadConfig(r io.Reader) (Config, error) {
+	var config Config
+	if err := yaml.NewDecoder(r).Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("failed to decode behavior tree: %w", err)
+	}
+	if config.BehaviorTree.Type == "" {
+		return Config{}, errors.New("behavior tree has no root node")
+	}
+	return config, nil
+}
+
+// Build builds a new instance of the tree rooted at the node config. nodes keep
+// their own state between ticks so every tree that runs needs its own instance.
+//
+// the engine builds the composites sequence, selector, reactive_sequence,
+// reactive_selector and parallel, the decorators inverter, succeeder, repeat and
+// timeout and the wait leaf, every other node is built by parseLeaf
+func Build[T any](config NodeConfig, parseLeaf LeafParser[T]) (Node[T], error) {
+	node, err := build(config, parseLeaf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %q node: %w", config.Type, err)
+	}
+	return node, nil
+}
+
+func build[T any](config NodeConfig, parseLeaf LeafParser[T]) (Node[T], error) {
+	switch config.Type {
+	case "sequence", "selector", "reactive_sequence", "reactive_selector", "parallel":
+		children, err := buildChildren(config, parseLeaf)
+		if err != nil {
+			return nil, err
+		}
+		switch config.Type {
+		case "sequence":
+			return NewSequence(children...), nil
+		case "selector":
+			return NewSelector(children...), nil
+		case "reactive_sequence":
+			return NewReactiveSequence(children...), nil
+		case "reactive_selector":
+			return NewReactiveSelector(children...), nil
+		}
+		requireAll, err := config.Bool("require_all", true)
+		if err != nil {
+			return nil, err
+		}
+		return NewParallel(requireAll, children...), nil
+	case "inverter", "succeeder", "repeat", "timeout":
+		if config.Child == nil {
+			return nil, errors.New("decorator has no child")
+		}
+		child, err := Build(*config.Child, parseLeaf)
+		if err != nil {
+			return nil, err
+		}
+		switch config.Type {
+		case "inverter":
+			return NewInverter(child), nil
+		case "succeeder":
+			return NewSucceeder(child), nil
+		case "repeat":
+			count, err := config.Int("count", 0)
+			if err != nil {
+				return nil, err
+			}
+			return NewRepeat(count, child), nil
+		}
+		duration, err := config.Seconds("seconds", 0)
+		if err != nil {
+			return nil, err
+		}
+		return NewTimeout(duration, child), nil
+	case "wait":
+		duration, err := config.Seconds("seconds", 0)
+		if err != nil {
+			return nil, err
+		}
+		return NewWait[T](duration), nil
+	case "":
+		return nil, errors.New("node has no type")
+	}
+
+	if parseLeaf == nil {
+		return nil, errors.New("unknown node type")
+	}
+	return parseLeaf(config)
+}
+
+func buildChildren[T any](config NodeConfig, parseLeaf LeafParser[T]) ([]Node[T], error) {
+	if len(config.Children) == 0 {
+		return nil, errors.New("composite has no children")
+	}
+	children := make([]Node[T], 0, len(config.Children))
+	for _, childConfig := range config.Children {
+		child, err := Build(childConfig, parseLeaf)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// Float returns the parameter as a float, or the fallback if it isn't set
+func (c NodeConfig) Float(key string, fallback float64) (float64, error) {
+	switch value := c.Params[key].(type) {
+	case nil:
+		return fallback, nil
+	case int:
+		return float64(value), nil
+	case float64:
+		return value, nil
+	default:
+		return 0, fmt.Errorf("parameter %q is a %T, not a number", key, value)
+	}
+}
+
+// Int returns the parameter as an int, or the fallback if it isn't set
+func (c NodeConfig) Int(key string, fallback int) (int, error) {
+	switch value := c.Params[key].(type) {
+	case nil:
+		return fallback, nil
+	case int:
+		return value, nil
+	default:
+		return 0, fmt.Errorf("parameter %q is a %T, not an integer", key, value)
+	}
+}
+
+// String returns the parameter as a string, or the fallback if it isn't set
+func (c NodeConfig) String(key string, fallback string) (string, error) {
+	switch value := c.Params[key].(type) {
+	case nil:
+		return fallback, nil
+	case string:
+		return value, nil
+	default:
+		return "", fmt.Errorf("parameter %q is a %T, not a string", key, value)
+	}
+}
+
+// Bool returns the parameter as a bool, or the fallback if it isn't set
+func (c NodeConfig) Bool(key string, fallback bool) (bool, error) {
+	switch value := c.Params[key].(type) {
+	case nil:
+		return fallback, nil
+	case bool:
+		return value, nil
+	default:
+		return false, fmt.Errorf("parameter %q is a %T, not a bool", key, value)
+	}
+}
+
+// Seconds returns the parameter, given in seconds, as a duration or the
+// fallback if it isn't set
+func (c NodeConfig) Seconds(key string, fallback time.Duration) (time.Duration, error) {
+	if _, ok := c.Params[key]; !ok {
+		return fallback, nil
+	}
+	seconds, err := c.Float(key, 0)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,115 @@
+package behaviortree
+
+import "time"
+
+// Inverter flips the success or failure of its child
+type Inverter[T any] struct {
+	child Node[T]
+}
+
+func NewInverter[T any](child Node[T]) *Inverter[T] {
+	return &Inverter[T]{child: child}
+}
+
+func (n *Inverter[T]) Tick(ctx T, bb *Blackboard, delta time.Duration) Status {
+	switch n.child.Tick(ctx, bb, delta) {
+	case StatusSuccess:
+		return StatusFailure
+	case StatusFailure:
+		return StatusSuccess
+	}
+	return StatusRunning
+}
+
+func (n *Inverter[T]) Reset() {
+	n.child.Reset()
+}
+
+// Succeeder succeeds once its child finishes whether it failed or not
+type Succeeder[T any] struct {
+	child Node[T]
+}
+
+func NewSucceeder[T any](child Node[T]) *Succeeder[T] {
+	return &Succeeder[T]{child: child}
+}
+
+func (n *Succeeder[T]) Tick(ctx T, bb *Blackboard, delta time.Duration) Status {
+	if n.child.Tick(ctx, bb, delta) == StatusRunning {
+		return StatusRunning
+	}
+	return StatusSuccess
+}
+
+func (n *Succeeder[T]) Reset() {
+	n.child.Reset()
+}
+
+// Repeat runs its child again each time it succeeds, up to count times or
+// forever if count is zero. it fails as soon as its child fails. the child
+// restarts on the tick after it succeeds so a child that succeeds immediately
+// doesn't loop within a single tick
+type Repeat[T any] struct {
+	child Node[T]
+	count int
+	done  int
+}
+
+func NewRepeat[T any](count int, child Node[T]) *Repeat[T] {
+	return &Repeat[T]{child: child, count: count}
+}
+
+func (n *Repeat[T]) Tick(ctx T, bb *Blackboard, delta time.Duration) Status {
+	status := n.child.Tick(ctx, bb, delta)
+	if status == StatusRunning {
+		return StatusRunning
+	}
+
+	n.child.Reset()
+	if status == StatusFailure {
+		n.done = 0
+		return StatusFailure
+	}
+
+	n.done++
+	if n.count > 0 && n.done >= n.count {
+		n.done = 0
+		return StatusSuccess
+	}
+	return StatusRunning
+}
+
+func (n *Repeat[T]) Reset() {
+	n.child.Reset()
+	n.done = 0
+}
+
+// Timeout fails and interrupts its child if it's still running after the
+// duration
+type Timeout[T any] struct {
+	child    Node[T]
+	duration time.Duration
+	elapsed  time.Duration
+}
+
+func NewTimeout[T any](duration time.Duration, child Node[T]) *Timeout[T] {
+	return &Timeout[T]{child: child, duration: duration}
+}
+
+func (n *Timeout[T]) Tick(ctx T, bb *Blackboard, delta time.Duration) Status {
+	n.elapsed += delta
+	status := n.child.Tick(ctx, bb, delta)
+	if status == StatusRunning && n.elapsed >= n.duration {
+		n.Reset()
+		return StatusFailure
+	}
+	if status != StatusRunning {
+		n.elapsed = 0
+	}
+	return status
+}
+
+func (n *Timeout[T]) Reset() {
+	n.child.Reset()
+	n.elapsed = 0
+}
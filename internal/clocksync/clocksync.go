@@ -0,0 +1,105 @@
+package clocksync
+
+import "math"
+
+// the smoothed depth moves this fraction of the way to each reported depth, the
+// server reports its buffer every frame so single reports are noisy
+const depthSmoothing float64 = 0.1
+
+// Dilation steers how fast a client runs its command frames so that its inputs
+// arrive at the server with a target number of frames buffered. a client that
+// runs ahead of the server fills the buffer and adds latency, one that falls
+// behind runs the buffer dry and the server has to reuse stale inputs
+type Dilation struct {
+	// Gain is how much the frame duration is stretched per frame of buffer
+	// depth away from the target
+	Gain float64
+	// MaxDilation bounds the stretch so that time never visibly speeds up or
+	// slows down
+	MaxDilation float64
+
+	depth    float64
+	observed bool
+}
+
+func NewDilation(gain, maxDilation float64) *Dilation {
+	return &Dilation{Gain: gain, MaxDilation: maxDilation}
+}
+
+// Observe records a buffer depth reported by the server
+func (d *Dilation) Observe(depth int) {
+	if !d.observed {
+		d.depth = float64(depth)
+		d.observed = true
+		return
+	}
+	d.depth += (float64(depth) - d.depth) * depthSmoothing
+}
+
+// Depth returns the smoothed buffer depth
+func (d *Dilation) Depth() float64 {
+	return d.depth
+}
+
+// Scale returns how much to stretch the command frame duration by. above 1 the
+// client slows down to let the buffer drain, below 1 it speeds up to fill it
+func (d *Dilation) Scale(targetDepth float64) float64 {
+	if !d.observed {
+		return 1
+	}
+	stretch := (d.depth - targetDepth) * d.Gain
+	return 1 + max(-d.MaxDilation, min(d.MaxDilation, stretch))
+}
+
+// Reset forgets the observed depth, for when the client reconnects
+func (d *Dilation) Reset() {
+	d.depth = 0
+	d.observed = false
+}
+
+// the estimated offset between server and local frames moves this fraction of
+// the way to each observed offset
+const offsetSmoothing float64 = 0.1
+
+// Arrivals estimates the server's command frame from when its snapshots arrive
+// on the client, along with how much their arrival times jitter
+type Arrivals struct {
+	offset      float64
+	jitter      float64
+	lastTransit int
+	observed    bool
+}
+
+func NewArrivals() *Arrivals {
+	return &Arrivals{}
+}
+
+// Observe records that the snapshot from serverFrame arrived on localFrame
+func (a *Arrivals) Observe(serverFrame, localFrame int) {
+	offset := float64(serverFrame - localFrame)
+	transit := localFrame - serverFrame
+	if !a.observed {
+		a.offset = offset
+		a.lastTransit = transit
+		a.observed = true
+		return
+	}
+
+	// jitter is measured as in rfc 3550, the smoothed difference in transit time
+	// between consecutive snapshots. the unknown clock offset cancels out
+	difference := math.Abs(float64(transit - a.lastTransit))
+	a.jitter += (difference - a.jitter) / 16
+	a.lastTransit = transit
+	a.offset += (offset - a.offset) * offsetSmoothing
+}
+
+// ServerFrame returns the estimated server command frame of the most recent
+// snapshot that would have arrived by localFrame
+func (a *Arrivals) ServerFrame(localFrame int) float64 {
+	return float64(localFrame) + a.offset
+}
+
+// Jitter returns the smoothed jitter of snapshot arrivals in command frames
+func (a *Arrivals) Jitter() float64 {
+	return a.jitter
+}
@@ -0,0 +1,79 @@
+package clocksync
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScale(t *testing.T) {
+	dilation := NewDilation(0.02, 0.1)
+	if scale := dilation.Scale(2); scale != 1 {
+		t.Fatalf("scale = %v, want 1 before anything is observed", scale)
+	}
+
+	// the first observation is taken as is
+	dilation.Observe(4)
+	if scale := dilation.Scale(2); math.Abs(scale-1.04) > 1e-9 {
+		t.Fatalf("scale = %v, want 1.04 with a full buffer", scale)
+	}
+	if scale := dilation.Scale(4); scale != 1 {
+		t.Fatalf("scale = %v, want 1 on target", scale)
+	}
+	if scale := dilation.Scale(20); math.Abs(scale-0.9) > 1e-9 {
+		t.Fatalf("scale = %v, want it clamped to 0.9", scale)
+	}
+}
+
+func TestObserveSmoothsDepth(t *testing.T) {
+	dilation := NewDilation(0.02, 0.1)
+	dilation.Observe(2)
+	dilation.Observe(12)
+	if depth := dilation.Depth(); math.Abs(depth-3) > 1e-9 {
+		t.Fatalf("depth = %v, want a single spike smoothed to 3", depth)
+	}
+
+	for range 200 {
+		dilation.Observe(0)
+	}
+	if depth := dilation.Depth(); depth > 1e-6 {
+		t.Fatalf("depth = %v, want it to settle on 0", depth)
+	}
+	if scale := dilation.Scale(2); math.Abs(scale-0.96) > 1e-6 {
+		t.Fatalf("scale = %v, want 0.96 with an empty buffer", scale)
+	}
+
+	dilation.Reset()
+	if scale := dilation.Scale(2); scale != 1 {
+		t.Fatalf("scale = %v, want 1 after a reset", scale)
+	}
+}
+
+func TestArrivals(t *testing.T) {
+	arrivals := NewArrivals()
+
+	// snapshots every 10 frames with a steady 5 frame transit
+	for i := 1; i <= 50; i++ {
+		arrivals.Observe(i*10, 1000+i*10+5)
+	}
+	if jitter := arrivals.Jitter(); jitter != 0 {
+		t.Fatalf("jitter = %v, want 0 for steady arrivals", jitter)
+	}
+	if frame := arrivals.ServerFrame(1505); math.Abs(frame-500) > 1e-9 {
+		t.Fatalf("server frame = %v, want 500", frame)
+	}
+
+	// transit alternating between 5 and 9 frames
+	for i := 51; i <= 300; i++ {
+		transit := 5
+		if i%2 == 0 {
+			transit = 9
+		}
+		arrivals.Observe(i*10, 1000+i*10+transit)
+	}
+	if jitter := arrivals.Jitter(); math.Abs(jitter-4) > 0.01 {
+		t.Fatalf("jitter = %v, want 4", jitter)
+	}
+	if frame := arrivals.ServerFrame(4007); math.Abs(frame-3000) > 0.5 {
+		t.Fatalf("server frame = %v, want about 3000 for the average transit", frame)
+	}
+}
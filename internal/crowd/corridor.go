@@ -0,0 +1,74 @@
+package crowd
+
+import (
+	"slices"
+
+	"github.com/kkevinchou/izzet/internal/navmesh"
+)
+
+// updateCorridor trims the polygons the agent has left behind from the front of
+// its corridor and finds the corners of the straight path from its current
+// position. running the funnel from where the agent actually is, rather than
+// from where the path was found, keeps the path tight when avoidance pushes the
+// agent to the side and lets it cut corners it can now see past. it returns
+// false if the agent is too far off the corridor to follow it
+func (c *Crowd) updateCorridor(agent *Agent) bool {
+	if c.navMesh == nil || len(agent.Corridor) == 0 || !c.validCorridor(agent.Corridor) {
+		return false
+	}
+
+	_, ref, _ := navmesh.FindNearestPolygon(c.navMesh, agent.Position, agent.Filter)
+	if ref == navmesh.InvalidPolyRef {
+		return false
+	}
+
+	if i := slices.Index(agent.Corridor, ref); i != -1 {
+		agent.Corridor = agent.Corridor[i:]
+	} else if i := c.adjacentCorridorIndex(ref, agent.Corridor); i != -1 {
+		// the agent was pushed onto a polygon next to its corridor
+		agent.Corridor = append([]navmesh.PolyRef{ref}, agent.Corridor[i:]...)
+	} else {
+		return false
+	}
+
+	path, connections := navmesh.FindStraightPathWithConnections(c.navMesh, agent.Position, agent.Target, agent.Corridor)
+	if len(path) == 0 {
+		return false
+	}
+
+	// the first point of the path is the agent's position
+	corners, cornerConnections := path[1:], connections[1:]
+	for len(corners) > 1 && cornerConnections[0] == -1 && corners[0].Sub(agent.Position).Len() < cornerEpsilon {
+		corners, cornerConnections = corners[1:], cornerConnections[1:]
+	}
+	agent.Corners = corners
+	agent.CornerConnections = cornerConnections
+	return true
+}
+
+// adjacentCorridorIndex returns the index of the furthest polygon along the
+// corridor that shares an edge with the polygon, or -1. off-mesh connections
+// aren't walkable from the side so the search stops at the first one
+func (c *Crowd) adjacentCorridorIndex(ref navmesh.PolyRef, corridor []navmesh.PolyRef) int {
+	index := -1
+	for i, corridorRef := range corridor {
+		if corridorRef.IsOffMeshConnection() {
+			break
+		}
+		if _, _, ok := navmesh.GetPortal(c.navMesh, ref, corridorRef); ok {
+			index = i
+		}
+	}
+	return index
+}
+
+// validCorridor returns whether every polygon in the corridor is still on the
+// nav mesh, corridors through tiles rebuilt since they were found aren't
+func (c *Crowd) validCorridor(corridor []navmesh.PolyRef) bool {
+	for _, ref := range corridor {
+		if !c.navMesh.ValidPolyRef(ref) {
+			return false
+		}
+	}
+	return true
+}
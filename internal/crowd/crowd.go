@@ -0,0 +1,388 @@
+package crowd
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/navmesh"
+)
+
+// cornerEpsilon is how close an agent has to be to a corner to move on to the
+// next one
+const cornerEpsilon = 0.01
+
+// Config holds the settings shared by every agent in a crowd
+type Config struct {
+	// NeighborDist is how far away other agents are taken into account for
+	// avoidance, measured between their centers
+	NeighborDist float64
+	// NeighborHeight is how far apart vertically agents can be while still
+	// avoiding each other so agents on different floors don't interact
+	NeighborHeight float64
+	// MaxNeighbors caps how many of the nearest agents each agent avoids
+	MaxNeighbors int
+	// TimeHorizon is how many seconds ahead collisions between agents are avoided
+	TimeHorizon float64
+	// ArrivalDistance is how close an agent has to be to its target, or to the
+	// start of an off-mesh connection, to reach it
+	ArrivalDistance float64
+	// SlowDownDistance is the distance from the target at which agents start
+	// slowing down, zero disables slowing down
+	SlowDownDistance float64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		NeighborDist:     6,
+		NeighborHeight:   2,
+		MaxNeighbors:     8,
+		TimeHorizon:      1.5,
+		ArrivalDistance:  0.5,
+		SlowDownDistance: 1,
+	}
+}
+
+// AgentParams are the per agent settings of a crowd agent
+type AgentParams struct {
+	Radius   float64
+	MaxSpeed float64
+	// Priority decides how much of the avoidance an agent takes on, when two
+	// agents meet each one steers out of the way in proportion to the other's
+	// priority so higher priority agents hold their course
+	Priority float64
+}
+
+type AgentState string
+
+const (
+	// AgentStateIdle agents have no target, they stand still unless other agents
+	// push them out of the way
+	AgentStateIdle AgentState = "IDLE"
+	// AgentStateMoving agents are following their corridor to their target
+	AgentStateMoving AgentState = "MOVING"
+	// AgentStateOffMesh agents have reached the start of an off-mesh connection
+	// and wait for the caller to move them across with CompleteOffMeshConnection
+	AgentStateOffMesh AgentState = "OFFMESH"
+)
+
+// Agent is a member of the crowd. the caller keeps Position in sync with its
+// entity and applies Velocity after each update
+type Agent struct {
+	ID     int
+	Params AgentParams
+	// Filter is the query filter the agent's corridor was found with, nil lets
+	// the agent onto every area
+	Filter *navmesh.QueryFilter
+
+	State           AgentState
+	Position        mgl64.Vec3
+	Velocity        mgl64.Vec3
+	DesiredVelocity mgl64.Vec3
+
+	Target mgl64.Vec3
+	// Corridor is the path of polygons from the polygon the agent is on to the
+	// target's. it's trimmed as the agent moves along so it always starts at the
+	// agent's polygon
+	Corridor []navmesh.PolyRef
+	// Corners is the straight path from the agent's position along the corridor,
+	// not including the position itself. CornerConnections holds the off-mesh
+	// connection that starts at each corner or -1
+	Corners           []mgl64.Vec3
+	CornerConnections []int
+
+	// Replan is set once the agent has strayed off its corridor, the corridor is
+	// kept until a new one is set with SetTarget
+	Replan bool
+
+	// OffMeshConnection is the off-mesh connection the agent has reached while in
+	// AgentStateOffMesh, the agent crosses it from OffMeshStart to OffMeshEnd
+	OffMeshConnection int
+	OffMeshStart      mgl64.Vec3
+	OffMeshEnd        mgl64.Vec3
+}
+
+// Crowd moves agents along their corridors while steering them around each
+// other. agents are updated in order of their ids so updates are deterministic
+type Crowd struct {
+	config  Config
+	navMesh *navmesh.CompiledNavMesh
+	agents  map[int]*Agent
+}
+
+func New(config Config) *Crowd {
+	return &Crowd{config: config, agents: map[int]*Agent{}}
+}
+
+// SetNavMesh sets the nav mesh agents move on, a nil nav mesh lets agents move
+// anywhere
+func (c *Crowd) SetNavMesh(nm *navmesh.CompiledNavMesh) {
+	c.navMesh = nm
+}
+
+// AddAgent adds an agent with the id at the position, or returns the existing
+// agent if there already is one with the id
+func (c *Crowd) AddAgent(id int, position mgl64.Vec3, params AgentParams) *Agent {
+	if agent, ok := c.agents[id]; ok {
+		return agent
+	}
+	agent := &Agent{ID: id, Params: params, State: AgentStateIdle, Position: position, OffMeshConnection: -1}
+	c.agents[id] = agent
+	return agent
+}
+
+func (c *Crowd) RemoveAgent(id int) {
+	delete(c.agents, id)
+}
+
+// Agent returns the agent with the id, or nil
+func (c *Crowd) Agent(id int) *Agent {
+	return c.agents[id]
+}
+
+// Agents returns the agents sorted by id
+func (c *Crowd) Agents() []*Agent {
+	var agents []*Agent
+	for _, id := range slices.Sorted(maps.Keys(c.agents)) {
+		agents = append(agents, c.agents[id])
+	}
+	return agents
+}
+
+// SetTarget sends the agent to the target along the corridor, which is a path of
+// polygons from the agent's polygon to the target's e.g. from navmesh.FindPath
+func (c *Crowd) SetTarget(id int, target mgl64.Vec3, corridor []navmesh.PolyRef) {
+	agent := c.agents[id]
+	if agent == nil {
+		return
+	}
+	if len(corridor) == 0 {
+		c.ResetTarget(id)
+		return
+	}
+
+	agent.State = AgentStateMoving
+	agent.Target = target
+	agent.Corridor = slices.Clone(corridor)
+	agent.Corners = nil
+	agent.CornerConnections = nil
+	agent.Replan = false
+	agent.OffMeshConnection = -1
+}
+
+// ResetTarget stops the agent, agents crossing an off-mesh connection keep their
+// state until they complete it
+func (c *Crowd) ResetTarget(id int) {
+	agent := c.agents[id]
+	if agent == nil {
+		return
+	}
+	if agent.State != AgentStateOffMesh {
+		agent.State = AgentStateIdle
+	}
+	agent.Corridor = nil
+	agent.Corners = nil
+	agent.CornerConnections = nil
+	agent.Replan = false
+}
+
+// CompleteOffMeshConnection places the agent at the end of the off-mesh
+// connection it was crossing and resumes following its corridor past it
+func (c *Crowd) CompleteOffMeshConnection(id int) {
+	agent := c.agents[id]
+	if agent == nil || agent.State != AgentStateOffMesh {
+		return
+	}
+
+	agent.Position = agent.OffMeshEnd
+	agent.State = AgentStateIdle
+	if i := slices.Index(agent.Corridor, navmesh.OffMeshConnectionRef(agent.OffMeshConnection)); i != -1 && i+1 < len(agent.Corridor) {
+		agent.Corridor = agent.Corridor[i+1:]
+		agent.State = AgentStateMoving
+	}
+	agent.OffMeshConnection = -1
+	agent.Corners = nil
+	agent.CornerConnections = nil
+}
+
+// Update advances the crowd by delta. each moving agent first steers towards the
+// next corner of its corridor, then the velocities of all the agents are
+// adjusted to avoid each other and clamped to the nav mesh, and finally the
+// agents are moved
+func (c *Crowd) Update(delta time.Duration) {
+	dt := delta.Seconds()
+	if dt <= 0 {
+		return
+	}
+
+	agents := c.Agents()
+	for _, agent := range agents {
+		agent.DesiredVelocity = mgl64.Vec3{}
+		if agent.State == AgentStateMoving && !agent.Replan {
+			c.steer(agent)
+		}
+	}
+
+	velocities := make([]mgl64.Vec3, len(agents))
+	for i, agent := range agents {
+		if agent.State == AgentStateOffMesh {
+			continue
+		}
+		velocities[i] = c.avoid(agent, agents, dt)
+	}
+
+	for i, agent := range agents {
+		if agent.State == AgentStateOffMesh {
+			agent.Velocity = mgl64.Vec3{}
+			continue
+		}
+		agent.Velocity, agent.Position = c.constrainToNavMesh(agent, velocities[i], dt)
+	}
+}
+
+// steer updates the agent's corridor and corners and sets its desired velocity
+// towards the next corner
+func (c *Crowd) steer(agent *Agent) {
+	if !c.updateCorridor(agent) {
+		agent.Replan = true
+		return
+	}
+
+	if len(agent.Corners) == 0 {
+		agent.State = AgentStateIdle
+		return
+	}
+
+	next := agent.Corners[0]
+	distance := next.Sub(agent.Position).Len()
+	if len(agent.Corners) == 1 && distance < c.config.ArrivalDistance {
+		agent.State = AgentStateIdle
+		agent.Corridor = nil
+		agent.Corners = nil
+		agent.CornerConnections = nil
+		return
+	}
+
+	if connection := agent.CornerConnections[0]; connection != -1 && distance < c.config.ArrivalDistance {
+		agent.State = AgentStateOffMesh
+		agent.OffMeshConnection = connection
+		agent.OffMeshStart = next
+		agent.OffMeshEnd = agent.Corners[1]
+		return
+	}
+
+	direction := next.Sub(agent.Position)
+	direction[1] = 0
+	if direction.LenSqr() == 0 {
+		return
+	}
+
+	speed := agent.Params.MaxSpeed
+	if len(agent.Corners) == 1 && c.config.SlowDownDistance > 0 {
+		speed *= min(distance/c.config.SlowDownDistance, 1)
+	}
+	agent.DesiredVelocity = direction.Normalize().Mul(speed)
+}
+
+// avoid returns the velocity closest to the agent's desired velocity that
+// doesn't run into its neighbors
+func (c *Crowd) avoid(agent *Agent, agents []*Agent, dt float64) mgl64.Vec3 {
+	neighbors := c.neighbors(agent, agents)
+	velocity := avoidanceVelocity(
+		agent.ID,
+		xz(agent.Position),
+		xz(agent.Velocity),
+		xz(agent.DesiredVelocity),
+		agent.Params.Radius,
+		agent.Params.MaxSpeed,
+		c.config.TimeHorizon,
+		dt,
+		neighbors,
+	)
+	return mgl64.Vec3{velocity.X(), 0, velocity.Y()}
+}
+
+// neighbors returns the agents nearest to the agent, sorted by distance and then
+// id
+func (c *Crowd) neighbors(agent *Agent, agents []*Agent) []neighbor {
+	type candidate struct {
+		agent  *Agent
+		distSq float64
+	}
+
+	var candidates []candidate
+	for _, other := range agents {
+		if other == agent || other.State == AgentStateOffMesh {
+			continue
+		}
+		offset := other.Position.Sub(agent.Position)
+		if offset.Y() > c.config.NeighborHeight || offset.Y() < -c.config.NeighborHeight {
+			continue
+		}
+		offset[1] = 0
+		distSq := offset.LenSqr()
+		if distSq > c.config.NeighborDist*c.config.NeighborDist {
+			continue
+		}
+		candidates = append(candidates, candidate{agent: other, distSq: distSq})
+	}
+
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if a.distSq < b.distSq {
+			return -1
+		} else if a.distSq > b.distSq {
+			return 1
+		}
+		return a.agent.ID - b.agent.ID
+	})
+	if len(candidates) > c.config.MaxNeighbors {
+		candidates = candidates[:c.config.MaxNeighbors]
+	}
+
+	neighbors := make([]neighbor, 0, len(candidates))
+	for _, candidate := range candidates {
+		other := candidate.agent
+		neighbors = append(neighbors, neighbor{
+			ID:             other.ID,
+			Position:       xz(other.Position),
+			Velocity:       xz(other.Velocity),
+			Radius:         other.Params.Radius,
+			Responsibility: responsibility(agent.Params, other.Params),
+		})
+	}
+	return neighbors
+}
+
+// responsibility returns the share of avoiding a collision between two agents
+// that falls on the first one
+func responsibility(params, other AgentParams) float64 {
+	total := params.Priority + other.Priority
+	if total <= 0 {
+		return 0.5
+	}
+	return other.Priority / total
+}
+
+// constrainToNavMesh clamps the velocity so the agent slides along the walls of
+// the nav mesh rather than moving off it and returns it along with the agent's
+// new position, which is snapped onto the nav mesh surface
+func (c *Crowd) constrainToNavMesh(agent *Agent, velocity mgl64.Vec3, dt float64) (mgl64.Vec3, mgl64.Vec3) {
+	next := agent.Position.Add(velocity.Mul(dt))
+	if c.navMesh == nil {
+		return velocity, next
+	}
+
+	position, visited := navmesh.MoveAlongSurface(c.navMesh, agent.Position, next, agent.Filter)
+	if len(visited) == 0 {
+		return velocity, next
+	}
+
+	velocity = position.Sub(agent.Position)
+	velocity[1] = 0
+	return velocity.Mul(1 / dt), position
+}
+
+func xz(v mgl64.Vec3) mgl64.Vec2 {
+	return mgl64.Vec2{v.X(), v.Z()}
+}
@@ -0,0 +1,339 @@
+package crowd
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/navmesh"
+)
+
+const testDelta = time.Second / 60
+
+// gridNavMesh builds a nav mesh with a unit square polygon for every '#' in the
+// rows, row i covers z from i to i+1
+func gridNavMesh(rows []string) *navmesh.CompiledNavMesh {
+	width, height := len(rows[0]), len(rows)
+	vertex := func(x, z int) int { return x + z*(width+1) }
+	polygons := map[[2]int]int{}
+	for z, row := range rows {
+		for x, cell := range row {
+			if cell == '#' {
+				polygons[[2]int{x, z}] = len(polygons)
+			}
+		}
+	}
+	cell := func(x, z int) int {
+		if poly, ok := polygons[[2]int{x, z}]; ok {
+			return poly
+		}
+		return -1
+	}
+
+	var tile navmesh.CTile
+	for z := range height + 1 {
+		for x := range width + 1 {
+			tile.Vertices = append(tile.Vertices, mgl64.Vec3{float64(x), 0, float64(z)})
+		}
+	}
+	for z, row := range rows {
+		for x, c := range row {
+			if c != '#' {
+				continue
+			}
+			polygon := navmesh.CPolygon{
+				Vertices:      []int{vertex(x, z), vertex(x, z+1), vertex(x+1, z+1), vertex(x+1, z)},
+				PolyNeighbors: []int{cell(x-1, z), cell(x, z+1), cell(x+1, z), cell(x, z-1)},
+				Area:          navmesh.WALKABLE_AREA,
+			}
+			var verts []mgl64.Vec3
+			for _, v := range polygon.Vertices {
+				verts = append(verts, tile.Vertices[v])
+			}
+			tile.Polygons = append(tile.Polygons, polygon)
+			tile.DetailedVertices = append(tile.DetailedVertices, verts)
+			tile.DetailedPolygon = append(tile.DetailedPolygon, navmesh.CDetailedPolygon{Triangles: []navmesh.CDetailedTriangle{
+				{Vertices: [3]int{0, 1, 2}}, {Vertices: [3]int{0, 2, 3}},
+			}})
+		}
+	}
+	nm := &navmesh.CompiledNavMesh{Tiles: []navmesh.CTile{tile}}
+	navmesh.LinkTiles(nm)
+	return nm
+}
+
+var testParams = AgentParams{Radius: 0.4, MaxSpeed: 4, Priority: 1}
+
+// moveTo adds an agent at start and sends it to goal
+func moveTo(c *Crowd, nm *navmesh.CompiledNavMesh, id int, start, goal mgl64.Vec3, params AgentParams) *Agent {
+	agent := c.AddAgent(id, start, params)
+	c.SetTarget(id, goal, navmesh.FindPath(nm, start, goal, nil))
+	return agent
+}
+
+// runUntilIdle updates the crowd until every agent is idle, failing the test if
+// it takes longer than steps updates
+func runUntilIdle(t *testing.T, c *Crowd, steps int, check func()) {
+	t.Helper()
+	for range steps {
+		c.Update(testDelta)
+		if check != nil {
+			check()
+		}
+
+		idle := true
+		for _, agent := range c.Agents() {
+			if agent.State != AgentStateIdle {
+				idle = false
+			}
+		}
+		if idle {
+			return
+		}
+	}
+	t.Fatalf("agents didn't reach their targets within %d updates", steps)
+}
+
+func horizontalDistance(a, b mgl64.Vec3) float64 {
+	offset := a.Sub(b)
+	offset[1] = 0
+	return offset.Len()
+}
+
+func TestAgentFollowsCorridor(t *testing.T) {
+	nm := gridNavMesh([]string{
+		"#####",
+		"....#",
+		"....#",
+		"#####",
+	})
+	c := New(DefaultConfig())
+	c.SetNavMesh(nm)
+
+	start := mgl64.Vec3{0.5, 0, 0.5}
+	goal := mgl64.Vec3{0.5, 0, 3.5}
+	agent := moveTo(c, nm, 1, start, goal, testParams)
+
+	runUntilIdle(t, c, 600, func() {
+		if _, _, over := navmesh.FindNearestPolygon(nm, agent.Position, nil); !over {
+			t.Fatalf("agent left the nav mesh at %v", agent.Position)
+		}
+	})
+
+	if dist := horizontalDistance(agent.Position, goal); dist > DefaultConfig().ArrivalDistance {
+		t.Fatalf("agent stopped %v from the goal", dist)
+	}
+}
+
+func TestHeadOnAgentsAvoidEachOther(t *testing.T) {
+	nm := gridNavMesh([]string{
+		"##########",
+		"##########",
+		"##########",
+		"##########",
+	})
+	c := New(DefaultConfig())
+	c.SetNavMesh(nm)
+
+	left := mgl64.Vec3{0.5, 0, 2}
+	right := mgl64.Vec3{9.5, 0, 2}
+	a := moveTo(c, nm, 1, left, right, testParams)
+	b := moveTo(c, nm, 2, right, left, testParams)
+
+	// avoidance can let agents brush against each other slightly within a step
+	minDist := testParams.Radius*2 - 0.05
+	runUntilIdle(t, c, 600, func() {
+		if dist := horizontalDistance(a.Position, b.Position); dist < minDist {
+			t.Fatalf("agents overlapped, %v apart", dist)
+		}
+	})
+
+	if dist := horizontalDistance(a.Position, right); dist > DefaultConfig().ArrivalDistance {
+		t.Fatalf("first agent stopped %v from its goal", dist)
+	}
+	if dist := horizontalDistance(b.Position, left); dist > DefaultConfig().ArrivalDistance {
+		t.Fatalf("second agent stopped %v from its goal", dist)
+	}
+}
+
+func TestPriority(t *testing.T) {
+	nm := gridNavMesh([]string{
+		"##########",
+		"##########",
+		"##########",
+		"##########",
+	})
+	c := New(DefaultConfig())
+	c.SetNavMesh(nm)
+
+	left := mgl64.Vec3{0.5, 0, 2}
+	right := mgl64.Vec3{9.5, 0, 2}
+	high := testParams
+	high.Priority = 10
+	a := moveTo(c, nm, 1, left, right, high)
+	b := moveTo(c, nm, 2, right, left, testParams)
+
+	var deviationA, deviationB float64
+	runUntilIdle(t, c, 600, func() {
+		deviationA = max(deviationA, math.Abs(a.Position.Z()-2))
+		deviationB = max(deviationB, math.Abs(b.Position.Z()-2))
+	})
+
+	if deviationA >= deviationB {
+		t.Fatalf("high priority agent deviated %v, more than the low priority agent's %v", deviationA, deviationB)
+	}
+}
+
+func TestIdleAgentsMakeWay(t *testing.T) {
+	nm := gridNavMesh([]string{
+		"##########",
+		"##########",
+		"##########",
+	})
+	c := New(DefaultConfig())
+	c.SetNavMesh(nm)
+
+	start := mgl64.Vec3{0.5, 0, 1.5}
+	goal := mgl64.Vec3{9.5, 0, 1.5}
+	mover := moveTo(c, nm, 1, start, goal, testParams)
+	idle := c.AddAgent(2, mgl64.Vec3{5, 0, 1.5}, testParams)
+
+	minDist := testParams.Radius*2 - 0.05
+	runUntilIdle(t, c, 600, func() {
+		if dist := horizontalDistance(mover.Position, idle.Position); dist < minDist {
+			t.Fatalf("agents overlapped, %v apart", dist)
+		}
+		if _, _, over := navmesh.FindNearestPolygon(nm, idle.Position, nil); !over {
+			t.Fatalf("idle agent was pushed off the nav mesh to %v", idle.Position)
+		}
+	})
+
+	if dist := horizontalDistance(mover.Position, goal); dist > DefaultConfig().ArrivalDistance {
+		t.Fatalf("agent stopped %v from the goal", dist)
+	}
+}
+
+func TestCrowdIsDeterministic(t *testing.T) {
+	run := func() []mgl64.Vec3 {
+		nm := gridNavMesh([]string{
+			"##########",
+			"##########",
+			"##########",
+			"##########",
+			"##########",
+		})
+		c := New(DefaultConfig())
+		c.SetNavMesh(nm)
+
+		// two groups crossing paths, with an agent spawned on top of another
+		for i := range 4 {
+			z := 0.5 + float64(i)
+			moveTo(c, nm, i+1, mgl64.Vec3{0.5, 0, z}, mgl64.Vec3{9.5, 0, 4.5 - float64(i)}, testParams)
+			moveTo(c, nm, i+5, mgl64.Vec3{9.5, 0, z}, mgl64.Vec3{0.5, 0, z}, testParams)
+		}
+		moveTo(c, nm, 9, mgl64.Vec3{0.5, 0, 0.5}, mgl64.Vec3{9.5, 0, 0.5}, testParams)
+
+		for range 300 {
+			c.Update(testDelta)
+		}
+
+		var positions []mgl64.Vec3
+		for _, agent := range c.Agents() {
+			positions = append(positions, agent.Position)
+		}
+		return positions
+	}
+
+	first := run()
+	for range 3 {
+		again := run()
+		for i := range first {
+			if first[i] != again[i] {
+				t.Fatalf("agent %d ended at %v and then %v", i+1, first[i], again[i])
+			}
+		}
+	}
+}
+
+func TestOffMeshConnection(t *testing.T) {
+	nm := gridNavMesh([]string{"##.##"})
+	nm.OffMeshConnections = []navmesh.OffMeshConnection{{Start: mgl64.Vec3{1.5, 0, 0.5}, End: mgl64.Vec3{3.5, 0, 0.5}, Radius: 0.5}}
+	navmesh.LinkTiles(nm)
+
+	c := New(DefaultConfig())
+	c.SetNavMesh(nm)
+	goal := mgl64.Vec3{4.5, 0, 0.5}
+	agent := moveTo(c, nm, 1, mgl64.Vec3{0.5, 0, 0.5}, goal, testParams)
+
+	for range 120 {
+		c.Update(testDelta)
+		if agent.State == AgentStateOffMesh {
+			break
+		}
+	}
+	if agent.State != AgentStateOffMesh {
+		t.Fatalf("agent state = %s, want %s", agent.State, AgentStateOffMesh)
+	}
+	if agent.OffMeshConnection != 0 {
+		t.Fatalf("off-mesh connection = %d, want 0", agent.OffMeshConnection)
+	}
+
+	// agents crossing a connection are left alone
+	position := agent.Position
+	c.Update(testDelta)
+	if agent.Position != position {
+		t.Fatalf("agent moved from %v to %v while crossing", position, agent.Position)
+	}
+
+	c.CompleteOffMeshConnection(1)
+	if agent.Position != agent.OffMeshEnd {
+		t.Fatalf("agent position = %v, want the end of the connection %v", agent.Position, agent.OffMeshEnd)
+	}
+	runUntilIdle(t, c, 120, nil)
+	if dist := horizontalDistance(agent.Position, goal); dist > DefaultConfig().ArrivalDistance {
+		t.Fatalf("agent stopped %v from the goal", dist)
+	}
+}
+
+func TestReplanOffCorridor(t *testing.T) {
+	nm := gridNavMesh([]string{
+		"#####",
+		"#...#",
+		"#####",
+	})
+	c := New(DefaultConfig())
+	c.SetNavMesh(nm)
+
+	agent := moveTo(c, nm, 1, mgl64.Vec3{0.5, 0, 0.5}, mgl64.Vec3{4.5, 0, 0.5}, testParams)
+	// teleport the agent onto the far side of the loop
+	agent.Position = mgl64.Vec3{2.5, 0, 2.5}
+	c.Update(testDelta)
+
+	if !agent.Replan {
+		t.Fatalf("agent off its corridor wasn't flagged for replanning")
+	}
+	if agent.Velocity != (mgl64.Vec3{}) {
+		t.Fatalf("agent waiting for a new corridor moved at %v", agent.Velocity)
+	}
+}
+
+func TestReplanRebuiltTile(t *testing.T) {
+	nm := gridNavMesh([]string{"#####"})
+	c := New(DefaultConfig())
+	c.SetNavMesh(nm)
+
+	agent := moveTo(c, nm, 1, mgl64.Vec3{0.5, 0, 0.5}, mgl64.Vec3{4.5, 0, 0.5}, testParams)
+	c.Update(testDelta)
+	if agent.Replan {
+		t.Fatal("agent on a valid corridor was flagged for replanning")
+	}
+
+	// the rebuilt tile has the same polygons but the corridor refers to the old
+	// one
+	nm.SetTiles([]navmesh.CTile{nm.Tiles[0]})
+	c.Update(testDelta)
+	if !agent.Replan {
+		t.Fatal("agent with a corridor through a rebuilt tile wasn't flagged for replanning")
+	}
+}
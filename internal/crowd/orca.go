@@ -0,0 +1,252 @@
+package crowd
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// orcaEpsilon is the tolerance used when checking whether constraint lines are
+// parallel
+const orcaEpsilon = 0.00001
+
+// orcaLine is a half-plane constraint on an agent's velocity, valid velocities
+// are to the left of the line
+type orcaLine struct {
+	Point     mgl64.Vec2
+	Direction mgl64.Vec2
+}
+
+// neighbor is the state of another agent that's taken into account when picking
+// a velocity. the positions and velocities are on the xz plane
+type neighbor struct {
+	ID             int
+	Position       mgl64.Vec2
+	Velocity       mgl64.Vec2
+	Radius         float64
+	Responsibility float64
+}
+
+// avoidanceVelocity returns the velocity closest to preferred that avoids
+// colliding with the neighbors within timeHorizon, following the ORCA approach
+// of RVO2. each agent takes its neighbor's Responsibility share of avoiding the
+// collision. when no velocity satisfies every neighbor the one that violates
+// them the least is picked
+func avoidanceVelocity(id int, position, velocity, preferred mgl64.Vec2, radius, maxSpeed, timeHorizon, timeStep float64, neighbors []neighbor) mgl64.Vec2 {
+	lines := make([]orcaLine, 0, len(neighbors))
+	invTimeHorizon := 1 / timeHorizon
+
+	for _, other := range neighbors {
+		relativePosition := other.Position.Sub(position)
+		relativeVelocity := velocity.Sub(other.Velocity)
+		distSq := relativePosition.LenSqr()
+		combinedRadius := radius + other.Radius
+		combinedRadiusSq := combinedRadius * combinedRadius
+
+		var line orcaLine
+		var u mgl64.Vec2
+
+		if distSq > combinedRadiusSq {
+			// no collision yet
+			w := relativeVelocity.Sub(relativePosition.Mul(invTimeHorizon))
+			wLengthSq := w.LenSqr()
+			dotProduct := w.Dot(relativePosition)
+
+			if dotProduct < 0 && dotProduct*dotProduct > combinedRadiusSq*wLengthSq {
+				// project on the cut-off circle
+				wLength := math.Sqrt(wLengthSq)
+				unitW := w.Mul(1 / wLength)
+				line.Direction = mgl64.Vec2{unitW.Y(), -unitW.X()}
+				u = unitW.Mul(combinedRadius*invTimeHorizon - wLength)
+			} else {
+				// project on the legs
+				leg := math.Sqrt(distSq - combinedRadiusSq)
+				if det(relativePosition, w) > 0 {
+					line.Direction = mgl64.Vec2{
+						relativePosition.X()*leg - relativePosition.Y()*combinedRadius,
+						relativePosition.X()*combinedRadius + relativePosition.Y()*leg,
+					}.Mul(1 / distSq)
+				} else {
+					line.Direction = mgl64.Vec2{
+						relativePosition.X()*leg + relativePosition.Y()*combinedRadius,
+						-relativePosition.X()*combinedRadius + relativePosition.Y()*leg,
+					}.Mul(-1 / distSq)
+				}
+				u = line.Direction.Mul(relativeVelocity.Dot(line.Direction)).Sub(relativeVelocity)
+			}
+		} else {
+			// already colliding, resolve it within the time step
+			invTimeStep := 1 / timeStep
+			w := relativeVelocity.Sub(relativePosition.Mul(invTimeStep))
+			wLength := w.Len()
+
+			var unitW mgl64.Vec2
+			if wLength < orcaEpsilon {
+				// agents on top of each other that aren't moving apart get pushed
+				// in a direction that's deterministic on their ids
+				unitW = stableSeparationDirection(id, other.ID)
+			} else {
+				unitW = w.Mul(1 / wLength)
+			}
+			line.Direction = mgl64.Vec2{unitW.Y(), -unitW.X()}
+			u = unitW.Mul(combinedRadius*invTimeStep - wLength)
+		}
+
+		line.Point = velocity.Add(u.Mul(other.Responsibility))
+		lines = append(lines, line)
+	}
+
+	result, lineFail := linearProgram2(lines, maxSpeed, preferred, false)
+	if lineFail < len(lines) {
+		result = linearProgram3(lines, lineFail, maxSpeed, result)
+	}
+	return result
+}
+
+// linearProgram1 solves a one dimensional linear program on the line lineNo
+// subject to the lines before it and the max speed circle
+func linearProgram1(lines []orcaLine, lineNo int, radius float64, optVelocity mgl64.Vec2, directionOpt bool) (mgl64.Vec2, bool) {
+	line := lines[lineNo]
+	dotProduct := line.Point.Dot(line.Direction)
+	discriminant := dotProduct*dotProduct + radius*radius - line.Point.LenSqr()
+	if discriminant < 0 {
+		// the max speed circle fully invalidates the line
+		return mgl64.Vec2{}, false
+	}
+
+	sqrtDiscriminant := math.Sqrt(discriminant)
+	tLeft := -dotProduct - sqrtDiscriminant
+	tRight := -dotProduct + sqrtDiscriminant
+
+	for i := range lineNo {
+		denominator := det(line.Direction, lines[i].Direction)
+		numerator := det(lines[i].Direction, line.Point.Sub(lines[i].Point))
+
+		if math.Abs(denominator) <= orcaEpsilon {
+			// the lines are parallel
+			if numerator < 0 {
+				return mgl64.Vec2{}, false
+			}
+			continue
+		}
+
+		t := numerator / denominator
+		if denominator >= 0 {
+			tRight = min(tRight, t)
+		} else {
+			tLeft = max(tLeft, t)
+		}
+
+		if tLeft > tRight {
+			return mgl64.Vec2{}, false
+		}
+	}
+
+	if directionOpt {
+		if optVelocity.Dot(line.Direction) > 0 {
+			return line.Point.Add(line.Direction.Mul(tRight)), true
+		}
+		return line.Point.Add(line.Direction.Mul(tLeft)), true
+	}
+
+	t := line.Direction.Dot(optVelocity.Sub(line.Point))
+	if t < tLeft {
+		return line.Point.Add(line.Direction.Mul(tLeft)), true
+	} else if t > tRight {
+		return line.Point.Add(line.Direction.Mul(tRight)), true
+	}
+	return line.Point.Add(line.Direction.Mul(t)), true
+}
+
+// linearProgram2 solves a two dimensional linear program subject to the lines
+// and the max speed circle. it returns the index of the line it failed on, or
+// len(lines) if it succeeded
+func linearProgram2(lines []orcaLine, radius float64, optVelocity mgl64.Vec2, directionOpt bool) (mgl64.Vec2, int) {
+	var result mgl64.Vec2
+	if directionOpt {
+		// optVelocity is a unit direction
+		result = optVelocity.Mul(radius)
+	} else if optVelocity.LenSqr() > radius*radius {
+		result = optVelocity.Normalize().Mul(radius)
+	} else {
+		result = optVelocity
+	}
+
+	for i := range lines {
+		if det(lines[i].Direction, lines[i].Point.Sub(result)) > 0 {
+			// the result doesn't satisfy the line
+			next, ok := linearProgram1(lines, i, radius, optVelocity, directionOpt)
+			if !ok {
+				return result, i
+			}
+			result = next
+		}
+	}
+
+	return result, len(lines)
+}
+
+// linearProgram3 is the fallback when the lines can't all be satisfied, it
+// finds the velocity that minimizes the largest violation of the lines starting
+// from beginLine
+func linearProgram3(lines []orcaLine, beginLine int, radius float64, result mgl64.Vec2) mgl64.Vec2 {
+	var distance float64
+
+	for i := beginLine; i < len(lines); i++ {
+		if det(lines[i].Direction, lines[i].Point.Sub(result)) <= distance {
+			continue
+		}
+
+		var projLines []orcaLine
+		for j := range i {
+			var line orcaLine
+			determinant := det(lines[i].Direction, lines[j].Direction)
+
+			if math.Abs(determinant) <= orcaEpsilon {
+				if lines[i].Direction.Dot(lines[j].Direction) > 0 {
+					// the lines point in the same direction
+					continue
+				}
+				line.Point = lines[i].Point.Add(lines[j].Point).Mul(0.5)
+			} else {
+				t := det(lines[j].Direction, lines[i].Point.Sub(lines[j].Point)) / determinant
+				line.Point = lines[i].Point.Add(lines[i].Direction.Mul(t))
+			}
+
+			line.Direction = lines[j].Direction.Sub(lines[i].Direction).Normalize()
+			projLines = append(projLines, line)
+		}
+
+		optDirection := mgl64.Vec2{-lines[i].Direction.Y(), lines[i].Direction.X()}
+		if next, lineFail := linearProgram2(projLines, radius, optDirection, true); lineFail == len(projLines) {
+			// in principle this always succeeds, a failure is due to floating point
+			// error and the previous result is kept
+			result = next
+		}
+
+		distance = det(lines[i].Direction, lines[i].Point.Sub(result))
+	}
+
+	return result
+}
+
+func det(a, b mgl64.Vec2) float64 {
+	return a.X()*b.Y() - a.Y()*b.X()
+}
+
+// stableSeparationDirection returns a pseudorandom direction for selfID to move
+// away from otherID that's deterministic on the ids, the direction for otherID
+// is the opposite
+func stableSeparationDirection(selfID, otherID int) mgl64.Vec2 {
+	a := selfID
+	b := otherID
+	sign := 1.0
+	if a > b {
+		a, b = b, a
+		sign = -1
+	}
+
+	hash := uint32(a)*73856093 ^ uint32(b)*19349663
+	angle := float64(hash&0xffff) / 0x10000 * 2 * math.Pi
+	return mgl64.Vec2{math.Cos(angle) * sign, math.Sin(angle) * sign}
+}
@@ -0,0 +1,41 @@
+package damage
+
+import "math"
+
+// Type is the kind of damage that's dealt, resistances reduce the damage of a
+// single type
+type Type string
+
+const (
+	TypeBallistic Type = "BALLISTIC"
+	TypeMelee     Type = "MELEE"
+)
+
+var Types = []Type{TypeBallistic, TypeMelee}
+
+// armorScale is the amount of armor that halves the damage taken
+const armorScale float64 = 100
+
+// Modifiers reduce the damage an entity takes
+type Modifiers struct {
+	// Armor reduces damage of every type by armor / (armor + 100), so the
+	// reduction falls off as armor is stacked
+	Armor float64
+	// Resistances reduce damage of a type by a fraction up to 1, negative
+	// resistances increase the damage taken
+	Resistances map[Type]float64
+}
+
+// Apply returns how much of the damage is taken after the modifiers, rounded to
+// the nearest whole amount. resistances are applied before armor
+func Apply(amount int, damageType Type, modifiers Modifiers) int {
+	if amount <= 0 {
+		return 0
+	}
+
+	multiplier := 1 - min(modifiers.Resistances[damageType], 1)
+	if modifiers.Armor > 0 {
+		multiplier *= armorScale / (armorScale + modifiers.Armor)
+	}
+	return int(math.Round(float64(amount) * multiplier))
+}
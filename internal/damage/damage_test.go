@@ -0,0 +1,30 @@
+package damage
+
+import "testing"
+
+func TestApply(t *testing.T) {
+	testCases := []struct {
+		name      string
+		amount    int
+		modifiers Modifiers
+		want      int
+	}{
+		{name: "no modifiers", amount: 50, want: 50},
+		{name: "armor", amount: 50, modifiers: Modifiers{Armor: 100}, want: 25},
+		{name: "negative armor", amount: 50, modifiers: Modifiers{Armor: -100}, want: 50},
+		{name: "resistance", amount: 50, modifiers: Modifiers{Resistances: map[Type]float64{TypeBallistic: 0.2}}, want: 40},
+		{name: "other type resistance", amount: 50, modifiers: Modifiers{Resistances: map[Type]float64{TypeMelee: 0.2}}, want: 50},
+		{name: "immune", amount: 50, modifiers: Modifiers{Resistances: map[Type]float64{TypeBallistic: 1.5}}, want: 0},
+		{name: "vulnerable", amount: 50, modifiers: Modifiers{Resistances: map[Type]float64{TypeBallistic: -0.5}}, want: 75},
+		{name: "resistance and armor", amount: 50, modifiers: Modifiers{Armor: 25, Resistances: map[Type]float64{TypeBallistic: 0.5}}, want: 20},
+		{name: "negative damage", amount: -10, want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Apply(tc.amount, TypeBallistic, tc.modifiers); got != tc.want {
+				t.Fatalf("damage = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
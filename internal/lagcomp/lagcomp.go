@@ -0,0 +1,90 @@
+package lagcomp
+
+import "github.com/go-gl/mathgl/mgl64"
+
+type Transform struct {
+	Position mgl64.Vec3
+	Rotation mgl64.Quat
+}
+
+// Frame is the transforms of entities at the end of a command frame, by entity
+// id
+type Frame struct {
+	CommandFrame int
+	Transforms   map[int]Transform
+}
+
+// History keeps the frames of the most recent command frames so hit checks can
+// be done against the world as a client saw it
+type History struct {
+	frames []Frame
+	// next is the index the next frame is recorded at
+	next  int
+	count int
+}
+
+// NewHistory returns a history that keeps the last size frames
+func NewHistory(size int) *History {
+	return &History{frames: make([]Frame, max(size, 1))}
+}
+
+// Record adds the transforms for the command frame, replacing the oldest frame
+// once the history is full. frames are expected to be recorded in order
+func (h *History) Record(commandFrame int, transforms map[int]Transform) {
+	h.frames[h.next] = Frame{CommandFrame: commandFrame, Transforms: transforms}
+	h.next = (h.next + 1) % len(h.frames)
+	h.count = min(h.count+1, len(h.frames))
+}
+
+// Frame returns the most recent frame at or before the command frame, or the
+// oldest frame if the command frame is older than the history
+func (h *History) Frame(commandFrame int) (Frame, bool) {
+	if h.count == 0 {
+		return Frame{}, false
+	}
+
+	for i := 1; i <= h.count; i++ {
+		frame := h.frames[(h.next-i+len(h.frames))%len(h.frames)]
+		if frame.CommandFrame <= commandFrame {
+			return frame, true
+		}
+	}
+	return h.frames[(h.next-h.count+len(h.frames))%len(h.frames)], true
+}
+
+// RewindFrame returns the command frame to check hits at for a shot taken at the
+// current frame by a client that saw the world as it was at perceivedFrame. the
+// rewind is limited to maxRewind frames so laggy clients can't hit targets that
+// have long since moved
+func RewindFrame(currentFrame, perceivedFrame, maxRewind int) int {
+	return min(max(perceivedFrame, currentFrame-maxRewind), currentFrame)
+}
+
+// Entity is an entity that can be moved back to where it was in a frame
+type Entity interface {
+	GetID() int
+	Transform() Transform
+	SetTransform(transform Transform)
+}
+
+// Rewind moves the entities that are in the frame to their transforms in it. the
+// rewound entities are returned along with a func that moves them back
+func Rewind[T Entity](frame Frame, entities []T) ([]T, func()) {
+	var rewound []T
+	var restore []Transform
+	for _, e := range entities {
+		transform, ok := frame.Transforms[e.GetID()]
+		if !ok {
+			continue
+		}
+		rewound = append(rewound, e)
+		restore = append(restore, e.Transform())
+		e.SetTransform(transform)
+	}
+
+	return rewound, func() {
+		for i, e := range rewound {
+			e.SetTransform(restore[i])
+		}
+	}
+}
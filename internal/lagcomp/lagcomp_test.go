@@ -0,0 +1,125 @@
+package lagcomp
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/collision"
+	"github.com/kkevinchou/izzet/internal/collision/collider"
+)
+
+// capsuleEntity is a unit radius capsule standing at its position
+type capsuleEntity struct {
+	id        int
+	transform Transform
+}
+
+func (e *capsuleEntity) GetID() int                       { return e.id }
+func (e *capsuleEntity) Transform() Transform             { return e.transform }
+func (e *capsuleEntity) SetTransform(transform Transform) { e.transform = transform }
+func (e *capsuleEntity) HasTriMeshCollider() bool         { return false }
+func (e *capsuleEntity) TriMeshCollider() collider.TriMesh {
+	return collider.TriMesh{}
+}
+func (e *capsuleEntity) HasCapsuleCollider() bool { return true }
+func (e *capsuleEntity) CapsuleCollider() collider.Capsule {
+	position := e.transform.Position
+	return collider.Capsule{Radius: 1, Bottom: position.Add(mgl64.Vec3{0, 1, 0}), Top: position.Add(mgl64.Vec3{0, 2, 0})}
+}
+func (e *capsuleEntity) BoundingBox() collider.BoundingBox {
+	position := e.transform.Position
+	return collider.BoundingBox{MinVertex: position.Sub(mgl64.Vec3{1, 0, 1}), MaxVertex: position.Add(mgl64.Vec3{1, 3, 1})}
+}
+
+// movingHistory records an entity moving 1 unit along x per command frame,
+// from x = 0 at frame 1 to x = 9 at frame 10
+func movingHistory(size int) *History {
+	history := NewHistory(size)
+	for frame := 1; frame <= 10; frame++ {
+		history.Record(frame, map[int]Transform{
+			1: {Position: mgl64.Vec3{float64(frame - 1), 0, 0}, Rotation: mgl64.QuatIdent()},
+		})
+	}
+	return history
+}
+
+func TestHistoryFrame(t *testing.T) {
+	history := movingHistory(5)
+
+	testCases := []struct {
+		name         string
+		commandFrame int
+		want         int
+	}{
+		{name: "recorded", commandFrame: 8, want: 8},
+		{name: "latest", commandFrame: 10, want: 10},
+		{name: "ahead of the history", commandFrame: 12, want: 10},
+		{name: "older than the history", commandFrame: 2, want: 6},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			frame, ok := history.Frame(tc.commandFrame)
+			if !ok || frame.CommandFrame != tc.want {
+				t.Fatalf("frame = %d, %v, want %d", frame.CommandFrame, ok, tc.want)
+			}
+		})
+	}
+
+	if _, ok := NewHistory(5).Frame(1); ok {
+		t.Fatal("expected no frame in an empty history")
+	}
+}
+
+func TestRewindFrame(t *testing.T) {
+	if got := RewindFrame(100, 90, 20); got != 90 {
+		t.Fatalf("rewind frame = %d, want 90", got)
+	}
+	if got := RewindFrame(100, 50, 20); got != 80 {
+		t.Fatalf("rewind frame = %d, want it clamped to 80", got)
+	}
+	if got := RewindFrame(100, 105, 20); got != 100 {
+		t.Fatalf("rewind frame = %d, want it clamped to 100", got)
+	}
+}
+
+func TestRewindClosestHit(t *testing.T) {
+	history := movingHistory(10)
+	target := &capsuleEntity{id: 1, transform: Transform{Position: mgl64.Vec3{9, 0, 0}, Rotation: mgl64.QuatIdent()}}
+	bystander := &capsuleEntity{id: 2, transform: Transform{Position: mgl64.Vec3{3, 0, 5}, Rotation: mgl64.QuatIdent()}}
+	entities := []*capsuleEntity{target, bystander}
+
+	// a shot down the z axis at x = 3, where the target was at frame 4
+	line := collider.Line{P1: mgl64.Vec3{3, 1.5, -10}, P2: mgl64.Vec3{3, 1.5, 10}}
+
+	if id, _, hit := collision.ClosestHit(line, entities); !hit || id != 2 {
+		t.Fatalf("hit = %d, %v, want the bystander without rewinding", id, hit)
+	}
+
+	frame, _ := history.Frame(RewindFrame(10, 4, 8))
+	rewound, restore := Rewind(frame, entities)
+	if len(rewound) != 1 || rewound[0] != target {
+		t.Fatalf("rewound = %v, want only the target", rewound)
+	}
+	id, point, hit := collision.ClosestHit(line, entities)
+	restore()
+
+	if !hit || id != 1 {
+		t.Fatalf("hit = %d, %v, want the rewound target", id, hit)
+	}
+	if point.Sub(mgl64.Vec3{3, 1.5, -1}).Len() > 1e-6 {
+		t.Fatalf("hit point = %v, want the front of the rewound target", point)
+	}
+	if target.transform.Position != (mgl64.Vec3{9, 0, 0}) {
+		t.Fatalf("target position = %v, want it restored", target.transform.Position)
+	}
+
+	// rewinding past the max window checks the target where it was at frame 6
+	frame, _ = history.Frame(RewindFrame(10, 4, 4))
+	_, restore = Rewind(frame, entities)
+	id, _, _ = collision.ClosestHit(line, entities)
+	restore()
+	if id != 2 {
+		t.Fatalf("hit = %d, want the bystander once the rewind is clamped", id)
+	}
+}
@@ -39,6 +39,17 @@ type CompactHeightField struct {
 	maxDistance          int
 	maxRegionID          int
 	CellSize, CellHeight float64
+	// borderSize is the number of cells around the edges that were clipped by
+	// ClipBorder
+	borderSize int
+}
+
+// onTileBorder returns whether the cell is next to the clipped border of a tile
+func (chf *CompactHeightField) onTileBorder(x, z int) bool {
+	if chf.borderSize <= 0 {
+		return false
+	}
+	return x == chf.borderSize || z == chf.borderSize || x == chf.width-chf.borderSize-1 || z == chf.height-chf.borderSize-1
 }
 
 func NewCompactHeightField(walkableHeight, walkableClimb int, hf *HeightField) *CompactHeightField {
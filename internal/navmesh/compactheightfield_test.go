@@ -12,8 +12,8 @@ func TestCompactHeightField(t *testing.T) {
 	// hf.AddVoxel(0, 0, 0, true)
 	// hf.AddVoxel(1, 0, 0, true)
 
-	hf.AddSpan(0, 0, 0, 1, true, 1)
-	hf.AddSpan(1, 0, 0, 1, true, 1)
+	hf.AddSpan(0, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 0, 0, 1, WALKABLE_AREA, 1)
 
 	chf := NewCompactHeightField(1, 0, hf)
 
@@ -28,10 +28,10 @@ func TestCompactHeightField(t *testing.T) {
 func TestClimbableHeight(t *testing.T) {
 	hf := NewHeightField(100, 100, mgl64.Vec3{0, 0, 0}, mgl64.Vec3{100, 100, 100}, 1, 1)
 
-	hf.AddSpan(0, 0, 0, 1, true, 1)
-	hf.AddSpan(0, 0, 1, 1, true, 1)
+	hf.AddSpan(0, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(0, 0, 1, 1, WALKABLE_AREA, 1)
 
-	hf.AddSpan(1, 0, 0, 1, true, 1)
+	hf.AddSpan(1, 0, 0, 1, WALKABLE_AREA, 1)
 
 	chf := NewCompactHeightField(1, 1, hf)
 
@@ -47,11 +47,11 @@ func TestClimbableHeight(t *testing.T) {
 func TestNotClimbableHeight(t *testing.T) {
 	hf := NewHeightField(100, 100, mgl64.Vec3{0, 0, 0}, mgl64.Vec3{100, 100, 100}, 1, 1)
 
-	hf.AddSpan(0, 0, 0, 1, true, 1)
-	hf.AddSpan(0, 0, 1, 2, true, 1)
-	hf.AddSpan(0, 0, 2, 3, true, 1)
+	hf.AddSpan(0, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(0, 0, 1, 2, WALKABLE_AREA, 1)
+	hf.AddSpan(0, 0, 2, 3, WALKABLE_AREA, 1)
 
-	hf.AddSpan(1, 0, 0, 1, true, 1)
+	hf.AddSpan(1, 0, 0, 1, WALKABLE_AREA, 1)
 
 	chf := NewCompactHeightField(1, 1, hf)
 
@@ -64,10 +64,10 @@ func TestNotClimbableHeight(t *testing.T) {
 func TestNotWalkableHeight(t *testing.T) {
 	hf := NewHeightField(100, 100, mgl64.Vec3{0, 0, 0}, mgl64.Vec3{100, 100, 100}, 1, 1)
 
-	hf.AddSpan(0, 0, 0, 1, true, 1)
+	hf.AddSpan(0, 0, 0, 1, WALKABLE_AREA, 1)
 
-	hf.AddSpan(1, 0, 0, 1, true, 1)
-	hf.AddSpan(1, 0, 2, 3, true, 1)
+	hf.AddSpan(1, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 0, 2, 3, WALKABLE_AREA, 1)
 
 	chf := NewCompactHeightField(3, 0, hf)
 
@@ -1,12 +1,50 @@
 package navmesh
 
-import "github.com/go-gl/mathgl/mgl64"
+import (
+	"math"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// tileEdgeEpsilon is how far a vertex can be from a tile's border while still
+// being considered on it
+const tileEdgeEpsilon = 1e-3
+
+// PolyRef identifies a polygon within a compiled nav mesh
+type PolyRef struct {
+	Tile int
+	Poly int
+	// Salt is the salt of the tile when the ref was made, refs to a tile that has
+	// since been replaced are no longer valid
+	Salt uint32
+}
+
+var InvalidPolyRef = PolyRef{Tile: -1, Poly: -1}
 
 type CompiledNavMesh struct {
+	// Origin is the minimum corner of the tile at grid position (0, 0)
+	Origin mgl64.Vec3
+	// TileWidth is the width of a tile along x and z. a nav mesh with a TileWidth
+	// of zero is a single tile covering the whole volume
+	TileWidth float64
+	// WalkableClimb is the largest height difference between two polygon edges on
+	// a tile border that are still linked together
+	WalkableClimb float64
+	// Tiles keep their index for as long as they're on the nav mesh so polygon
+	// refs stay valid. removed tiles leave an empty slot behind that's reused by
+	// the next tile added
 	Tiles []CTile
+	// OffMeshConnections are linked to the polygons at their ends by LinkTiles
+	OffMeshConnections []OffMeshConnection
 }
 
 type CTile struct {
+	// X and Z are the tile's position in the tile grid
+	X, Z int
+	// Salt is bumped each time the tile in this slot is replaced or removed
+	Salt uint32
+
 	Vertices         []mgl64.Vec3
 	Polygons         []CPolygon
 	DetailedPolygon  []CDetailedPolygon
@@ -14,8 +52,28 @@ type CTile struct {
 }
 
 type CPolygon struct {
-	Vertices      []int
+	Vertices []int
+	// PolyNeighbors holds the neighboring polygon in the same tile for each edge,
+	// or -1 if there isn't one
 	PolyNeighbors []int
+	// Links connect edges on the tile border to polygons in neighboring tiles and
+	// the polygon to off-mesh connections that start on it. they're derived from
+	// the tiles by LinkTiles rather than baked
+	Links []CLink
+	// Area is the area type of the walkable surface the polygon was built from
+	Area AREA_TYPE
+}
+
+// CLink connects a polygon edge to a polygon in a neighboring tile. tiles are
+// built separately so the polygons on either side of a border don't share
+// vertices, Left and Right are the portion of the edge that the polygons share.
+// links to off-mesh connections have an Edge of -1 and Left and Right are both
+// the end of the connection
+type CLink struct {
+	Edge  int
+	Ref   PolyRef
+	Left  mgl64.Vec3
+	Right mgl64.Vec3
 }
 
 type CDetailedPolygon struct {
@@ -27,10 +85,18 @@ type CDetailedTriangle struct {
 	OnHull   [3]bool
 }
 
+// CompileNavMesh compiles a navigation mesh into a nav mesh made of a single tile
 func CompileNavMesh(inNavMesh *NavigationMesh) *CompiledNavMesh {
-	nm := &CompiledNavMesh{}
-	nm.Tiles = append(nm.Tiles, CTile{})
-	tile := &nm.Tiles[0]
+	return &CompiledNavMesh{
+		Origin: inNavMesh.Volume.MinVertex,
+		Tiles:  []CTile{CompileTile(inNavMesh)},
+	}
+}
+
+// CompileTile compiles a navigation mesh into a tile, positions are converted
+// to world space
+func CompileTile(inNavMesh *NavigationMesh) CTile {
+	var tile CTile
 
 	cs := inNavMesh.Mesh.CellSize
 	ch := inNavMesh.Mesh.CellHeight
@@ -46,6 +112,7 @@ func CompileNavMesh(inNavMesh *NavigationMesh) *CompiledNavMesh {
 		tile.Polygons = append(tile.Polygons, CPolygon{
 			Vertices:      p.Verts[:],
 			PolyNeighbors: p.polyNeighbor[:],
+			Area:          p.Area,
 		})
 	}
 
@@ -63,5 +130,214 @@ func CompileNavMesh(inNavMesh *NavigationMesh) *CompiledNavMesh {
 		}
 	}
 
-	return nm
+	return tile
+}
+
+// TileAt returns the index of the tile at the grid position, or -1 if there's
+// no tile there
+func (nm *CompiledNavMesh) TileAt(x, z int) int {
+	return slices.IndexFunc(nm.Tiles, func(tile CTile) bool {
+		return tile.X == x && tile.Z == z && len(tile.Polygons) > 0
+	})
+}
+
+// tileIndices returns the index of each tile by its grid position
+func (nm *CompiledNavMesh) tileIndices() map[[2]int]int {
+	indices := make(map[[2]int]int, len(nm.Tiles))
+	for i, tile := range nm.Tiles {
+		if len(tile.Polygons) > 0 {
+			indices[[2]int{tile.X, tile.Z}] = i
+		}
+	}
+	return indices
+}
+
+// ValidPolyRef returns whether the ref is to a polygon on the nav mesh. refs to
+// tiles that have been replaced since the ref was made aren't valid even if the
+// new tile has a polygon at the same index
+func (nm *CompiledNavMesh) ValidPolyRef(ref PolyRef) bool {
+	if ref.IsOffMeshConnection() {
+		return ref.Poly >= 0 && ref.Poly < len(nm.OffMeshConnections)
+	}
+	if ref.Tile < 0 || ref.Tile >= len(nm.Tiles) {
+		return false
+	}
+	tile := nm.Tiles[ref.Tile]
+	return tile.Salt == ref.Salt && ref.Poly >= 0 && ref.Poly < len(tile.Polygons)
+}
+
+// TilePosition returns the grid position of the tile containing the point
+func (nm *CompiledNavMesh) TilePosition(point mgl64.Vec3) (int, int) {
+	if nm.TileWidth == 0 {
+		return 0, 0
+	}
+	x := int(math.Floor((point.X() - nm.Origin.X()) / nm.TileWidth))
+	z := int(math.Floor((point.Z() - nm.Origin.Z()) / nm.TileWidth))
+	return x, z
+}
+
+// Clone copies the nav mesh so its tiles can be replaced and relinked without
+// affecting the original, the geometry of the tiles is shared
+func (nm *CompiledNavMesh) Clone() *CompiledNavMesh {
+	clone := *nm
+	clone.Tiles = slices.Clone(nm.Tiles)
+	for i := range clone.Tiles {
+		clone.Tiles[i].Polygons = slices.Clone(clone.Tiles[i].Polygons)
+	}
+	clone.OffMeshConnections = slices.Clone(nm.OffMeshConnections)
+	return &clone
+}
+
+// SetTiles replaces the tiles at the grid positions of the given tiles, tiles
+// without polygons remove the tile at their position. replaced and removed tiles
+// keep their slot with a new salt so refs to their old polygons are invalidated.
+// links are rebuilt
+func (nm *CompiledNavMesh) SetTiles(tiles []CTile) {
+	indices := nm.tileIndices()
+	for _, tile := range tiles {
+		position := [2]int{tile.X, tile.Z}
+		i, found := indices[position]
+		if len(tile.Polygons) == 0 {
+			if found {
+				delete(indices, position)
+				nm.Tiles[i] = CTile{X: tile.X, Z: tile.Z, Salt: nm.Tiles[i].Salt + 1}
+			}
+			continue
+		}
+
+		if !found {
+			i = slices.IndexFunc(nm.Tiles, func(tile CTile) bool { return len(tile.Polygons) == 0 })
+			if i == -1 {
+				tile.Salt = 0
+				nm.Tiles = append(nm.Tiles, tile)
+				indices[position] = len(nm.Tiles) - 1
+				continue
+			}
+			indices[position] = i
+		}
+		tile.Salt = nm.Tiles[i].Salt + 1
+		nm.Tiles[i] = tile
+	}
+	LinkTiles(nm)
+}
+
+// LinkTiles connects polygon edges on the border of each tile to the polygons
+// across the border in the neighboring tile, then attaches the off-mesh
+// connections. existing links are replaced
+func LinkTiles(nm *CompiledNavMesh) {
+	for i := range nm.Tiles {
+		for j := range nm.Tiles[i].Polygons {
+			nm.Tiles[i].Polygons[j].Links = nil
+		}
+	}
+
+	if nm.TileWidth != 0 {
+		indices := nm.tileIndices()
+		sides := [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+		for i := range nm.Tiles {
+			tile := &nm.Tiles[i]
+			for _, side := range sides {
+				neighbor, ok := indices[[2]int{tile.X + side[0], tile.Z + side[1]}]
+				if !ok {
+					continue
+				}
+				linkTileSide(nm, i, neighbor, side)
+			}
+		}
+	}
+
+	linkOffMeshConnections(nm)
+}
+
+// linkTileSide links the polygons of a tile to the neighboring tile on the given
+// side. edges are matched by overlapping along the border and by height
+func linkTileSide(nm *CompiledNavMesh, tileIndex, neighborIndex int, side [2]int) {
+	tile := &nm.Tiles[tileIndex]
+	neighbor := &nm.Tiles[neighborIndex]
+
+	// axis is the coordinate that's constant along the border, the edges are
+	// compared along the other axis
+	axis, along, position, direction := 0, 2, tile.X, side[0]
+	if side[0] == 0 {
+		axis, along, position, direction = 2, 0, tile.Z, side[1]
+	}
+
+	border := nm.Origin[axis] + float64(position+max(direction, 0))*nm.TileWidth
+
+	for pi := range tile.Polygons {
+		polygon := &tile.Polygons[pi]
+		for edge := range polygon.Vertices {
+			if polygon.PolyNeighbors[edge] != -1 {
+				continue
+			}
+
+			right := tile.Vertices[polygon.Vertices[edge]]
+			left := tile.Vertices[polygon.Vertices[(edge+1)%len(polygon.Vertices)]]
+			if math.Abs(right[axis]-border) > tileEdgeEpsilon || math.Abs(left[axis]-border) > tileEdgeEpsilon {
+				continue
+			}
+
+			for npi, neighborPolygon := range neighbor.Polygons {
+				for neighborEdge := range neighborPolygon.Vertices {
+					if neighborPolygon.PolyNeighbors[neighborEdge] != -1 {
+						continue
+					}
+
+					nv0 := neighbor.Vertices[neighborPolygon.Vertices[neighborEdge]]
+					nv1 := neighbor.Vertices[neighborPolygon.Vertices[(neighborEdge+1)%len(neighborPolygon.Vertices)]]
+					if math.Abs(nv0[axis]-border) > tileEdgeEpsilon || math.Abs(nv1[axis]-border) > tileEdgeEpsilon {
+						continue
+					}
+
+					portalRight, portalLeft, ok := edgeOverlap(right, left, nv0, nv1, along, nm.WalkableClimb)
+					if !ok {
+						continue
+					}
+
+					polygon.Links = append(polygon.Links, CLink{
+						Edge:  edge,
+						Ref:   PolyRef{Tile: neighborIndex, Poly: npi, Salt: neighbor.Salt},
+						Left:  portalLeft,
+						Right: portalRight,
+					})
+				}
+			}
+		}
+	}
+}
+
+// edgeOverlap returns the portion of the edge from right to left that overlaps
+// the edge from v0 to v1 along the given axis. edges overlap if they're within
+// climb of each other at both ends of the overlap
+func edgeOverlap(right, left, v0, v1 mgl64.Vec3, along int, climb float64) (mgl64.Vec3, mgl64.Vec3, bool) {
+	if math.Abs(left[along]-right[along]) < tileEdgeEpsilon || math.Abs(v1[along]-v0[along]) < tileEdgeEpsilon {
+		return mgl64.Vec3{}, mgl64.Vec3{}, false
+	}
+
+	// parameterize both edges along the axis, t = 0 at right and t = 1 at left
+	toT := func(value float64) float64 {
+		return (value - right[along]) / (left[along] - right[along])
+	}
+	t0, t1 := toT(v0[along]), toT(v1[along])
+	if t0 > t1 {
+		t0, t1 = t1, t0
+	}
+	tMin := math.Max(t0, 0)
+	tMax := math.Min(t1, 1)
+	if (tMax-tMin)*math.Abs(left[along]-right[along]) < tileEdgeEpsilon {
+		return mgl64.Vec3{}, mgl64.Vec3{}, false
+	}
+
+	portalRight := right.Add(left.Sub(right).Mul(tMin))
+	portalLeft := right.Add(left.Sub(right).Mul(tMax))
+
+	for _, p := range []mgl64.Vec3{portalRight, portalLeft} {
+		s := (p[along] - v0[along]) / (v1[along] - v0[along])
+		height := v0.Y() + (v1.Y()-v0.Y())*s
+		if math.Abs(height-p.Y()) > climb+tileEdgeEpsilon {
+			return mgl64.Vec3{}, mgl64.Vec3{}, false
+		}
+	}
+
+	return portalRight, portalLeft, true
 }
@@ -1,6 +1,10 @@
 package navmesh
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+
 	"github.com/go-gl/mathgl/mgl64"
 )
 
@@ -48,6 +52,9 @@ type ContourSet struct {
 	Contours             []Contour
 	RawContours          []RawContour
 	CellSize, CellHeight float64
+	// Warnings are problems with regions whose holes couldn't be merged into their
+	// outline, the holes are left as separate contours
+	Warnings []error
 }
 
 func BuildContours(chf *CompactHeightField, maxError float64, maxEdgeLength int) *ContourSet {
@@ -114,8 +121,7 @@ func BuildContours(chf *CompactHeightField, maxError float64, maxEdgeLength int)
 				verts := getContourPoints(x, z, int(i), chf, flags)
 				simplified := simplifyContour(verts, maxError, maxEdgeLength)
 
-				// TODO - implement
-				simplified = removeDegenderateSegments(simplified)
+				simplified = removeDegenerateSegments(simplified)
 
 				if len(simplified) >= 3 {
 					contour := Contour{
@@ -131,7 +137,7 @@ func BuildContours(chf *CompactHeightField, maxError float64, maxEdgeLength int)
 		}
 	}
 
-	// Merge holes
+	// merge holes
 	if len(contourSet.Contours) > 0 {
 		var numHoles int
 		winding := make([]int, len(contourSet.Contours))
@@ -144,19 +150,201 @@ func BuildContours(chf *CompactHeightField, maxError float64, maxEdgeLength int)
 				winding[i] = -1
 				numHoles++
 			}
-
 		}
 
-		// TODO - merge holes
 		if numHoles > 0 {
-			// panic("holes detected")
-			// numRegions := chf.maxRegionID + 1
+			// each region is expected to have a single outline, holes are
+			// negatively wound contours within it
+			regions := make([]contourRegion, chf.maxRegionID+1)
+			for i := range contourSet.Contours {
+				contour := &contourSet.Contours[i]
+				region := &regions[contour.RegionID]
+				if winding[i] > 0 {
+					if region.outline != nil {
+						contourSet.Warnings = append(contourSet.Warnings, fmt.Errorf("multiple outlines for region %d", contour.RegionID))
+					}
+					region.outline = contour
+				} else {
+					region.holes = append(region.holes, contourHole{contour: contour})
+				}
+			}
+
+			for i := range regions {
+				region := &regions[i]
+				if len(region.holes) == 0 {
+					continue
+				}
+				if region.outline == nil {
+					contourSet.Warnings = append(contourSet.Warnings, fmt.Errorf("bad outline for region %d, contour simplification is likely too aggressive", i))
+					continue
+				}
+				if err := mergeRegionHoles(region); err != nil {
+					contourSet.Warnings = append(contourSet.Warnings, err)
+				}
+			}
 		}
 	}
 
 	return contourSet
 }
 
+type contourHole struct {
+	contour    *Contour
+	minX, minZ int
+	leftmost   int
+}
+
+type contourRegion struct {
+	outline *Contour
+	holes   []contourHole
+}
+
+type potentialDiagonal struct {
+	vert int
+	dist int
+}
+
+// mergeRegionHoles connects each hole of the region to its outline with a pair
+// of overlapping edges, leaving a single contour that the polygon mesh can be
+// triangulated from. holes that can't be connected are left as they are and
+// reported in the error
+func mergeRegionHoles(region *contourRegion) error {
+	// sort holes from left to right
+	for i := range region.holes {
+		hole := &region.holes[i]
+		hole.minX, hole.minZ, hole.leftmost = findLeftmostVertex(hole.contour.Verts)
+	}
+	slices.SortFunc(region.holes, func(a, b contourHole) int {
+		if a.minX == b.minX {
+			return a.minZ - b.minZ
+		}
+		return a.minX - b.minX
+	})
+
+	outline := region.outline
+	var diags []potentialDiagonal
+	var errs []error
+
+	for i := range region.holes {
+		hole := region.holes[i].contour
+		index := -1
+		bestVertex := region.holes[i].leftmost
+
+		for range len(hole.Verts) {
+			// the hole vertex must be within the cone formed by three consecutive
+			// vertices of the outline
+			diags = diags[:0]
+			corner := hole.Verts[bestVertex]
+			for j := range outline.Verts {
+				if inContourCone(j, outline.Verts, corner) {
+					dx := outline.Verts[j].X - corner.X
+					dz := outline.Verts[j].Z - corner.Z
+					diags = append(diags, potentialDiagonal{vert: j, dist: dx*dx + dz*dz})
+				}
+			}
+
+			// prefer the shortest connection
+			slices.SortStableFunc(diags, func(a, b potentialDiagonal) int {
+				return a.dist - b.dist
+			})
+
+			// find a diagonal that doesn't intersect the outline or the remaining holes
+			for _, diag := range diags {
+				pt := outline.Verts[diag.vert]
+				intersects := intersectSegContour(pt, corner, diag.vert, outline.Verts)
+				for k := i; k < len(region.holes) && !intersects; k++ {
+					intersects = intersectSegContour(pt, corner, -1, region.holes[k].contour.Verts)
+				}
+				if !intersects {
+					index = diag.vert
+					break
+				}
+			}
+
+			if index != -1 {
+				break
+			}
+
+			// every diagonal from this vertex intersects something, try the next one
+			bestVertex = (bestVertex + 1) % len(hole.Verts)
+		}
+
+		if index == -1 {
+			errs = append(errs, fmt.Errorf("failed to find a merge point for a hole in region %d", outline.RegionID))
+			continue
+		}
+
+		mergeContours(outline, hole, index, bestVertex)
+	}
+	return errors.Join(errs...)
+}
+
+// mergeContours splices b into a, connecting vertex ia of a to vertex ib of b. b
+// is left empty
+func mergeContours(a, b *Contour, ia, ib int) {
+	verts := make([]SimplifiedVertex, 0, len(a.Verts)+len(b.Verts)+2)
+
+	// both contours are walked all the way around so the connecting vertices are
+	// repeated, forming the two edges of the bridge between them
+	for i := 0; i <= len(a.Verts); i++ {
+		verts = append(verts, a.Verts[(ia+i)%len(a.Verts)])
+	}
+	for i := 0; i <= len(b.Verts); i++ {
+		verts = append(verts, b.Verts[(ib+i)%len(b.Verts)])
+	}
+
+	a.Verts = verts
+	b.Verts = nil
+}
+
+func findLeftmostVertex(verts []SimplifiedVertex) (int, int, int) {
+	minX, minZ, leftmost := verts[0].X, verts[0].Z, 0
+	for i := 1; i < len(verts); i++ {
+		if verts[i].X < minX || (verts[i].X == minX && verts[i].Z < minZ) {
+			minX, minZ, leftmost = verts[i].X, verts[i].Z, i
+		}
+	}
+	return minX, minZ, leftmost
+}
+
+// inContourCone returns whether pj is within the cone formed by vertex i of the
+// contour and its neighbors
+func inContourCone(i int, verts []SimplifiedVertex, pj SimplifiedVertex) bool {
+	pi := verts[i]
+	pnext := verts[next(i, len(verts))]
+	pprev := verts[prev(i, len(verts))]
+
+	// pi is convex
+	if leftOn(pprev, pi, pnext) {
+		return left(pi, pj, pprev) && left(pj, pi, pnext)
+	}
+
+	// pi is reflex
+	return !(leftOn(pi, pj, pnext) && leftOn(pj, pi, pprev))
+}
+
+// intersectSegContour returns whether the segment from d0 to d1 intersects the
+// contour, ignoring edges incident to vertex i
+func intersectSegContour(d0, d1 SimplifiedVertex, i int, verts []SimplifiedVertex) bool {
+	for k := range verts {
+		k1 := next(k, len(verts))
+		if i == k || i == k1 {
+			continue
+		}
+
+		p0 := verts[k]
+		p1 := verts[k1]
+		if vequal(d0, p0) || vequal(d1, p0) || vequal(d0, p1) || vequal(d1, p1) {
+			continue
+		}
+
+		if intersect(d0, d1, p0, p1) {
+			return true
+		}
+	}
+	return false
+}
+
 func calcAreaOfPolygon2D(verts []SimplifiedVertex) int {
 	area := 0
 	j := len(verts) - 1
@@ -541,6 +729,14 @@ func distancePtSeg2D(x, z, px, pz, qx, qz int) float64 {
 	return dx*dx + dz*dz
 }
 
-func removeDegenderateSegments(vertices []SimplifiedVertex) []SimplifiedVertex {
+// removeDegenerateSegments removes adjacent vertices that are equal on the xz
+// plane, they would otherwise confuse triangulation
+func removeDegenerateSegments(vertices []SimplifiedVertex) []SimplifiedVertex {
+	for i := 0; i < len(vertices); i++ {
+		ni := next(i, len(vertices))
+		if vequal(vertices[i], vertices[ni]) {
+			vertices = slices.Delete(vertices, i, i+1)
+		}
+	}
 	return vertices
 }
@@ -2,6 +2,7 @@ package navmesh
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -27,3 +28,81 @@ func TestSimplifyContour(t *testing.T) {
 
 	simplifyContour(vertices, 1, 1)
 }
+
+// courtyard is a floor with a hole in the middle
+var courtyard = []string{
+	"############",
+	"############",
+	"############",
+	"###......###",
+	"###......###",
+	"###......###",
+	"###......###",
+	"############",
+	"############",
+	"############",
+}
+
+func TestBuildContoursMergesHoles(t *testing.T) {
+	chf := chfFromGrid(courtyard)
+	BuildDistanceField(chf)
+	BuildRegions(chf, 999, 0, 400)
+	contourSet := BuildContours(chf, 1, 12)
+	if len(contourSet.Warnings) != 0 {
+		t.Fatalf("expected no warnings, got %v", contourSet.Warnings)
+	}
+
+	var contours []Contour
+	for _, contour := range contourSet.Contours {
+		if len(contour.Verts) > 0 {
+			contours = append(contours, contour)
+		}
+	}
+	if len(contours) != 1 {
+		t.Fatalf("expected the hole to be merged into a single contour, got %d contours", len(contours))
+	}
+
+	// the outline is walked from the vertex closest to the hole, followed by the
+	// hole itself, each repeating its first vertex to form the bridge
+	expected := [][2]int{
+		{0, 0}, {0, 10}, {12, 10}, {12, 0}, {0, 0},
+		{3, 3}, {9, 3}, {9, 7}, {3, 7}, {3, 3},
+	}
+	var actual [][2]int
+	for _, v := range contours[0].Verts {
+		actual = append(actual, [2]int{v.X, v.Z})
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected contour %v, got %v", expected, actual)
+	}
+}
+
+func TestMergeRegionHolesFailure(t *testing.T) {
+	// a degenerate outline has no corner the hole can be bridged to
+	outline := &Contour{RegionID: 4, Verts: []SimplifiedVertex{{X: 0}, {X: 10}, {X: 20}}}
+	hole := &Contour{RegionID: 4, Verts: []SimplifiedVertex{{X: 3, Z: 3}, {X: 3, Z: 7}, {X: 9, Z: 7}, {X: 9, Z: 3}}}
+	region := &contourRegion{outline: outline, holes: []contourHole{{contour: hole}}}
+
+	err := mergeRegionHoles(region)
+	if err == nil || err.Error() != "failed to find a merge point for a hole in region 4" {
+		t.Fatalf("error = %v, want a failed merge point for region 4", err)
+	}
+	if len(outline.Verts) != 3 || len(hole.Verts) != 4 {
+		t.Fatal("expected the hole to be left unmerged")
+	}
+}
+
+func TestRemoveDegenerateSegments(t *testing.T) {
+	vertices := []SimplifiedVertex{
+		{X: 0, Z: 0},
+		{X: 0, Z: 4},
+		{X: 0, Y: 1, Z: 4},
+		{X: 4, Z: 4},
+		{X: 0, Z: 0},
+	}
+
+	vertices = removeDegenerateSegments(vertices)
+	if len(vertices) != 3 {
+		t.Fatalf("expected vertices equal on the xz plane to be removed, got %v", vertices)
+	}
+}
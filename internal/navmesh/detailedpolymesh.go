@@ -3,8 +3,6 @@ package navmesh
 import (
 	"fmt"
 	"math"
-
-	"github.com/kkevinchou/izzet/izzet/runtimeconfig"
 )
 
 const (
@@ -64,7 +62,7 @@ type DetailedEdge struct {
 	l, r int
 }
 
-func BuildDetailedPolyMesh(mesh *Mesh, chf *CompactHeightField, runtimeConfig *runtimeconfig.RuntimeConfig) *DetailedMesh {
+func BuildDetailedPolyMesh(mesh *Mesh, chf *CompactHeightField, sampleDist float64, sampleMaxError float64) *DetailedMesh {
 	bounds := make([]Bound, len(mesh.Polygons))
 	var maxhw, maxhh int
 	var nPolyVerts int
@@ -137,7 +135,7 @@ func BuildDetailedPolyMesh(mesh *Mesh, chf *CompactHeightField, runtimeConfig *r
 		hp.width = bounds[i].xmax - bounds[i].xmin
 		hp.height = bounds[i].zmax - bounds[i].zmin
 		getHeightData(chf, hp, polygon.RegionID)
-		verts, tris := buildDetailedPoly(chf, polyVerts, float64(runtimeConfig.NavigationmeshSampleDist), float64(runtimeConfig.NavigationmeshMaxError), heightSearchRadius, hp, &dmesh, i, runtimeConfig)
+		verts, tris := buildDetailedPoly(chf, polyVerts, sampleDist, sampleMaxError, heightSearchRadius, hp, &dmesh, i)
 
 		// move detailed verts to world space
 		for j := range len(verts) {
@@ -257,7 +255,7 @@ func getHeightData(chf *CompactHeightField, hp HeightPatch, regionID int) {
 	}
 }
 
-func buildDetailedPoly(chf *CompactHeightField, inVerts []DetailedVertex, sampleDist, sampleMaxError float64, heightSearchRadius int, hp HeightPatch, dmesh *DetailedMesh, polyIndex int, runtimeConfig *runtimeconfig.RuntimeConfig) ([]DetailedVertex, []Triangle) {
+func buildDetailedPoly(chf *CompactHeightField, inVerts []DetailedVertex, sampleDist, sampleMaxError float64, heightSearchRadius int, hp HeightPatch, dmesh *DetailedMesh, polyIndex int) ([]DetailedVertex, []Triangle) {
 	cs := chf.CellSize
 	ch := chf.CellHeight
 	ics := 1 / cs
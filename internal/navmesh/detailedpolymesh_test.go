@@ -2,8 +2,6 @@ package navmesh
 
 import (
 	"testing"
-
-	"github.com/kkevinchou/izzet/izzet/runtimeconfig"
 )
 
 func TestPolyMeshDetail(t *testing.T) {
@@ -27,7 +25,7 @@ func TestPolyMeshDetail(t *testing.T) {
 	mesh := BuildPolyMesh(contourSet)
 
 	chf := newFlatCompactHeightField(200, 200, 101, 1)
-	BuildDetailedPolyMesh(mesh, chf, runtimeconfig.DefaultRuntimeConfig())
+	BuildDetailedPolyMesh(mesh, chf, 1, 1)
 }
 
 func newFlatCompactHeightField(width, height, populatedWidth, regionID int) *CompactHeightField {
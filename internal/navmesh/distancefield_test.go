@@ -11,17 +11,17 @@ func TestDistanceField(t *testing.T) {
 
 	// 3x3 voxels on xz plane
 
-	hf.AddSpan(0, 0, 0, 1, true, 1)
-	hf.AddSpan(1, 0, 0, 1, true, 1)
-	hf.AddSpan(2, 0, 0, 1, true, 1)
+	hf.AddSpan(0, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 0, 0, 1, WALKABLE_AREA, 1)
 
-	hf.AddSpan(0, 1, 0, 1, true, 1)
-	hf.AddSpan(1, 1, 0, 1, true, 1)
-	hf.AddSpan(2, 1, 0, 1, true, 1)
+	hf.AddSpan(0, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 1, 0, 1, WALKABLE_AREA, 1)
 
-	hf.AddSpan(0, 2, 0, 1, true, 1)
-	hf.AddSpan(1, 2, 0, 1, true, 1)
-	hf.AddSpan(2, 2, 0, 1, true, 1)
+	hf.AddSpan(0, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 2, 0, 1, WALKABLE_AREA, 1)
 
 	chf := NewCompactHeightField(1, 1, hf)
 	BuildDistanceField(chf)
@@ -36,16 +36,16 @@ func TestDistanceFieldAllBorders(t *testing.T) {
 
 	// 3x3 voxels on xz plane
 
-	hf.AddSpan(0, 0, 0, 1, true, 1)
-	hf.AddSpan(1, 0, 0, 1, true, 1)
-	hf.AddSpan(2, 0, 0, 1, true, 1)
+	hf.AddSpan(0, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 0, 0, 1, WALKABLE_AREA, 1)
 
-	hf.AddSpan(0, 1, 0, 1, true, 1)
-	hf.AddSpan(2, 1, 0, 1, true, 1)
+	hf.AddSpan(0, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 1, 0, 1, WALKABLE_AREA, 1)
 
-	hf.AddSpan(0, 2, 0, 1, true, 1)
-	hf.AddSpan(1, 2, 0, 1, true, 1)
-	hf.AddSpan(2, 2, 0, 1, true, 1)
+	hf.AddSpan(0, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 2, 0, 1, WALKABLE_AREA, 1)
 
 	chf := NewCompactHeightField(1, 1, hf)
 	BuildDistanceField(chf)
@@ -60,61 +60,61 @@ func TestDistanceFieldBlur(t *testing.T) {
 
 	// 5x5 voxels on xz plane
 
-	hf.AddSpan(0, 0, 0, 1, true, 1)
-	hf.AddSpan(1, 0, 0, 1, true, 1)
-	hf.AddSpan(2, 0, 0, 1, true, 1)
-	hf.AddSpan(3, 0, 0, 1, true, 1)
-	hf.AddSpan(4, 0, 0, 1, true, 1)
-	hf.AddSpan(5, 0, 0, 1, true, 1)
-	hf.AddSpan(6, 0, 0, 1, true, 1)
-
-	hf.AddSpan(0, 1, 0, 1, true, 1)
-	hf.AddSpan(1, 1, 0, 1, true, 1)
-	hf.AddSpan(2, 1, 0, 1, true, 1)
-	hf.AddSpan(3, 1, 0, 1, true, 1)
-	hf.AddSpan(4, 1, 0, 1, true, 1)
-	hf.AddSpan(5, 1, 0, 1, true, 1)
-	hf.AddSpan(6, 1, 0, 1, true, 1)
-
-	hf.AddSpan(0, 2, 0, 1, true, 1)
-	hf.AddSpan(1, 2, 0, 1, true, 1)
-	hf.AddSpan(2, 2, 0, 1, true, 1)
-	hf.AddSpan(3, 2, 0, 1, true, 1)
-	hf.AddSpan(4, 2, 0, 1, true, 1)
-	hf.AddSpan(5, 2, 0, 1, true, 1)
-	hf.AddSpan(6, 2, 0, 1, true, 1)
-
-	hf.AddSpan(0, 3, 0, 1, true, 1)
-	hf.AddSpan(1, 3, 0, 1, true, 1)
-	hf.AddSpan(2, 3, 0, 1, true, 1)
-	hf.AddSpan(3, 3, 0, 1, true, 1)
-	hf.AddSpan(4, 3, 0, 1, true, 1)
-	hf.AddSpan(5, 3, 0, 1, true, 1)
-	hf.AddSpan(6, 3, 0, 1, true, 1)
-
-	hf.AddSpan(0, 4, 0, 1, true, 1)
-	hf.AddSpan(1, 4, 0, 1, true, 1)
-	hf.AddSpan(2, 4, 0, 1, true, 1)
-	hf.AddSpan(3, 4, 0, 1, true, 1)
-	hf.AddSpan(4, 4, 0, 1, true, 1)
-	hf.AddSpan(5, 4, 0, 1, true, 1)
-	hf.AddSpan(6, 4, 0, 1, true, 1)
-
-	hf.AddSpan(0, 5, 0, 1, true, 1)
-	hf.AddSpan(1, 5, 0, 1, true, 1)
-	hf.AddSpan(2, 5, 0, 1, true, 1)
-	hf.AddSpan(3, 5, 0, 1, true, 1)
-	hf.AddSpan(4, 5, 0, 1, true, 1)
-	hf.AddSpan(5, 5, 0, 1, true, 1)
-	hf.AddSpan(6, 5, 0, 1, true, 1)
-
-	hf.AddSpan(0, 6, 0, 1, true, 1)
-	hf.AddSpan(1, 6, 0, 1, true, 1)
-	hf.AddSpan(2, 6, 0, 1, true, 1)
-	hf.AddSpan(3, 6, 0, 1, true, 1)
-	hf.AddSpan(4, 6, 0, 1, true, 1)
-	hf.AddSpan(5, 6, 0, 1, true, 1)
-	hf.AddSpan(6, 6, 0, 1, true, 1)
+	hf.AddSpan(0, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(3, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(4, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(5, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(6, 0, 0, 1, WALKABLE_AREA, 1)
+
+	hf.AddSpan(0, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(3, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(4, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(5, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(6, 1, 0, 1, WALKABLE_AREA, 1)
+
+	hf.AddSpan(0, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(3, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(4, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(5, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(6, 2, 0, 1, WALKABLE_AREA, 1)
+
+	hf.AddSpan(0, 3, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 3, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 3, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(3, 3, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(4, 3, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(5, 3, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(6, 3, 0, 1, WALKABLE_AREA, 1)
+
+	hf.AddSpan(0, 4, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 4, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 4, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(3, 4, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(4, 4, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(5, 4, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(6, 4, 0, 1, WALKABLE_AREA, 1)
+
+	hf.AddSpan(0, 5, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 5, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 5, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(3, 5, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(4, 5, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(5, 5, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(6, 5, 0, 1, WALKABLE_AREA, 1)
+
+	hf.AddSpan(0, 6, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 6, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 6, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(3, 6, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(4, 6, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(5, 6, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(6, 6, 0, 1, WALKABLE_AREA, 1)
 
 	chf := NewCompactHeightField(1, 1, hf)
 	BuildDistanceField(chf)
@@ -1,6 +1,11 @@
 package navmesh
 
-import "math"
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/collision/collider"
+)
 
 func ErodeWalkableArea(chf *CompactHeightField, erosionRadius int) {
 	distances := computeDistances(chf)
@@ -13,6 +18,109 @@ func ErodeWalkableArea(chf *CompactHeightField, erosionRadius int) {
 	}
 }
 
+// ClipBorder removes the walkable area within borderSize cells of the edges of
+// the compact height field. tiles are built with a border of padding so that
+// erosion sees the geometry of neighboring tiles, but the padding itself belongs
+// to those tiles and shouldn't end up in this tile's polygons
+func ClipBorder(chf *CompactHeightField, borderSize int) {
+	if borderSize <= 0 {
+		return
+	}
+	chf.borderSize = borderSize
+
+	for z := range chf.height {
+		for x := range chf.width {
+			if x >= borderSize && x < chf.width-borderSize && z >= borderSize && z < chf.height-borderSize {
+				continue
+			}
+			cell := &chf.cells[x+z*chf.width]
+			for i := cell.SpanIndex; i < cell.SpanIndex+SpanIndex(cell.SpanCount); i++ {
+				chf.areas[i] = NULL_AREA
+			}
+		}
+	}
+}
+
+// MarkBoxArea sets the area type of the walkable spans whose floor is within the
+// box. the box is in world space
+func MarkBoxArea(chf *CompactHeightField, box collider.BoundingBox, area AREA_TYPE) {
+	minX := int(math.Floor((box.MinVertex.X() - chf.bMin.X()) / chf.CellSize))
+	minY := int(math.Floor((box.MinVertex.Y() - chf.bMin.Y()) / chf.CellHeight))
+	minZ := int(math.Floor((box.MinVertex.Z() - chf.bMin.Z()) / chf.CellSize))
+	maxX := int(math.Floor((box.MaxVertex.X() - chf.bMin.X()) / chf.CellSize))
+	maxY := int(math.Floor((box.MaxVertex.Y() - chf.bMin.Y()) / chf.CellHeight))
+	maxZ := int(math.Floor((box.MaxVertex.Z() - chf.bMin.Z()) / chf.CellSize))
+
+	if maxX < 0 || minX >= chf.width || maxZ < 0 || minZ >= chf.height {
+		return
+	}
+
+	minX = max(minX, 0)
+	maxX = min(maxX, chf.width-1)
+	minZ = max(minZ, 0)
+	maxZ = min(maxZ, chf.height-1)
+
+	for z := minZ; z <= maxZ; z++ {
+		for x := minX; x <= maxX; x++ {
+			cell := &chf.cells[x+z*chf.width]
+			for i := cell.SpanIndex; i < cell.SpanIndex+SpanIndex(cell.SpanCount); i++ {
+				span := chf.spans[i]
+				if span.y < minY || span.y > maxY {
+					continue
+				}
+				if chf.areas[i] == NULL_AREA {
+					continue
+				}
+				chf.areas[i] = area
+			}
+		}
+	}
+}
+
+// MarkCylinderArea sets the area type of the walkable spans whose floor is within
+// the cylinder. center is the center of the bottom of the cylinder in world space
+func MarkCylinderArea(chf *CompactHeightField, center mgl64.Vec3, radius, height float64, area AREA_TYPE) {
+	minX := int(math.Floor((center.X() - radius - chf.bMin.X()) / chf.CellSize))
+	minY := int(math.Floor((center.Y() - chf.bMin.Y()) / chf.CellHeight))
+	minZ := int(math.Floor((center.Z() - radius - chf.bMin.Z()) / chf.CellSize))
+	maxX := int(math.Floor((center.X() + radius - chf.bMin.X()) / chf.CellSize))
+	maxY := int(math.Floor((center.Y() + height - chf.bMin.Y()) / chf.CellHeight))
+	maxZ := int(math.Floor((center.Z() + radius - chf.bMin.Z()) / chf.CellSize))
+
+	if maxX < 0 || minX >= chf.width || maxZ < 0 || minZ >= chf.height {
+		return
+	}
+
+	minX = max(minX, 0)
+	maxX = min(maxX, chf.width-1)
+	minZ = max(minZ, 0)
+	maxZ = min(maxZ, chf.height-1)
+
+	radiusSq := radius * radius
+	for z := minZ; z <= maxZ; z++ {
+		for x := minX; x <= maxX; x++ {
+			// test the center of the cell against the cylinder
+			dx := chf.bMin.X() + (float64(x)+0.5)*chf.CellSize - center.X()
+			dz := chf.bMin.Z() + (float64(z)+0.5)*chf.CellSize - center.Z()
+			if dx*dx+dz*dz > radiusSq {
+				continue
+			}
+
+			cell := &chf.cells[x+z*chf.width]
+			for i := cell.SpanIndex; i < cell.SpanIndex+SpanIndex(cell.SpanCount); i++ {
+				span := chf.spans[i]
+				if span.y < minY || span.y > maxY {
+					continue
+				}
+				if chf.areas[i] == NULL_AREA {
+					continue
+				}
+				chf.areas[i] = area
+			}
+		}
+	}
+}
+
 func FilterLowHeightSpans(walkableHeight int, hf *HeightField) {
 	xSize := hf.Width
 	zSize := hf.Height
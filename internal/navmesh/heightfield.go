@@ -50,16 +50,11 @@ func (hf *HeightField) SpanCount() int {
 	return count
 }
 
-func (hf *HeightField) AddSpan(x, z, sMin, sMax int, walkable bool, areaMergeThreshold int) {
+func (hf *HeightField) AddSpan(x, z, sMin, sMax int, area AREA_TYPE, areaMergeThreshold int) {
 	var previousSpan *Span
 	columnIndex := x + z*hf.Width
 	currentSpan := hf.Spans[columnIndex]
 
-	area := NULL_AREA
-	if walkable {
-		area = WALKABLE_AREA
-	}
-
 	newSpan := &Span{Min: sMin, Max: sMax, Area: area, X: x, Z: z}
 
 	for currentSpan != nil {
@@ -84,7 +79,6 @@ func (hf *HeightField) AddSpan(x, z, sMin, sMax int, walkable bool, areaMergeThr
 			if int(math.Abs(float64(newSpan.Max-currentSpan.Max))) <= areaMergeThreshold {
 				// higher area ID numbers indicate higher resolution priority
 				// NULL_AREA is the smallest
-				// WALKABLE_AREA is the largest
 				newSpan.Area = max(newSpan.Area, currentSpan.Area)
 			}
 
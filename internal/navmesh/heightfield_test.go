@@ -20,7 +20,7 @@ func TestHeightField(t *testing.T) {
 	// X
 
 	// first voxel
-	hf.AddSpan(0, 0, 0, 1, true, 1)
+	hf.AddSpan(0, 0, 0, 1, WALKABLE_AREA, 1)
 	count = hf.SpanCount()
 	if count != 1 {
 		t.Fatalf("count %d != 1", count)
@@ -32,7 +32,7 @@ func TestHeightField(t *testing.T) {
 	// X
 
 	// merge
-	hf.AddSpan(0, 0, 1, 2, true, 1)
+	hf.AddSpan(0, 0, 1, 2, WALKABLE_AREA, 1)
 	count = hf.SpanCount()
 	if count != 1 {
 		t.Fatalf("count %d != 1", count)
@@ -44,7 +44,7 @@ func TestHeightField(t *testing.T) {
 	// X
 
 	// no merge
-	hf.AddSpan(0, 0, 3, 4, true, 1)
+	hf.AddSpan(0, 0, 3, 4, WALKABLE_AREA, 1)
 	count = hf.SpanCount()
 	if count != 2 {
 		t.Fatalf("count %d != 2", count)
@@ -56,7 +56,7 @@ func TestHeightField(t *testing.T) {
 	// X
 
 	// merge it all
-	hf.AddSpan(0, 0, 2, 3, true, 1)
+	hf.AddSpan(0, 0, 2, 3, WALKABLE_AREA, 1)
 	count = hf.SpanCount()
 	if count != 1 {
 		t.Fatalf("count %d != 1", count)
@@ -68,14 +68,14 @@ func TestHeightField(t *testing.T) {
 	// X
 
 	// nothing happens at the top
-	hf.AddSpan(0, 0, 3, 4, true, 1)
+	hf.AddSpan(0, 0, 3, 4, WALKABLE_AREA, 1)
 	count = hf.SpanCount()
 	if count != 1 {
 		t.Fatalf("count %d != 1", count)
 	}
 
 	// nothing happens at the bottom
-	hf.AddSpan(0, 0, 0, 1, true, 1)
+	hf.AddSpan(0, 0, 0, 1, WALKABLE_AREA, 1)
 	count = hf.SpanCount()
 	if count != 1 {
 		t.Fatalf("count %d != 1", count)
@@ -85,10 +85,10 @@ func TestHeightField(t *testing.T) {
 func TestFilterLowHeightSpans(t *testing.T) {
 	walkableHeight := 4
 	hf := NewHeightField(100, 100, mgl64.Vec3{0, 0, 0}, mgl64.Vec3{100, 100, 100}, 1, 1)
-	hf.AddSpan(0, 0, 0, 1, true, 1)
+	hf.AddSpan(0, 0, 0, 1, WALKABLE_AREA, 1)
 
 	// okay
-	hf.AddSpan(0, 0, 5, 6, true, 1)
+	hf.AddSpan(0, 0, 5, 6, WALKABLE_AREA, 1)
 	FilterLowHeightSpans(walkableHeight, hf)
 
 	if hf.Spans[0].Area == NULL_AREA {
@@ -96,7 +96,7 @@ func TestFilterLowHeightSpans(t *testing.T) {
 	}
 
 	// not okay
-	hf.AddSpan(0, 0, 4, 5, true, 1)
+	hf.AddSpan(0, 0, 4, 5, WALKABLE_AREA, 1)
 	FilterLowHeightSpans(5, hf)
 
 	if hf.Spans[0].Area == WALKABLE_AREA {
@@ -16,10 +16,33 @@ const spanHeightBits int = 13
 // / Defines the maximum value for rcSpan::smin and rcSpan::smax.
 const spanMaxHeight int = (1 << spanHeightBits) - 1
 
+// AREA_TYPE is stored per span and carried through to the polygons of the nav
+// mesh so queries can treat areas differently. every area type other than
+// NULL_AREA is walkable. where surfaces of different area types coincide, the
+// larger area type wins, so the other area types take priority over plain ground
 type AREA_TYPE int
 
 const NULL_AREA AREA_TYPE = 0
-const WALKABLE_AREA AREA_TYPE = 63
+const WALKABLE_AREA AREA_TYPE = 1
+const WATER_AREA AREA_TYPE = 2
+const GRASS_AREA AREA_TYPE = 3
+const ROAD_AREA AREA_TYPE = 4
+const DOOR_AREA AREA_TYPE = 5
+
+// maxAreas is the number of distinct area types
+const maxAreas int = 64
+
+// AreaTypes lists the walkable area types along with their names
+var AreaTypes = []struct {
+	Name string
+	Area AREA_TYPE
+}{
+	{Name: "Ground", Area: WALKABLE_AREA},
+	{Name: "Water", Area: WATER_AREA},
+	{Name: "Road", Area: ROAD_AREA},
+	{Name: "Grass", Area: GRASS_AREA},
+	{Name: "Door", Area: DOOR_AREA},
+}
 
 // match what recast uses, they also reserve 63 as unconnected but we don't use that
 const maxLayers int = 62
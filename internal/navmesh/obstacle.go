@@ -0,0 +1,52 @@
+package navmesh
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/collision/collider"
+)
+
+type ObstacleShape string
+
+const (
+	ObstacleShapeCylinder ObstacleShape = "CYLINDER"
+	ObstacleShapeBox      ObstacleShape = "BOX"
+)
+
+// Obstacle is a temporary obstacle that's carved out of the walkable area of a
+// nav mesh at runtime, e.g. a crate that was pushed into a corridor
+type Obstacle struct {
+	Shape ObstacleShape
+	// Position is the center of the bottom of a cylinder or the center of a box
+	Position mgl64.Vec3
+	// Radius and Height are the size of a cylinder
+	Radius float64
+	Height float64
+	// HalfExtents is the size of a box, boxes are axis aligned
+	HalfExtents mgl64.Vec3
+}
+
+// Bounds returns the bounding box of the obstacle
+func (o Obstacle) Bounds() collider.BoundingBox {
+	if o.Shape == ObstacleShapeBox {
+		return collider.BoundingBox{MinVertex: o.Position.Sub(o.HalfExtents), MaxVertex: o.Position.Add(o.HalfExtents)}
+	}
+	return collider.BoundingBox{
+		MinVertex: o.Position.Sub(mgl64.Vec3{o.Radius, 0, o.Radius}),
+		MaxVertex: o.Position.Add(mgl64.Vec3{o.Radius, o.Height, o.Radius}),
+	}
+}
+
+// MarkObstacle removes the walkable spans under the obstacle. spans up to the
+// walkable climb below the obstacle are removed as well so obstacles resting on
+// the ground block it. obstacles should be marked before the walkable area is
+// eroded so agents keep their radius away from them
+func MarkObstacle(chf *CompactHeightField, obstacle Obstacle) {
+	climb := float64(chf.walkableClimb) * chf.CellHeight
+	if obstacle.Shape == ObstacleShapeBox {
+		box := obstacle.Bounds()
+		box.MinVertex[1] -= climb
+		MarkBoxArea(chf, box, NULL_AREA)
+		return
+	}
+	MarkCylinderArea(chf, obstacle.Position.Sub(mgl64.Vec3{0, climb, 0}), obstacle.Radius, obstacle.Height+climb, NULL_AREA)
+}
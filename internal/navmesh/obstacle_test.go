@@ -0,0 +1,62 @@
+package navmesh
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// blockedCells returns the number of cells whose span was removed
+func blockedCells(chf *CompactHeightField) int {
+	var blocked int
+	for _, area := range chf.areas {
+		if area == NULL_AREA {
+			blocked++
+		}
+	}
+	return blocked
+}
+
+func TestMarkObstacle(t *testing.T) {
+	floor := []string{
+		"#######",
+		"#######",
+		"#######",
+		"#######",
+		"#######",
+		"#######",
+		"#######",
+	}
+
+	testCases := []struct {
+		name     string
+		obstacle Obstacle
+		want     int
+	}{
+		{
+			name:     "cylinder",
+			obstacle: Obstacle{Shape: ObstacleShapeCylinder, Position: mgl64.Vec3{3.5, 1, 3.5}, Radius: 1.2, Height: 2},
+			want:     5,
+		},
+		{
+			name:     "box",
+			obstacle: Obstacle{Shape: ObstacleShapeBox, Position: mgl64.Vec3{1, 1.5, 1}, HalfExtents: mgl64.Vec3{0.5, 0.5, 0.5}},
+			want:     4,
+		},
+		{
+			name:     "above the floor",
+			obstacle: Obstacle{Shape: ObstacleShapeCylinder, Position: mgl64.Vec3{3.5, 5, 3.5}, Radius: 1.2, Height: 2},
+			want:     0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chf := chfFromGrid(floor)
+			MarkObstacle(chf, tc.obstacle)
+			if got := blockedCells(chf); got != tc.want {
+				t.Fatalf("expected %d cells to be blocked, got %d", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package navmesh
+
+import "github.com/go-gl/mathgl/mgl64"
+
+// OffMeshTile is the tile of the PolyRef of an off-mesh connection, the Poly of
+// the ref is the index of the connection in CompiledNavMesh.OffMeshConnections
+const OffMeshTile int = -2
+
+// OffMeshConnection links two points on the nav mesh that aren't connected by
+// walkable polygons, e.g. jumping down a ledge, climbing a ladder or a teleporter
+type OffMeshConnection struct {
+	Start mgl64.Vec3
+	End   mgl64.Vec3
+	// Radius is how far from a polygon each end of the connection can be while
+	// still being attached to it
+	Radius float64
+	// Bidirectional connections can be traversed from End to Start as well
+	Bidirectional bool
+	// Area is the area type used to filter and weigh the connection
+	Area AREA_TYPE
+	// Cost is added to the cost of traversing the connection on top of the
+	// distance between its ends
+	Cost float64
+
+	// StartRef and EndRef are the polygons the ends of the connection are attached
+	// to and StartPoint and EndPoint are the ends snapped onto them. they're
+	// derived by LinkTiles rather than baked, the refs are InvalidPolyRef if there's
+	// no polygon within the radius
+	StartRef   PolyRef
+	EndRef     PolyRef
+	StartPoint mgl64.Vec3
+	EndPoint   mgl64.Vec3
+}
+
+// OffMeshConnectionRef returns the ref used for the off-mesh connection at the
+// index in paths
+func OffMeshConnectionRef(index int) PolyRef {
+	return PolyRef{Tile: OffMeshTile, Poly: index}
+}
+
+// IsOffMeshConnection returns whether the ref is an off-mesh connection rather
+// than a polygon
+func (r PolyRef) IsOffMeshConnection() bool {
+	return r.Tile == OffMeshTile
+}
+
+// linkOffMeshConnections attaches each end of the off-mesh connections to the
+// nearest polygon and links the polygons to the connection. the end polygon is
+// only linked back for bidirectional connections
+func linkOffMeshConnections(nm *CompiledNavMesh) {
+	for i := range nm.OffMeshConnections {
+		connection := &nm.OffMeshConnections[i]
+		connection.StartPoint, connection.StartRef = attachOffMeshPoint(nm, connection.Start, connection.Radius)
+		connection.EndPoint, connection.EndRef = attachOffMeshPoint(nm, connection.End, connection.Radius)
+		if connection.StartRef == InvalidPolyRef || connection.EndRef == InvalidPolyRef || connection.StartRef == connection.EndRef {
+			continue
+		}
+
+		ref := OffMeshConnectionRef(i)
+		start := polygonAt(nm, connection.StartRef)
+		start.Links = append(start.Links, CLink{Edge: -1, Ref: ref, Left: connection.StartPoint, Right: connection.StartPoint})
+		if connection.Bidirectional {
+			end := polygonAt(nm, connection.EndRef)
+			end.Links = append(end.Links, CLink{Edge: -1, Ref: ref, Left: connection.EndPoint, Right: connection.EndPoint})
+		}
+	}
+}
+
+// attachOffMeshPoint returns the nearest point on the nav mesh to point and the
+// polygon it's on, or InvalidPolyRef if it's further than radius away
+func attachOffMeshPoint(nm *CompiledNavMesh, point mgl64.Vec3, radius float64) (mgl64.Vec3, PolyRef) {
+	nearest, ref, _ := FindNearestPolygon(nm, point, nil)
+	if ref == InvalidPolyRef || nearest.Sub(point).Len() > radius {
+		return point, InvalidPolyRef
+	}
+	return nearest, ref
+}
+
+// offMeshConnectionNeighbors returns the polygons that the connection leads to
+func offMeshConnectionNeighbors(nm *CompiledNavMesh, ref PolyRef) []PolyRef {
+	connection := nm.OffMeshConnections[ref.Poly]
+	if connection.Bidirectional {
+		return []PolyRef{connection.StartRef, connection.EndRef}
+	}
+	return []PolyRef{connection.EndRef}
+}
+
+// offMeshConnectionPoint returns the end of the connection attached to the
+// polygon
+func offMeshConnectionPoint(connection OffMeshConnection, polygon PolyRef) (mgl64.Vec3, bool) {
+	if polygon == connection.EndRef {
+		return connection.EndPoint, true
+	}
+	if polygon == connection.StartRef && connection.Bidirectional {
+		return connection.StartPoint, true
+	}
+	return mgl64.Vec3{}, false
+}
@@ -1,7 +1,79 @@
 package navmesh
 
-import "github.com/go-gl/mathgl/mgl64"
+import (
+	"math"
+	"slices"
 
-func MoveAlongSurface(tile CTile, path []int, targets []mgl64.Vec3) {
+	"github.com/go-gl/mathgl/mgl64"
+)
 
+// MoveAlongSurface moves from start towards end while staying on the nav mesh and
+// returns where it ended up along with the polygons it went through. if end
+// can't be reached directly the result slides along the walls in the way, which
+// suits small per frame movements like steering agents rather than long
+// distances. polygons that don't pass the filter are treated as walls. the
+// search stays within a circle around the segment so walls far off to the side
+// are ignored
+func MoveAlongSurface(nm *CompiledNavMesh, start, end mgl64.Vec3, filter *QueryFilter) (mgl64.Vec3, []PolyRef) {
+	start, startRef, _ := FindNearestPolygon(nm, start, filter)
+	if startRef == InvalidPolyRef {
+		return start, nil
+	}
+
+	searchPos := start.Add(end).Mul(0.5)
+	searchRadius := start.Sub(end).Len()/2 + edgeEpsilon
+	searchRadiusSq := searchRadius * searchRadius
+
+	bestPos := start
+	bestRef := startRef
+	bestDistSq := math.MaxFloat64
+
+	parents := map[PolyRef]PolyRef{startRef: InvalidPolyRef}
+	queue := []PolyRef{startRef}
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+
+		tile := nm.Tiles[ref.Tile]
+		if pointInPoly(tile, ref.Poly, end) {
+			bestPos = end
+			bestRef = ref
+			break
+		}
+
+		verts := polygonVertices(tile, ref.Poly)
+		for edge := range verts {
+			va, vb := verts[edge], verts[(edge+1)%len(verts)]
+			portals := edgePortals(nm, ref, edge, filter)
+
+			for _, segment := range edgeWallSegments(va, vb, portals) {
+				distSq, t := distancePtSeg2Df(end.X(), end.Z(), segment[0].X(), segment[0].Z(), segment[1].X(), segment[1].Z())
+				if distSq < bestDistSq {
+					bestDistSq = distSq
+					bestPos = segment[0].Add(segment[1].Sub(segment[0]).Mul(t))
+					bestRef = ref
+				}
+			}
+
+			for _, portal := range portals {
+				if _, ok := parents[portal.Ref]; ok {
+					continue
+				}
+				distSq, _ := distancePtSeg2Df(searchPos.X(), searchPos.Z(), portal.Left.X(), portal.Left.Z(), portal.Right.X(), portal.Right.Z())
+				if distSq > searchRadiusSq {
+					continue
+				}
+				parents[portal.Ref] = ref
+				queue = append(queue, portal.Ref)
+			}
+		}
+	}
+
+	var visited []PolyRef
+	for ref := bestRef; ref != InvalidPolyRef; ref = parents[ref] {
+		visited = append(visited, ref)
+	}
+	slices.Reverse(visited)
+
+	return projectPathPoint(nm.Tiles[bestRef.Tile], bestRef.Poly, bestPos), visited
 }
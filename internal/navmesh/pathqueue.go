@@ -0,0 +1,191 @@
+package navmesh
+
+import (
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// PathStatus is the status of a path request, the zero value means there's no
+// request
+type PathStatus string
+
+const (
+	PathStatusPending PathStatus = "PENDING"
+	PathStatusReady   PathStatus = "READY"
+	// PathStatusFailed requests couldn't find any path, e.g. because the start or
+	// goal isn't on the nav mesh
+	PathStatusFailed PathStatus = "FAILED"
+)
+
+// InvalidPathRequest is never returned by PathQueue.Request
+const InvalidPathRequest int = 0
+
+type pathRequestKey struct {
+	startPolygon PolyRef
+	goalPolygon  PolyRef
+	filter       *QueryFilter
+}
+
+type pathRequest struct {
+	key    pathRequestKey
+	start  mgl64.Vec3
+	goal   mgl64.Vec3
+	query  *SlicedPathQuery
+	status PathStatus
+	path   []PolyRef
+	// refs counts the callers sharing the request, it's dropped once they've all
+	// released it
+	refs int
+}
+
+// PathQueue spreads path searches over several frames. each update expands at
+// most a budget of nodes across the pending requests, oldest first, so a burst
+// of requests doesn't stall the frame they're made in. pending requests between
+// the same polygons with the same filter are shared
+type PathQueue struct {
+	nm       *CompiledNavMesh
+	budget   int
+	nextID   int
+	requests map[int]*pathRequest
+	// pending holds the ids of the requests that haven't finished in the order
+	// they were made
+	pending []int
+}
+
+// NewPathQueue returns a queue that expands up to budget nodes per update
+func NewPathQueue(budget int) *PathQueue {
+	return &PathQueue{budget: budget, nextID: 1, requests: map[int]*pathRequest{}}
+}
+
+// SetNavMesh sets the nav mesh paths are searched on. pending requests restart
+// when it changes since their partial searches refer to the old polygons, and
+// ready paths through tiles that were rebuilt are searched again
+func (q *PathQueue) SetNavMesh(nm *CompiledNavMesh) {
+	if nm == q.nm {
+		return
+	}
+	q.nm = nm
+	for _, id := range q.pending {
+		request := q.requests[id]
+		request.query = nil
+		request.key = q.requestKey(request.start, request.goal, request.key.filter)
+	}
+
+	// walk the requests in id order so they're searched in the order they were
+	// made
+	var stale []int
+	for id, request := range q.requests {
+		if request.status == PathStatusReady && !validPath(nm, request.path) {
+			stale = append(stale, id)
+		}
+	}
+	slices.Sort(stale)
+	for _, id := range stale {
+		request := q.requests[id]
+		request.status = PathStatusPending
+		request.path = nil
+		request.key = q.requestKey(request.start, request.goal, request.key.filter)
+		q.pending = append(q.pending, id)
+	}
+}
+
+func validPath(nm *CompiledNavMesh, path []PolyRef) bool {
+	if nm == nil {
+		return false
+	}
+	for _, ref := range path {
+		if !nm.ValidPolyRef(ref) {
+			return false
+		}
+	}
+	return true
+}
+
+// Request queues a search for a path from start to goal and returns the id of
+// the request. requests between the same polygons with the same filter as a
+// pending request share it, either way the caller releases the id once it's done
+// with it
+func (q *PathQueue) Request(start, goal mgl64.Vec3, filter *QueryFilter) int {
+	key := q.requestKey(start, goal, filter)
+	for _, id := range q.pending {
+		if request := q.requests[id]; request.key == key {
+			request.refs++
+			return id
+		}
+	}
+
+	id := q.nextID
+	q.nextID++
+	q.requests[id] = &pathRequest{key: key, start: start, goal: goal, status: PathStatusPending, refs: 1}
+	q.pending = append(q.pending, id)
+	return id
+}
+
+// Result returns the status of the request and its path once it's ready
+func (q *PathQueue) Result(id int) ([]PolyRef, PathStatus) {
+	request, ok := q.requests[id]
+	if !ok {
+		return nil, PathStatusFailed
+	}
+	return request.path, request.status
+}
+
+// Release gives up the caller's share of the request, requests are dropped once
+// every caller has released them even if they're still pending
+func (q *PathQueue) Release(id int) {
+	request, ok := q.requests[id]
+	if !ok {
+		return
+	}
+	request.refs--
+	if request.refs > 0 {
+		return
+	}
+
+	delete(q.requests, id)
+	for i, pendingID := range q.pending {
+		if pendingID == id {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			break
+		}
+	}
+}
+
+// Pending returns the number of requests that haven't finished
+func (q *PathQueue) Pending() int {
+	return len(q.pending)
+}
+
+// Update runs the pending searches until they're done or the node budget is used
+// up, searches that don't finish resume from where they left off next update.
+// it returns the number of nodes expanded
+func (q *PathQueue) Update() int {
+	var expanded int
+	for len(q.pending) > 0 && expanded < q.budget {
+		request := q.requests[q.pending[0]]
+		if request.query == nil {
+			request.query = NewSlicedPathQuery(q.nm, request.start, request.goal, request.key.filter)
+		}
+
+		expanded += request.query.Update(q.budget - expanded)
+		if !request.query.Done() {
+			break
+		}
+
+		request.path = request.query.Path()
+		request.query = nil
+		request.status = PathStatusReady
+		if len(request.path) == 0 {
+			request.status = PathStatusFailed
+		}
+		q.pending = q.pending[1:]
+	}
+	return expanded
+}
+
+func (q *PathQueue) requestKey(start, goal mgl64.Vec3, filter *QueryFilter) pathRequestKey {
+	_, startPolygon, _ := FindNearestPolygon(q.nm, start, filter)
+	_, goalPolygon, _ := FindNearestPolygon(q.nm, goal, filter)
+	return pathRequestKey{startPolygon: startPolygon, goalPolygon: goalPolygon, filter: filter}
+}
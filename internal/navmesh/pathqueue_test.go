@@ -0,0 +1,212 @@
+package navmesh
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+func rowNavMesh(width int) *CompiledNavMesh {
+	row := make([]AREA_TYPE, width)
+	for i := range row {
+		row[i] = WALKABLE_AREA
+	}
+	return gridNavMesh([][]AREA_TYPE{row})
+}
+
+func TestSlicedPathQuery(t *testing.T) {
+	g, w := WALKABLE_AREA, WATER_AREA
+	nm := gridNavMesh([][]AREA_TYPE{
+		{g, g, g, g, g},
+		{g, w, w, w, g},
+		{g, g, g, w, g},
+		{w, w, g, g, g},
+	})
+	filter := NewQueryFilter()
+	filter.SetAreaCost(WATER_AREA, 5)
+	start := mgl64.Vec3{0.5, 0, 0.5}
+	goal := mgl64.Vec3{2.5, 0, 2.5}
+
+	query := NewSlicedPathQuery(nm, start, goal, filter)
+	var updates int
+	for !query.Done() {
+		if expanded := query.Update(1); expanded > 1 {
+			t.Fatalf("expanded %d nodes with a budget of 1", expanded)
+		}
+		if !query.Done() && query.Path() != nil {
+			t.Fatal("got a path before the search was done")
+		}
+		updates++
+	}
+
+	if updates < 2 {
+		t.Fatalf("search finished in %d updates, expected it to be sliced", updates)
+	}
+	if want := FindPath(nm, start, goal, filter); !slices.Equal(query.Path(), want) {
+		t.Fatalf("path = %v, want %v", query.Path(), want)
+	}
+}
+
+func TestPathQueue(t *testing.T) {
+	nm := rowNavMesh(10)
+	queue := NewPathQueue(3)
+	queue.SetNavMesh(nm)
+
+	start := mgl64.Vec3{0.5, 0, 0.5}
+	goal := mgl64.Vec3{9.5, 0, 0.5}
+	id := queue.Request(start, goal, nil)
+	if id == InvalidPathRequest {
+		t.Fatal("got an invalid request id")
+	}
+
+	var updates int
+	for {
+		if expanded := queue.Update(); expanded > 3 {
+			t.Fatalf("expanded %d nodes with a budget of 3", expanded)
+		}
+		updates++
+		if _, status := queue.Result(id); status != PathStatusPending {
+			break
+		}
+	}
+
+	path, status := queue.Result(id)
+	if status != PathStatusReady {
+		t.Fatalf("status = %s, want %s", status, PathStatusReady)
+	}
+	if want := FindPath(nm, start, goal, nil); !slices.Equal(path, want) {
+		t.Fatalf("path = %v, want %v", path, want)
+	}
+	if updates != 4 {
+		t.Fatalf("took %d updates, want 4", updates)
+	}
+
+	queue.Release(id)
+	if _, status := queue.Result(id); status != PathStatusFailed {
+		t.Fatalf("released request status = %s, want %s", status, PathStatusFailed)
+	}
+}
+
+func TestPathQueueOrder(t *testing.T) {
+	nm := rowNavMesh(10)
+	queue := NewPathQueue(4)
+	queue.SetNavMesh(nm)
+
+	first := queue.Request(mgl64.Vec3{0.5, 0, 0.5}, mgl64.Vec3{9.5, 0, 0.5}, nil)
+	second := queue.Request(mgl64.Vec3{9.5, 0, 0.5}, mgl64.Vec3{8.5, 0, 0.5}, nil)
+
+	queue.Update()
+	if _, status := queue.Result(second); status != PathStatusPending {
+		t.Fatalf("second request finished before the first, status = %s", status)
+	}
+	queue.Update()
+	queue.Update()
+	if _, status := queue.Result(first); status != PathStatusReady {
+		t.Fatalf("first request status = %s, want %s", status, PathStatusReady)
+	}
+	if _, status := queue.Result(second); status != PathStatusReady {
+		t.Fatalf("second request status = %s, want %s", status, PathStatusReady)
+	}
+	if queue.Pending() != 0 {
+		t.Fatalf("%d requests still pending", queue.Pending())
+	}
+}
+
+func TestPathQueueDeduplicates(t *testing.T) {
+	queue := NewPathQueue(1)
+	queue.SetNavMesh(rowNavMesh(10))
+	goal := mgl64.Vec3{9.5, 0, 0.5}
+
+	first := queue.Request(mgl64.Vec3{0.25, 0, 0.5}, goal, nil)
+	second := queue.Request(mgl64.Vec3{0.75, 0, 0.25}, goal, nil)
+	if first != second {
+		t.Fatalf("requests from the same polygon weren't shared, got %d and %d", first, second)
+	}
+
+	filter := NewQueryFilter()
+	if other := queue.Request(mgl64.Vec3{0.25, 0, 0.5}, goal, filter); other == first {
+		t.Fatal("requests with different filters were shared")
+	}
+	if other := queue.Request(mgl64.Vec3{1.5, 0, 0.5}, goal, nil); other == first {
+		t.Fatal("requests from different polygons were shared")
+	}
+
+	queue.Release(first)
+	if _, status := queue.Result(second); status != PathStatusPending {
+		t.Fatalf("request was dropped while still shared, status = %s", status)
+	}
+	queue.Release(second)
+	if queue.Pending() != 2 {
+		t.Fatalf("pending = %d, want 2", queue.Pending())
+	}
+}
+
+func TestPathQueueFailed(t *testing.T) {
+	w := WATER_AREA
+	queue := NewPathQueue(10)
+	queue.SetNavMesh(gridNavMesh([][]AREA_TYPE{{w, w}}))
+	filter := NewQueryFilter()
+	filter.Exclude(WATER_AREA)
+
+	id := queue.Request(mgl64.Vec3{0.5, 0, 0.5}, mgl64.Vec3{1.5, 0, 0.5}, filter)
+	queue.Update()
+	if path, status := queue.Result(id); status != PathStatusFailed || path != nil {
+		t.Fatalf("result = %v, %s, want no path and %s", path, status, PathStatusFailed)
+	}
+}
+
+func TestPathQueueNavMeshChange(t *testing.T) {
+	queue := NewPathQueue(2)
+	queue.SetNavMesh(rowNavMesh(10))
+
+	start := mgl64.Vec3{0.5, 0, 0.5}
+	goal := mgl64.Vec3{9.5, 0, 0.5}
+	id := queue.Request(start, goal, nil)
+	queue.Update()
+
+	// the partial search is dropped and runs again on the new nav mesh
+	nm := ledgeNavMesh()
+	queue.SetNavMesh(nm)
+	for queue.Pending() > 0 {
+		queue.Update()
+	}
+
+	path, status := queue.Result(id)
+	if status != PathStatusReady {
+		t.Fatalf("status = %s, want %s", status, PathStatusReady)
+	}
+	if want := FindPath(nm, start, goal, nil); !slices.Equal(path, want) {
+		t.Fatalf("path = %v, want %v", path, want)
+	}
+}
+
+func TestPathQueueRebuiltTiles(t *testing.T) {
+	nm := twoTileNavMesh()
+	queue := NewPathQueue(10)
+	queue.SetNavMesh(nm)
+
+	start := mgl64.Vec3{0.5, 0, 0.9}
+	goal := mgl64.Vec3{1.5, 0, 0.1}
+	id := queue.Request(start, goal, nil)
+	queue.Update()
+
+	// a copy with the right tile rebuilt, ready paths through it are searched
+	// again
+	rebuilt := *nm
+	rebuilt.Tiles = slices.Clone(nm.Tiles)
+	rebuilt.SetTiles([]CTile{nm.Tiles[1]})
+	queue.SetNavMesh(&rebuilt)
+	if _, status := queue.Result(id); status != PathStatusPending {
+		t.Fatalf("status = %s, want %s", status, PathStatusPending)
+	}
+	queue.Update()
+
+	path, status := queue.Result(id)
+	if status != PathStatusReady {
+		t.Fatalf("status = %s, want %s", status, PathStatusReady)
+	}
+	if want := FindPath(&rebuilt, start, goal, nil); !slices.Equal(path, want) || path[1].Salt != 1 {
+		t.Fatalf("path = %v, want %v", path, want)
+	}
+}
@@ -36,13 +36,13 @@ type Polygon struct {
 	polyNeighbor []int
 
 	RegionID int
+	Area     AREA_TYPE
 }
 
 type Mesh struct {
 	Vertices             []PolyVertex
 	Polygons             []Polygon
 	PremergeTriangles    []Polygon
-	areas                []AREA_TYPE
 	maxEdgeError         float64
 	bMin, bMax           mgl64.Vec3
 	CellSize, CellHeight float64
@@ -95,10 +95,12 @@ func BuildPolyMesh(contourSet *ContourSet) *Mesh {
 		var polygons []Polygon
 
 		for j := 0; j < len(tris); j++ {
-			t := tris[j]
-			if t.a != t.b && t.a != t.c && t.b != t.c {
+			a, b, c := indices[tris[j].a], indices[tris[j].b], indices[tris[j].c]
+			// contours with merged holes visit the vertices of the bridge to the hole
+			// twice, triangles between the copies are degenerate
+			if a != b && a != c && b != c {
 				p := Polygon{
-					Verts:        []int{indices[t.a], indices[t.b], indices[t.c]},
+					Verts:        []int{a, b, c},
 					polyNeighbor: []int{-1, -1, -1},
 					RegionID:     contour.RegionID,
 				}
@@ -154,7 +156,7 @@ func BuildPolyMesh(contourSet *ContourSet) *Mesh {
 
 		// store polygons
 		for _, polygon := range polygons {
-			mesh.areas = append(mesh.areas, contour.area)
+			polygon.Area = contour.area
 			mesh.Polygons = append(mesh.Polygons, polygon)
 			if len(mesh.Polygons) > maxTris {
 				panic(fmt.Sprintf("too many polygons %d, max: %d", len(mesh.Polygons), maxTris))
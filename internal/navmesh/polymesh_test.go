@@ -1,7 +1,11 @@
 package navmesh
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/collision/collider"
 )
 
 func TestBuildMeshAdjacency(t *testing.T) {
@@ -180,3 +184,62 @@ func TestInCone(t *testing.T) {
 func TestDiagonalie(t *testing.T) {
 
 }
+
+func TestBuildPolyMeshWithHole(t *testing.T) {
+	chf := chfFromGrid(courtyard)
+	BuildDistanceField(chf)
+	BuildRegions(chf, 999, 0, 400)
+	mesh := BuildPolyMesh(BuildContours(chf, 1, 12))
+
+	expected := [][]int{{7, 4, 0, 1}, {5, 6, 2, 3}, {3, 0, 4, 5}, {6, 7, 1, 2}}
+	var actual [][]int
+	for _, polygon := range mesh.Polygons {
+		actual = append(actual, polygon.Verts)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected polygons %v, got %v", expected, actual)
+	}
+
+	// the polygons cover the floor around the hole but not the hole itself
+	area := 0
+	for _, polygon := range mesh.Polygons {
+		var verts []SimplifiedVertex
+		for _, i := range polygon.Verts {
+			v := mesh.Vertices[i]
+			verts = append(verts, SimplifiedVertex{X: v.X, Z: v.Z})
+		}
+		area += calcAreaOfPolygon2D(verts)
+	}
+	if area != 12*10-6*4 {
+		t.Fatalf("expected the polygons to cover an area of %d, got %d", 12*10-6*4, area)
+	}
+}
+
+func TestBuildPolyMeshAreas(t *testing.T) {
+	chf := chfFromGrid([]string{
+		"############",
+		"############",
+		"############",
+		"############",
+		"############",
+		"############",
+	})
+	MarkBoxArea(chf, collider.BoundingBox{MinVertex: mgl64.Vec3{6, -1, 0}, MaxVertex: mgl64.Vec3{11.5, 5, 5.5}}, WATER_AREA)
+	BuildDistanceField(chf)
+	BuildRegions(chf, 999, 0, 400)
+	mesh := BuildPolyMesh(BuildContours(chf, 1, 12))
+
+	areas := map[AREA_TYPE]bool{}
+	for _, polygon := range mesh.Polygons {
+		areas[polygon.Area] = true
+		for _, i := range polygon.Verts {
+			x := mesh.Vertices[i].X
+			if polygon.Area == WATER_AREA && x < 6 || polygon.Area == WALKABLE_AREA && x > 6 {
+				t.Fatalf("expected the polygon's area to match the marked box, got %v with vertex x = %d", polygon.Area, x)
+			}
+		}
+	}
+	if !areas[WATER_AREA] || !areas[WALKABLE_AREA] {
+		t.Fatalf("expected both ground and water polygons, got %v", areas)
+	}
+}
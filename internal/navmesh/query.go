@@ -1,7 +1,6 @@
 package navmesh
 
 import (
-	"fmt"
 	"math"
 	"slices"
 
@@ -12,14 +11,14 @@ import (
 type pathPortal struct {
 	Left        mgl64.Vec3
 	Right       mgl64.Vec3
-	ProjectPoly int
+	ProjectPoly PolyRef
 	End         bool
 }
 
 type Node struct {
 	Cost     float64
 	Total    float64
-	Polygon  int
+	Polygon  PolyRef
 	Parent   *Node
 	Position mgl64.Vec3
 
@@ -30,63 +29,124 @@ type Node struct {
 var PATHPOLYGONS map[int]bool
 var PATHVERTICES []mgl64.Vec3
 
-// FindPath returns a list of polygons through which it is possible to path from start to goal
-func FindPath(nm *CompiledNavMesh, start, goal mgl64.Vec3) []int {
-	tile := nm.Tiles[0]
+// FindPath returns a list of polygons through which it is possible to path from
+// start to goal. only polygons that pass the filter are visited and their cost is
+// weighted by the filter, a nil filter treats every area the same
+func FindPath(nm *CompiledNavMesh, start, goal mgl64.Vec3, filter *QueryFilter) []PolyRef {
+	query := NewSlicedPathQuery(nm, start, goal, filter)
+	query.Update(math.MaxInt)
+	return query.Path()
+}
+
+// SlicedPathQuery is a FindPath search that can be run a few nodes at a time so
+// long searches are spread over several frames
+type SlicedPathQuery struct {
+	nm     *CompiledNavMesh
+	goal   mgl64.Vec3
+	filter *QueryFilter
+
+	goalPolygon  PolyRef
+	open         *gheap.Heap[*Node]
+	nodeMap      map[PolyRef]*Node
+	lastBestNode *Node
+	lastBestCost float64
+	done         bool
+}
 
-	_, startPolygon, _ := FindNearestPolygon(tile, start)
-	_, goalPolygon, _ := FindNearestPolygon(tile, goal)
+// NewSlicedPathQuery starts a search for a path from start to goal, nothing is
+// searched until Update is called
+func NewSlicedPathQuery(nm *CompiledNavMesh, start, goal mgl64.Vec3, filter *QueryFilter) *SlicedPathQuery {
+	query := &SlicedPathQuery{nm: nm, goal: goal, filter: filter}
+
+	_, startPolygon, _ := FindNearestPolygon(nm, start, filter)
+	_, goalPolygon, _ := FindNearestPolygon(nm, goal, filter)
+	if startPolygon == InvalidPolyRef || goalPolygon == InvalidPolyRef {
+		query.done = true
+		return query
+	}
 
 	startNode := &Node{Polygon: startPolygon, Cost: 0, Position: start}
-	lastBestNode := startNode
-	lastBestCost := start.Sub(goal).Len()
+	query.goalPolygon = goalPolygon
+	query.lastBestNode = startNode
+	query.lastBestCost = start.Sub(goal).Len()
+	query.open = gheap.New(Less)
+	query.open.Push(startNode)
+	query.nodeMap = map[PolyRef]*Node{}
+	return query
+}
 
-	open := gheap.New(Less)
-	open.Push(startNode)
+// Done returns whether the search has finished
+func (q *SlicedPathQuery) Done() bool {
+	return q.done
+}
 
-	nodeMap := map[int]*Node{}
+// Update expands up to maxNodes nodes of the search and returns how many were
+// expanded
+func (q *SlicedPathQuery) Update(maxNodes int) int {
+	nm, goal, filter := q.nm, q.goal, q.filter
+	goalPolygon := q.goalPolygon
+	open := q.open
+
+	var expanded int
+	for !q.done && expanded < maxNodes {
+		if open.Len() == 0 {
+			q.done = true
+			break
+		}
 
-	for open.Len() > 0 {
 		node := open.Pop()
 		node.InOpenList = false
 		node.InClosedList = true
+		expanded++
 
-		polygonIndex := node.Polygon
-
-		if polygonIndex == goalPolygon {
-			lastBestNode = node
+		if node.Polygon == goalPolygon {
+			q.lastBestNode = node
+			q.done = true
 			break
 		}
 
-		polygon := tile.Polygons[polygonIndex]
+		area := areaAt(nm, node.Polygon)
 
 		var cost, heuristic float64
-		for _, neighborIndex := range polygon.PolyNeighbors {
-			if neighborIndex == -1 || (node.Parent != nil && node.Parent.Polygon == neighborIndex) {
+		for _, neighborRef := range polygonNeighbors(nm, node.Polygon) {
+			if node.Parent != nil && node.Parent.Polygon == neighborRef {
+				continue
+			}
+
+			neighborArea := areaAt(nm, neighborRef)
+			if !filter.PassFilter(neighborArea) {
 				continue
 			}
 
 			var neighborNode *Node
-			if nn, ok := nodeMap[neighborIndex]; ok {
+			if nn, ok := q.nodeMap[neighborRef]; ok {
 				neighborNode = nn
 			} else {
-				midpoint, success := GetEdgeMidpoint(tile, node.Polygon, neighborIndex)
+				left, right, success := GetPortal(nm, node.Polygon, neighborRef)
 				if !success {
 					panic("failed to get edge mid point")
 				}
 				neighborNode = &Node{
-					Position: midpoint,
-					Polygon:  neighborIndex,
+					Position: left.Add(right).Mul(.5),
+					Polygon:  neighborRef,
 				}
-				nodeMap[neighborIndex] = neighborNode
+				q.nodeMap[neighborRef] = neighborNode
 			}
 
-			if neighborIndex == goalPolygon {
-				endCost := neighborNode.Position.Sub(goal).Len()
-				cost = node.Cost + neighborNode.Position.Sub(node.Position).Len() + endCost
+			// the cost of reaching the portal is weighted by the polygon it's
+			// reached through
+			cost = node.Cost + filter.Cost(node.Position, neighborNode.Position, area)
+			if node.Polygon.IsOffMeshConnection() {
+				// off-mesh connections are traversed to their end, which isn't
+				// necessarily where the neighbor's node is
+				connection := nm.OffMeshConnections[node.Polygon.Poly]
+				end, _ := offMeshConnectionPoint(connection, neighborRef)
+				cost = node.Cost + filter.Cost(node.Position, end, area) + connection.Cost + filter.Cost(end, neighborNode.Position, neighborArea)
+			}
+			if neighborRef == goalPolygon {
+				cost += filter.Cost(neighborNode.Position, goal, neighborArea)
 				heuristic = 0
 			} else {
-				cost = node.Cost + neighborNode.Position.Sub(node.Position).Len()
 				heuristic = neighborNode.Position.Sub(goal).Len()
 			}
 
@@ -106,7 +166,7 @@ func FindPath(nm *CompiledNavMesh, start, goal mgl64.Vec3) []int {
 
 			if neighborNode.InOpenList {
 				for i, node := range open.Slice {
-					if node.Polygon == neighborIndex {
+					if node.Polygon == neighborRef {
 						open.Fix(i)
 						break
 					}
@@ -116,15 +176,27 @@ func FindPath(nm *CompiledNavMesh, start, goal mgl64.Vec3) []int {
 				open.Push(neighborNode)
 			}
 
-			if heuristic < lastBestCost {
-				lastBestNode = neighborNode
-				lastBestCost = heuristic
+			// paths can't end partway through an off-mesh connection
+			if heuristic < q.lastBestCost && !neighborRef.IsOffMeshConnection() {
+				q.lastBestNode = neighborNode
+				q.lastBestCost = heuristic
 			}
 		}
 	}
 
-	var path []int
-	n := lastBestNode
+	return expanded
+}
+
+// Path returns the path found by the search, or the path towards the polygon
+// nearest the goal if the goal can't be reached. it's nil until the search is
+// done or if start or goal aren't on the nav mesh
+func (q *SlicedPathQuery) Path() []PolyRef {
+	if !q.done {
+		return nil
+	}
+
+	var path []PolyRef
+	n := q.lastBestNode
 	for n != nil {
 		path = append(path, n.Polygon)
 		n = n.Parent
@@ -134,20 +206,122 @@ func FindPath(nm *CompiledNavMesh, start, goal mgl64.Vec3) []int {
 	return path
 }
 
+func polygonAt(nm *CompiledNavMesh, ref PolyRef) *CPolygon {
+	return &nm.Tiles[ref.Tile].Polygons[ref.Poly]
+}
+
+// areaAt returns the area type of the polygon or off-mesh connection
+func areaAt(nm *CompiledNavMesh, ref PolyRef) AREA_TYPE {
+	if ref.IsOffMeshConnection() {
+		return nm.OffMeshConnections[ref.Poly].Area
+	}
+	return polygonAt(nm, ref).Area
+}
+
+// polygonNeighbors returns the polygons that share an edge with the polygon,
+// including polygons in neighboring tiles, and the off-mesh connections that
+// start on it. the neighbors of an off-mesh connection are the polygons it leads
+// to
+func polygonNeighbors(nm *CompiledNavMesh, ref PolyRef) []PolyRef {
+	if ref.IsOffMeshConnection() {
+		return offMeshConnectionNeighbors(nm, ref)
+	}
+
+	polygon := nm.Tiles[ref.Tile].Polygons[ref.Poly]
+
+	var neighbors []PolyRef
+	for _, neighborIndex := range polygon.PolyNeighbors {
+		if neighborIndex == -1 {
+			continue
+		}
+		neighbors = append(neighbors, PolyRef{Tile: ref.Tile, Poly: neighborIndex, Salt: ref.Salt})
+	}
+	for _, link := range polygon.Links {
+		neighbors = append(neighbors, link.Ref)
+	}
+	return neighbors
+}
+
 // FindStraightPath takes polyPath which is a list of polygons and runs the funnel
 // algorithm along the portals between each polygon.
 //
 // the funnel is the actively managed funnel that we are attempting tho tighten
 // portals are the shared edge connection between two polygons
-func FindStraightPath(tile CTile, start, goal mgl64.Vec3, polyPath []int) []mgl64.Vec3 {
+func FindStraightPath(nm *CompiledNavMesh, start, goal mgl64.Vec3, polyPath []PolyRef) []mgl64.Vec3 {
+	path, _ := FindStraightPathWithConnections(nm, start, goal, polyPath)
+	return path
+}
+
+// FindStraightPathWithConnections is FindStraightPath for paths that can go
+// through off-mesh connections. the funnel is run separately between each
+// connection, and along with the path it returns the index of the off-mesh
+// connection that starts at each point or -1. the point after the start of a
+// connection is always its end. paths with consecutive polygons that don't
+// share a portal, e.g. from tiles that have been rebuilt since the path was
+// found, return no path
+func FindStraightPathWithConnections(nm *CompiledNavMesh, start, goal mgl64.Vec3, polyPath []PolyRef) ([]mgl64.Vec3, []int) {
+	// a path that ends on a connection is cut short at its start
+	for len(polyPath) > 0 && polyPath[len(polyPath)-1].IsOffMeshConnection() {
+		polyPath = polyPath[:len(polyPath)-1]
+	}
 	if len(polyPath) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	closestStart, _ := closestPointOnPoly(tile, polyPath[0], start)
-	closestGoal, _ := closestPointOnPoly(tile, polyPath[len(polyPath)-1], goal)
+	startPoly := polyPath[0]
+	goalPoly := polyPath[len(polyPath)-1]
+	closestStart, _ := closestPointOnPoly(nm.Tiles[startPoly.Tile], startPoly.Poly, start)
+	closestGoal, _ := closestPointOnPoly(nm.Tiles[goalPoly.Tile], goalPoly.Poly, goal)
 
-	portals := buildPathPortals(tile, polyPath, closestGoal)
+	var path []mgl64.Vec3
+	var connections []int
+	appendSegment := func(segment []mgl64.Vec3) {
+		for _, point := range segment {
+			if len(path) > 0 && vEqual(path[len(path)-1], point) {
+				continue
+			}
+			path = append(path, point)
+			connections = append(connections, -1)
+		}
+	}
+
+	segmentStart := closestStart
+	first := 0
+	for i, ref := range polyPath {
+		if !ref.IsOffMeshConnection() {
+			continue
+		}
+
+		connectionStart, _, startOK := GetPortal(nm, polyPath[i-1], ref)
+		connectionEnd, _, endOK := GetPortal(nm, ref, polyPath[i+1])
+		segment, ok := findStraightPathSegment(nm, segmentStart, connectionStart, polyPath[first:i])
+		if !startOK || !endOK || !ok {
+			return nil, nil
+		}
+
+		appendSegment(segment)
+		connections[len(connections)-1] = ref.Poly
+
+		segmentStart = connectionEnd
+		first = i + 1
+	}
+	segment, ok := findStraightPathSegment(nm, segmentStart, closestGoal, polyPath[first:])
+	if !ok {
+		return nil, nil
+	}
+	appendSegment(segment)
+
+	return path, connections
+}
+
+// findStraightPathSegment runs the funnel algorithm from closestStart on the first
+// polygon of polyPath to closestGoal on the last. it fails if consecutive
+// polygons don't share a portal
+func findStraightPathSegment(nm *CompiledNavMesh, closestStart, closestGoal mgl64.Vec3, polyPath []PolyRef) ([]mgl64.Vec3, bool) {
+	portals, ok := buildPathPortals(nm, polyPath, closestGoal)
+	if !ok {
+		return nil, false
+	}
 
 	funnelApex := closestStart
 	funnelLeft := funnelApex
@@ -174,8 +348,8 @@ func FindStraightPath(tile CTile, start, goal mgl64.Vec3, polyPath []int) []mgl6
 				rightIndex = i
 			} else {
 				// the portal's right vertex collapses the funnel and we've discovered a turning update the apex
-				if appendPortalPoint(&path, tile, portals[leftIndex], funnelLeft) {
-					return path
+				if appendPortalPoint(&path, nm, portals[leftIndex], funnelLeft) {
+					return path, true
 				}
 
 				// collapse the funnel into a new singular apex
@@ -200,8 +374,8 @@ func FindStraightPath(tile CTile, start, goal mgl64.Vec3, polyPath []int) []mgl6
 				leftIndex = i
 			} else {
 				// the portal's left vertex collapses the funnel and we've discovered a turning point update the apex
-				if appendPortalPoint(&path, tile, portals[rightIndex], funnelRight) {
-					return path
+				if appendPortalPoint(&path, nm, portals[rightIndex], funnelRight) {
+					return path, true
 				}
 
 				// collapse the funnel into a new singular apex
@@ -222,7 +396,7 @@ func FindStraightPath(tile CTile, start, goal mgl64.Vec3, polyPath []int) []mgl6
 
 	appendPoint(&path, closestGoal)
 
-	return path
+	return path, true
 }
 
 func vEqual(a, b mgl64.Vec3) bool {
@@ -264,6 +438,32 @@ func GetEdgeMidpoint(tile CTile, from, to int) (mgl64.Vec3, bool) {
 	return leftVert.Add(rightVert).Mul(.5), true
 }
 
+// GetPortal returns the left and right end of the edge that the from polygon
+// shares with the to polygon, which can be in a neighboring tile. portals to and
+// from off-mesh connections are the end of the connection on the polygon
+func GetPortal(nm *CompiledNavMesh, from, to PolyRef) (mgl64.Vec3, mgl64.Vec3, bool) {
+	if from.IsOffMeshConnection() {
+		point, ok := offMeshConnectionPoint(nm.OffMeshConnections[from.Poly], to)
+		return point, point, ok
+	}
+
+	tile := nm.Tiles[from.Tile]
+	if from.Tile == to.Tile {
+		left, right, success := GetPortalVertIndices(tile, from.Poly, to.Poly)
+		if success {
+			return tile.Vertices[left], tile.Vertices[right], true
+		}
+	}
+
+	for _, link := range tile.Polygons[from.Poly].Links {
+		if link.Ref == to {
+			return link.Left, link.Right, true
+		}
+	}
+
+	return mgl64.Vec3{}, mgl64.Vec3{}, false
+}
+
 func GetPortalVertIndices(tile CTile, from, to int) (int, int, bool) {
 	fromPoly := tile.Polygons[from]
 
@@ -283,13 +483,52 @@ func GetPortalVertIndices(tile CTile, from, to int) (int, int, bool) {
 	return -1, -1, false
 }
 
-func FindNearestPolygon(tile CTile, point mgl64.Vec3) (mgl64.Vec3, int, bool) {
+// FindNearestPolygon returns the nearest point on the nav mesh to point along with
+// the polygon it lies on and whether point is over the polygon. the tiles around
+// point are searched first, falling back to the whole nav mesh. polygons that
+// don't pass the filter are skipped
+func FindNearestPolygon(nm *CompiledNavMesh, point mgl64.Vec3, filter *QueryFilter) (mgl64.Vec3, PolyRef, bool) {
+	nearestPoint, nearestPoly, overPoly := mgl64.Vec3{}, InvalidPolyRef, false
+	if nm == nil {
+		return nearestPoint, nearestPoly, overPoly
+	}
+
+	x, z := nm.TilePosition(point)
+	nearestDistSq := math.MaxFloat64
+	searchTiles := func(nearby bool) {
+		for i, tile := range nm.Tiles {
+			if nearby && nm.TileWidth != 0 && (Abs(tile.X-x) > 1 || Abs(tile.Z-z) > 1) {
+				continue
+			}
+			pt, poly, op, distSq := findNearestPolygonInTile(tile, point, filter)
+			if poly != -1 && distSq < nearestDistSq {
+				nearestDistSq = distSq
+				nearestPoint = pt
+				nearestPoly = PolyRef{Tile: i, Poly: poly, Salt: tile.Salt}
+				overPoly = op
+			}
+		}
+	}
+
+	searchTiles(true)
+	if nearestPoly == InvalidPolyRef {
+		searchTiles(false)
+	}
+
+	return nearestPoint, nearestPoly, overPoly
+}
+
+func findNearestPolygonInTile(tile CTile, point mgl64.Vec3, filter *QueryFilter) (mgl64.Vec3, int, bool, float64) {
 	var nearestDistSq float64 = math.MaxFloat64
 	var nearestPoint mgl64.Vec3
-	var nearestPoly int
+	var nearestPoly int = -1
 	var overPoly bool
 
-	for i, _ := range tile.Polygons {
+	for i := range tile.Polygons {
+		if !filter.PassFilter(tile.Polygons[i].Area) {
+			continue
+		}
+
 		// find neareast point on the polygon
 		// height should be taken from the detailed mesh
 		pt, op := closestPointOnPoly(tile, i, point)
@@ -303,7 +542,7 @@ func FindNearestPolygon(tile CTile, point mgl64.Vec3) (mgl64.Vec3, int, bool) {
 		}
 	}
 
-	return nearestPoint, nearestPoly, overPoly
+	return nearestPoint, nearestPoly, overPoly, nearestDistSq
 }
 
 // closestPointOnPolyBoundary is faster than closestPointOnPoly, but does not return detailed heights
@@ -482,16 +721,16 @@ func Less(n0, n1 *Node) bool {
 	return n0.Cost < n1.Cost
 }
 
-func buildPathPortals(tile CTile, polyPath []int, closestGoal mgl64.Vec3) []pathPortal {
+func buildPathPortals(nm *CompiledNavMesh, polyPath []PolyRef, closestGoal mgl64.Vec3) ([]pathPortal, bool) {
 	portals := make([]pathPortal, 0, len(polyPath))
 	for i := 1; i < len(polyPath); i++ {
-		l, r, success := GetPortalVertIndices(tile, polyPath[i-1], polyPath[i])
+		left, right, success := GetPortal(nm, polyPath[i-1], polyPath[i])
 		if !success {
-			panic(fmt.Sprintf("could not find portal vertices between %d, %d", polyPath[i-1], polyPath[i]))
+			return nil, false
 		}
 		portals = append(portals, pathPortal{
-			Left:        tile.Vertices[l],
-			Right:       tile.Vertices[r],
+			Left:        left,
+			Right:       right,
 			ProjectPoly: polyPath[i],
 		})
 	}
@@ -503,16 +742,16 @@ func buildPathPortals(tile CTile, polyPath []int, closestGoal mgl64.Vec3) []path
 		End:         true,
 	})
 
-	return portals
+	return portals, true
 }
 
-func appendPortalPoint(path *[]mgl64.Vec3, tile CTile, portal pathPortal, point mgl64.Vec3) bool {
+func appendPortalPoint(path *[]mgl64.Vec3, nm *CompiledNavMesh, portal pathPortal, point mgl64.Vec3) bool {
 	if portal.End {
 		appendPoint(path, point)
 		return true
 	}
 
-	appendPoint(path, projectPathPoint(tile, portal.ProjectPoly, point))
+	appendPoint(path, projectPathPoint(nm.Tiles[portal.ProjectPoly.Tile], portal.ProjectPoly.Poly, point))
 	return false
 }
 
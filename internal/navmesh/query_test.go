@@ -1,6 +1,7 @@
 package navmesh
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/go-gl/mathgl/mgl64"
@@ -37,3 +38,356 @@ func TestProjectPathPointUsesExplicitPolygon(t *testing.T) {
 		t.Fatalf("projected y = %v, want %v", got, want)
 	}
 }
+
+// twoTileNavMesh is two unit tiles side by side along x. the right tile splits
+// the shared border between two polygons so it doesn't line up with the left
+// tile's vertices
+func twoTileNavMesh() *CompiledNavMesh {
+	nm := &CompiledNavMesh{
+		TileWidth:     1,
+		WalkableClimb: 0.1,
+		Tiles: []CTile{
+			{
+				X: 0, Z: 0,
+				Vertices: []mgl64.Vec3{{0, 0, 0}, {0, 0, 1}, {1, 0, 1}, {1, 0, 0}},
+				Polygons: []CPolygon{
+					{Vertices: []int{0, 1, 2, 3}, PolyNeighbors: []int{-1, -1, -1, -1}},
+				},
+			},
+			{
+				X: 1, Z: 0,
+				Vertices: []mgl64.Vec3{{1, 0, 0}, {1, 0, 0.5}, {2, 0, 0.5}, {2, 0, 0}, {1, 0, 1}, {2, 0, 1}},
+				Polygons: []CPolygon{
+					{Vertices: []int{0, 1, 2, 3}, PolyNeighbors: []int{-1, 1, -1, -1}},
+					{Vertices: []int{1, 4, 5, 2}, PolyNeighbors: []int{-1, -1, -1, 0}},
+				},
+			},
+		},
+	}
+	for i := range nm.Tiles {
+		tile := &nm.Tiles[i]
+		for _, polygon := range tile.Polygons {
+			var verts []mgl64.Vec3
+			for _, v := range polygon.Vertices {
+				verts = append(verts, tile.Vertices[v])
+			}
+			tile.DetailedVertices = append(tile.DetailedVertices, verts)
+			tile.DetailedPolygon = append(tile.DetailedPolygon, CDetailedPolygon{Triangles: []CDetailedTriangle{
+				{Vertices: [3]int{0, 1, 2}}, {Vertices: [3]int{0, 2, 3}},
+			}})
+		}
+	}
+	LinkTiles(nm)
+	return nm
+}
+
+func TestLinkTiles(t *testing.T) {
+	nm := twoTileNavMesh()
+
+	links := nm.Tiles[0].Polygons[0].Links
+	if len(links) != 2 {
+		t.Fatalf("expected the left polygon to link to both right polygons, got %+v", links)
+	}
+	for _, link := range links {
+		if link.Edge != 2 {
+			t.Fatalf("expected links on the border edge, got edge %d", link.Edge)
+		}
+		if link.Left.Sub(link.Right).Len() != 0.5 {
+			t.Fatalf("expected each link to cover half the border, got %v to %v", link.Right, link.Left)
+		}
+	}
+
+	for i, polygon := range nm.Tiles[1].Polygons {
+		if len(polygon.Links) != 1 || polygon.Links[0].Ref != (PolyRef{Tile: 0, Poly: 0}) {
+			t.Fatalf("expected polygon %d to link back to the left tile, got %+v", i, polygon.Links)
+		}
+	}
+}
+
+func TestSetTiles(t *testing.T) {
+	nm := twoTileNavMesh()
+	left, right := PolyRef{Tile: 0, Poly: 0}, PolyRef{Tile: 1, Poly: 0}
+	leftTile := nm.Tiles[0]
+
+	// replacing a tile keeps it in its slot but refs to the old tile are stale
+	nm.SetTiles([]CTile{leftTile})
+	if len(nm.Tiles) != 2 || nm.Tiles[0].X != 0 || nm.Tiles[1].X != 1 {
+		t.Fatalf("expected the tiles to keep their slots, got %d tiles", len(nm.Tiles))
+	}
+	if nm.ValidPolyRef(left) {
+		t.Fatal("expected refs to the replaced tile to be invalid")
+	}
+	if !nm.ValidPolyRef(right) {
+		t.Fatal("expected refs to the untouched tile to stay valid")
+	}
+	if _, ref, _ := FindNearestPolygon(nm, mgl64.Vec3{0.5, 0, 0.5}, nil); ref != (PolyRef{Tile: 0, Poly: 0, Salt: 1}) {
+		t.Fatalf("expected new refs to the replaced tile to have its new salt, got %v", ref)
+	}
+	if got := nm.Tiles[1].Polygons[0].Links[0].Ref; got != (PolyRef{Tile: 0, Poly: 0, Salt: 1}) {
+		t.Fatalf("expected the link to the replaced tile to have its new salt, got %v", got)
+	}
+
+	// removing a tile leaves its slot empty rather than shifting the others
+	nm.SetTiles([]CTile{{X: 0, Z: 0}})
+	if len(nm.Tiles) != 2 || len(nm.Tiles[0].Polygons) != 0 || nm.Tiles[1].X != 1 {
+		t.Fatal("expected the removed tile to leave an empty slot")
+	}
+	if !nm.ValidPolyRef(right) {
+		t.Fatal("expected refs to the other tile to stay valid after a removal")
+	}
+	if links := nm.Tiles[1].Polygons[0].Links; len(links) != 0 {
+		t.Fatalf("expected no links to the removed tile, got %+v", links)
+	}
+
+	// the empty slot is reused with a salt that no earlier ref has
+	leftTile.X, leftTile.Z = 0, 1
+	nm.SetTiles([]CTile{leftTile})
+	if len(nm.Tiles) != 2 || nm.Tiles[0].Z != 1 || nm.Tiles[0].Salt != 3 {
+		t.Fatalf("expected the new tile to reuse the empty slot with salt 3, got %+v", nm.Tiles[0])
+	}
+	if nm.ValidPolyRef(left) || nm.ValidPolyRef(PolyRef{Tile: 0, Poly: 0, Salt: 1}) {
+		t.Fatal("expected refs to the slot's earlier tiles to be invalid")
+	}
+}
+
+func TestLinkTilesHeight(t *testing.T) {
+	nm := twoTileNavMesh()
+	for i := range nm.Tiles[1].Vertices {
+		nm.Tiles[1].Vertices[i][1] = 1
+	}
+	LinkTiles(nm)
+
+	if len(nm.Tiles[0].Polygons[0].Links) != 0 {
+		t.Fatal("expected tiles with a height difference above the walkable climb to not be linked")
+	}
+}
+
+func TestFindPathAcrossTiles(t *testing.T) {
+	nm := twoTileNavMesh()
+	start := mgl64.Vec3{0.5, 0, 0.9}
+	goal := mgl64.Vec3{1.5, 0, 0.1}
+
+	path := FindPath(nm, start, goal, nil)
+	want := []PolyRef{{Tile: 0, Poly: 0}, {Tile: 1, Poly: 0}}
+	if !slices.Equal(path, want) {
+		t.Fatalf("path = %v, want %v", path, want)
+	}
+
+	straightPath := FindStraightPath(nm, start, goal, path)
+	if len(straightPath) != 2 || !vEqual(straightPath[0], start) || !vEqual(straightPath[1], goal) {
+		t.Fatalf("expected a straight line across the border, got %v", straightPath)
+	}
+}
+
+// gridNavMesh is a single tile of unit square polygons laid out along x and z
+// with the given area types, areas[z][x]
+func gridNavMesh(areas [][]AREA_TYPE) *CompiledNavMesh {
+	width, height := len(areas[0]), len(areas)
+	vertex := func(x, z int) int { return x + z*(width+1) }
+	cell := func(x, z int) int {
+		if x < 0 || x >= width || z < 0 || z >= height {
+			return -1
+		}
+		return x + z*width
+	}
+
+	var tile CTile
+	for z := range height + 1 {
+		for x := range width + 1 {
+			tile.Vertices = append(tile.Vertices, mgl64.Vec3{float64(x), 0, float64(z)})
+		}
+	}
+	for z := range height {
+		for x := range width {
+			polygon := CPolygon{
+				Vertices:      []int{vertex(x, z), vertex(x, z+1), vertex(x+1, z+1), vertex(x+1, z)},
+				PolyNeighbors: []int{cell(x-1, z), cell(x, z+1), cell(x+1, z), cell(x, z-1)},
+				Area:          areas[z][x],
+			}
+			var verts []mgl64.Vec3
+			for _, v := range polygon.Vertices {
+				verts = append(verts, tile.Vertices[v])
+			}
+			tile.Polygons = append(tile.Polygons, polygon)
+			tile.DetailedVertices = append(tile.DetailedVertices, verts)
+			tile.DetailedPolygon = append(tile.DetailedPolygon, CDetailedPolygon{Triangles: []CDetailedTriangle{
+				{Vertices: [3]int{0, 1, 2}}, {Vertices: [3]int{0, 2, 3}},
+			}})
+		}
+	}
+	return &CompiledNavMesh{Tiles: []CTile{tile}}
+}
+
+func TestFindPathQueryFilter(t *testing.T) {
+	g, w := WALKABLE_AREA, WATER_AREA
+	nm := gridNavMesh([][]AREA_TYPE{
+		{g, w, g},
+		{g, g, g},
+	})
+	start := mgl64.Vec3{0.5, 0, 0.5}
+	goal := mgl64.Vec3{2.5, 0, 0.5}
+	water := PolyRef{Tile: 0, Poly: 1}
+
+	pathThroughWater := []PolyRef{{Tile: 0, Poly: 0}, water, {Tile: 0, Poly: 2}}
+	aroundWater := []PolyRef{{Tile: 0, Poly: 0}, {Tile: 0, Poly: 3}, {Tile: 0, Poly: 4}, {Tile: 0, Poly: 5}, {Tile: 0, Poly: 2}}
+
+	excludeWater := NewQueryFilter()
+	excludeWater.Exclude(WATER_AREA)
+
+	expensiveWater := NewQueryFilter()
+	expensiveWater.SetAreaCost(WATER_AREA, 10)
+
+	testCases := []struct {
+		name   string
+		filter *QueryFilter
+		want   []PolyRef
+	}{
+		{name: "no filter", filter: nil, want: pathThroughWater},
+		{name: "default filter", filter: NewQueryFilter(), want: pathThroughWater},
+		{name: "exclude water", filter: excludeWater, want: aroundWater},
+		{name: "expensive water", filter: expensiveWater, want: aroundWater},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := FindPath(nm, start, goal, tc.filter)
+			if !slices.Equal(path, tc.want) {
+				t.Fatalf("path = %v, want %v", path, tc.want)
+			}
+		})
+	}
+}
+
+func TestFindPathPrefersCheaperAreas(t *testing.T) {
+	g, r := WALKABLE_AREA, ROAD_AREA
+	nm := gridNavMesh([][]AREA_TYPE{
+		{r, g, r},
+		{r, r, r},
+	})
+	start := mgl64.Vec3{0.5, 0, 0.5}
+	goal := mgl64.Vec3{2.5, 0, 0.5}
+
+	preferRoads := NewQueryFilter()
+	preferRoads.SetAreaCost(WALKABLE_AREA, 4)
+
+	path := FindPath(nm, start, goal, preferRoads)
+	want := []PolyRef{{Tile: 0, Poly: 0}, {Tile: 0, Poly: 3}, {Tile: 0, Poly: 4}, {Tile: 0, Poly: 5}, {Tile: 0, Poly: 2}}
+	if !slices.Equal(path, want) {
+		t.Fatalf("expected the path to stay on the road, got %v", path)
+	}
+}
+
+func TestFindNearestPolygonQueryFilter(t *testing.T) {
+	nm := gridNavMesh([][]AREA_TYPE{{WATER_AREA, WALKABLE_AREA}})
+
+	filter := NewQueryFilter()
+	filter.IncludeFlags = WALKABLE_AREA.Flag()
+
+	_, ref, over := FindNearestPolygon(nm, mgl64.Vec3{0.5, 0, 0.5}, filter)
+	if ref != (PolyRef{Tile: 0, Poly: 1}) || over {
+		t.Fatalf("expected the nearest polygon outside of the water, got %v", ref)
+	}
+}
+
+// ledgeNavMesh is a row of four unit polygons along x where the middle two aren't
+// connected, the right half can only be reached through off-mesh connections
+func ledgeNavMesh(connections ...OffMeshConnection) *CompiledNavMesh {
+	g := WALKABLE_AREA
+	nm := gridNavMesh([][]AREA_TYPE{{g, g, g, g}})
+	nm.Tiles[0].Polygons[1].PolyNeighbors[2] = -1
+	nm.Tiles[0].Polygons[2].PolyNeighbors[0] = -1
+	nm.OffMeshConnections = connections
+	LinkTiles(nm)
+	return nm
+}
+
+func TestFindPathOffMeshConnection(t *testing.T) {
+	jump := OffMeshConnection{Start: mgl64.Vec3{1.5, 0, 0.5}, End: mgl64.Vec3{2.5, 0, 0.5}, Radius: 0.5, Area: DOOR_AREA}
+	left := mgl64.Vec3{0.5, 0, 0.5}
+	right := mgl64.Vec3{3.5, 0, 0.5}
+	across := []PolyRef{{Tile: 0, Poly: 0}, {Tile: 0, Poly: 1}, OffMeshConnectionRef(0), {Tile: 0, Poly: 2}, {Tile: 0, Poly: 3}}
+	back := []PolyRef{{Tile: 0, Poly: 3}, {Tile: 0, Poly: 2}, OffMeshConnectionRef(0), {Tile: 0, Poly: 1}, {Tile: 0, Poly: 0}}
+
+	excludeDoors := NewQueryFilter()
+	excludeDoors.Exclude(DOOR_AREA)
+
+	bidirectional := jump
+	bidirectional.Bidirectional = true
+
+	testCases := []struct {
+		name       string
+		connection OffMeshConnection
+		filter     *QueryFilter
+		start      mgl64.Vec3
+		goal       mgl64.Vec3
+		want       []PolyRef
+	}{
+		{name: "across", connection: jump, start: left, goal: right, want: across},
+		{name: "one way", connection: jump, start: right, goal: left, want: []PolyRef{{Tile: 0, Poly: 3}, {Tile: 0, Poly: 2}}},
+		{name: "bidirectional", connection: bidirectional, start: right, goal: left, want: back},
+		{name: "filtered", connection: jump, filter: excludeDoors, start: left, goal: right, want: []PolyRef{{Tile: 0, Poly: 0}, {Tile: 0, Poly: 1}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nm := ledgeNavMesh(tc.connection)
+			path := FindPath(nm, tc.start, tc.goal, tc.filter)
+			if !slices.Equal(path, tc.want) {
+				t.Fatalf("path = %v, want %v", path, tc.want)
+			}
+		})
+	}
+}
+
+func TestOffMeshConnectionOutOfRadius(t *testing.T) {
+	nm := ledgeNavMesh(OffMeshConnection{Start: mgl64.Vec3{1.5, 2, 0.5}, End: mgl64.Vec3{2.5, 0, 0.5}, Radius: 0.5})
+
+	connection := nm.OffMeshConnections[0]
+	if connection.StartRef != InvalidPolyRef || connection.EndRef != (PolyRef{Tile: 0, Poly: 2}) {
+		t.Fatalf("expected only the end of the connection to be attached, got %v and %v", connection.StartRef, connection.EndRef)
+	}
+	if links := nm.Tiles[0].Polygons[1].Links; len(links) != 0 {
+		t.Fatalf("expected no links to a connection that isn't attached, got %+v", links)
+	}
+}
+
+func TestFindStraightPathWithConnections(t *testing.T) {
+	nm := ledgeNavMesh(OffMeshConnection{Start: mgl64.Vec3{1.5, 0, 0.5}, End: mgl64.Vec3{2.5, 0, 0.5}, Radius: 0.5})
+	start := mgl64.Vec3{0.5, 0, 0.1}
+	goal := mgl64.Vec3{3.5, 0, 0.9}
+
+	path, connections := FindStraightPathWithConnections(nm, start, goal, FindPath(nm, start, goal, nil))
+	want := []mgl64.Vec3{start, {1.5, 0, 0.5}, {2.5, 0, 0.5}, goal}
+	if len(path) != len(want) {
+		t.Fatalf("path = %v, want %v", path, want)
+	}
+	for i := range want {
+		if !vEqual(path[i], want[i]) {
+			t.Fatalf("path = %v, want %v", path, want)
+		}
+	}
+	if !slices.Equal(connections, []int{-1, 0, -1, -1}) {
+		t.Fatalf("expected the connection to start at the second point, got %v", connections)
+	}
+}
+
+func TestFindStraightPathWithoutPortal(t *testing.T) {
+	nm := ledgeNavMesh()
+	start := mgl64.Vec3{0.5, 0, 0.5}
+	goal := mgl64.Vec3{3.5, 0, 0.5}
+
+	// the middle polygons don't share an edge, e.g. a stale path across a tile
+	// that has since been rebuilt
+	polyPath := []PolyRef{{Tile: 0, Poly: 0}, {Tile: 0, Poly: 1}, {Tile: 0, Poly: 2}, {Tile: 0, Poly: 3}}
+	if path := FindStraightPath(nm, start, goal, polyPath); path != nil {
+		t.Fatalf("expected no path without a portal between polygons, got %v", path)
+	}
+
+	// the connection starts on the second polygon, not the first
+	jump := OffMeshConnection{Start: mgl64.Vec3{1.5, 0, 0.5}, End: mgl64.Vec3{2.5, 0, 0.5}, Radius: 0.5}
+	nm = ledgeNavMesh(jump)
+	polyPath = []PolyRef{{Tile: 0, Poly: 0}, OffMeshConnectionRef(0), {Tile: 0, Poly: 2}, {Tile: 0, Poly: 3}}
+	if path, connections := FindStraightPathWithConnections(nm, start, goal, polyPath); path != nil || connections != nil {
+		t.Fatalf("expected no path through a connection that doesn't link its polygons, got %v %v", path, connections)
+	}
+}
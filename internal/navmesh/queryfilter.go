@@ -0,0 +1,66 @@
+package navmesh
+
+import "github.com/go-gl/mathgl/mgl64"
+
+// AreaFlags is a set of area types, see AREA_TYPE.Flag
+type AreaFlags uint64
+
+// AllAreas includes every area type
+const AllAreas AreaFlags = ^AreaFlags(0)
+
+// Flag returns the flag representing the area type in AreaFlags
+func (a AREA_TYPE) Flag() AreaFlags {
+	return 1 << AreaFlags(a)
+}
+
+// QueryFilter controls which polygons queries can pass through and how costly it
+// is to cross them. a nil filter allows every area at a uniform cost
+type QueryFilter struct {
+	// IncludeFlags are the areas that can be passed through, ExcludeFlags take
+	// precedence over them
+	IncludeFlags AreaFlags
+	ExcludeFlags AreaFlags
+	// AreaCosts multiplies the distance travelled across polygons of each area
+	// type
+	AreaCosts [maxAreas]float64
+}
+
+// NewQueryFilter returns a filter that allows every area at a uniform cost
+func NewQueryFilter() *QueryFilter {
+	filter := &QueryFilter{IncludeFlags: AllAreas}
+	for i := range filter.AreaCosts {
+		filter.AreaCosts[i] = 1
+	}
+	return filter
+}
+
+// SetAreaCost sets the cost multiplier of travelling across the area type
+func (f *QueryFilter) SetAreaCost(area AREA_TYPE, cost float64) {
+	f.AreaCosts[area] = cost
+}
+
+// Exclude prevents queries from passing through the area types
+func (f *QueryFilter) Exclude(areas ...AREA_TYPE) {
+	for _, area := range areas {
+		f.ExcludeFlags |= area.Flag()
+	}
+}
+
+// PassFilter returns whether polygons and off-mesh connections of the area type
+// can be passed through
+func (f *QueryFilter) PassFilter(area AREA_TYPE) bool {
+	if f == nil {
+		return true
+	}
+	flag := area.Flag()
+	return f.IncludeFlags&flag != 0 && f.ExcludeFlags&flag == 0
+}
+
+// Cost returns the cost of travelling from a to b across the area type
+func (f *QueryFilter) Cost(a, b mgl64.Vec3, area AREA_TYPE) float64 {
+	dist := b.Sub(a).Len()
+	if f == nil {
+		return dist
+	}
+	return dist * f.AreaCosts[area]
+}
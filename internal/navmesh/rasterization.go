@@ -18,7 +18,7 @@ const (
 	AxisTypeZ AxisType = "Z"
 )
 
-func RasterizeTriangle(v0, v1, v2 mgl64.Vec3, cellSize, cellHeight float64, hf *HeightField, walkable bool, areaMergeThreshold int) int {
+func RasterizeTriangle(v0, v1, v2 mgl64.Vec3, cellSize, cellHeight float64, hf *HeightField, area AREA_TYPE, areaMergeThreshold int) int {
 	ics := 1.0 / cellSize
 	ich := 1.0 / cellHeight
 
@@ -141,7 +141,7 @@ func RasterizeTriangle(v0, v1, v2 mgl64.Vec3, cellSize, cellHeight float64, hf *
 			spanMinCellIndex := Clamp(int(math.Floor(spanMin*ich)), 0, spanMaxHeight)
 			spanMaxCellIndex := Clamp(int(math.Ceil(spanMax*ich)), spanMinCellIndex+1, spanMaxHeight)
 
-			hf.AddSpan(x, z, spanMinCellIndex, spanMaxCellIndex, walkable, areaMergeThreshold)
+			hf.AddSpan(x, z, spanMinCellIndex, spanMaxCellIndex, area, areaMergeThreshold)
 		}
 	}
 
@@ -1,10 +1,19 @@
 package navmesh
 
+import (
+	"math"
+	"slices"
+)
+
 const (
 	levelStepSize = 2
 )
 
-func BuildRegions(chf *CompactHeightField, iterationCount int, minRegionArea int, mergeRegionSize int) {
+// BuildRegions partitions the walkable spans into regions by watershedding the
+// distance field. connected regions that together have fewer than minRegionArea
+// spans are removed, and regions with up to mergeRegionArea spans are merged
+// into their smallest neighbor
+func BuildRegions(chf *CompactHeightField, iterationCount int, minRegionArea int, mergeRegionArea int) {
 	const maxStacks int = 8
 
 	// round the level up to the nearest even number
@@ -73,7 +82,7 @@ func BuildRegions(chf *CompactHeightField, iterationCount int, minRegionArea int
 
 	// expandRegions(0, chf, nil, distances, regions, true)
 
-	mergeAndFilterRegions(chf, regionIDs, minRegionArea, mergeRegionSize, &chf.maxRegionID)
+	mergeAndFilterRegions(chf, regionIDs, minRegionArea, mergeRegionArea, &chf.maxRegionID)
 
 	// update the region id for each span
 	for i := range chf.spanCount {
@@ -301,7 +310,7 @@ func expandRegions(level int, chf *CompactHeightField, stack []LevelStackEntry,
 }
 
 // mergeAndFilterRegions mutates regionIDs and maxRegionID
-func mergeAndFilterRegions(chf *CompactHeightField, regionIDs []int, minRegionArea int, mergeRegionSize int, maxRegionID *int) int {
+func mergeAndFilterRegions(chf *CompactHeightField, regionIDs []int, minRegionArea int, mergeRegionArea int, maxRegionID *int) {
 	numRegions := *maxRegionID + 1
 	regions := make([]Region, numRegions)
 	for i := 0; i < numRegions; i++ {
@@ -316,12 +325,15 @@ func mergeAndFilterRegions(chf *CompactHeightField, regionIDs []int, minRegionAr
 			spanCount := cell.SpanCount
 			for i := spanIndex; i < spanIndex+SpanIndex(spanCount); i++ {
 				regionID := regionIDs[i]
-				region := &regions[regionID]
 				if regionID == 0 || regionID >= numRegions {
 					continue
 				}
+				region := &regions[regionID]
 
 				region.spanCount++
+				if chf.onTileBorder(x, z) {
+					region.connectsToBorder = true
+				}
 
 				for j := spanIndex; j < spanIndex+SpanIndex(spanCount); j++ {
 					if i == j {
@@ -337,18 +349,23 @@ func mergeAndFilterRegions(chf *CompactHeightField, regionIDs []int, minRegionAr
 					addUniqueFloorRegion(region, floorID)
 				}
 
+				// the connections only need to be found once per region
+				if len(region.connections) > 0 {
+					continue
+				}
+
 				region.areaType = chf.areas[i]
 
 				edgeDir := -1
 				for _, dir := range dirs {
-					if isSolidEdge(chf, regionIDs, spanIndex, dir) {
+					if isSolidEdge(chf, regionIDs, i, dir) {
 						edgeDir = dir
 						break
 					}
 				}
 
 				if edgeDir != -1 {
-					region.connections = walkContour(chf, spanIndex, edgeDir, regionIDs)
+					region.connections = walkContour(chf, i, edgeDir, regionIDs)
 				}
 			}
 		}
@@ -359,7 +376,8 @@ func mergeAndFilterRegions(chf *CompactHeightField, regionIDs []int, minRegionAr
 	var stack []int
 	var trace []int
 
-	for _, region := range regions {
+	for i := range regions {
+		region := &regions[i]
 		if region.id == 0 {
 			continue
 		}
@@ -373,6 +391,7 @@ func mergeAndFilterRegions(chf *CompactHeightField, regionIDs []int, minRegionAr
 		stack = []int{region.id}
 		trace = nil
 		spanCount := 0
+		connectsToBorder := false
 		region.visited = true
 
 		for len(stack) > 0 {
@@ -381,6 +400,7 @@ func mergeAndFilterRegions(chf *CompactHeightField, regionIDs []int, minRegionAr
 			currentRegion := &regions[currentRegionID]
 
 			spanCount += currentRegion.spanCount
+			connectsToBorder = connectsToBorder || currentRegion.connectsToBorder
 			trace = append(trace, currentRegionID)
 
 			for _, conn := range currentRegion.connections {
@@ -396,8 +416,9 @@ func mergeAndFilterRegions(chf *CompactHeightField, regionIDs []int, minRegionAr
 			}
 		}
 
-		// remove clusters of regions that are too small
-		if spanCount < minRegionArea {
+		// remove clusters of regions that are too small. clusters that reach a tile
+		// border are kept since they may continue into the neighboring tile
+		if spanCount < minRegionArea && !connectsToBorder {
 			for _, regionID := range trace {
 				regions[regionID].spanCount = 0
 				regions[regionID].id = 0
@@ -407,6 +428,61 @@ func mergeAndFilterRegions(chf *CompactHeightField, regionIDs []int, minRegionAr
 
 	// merge small regions to neighbor regions
 
+	for {
+		mergeCount := 0
+		for i := range regions {
+			region := &regions[i]
+			if region.id == 0 || region.overlap || region.spanCount == 0 {
+				continue
+			}
+
+			// large regions that touch unwalkable area are kept as is, regions that
+			// are surrounded by other regions are merged regardless of their size
+			if region.spanCount > mergeRegionArea && isRegionConnectedToBorder(region) {
+				continue
+			}
+
+			// find the smallest neighboring region that can be merged with
+			smallest := math.MaxInt
+			mergeID := region.id
+			for _, conn := range region.connections {
+				neighborRegion := &regions[conn]
+				if neighborRegion.id == 0 || neighborRegion.overlap {
+					continue
+				}
+				if neighborRegion.spanCount < smallest && canMergeWithRegion(region, neighborRegion) && canMergeWithRegion(neighborRegion, region) {
+					smallest = neighborRegion.spanCount
+					mergeID = neighborRegion.id
+				}
+			}
+
+			if mergeID == region.id {
+				continue
+			}
+
+			oldID := region.id
+			if !mergeRegions(&regions[mergeID], region) {
+				continue
+			}
+
+			// point everything that referred to the merged region to its new id
+			for j := range regions {
+				if regions[j].id == 0 {
+					continue
+				}
+				if regions[j].id == oldID {
+					regions[j].id = mergeID
+				}
+				replaceNeighbor(&regions[j], oldID, mergeID)
+			}
+			mergeCount++
+		}
+
+		if mergeCount == 0 {
+			break
+		}
+	}
+
 	// compress region IDs
 
 	// mark valid regions we want to remap to the front
@@ -444,10 +520,96 @@ func mergeAndFilterRegions(chf *CompactHeightField, regionIDs []int, minRegionAr
 	for i := range chf.spanCount {
 		regionIDs[i] = regions[regionIDs[i]].id
 	}
+}
+
+// isRegionConnectedToBorder returns whether the region touches unwalkable area,
+// which shows up as region 0 in its connections
+func isRegionConnectedToBorder(region *Region) bool {
+	return slices.Contains(region.connections, 0)
+}
+
+// canMergeWithRegion returns whether a can absorb b. regions can only be merged
+// if they're the same area type, share a single stretch of border and aren't
+// stacked on top of each other
+func canMergeWithRegion(a, b *Region) bool {
+	if a.areaType != b.areaType {
+		return false
+	}
+
+	n := 0
+	for _, conn := range a.connections {
+		if conn == b.id {
+			n++
+		}
+	}
+	if n > 1 {
+		return false
+	}
+
+	return !slices.Contains(a.floors, b.id)
+}
+
+// mergeRegions merges b into a by splicing their connections together where
+// they meet
+func mergeRegions(a, b *Region) bool {
+	aConnections := slices.Clone(a.connections)
+	bConnections := b.connections
+
+	insertA := slices.Index(aConnections, b.id)
+	if insertA == -1 {
+		return false
+	}
+	insertB := slices.Index(bConnections, a.id)
+	if insertB == -1 {
+		return false
+	}
+
+	a.connections = nil
+	for i := range len(aConnections) - 1 {
+		a.connections = append(a.connections, aConnections[(insertA+1+i)%len(aConnections)])
+	}
+	for i := range len(bConnections) - 1 {
+		a.connections = append(a.connections, bConnections[(insertB+1+i)%len(bConnections)])
+	}
+	removeAdjacentNeighbors(a)
+
+	for _, floor := range b.floors {
+		addUniqueFloorRegion(a, floor)
+	}
+	a.spanCount += b.spanCount
+	b.spanCount = 0
+	b.connections = nil
+
+	return true
+}
 
-	// return overlapping regions
+func removeAdjacentNeighbors(region *Region) {
+	for i := 0; i < len(region.connections) && len(region.connections) > 1; {
+		ni := (i + 1) % len(region.connections)
+		if region.connections[i] == region.connections[ni] {
+			region.connections = slices.Delete(region.connections, i, i+1)
+		} else {
+			i++
+		}
+	}
+}
 
-	return 0
+func replaceNeighbor(region *Region, oldID, newID int) {
+	changed := false
+	for i, conn := range region.connections {
+		if conn == oldID {
+			region.connections[i] = newID
+			changed = true
+		}
+	}
+	for i, floor := range region.floors {
+		if floor == oldID {
+			region.floors[i] = newID
+		}
+	}
+	if changed {
+		removeAdjacentNeighbors(region)
+	}
 }
 
 func walkContour(chf *CompactHeightField, spanIndex SpanIndex, dir int, regionIDs []int) []int {
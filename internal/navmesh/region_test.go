@@ -11,35 +11,35 @@ func setupCHF() *CompactHeightField {
 
 	// 5x5 voxels on xz plane
 
-	hf.AddSpan(0, 0, 0, 1, true, 1)
-	hf.AddSpan(1, 0, 0, 1, true, 1)
-	hf.AddSpan(2, 0, 0, 1, true, 1)
-	hf.AddSpan(3, 0, 0, 1, true, 1)
-	hf.AddSpan(4, 0, 0, 1, true, 1)
-
-	hf.AddSpan(0, 1, 0, 1, true, 1)
-	hf.AddSpan(1, 1, 0, 1, true, 1)
-	hf.AddSpan(2, 1, 0, 1, true, 1)
-	hf.AddSpan(3, 1, 0, 1, true, 1)
-	hf.AddSpan(4, 1, 0, 1, true, 1)
-
-	hf.AddSpan(0, 2, 0, 1, true, 1)
-	hf.AddSpan(1, 2, 0, 1, true, 1)
-	hf.AddSpan(2, 2, 0, 1, true, 1)
-	hf.AddSpan(3, 2, 0, 1, true, 1)
-	hf.AddSpan(4, 2, 0, 1, true, 1)
-
-	hf.AddSpan(0, 3, 0, 1, true, 1)
-	hf.AddSpan(1, 3, 0, 1, true, 1)
-	hf.AddSpan(2, 3, 0, 1, true, 1)
-	hf.AddSpan(3, 3, 0, 1, true, 1)
-	hf.AddSpan(4, 3, 0, 1, true, 1)
-
-	hf.AddSpan(0, 4, 0, 1, true, 1)
-	hf.AddSpan(1, 4, 0, 1, true, 1)
-	hf.AddSpan(2, 4, 0, 1, true, 1)
-	hf.AddSpan(3, 4, 0, 1, true, 1)
-	hf.AddSpan(4, 4, 0, 1, true, 1)
+	hf.AddSpan(0, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(3, 0, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(4, 0, 0, 1, WALKABLE_AREA, 1)
+
+	hf.AddSpan(0, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(3, 1, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(4, 1, 0, 1, WALKABLE_AREA, 1)
+
+	hf.AddSpan(0, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(3, 2, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(4, 2, 0, 1, WALKABLE_AREA, 1)
+
+	hf.AddSpan(0, 3, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 3, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 3, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(3, 3, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(4, 3, 0, 1, WALKABLE_AREA, 1)
+
+	hf.AddSpan(0, 4, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(1, 4, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(2, 4, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(3, 4, 0, 1, WALKABLE_AREA, 1)
+	hf.AddSpan(4, 4, 0, 1, WALKABLE_AREA, 1)
 
 	return NewCompactHeightField(1, 1, hf)
 }
@@ -52,3 +52,117 @@ func TestBuildRegion(t *testing.T) {
 
 	BuildRegions(chf, 999, 1, 1)
 }
+
+// chfFromGrid builds a compact height field from rows of cells along z, where
+// '#' is a walkable cell and anything else is empty
+func chfFromGrid(rows []string) *CompactHeightField {
+	hf := NewHeightField(len(rows[0]), len(rows), mgl64.Vec3{0, 0, 0}, mgl64.Vec3{100, 100, 100}, 1, 1)
+	for z, row := range rows {
+		for x, cell := range row {
+			if cell == '#' {
+				hf.AddSpan(x, z, 0, 1, WALKABLE_AREA, 1)
+			}
+		}
+	}
+	return NewCompactHeightField(1, 1, hf)
+}
+
+// regionCount returns the number of distinct regions spans were assigned to
+func regionCount(chf *CompactHeightField) int {
+	regions := map[int]bool{}
+	for _, span := range chf.spans {
+		if span.regionID != 0 {
+			regions[span.regionID] = true
+		}
+	}
+	return len(regions)
+}
+
+func TestBuildRegionsRemovesSmallRegions(t *testing.T) {
+	chf := chfFromGrid([]string{
+		"######......",
+		"######......",
+		"######..##..",
+		"######..##..",
+		"######......",
+		"######......",
+	})
+	BuildDistanceField(chf)
+	BuildRegions(chf, 999, 8, 0)
+
+	if regionCount(chf) != 1 {
+		t.Fatalf("expected the small island to be removed, got %d regions", regionCount(chf))
+	}
+	island := chf.cells[8+2*chf.width]
+	if chf.spans[island.SpanIndex].regionID != 0 {
+		t.Fatal("expected the small island to not be assigned a region")
+	}
+}
+
+func TestBuildRegionsKeepsSmallRegionsOnTileBorder(t *testing.T) {
+	// the island is small but continues past the clipped border into the
+	// neighboring tile
+	chf := chfFromGrid([]string{
+		"..........",
+		"..........",
+		"..........",
+		"....##....",
+		"....##....",
+		"..........",
+		"#####.....",
+		"#####.....",
+		"#####.....",
+		"..........",
+	})
+	ClipBorder(chf, 2)
+	BuildDistanceField(chf)
+	BuildRegions(chf, 999, 8, 0)
+
+	if regionCount(chf) != 1 {
+		t.Fatalf("expected only the island on the tile border to be kept, got %d regions", regionCount(chf))
+	}
+	island := chf.cells[3+6*chf.width]
+	if chf.spans[island.SpanIndex].regionID == 0 {
+		t.Fatal("expected the island on the tile border to be kept")
+	}
+}
+
+func TestBuildRegionsMergesRegions(t *testing.T) {
+	// the two pillars split the floor into several regions. regions that share
+	// more than one edge can't be merged without forming a ring
+	rows := []string{
+		"########################",
+		"########################",
+		"########################",
+		"########################",
+		"########################",
+		"########################",
+		"######..########..######",
+		"######..########..######",
+		"########################",
+		"########################",
+		"########################",
+		"########################",
+		"########################",
+		"########################",
+		"########################",
+		"########################",
+	}
+
+	chf := chfFromGrid(rows)
+	BuildDistanceField(chf)
+	BuildRegions(chf, 999, 0, 0)
+	if regionCount(chf) != 3 {
+		t.Fatalf("expected 3 regions without merging, got %d", regionCount(chf))
+	}
+
+	chf = chfFromGrid(rows)
+	BuildDistanceField(chf)
+	BuildRegions(chf, 999, 0, 400)
+	if regionCount(chf) != 2 {
+		t.Fatalf("expected 2 regions after merging, got %d", regionCount(chf))
+	}
+	if chf.maxRegionID != 2 {
+		t.Fatalf("expected region ids to be compressed, got max region id %d", chf.maxRegionID)
+	}
+}
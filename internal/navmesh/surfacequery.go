@@ -0,0 +1,434 @@
+package navmesh
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+const (
+	// maxRandomPointAttempts is how many points FindRandomPointAroundCircle picks
+	// before settling for one outside the circle
+	maxRandomPointAttempts = 8
+	// edgeEpsilon is the shortest part of an edge that's treated as a wall
+	// between two portals
+	edgeEpsilon = 0.001
+)
+
+// RaycastHit is the result of casting a ray along the surface of the nav mesh
+type RaycastHit struct {
+	// Hit is whether the ray hit a wall before reaching its end
+	Hit bool
+	// T is how far along the ray it went, from 0 at the start to 1 at the end
+	T     float64
+	Point mgl64.Vec3
+	// Polygon is the polygon the ray stopped in, if the ray hit a wall it's an
+	// edge of this polygon
+	Polygon PolyRef
+	// Normal is the normal of the wall that was hit on the xz plane, facing back
+	// into the polygon
+	Normal mgl64.Vec3
+	// Path is the polygons the ray went through from the start to Polygon
+	Path []PolyRef
+}
+
+// WallHit is the nearest wall found by FindDistanceToWall
+type WallHit struct {
+	Distance float64
+	Point    mgl64.Vec3
+	// Normal is the normal of the wall on the xz plane, pointing away from it
+	// towards the walkable side
+	Normal mgl64.Vec3
+}
+
+// edgePortal is the part of a polygon edge that leads to a neighboring polygon
+type edgePortal struct {
+	Ref   PolyRef
+	Left  mgl64.Vec3
+	Right mgl64.Vec3
+}
+
+// Raycast casts a ray from start to end along the surface of the nav mesh,
+// walking polygon to polygon until it hits an edge it can't cross or reaches the
+// end. start is snapped onto the nav mesh first and polygons that don't pass the
+// filter are treated as walls. it returns false if there's no nav mesh around
+// start. unlike physics raycasts this answers whether an agent could walk in a
+// straight line between the points
+func Raycast(nm *CompiledNavMesh, start, end mgl64.Vec3, filter *QueryFilter) (RaycastHit, bool) {
+	start, ref, _ := FindNearestPolygon(nm, start, filter)
+	if ref == InvalidPolyRef {
+		return RaycastHit{}, false
+	}
+
+	hit := RaycastHit{Polygon: ref, Point: start}
+	visited := map[PolyRef]bool{}
+	for !visited[ref] {
+		visited[ref] = true
+		hit.Path = append(hit.Path, ref)
+		hit.Polygon = ref
+
+		tile := nm.Tiles[ref.Tile]
+		verts := polygonVertices(tile, ref.Poly)
+		_, tmax, _, edge, ok := intersectSegmentPoly2D(start, end, verts)
+		if !ok {
+			// the ray doesn't pass through the polygon, which only happens when
+			// it grazes a vertex on the way in
+			hit.Hit = true
+			return hit, true
+		}
+
+		hit.T = max(hit.T, tmax)
+		if edge == -1 {
+			// the ray ends inside the polygon
+			hit.T = 1
+			hit.Point = projectPathPoint(tile, ref.Poly, end)
+			return hit, true
+		}
+
+		crossing := start.Add(end.Sub(start).Mul(tmax))
+		next := InvalidPolyRef
+		va, vb := verts[edge], verts[(edge+1)%len(verts)]
+		s := edgeParam(va, vb, crossing)
+		for _, portal := range edgePortals(nm, ref, edge, filter) {
+			sl, sr := edgeParam(va, vb, portal.Left), edgeParam(va, vb, portal.Right)
+			if s >= min(sl, sr)-edgeEpsilon && s <= max(sl, sr)+edgeEpsilon {
+				next = portal.Ref
+				break
+			}
+		}
+
+		if next == InvalidPolyRef {
+			hit.Hit = true
+			hit.Point = projectPathPoint(tile, ref.Poly, crossing)
+			hit.Normal = wallNormal(va, vb)
+			return hit, true
+		}
+		ref = next
+	}
+
+	// polygons that overlap on the xz plane can loop back on themselves
+	hit.Hit = true
+	return hit, true
+}
+
+// FindDistanceToWall returns the nearest wall within maxRadius of center. walls
+// are polygon edges that don't lead to a polygon that passes the filter, and
+// only walls reachable from center without leaving the radius are considered.
+// it returns false if there's no wall within the radius
+func FindDistanceToWall(nm *CompiledNavMesh, center mgl64.Vec3, maxRadius float64, filter *QueryFilter) (WallHit, bool) {
+	center, startRef, _ := FindNearestPolygon(nm, center, filter)
+	if startRef == InvalidPolyRef {
+		return WallHit{}, false
+	}
+
+	radiusSq := maxRadius * maxRadius
+	var wall WallHit
+	var found bool
+
+	queue := []PolyRef{startRef}
+	visited := map[PolyRef]bool{startRef: true}
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+
+		verts := polygonVertices(nm.Tiles[ref.Tile], ref.Poly)
+		for edge := range verts {
+			va, vb := verts[edge], verts[(edge+1)%len(verts)]
+			portals := edgePortals(nm, ref, edge, filter)
+
+			for _, segment := range edgeWallSegments(va, vb, portals) {
+				distSq, t := distancePtSeg2Df(center.X(), center.Z(), segment[0].X(), segment[0].Z(), segment[1].X(), segment[1].Z())
+				if distSq >= radiusSq {
+					continue
+				}
+				radiusSq = distSq
+				found = true
+				wall.Point = segment[0].Add(segment[1].Sub(segment[0]).Mul(t))
+				wall.Normal = wallNormal(va, vb)
+			}
+
+			for _, portal := range portals {
+				if visited[portal.Ref] {
+					continue
+				}
+				distSq, _ := distancePtSeg2Df(center.X(), center.Z(), portal.Left.X(), portal.Left.Z(), portal.Right.X(), portal.Right.Z())
+				if distSq >= radiusSq {
+					continue
+				}
+				visited[portal.Ref] = true
+				queue = append(queue, portal.Ref)
+			}
+		}
+	}
+
+	if !found {
+		return WallHit{}, false
+	}
+	wall.Distance = math.Sqrt(radiusSq)
+	return wall, true
+}
+
+// FindRandomPoint returns a random point on the nav mesh along with the polygon
+// it's on. points are spread evenly by area across the polygons that pass the
+// filter, and the same rng state picks the same point. it returns false if no
+// polygon passes the filter
+func FindRandomPoint(nm *CompiledNavMesh, filter *QueryFilter, rng *rand.Rand) (mgl64.Vec3, PolyRef, bool) {
+	selected := InvalidPolyRef
+	var areaSum float64
+	for i, tile := range nm.Tiles {
+		for j, polygon := range tile.Polygons {
+			if !filter.PassFilter(polygon.Area) {
+				continue
+			}
+			// reservoir sample the polygons weighted by their area
+			area := polygonArea2D(polygonVertices(tile, j))
+			areaSum += area
+			if rng.Float64()*areaSum <= area {
+				selected = PolyRef{Tile: i, Poly: j, Salt: tile.Salt}
+			}
+		}
+	}
+
+	if selected == InvalidPolyRef {
+		return mgl64.Vec3{}, InvalidPolyRef, false
+	}
+	return randomPointInPolygon(nm, selected, rng), selected, true
+}
+
+// FindRandomPointAroundCircle returns a random point on the nav mesh that's
+// reachable from center without leaving the circle, along with the polygon it's
+// on. polygons are picked by area among the ones that can be reached so the
+// point is almost always within the radius, but it can land outside the circle
+// on polygons that straddle it. it returns false if center isn't on the nav mesh
+func FindRandomPointAroundCircle(nm *CompiledNavMesh, center mgl64.Vec3, radius float64, filter *QueryFilter, rng *rand.Rand) (mgl64.Vec3, PolyRef, bool) {
+	center, startRef, _ := FindNearestPolygon(nm, center, filter)
+	if startRef == InvalidPolyRef {
+		return mgl64.Vec3{}, InvalidPolyRef, false
+	}
+
+	radiusSq := radius * radius
+	selected := startRef
+	var areaSum float64
+
+	queue := []PolyRef{startRef}
+	visited := map[PolyRef]bool{startRef: true}
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+
+		verts := polygonVertices(nm.Tiles[ref.Tile], ref.Poly)
+		area := polygonArea2D(verts)
+		areaSum += area
+		if rng.Float64()*areaSum <= area {
+			selected = ref
+		}
+
+		for edge := range verts {
+			for _, portal := range edgePortals(nm, ref, edge, filter) {
+				if visited[portal.Ref] {
+					continue
+				}
+				distSq, _ := distancePtSeg2Df(center.X(), center.Z(), portal.Left.X(), portal.Left.Z(), portal.Right.X(), portal.Right.Z())
+				if distSq > radiusSq {
+					continue
+				}
+				visited[portal.Ref] = true
+				queue = append(queue, portal.Ref)
+			}
+		}
+	}
+
+	var point mgl64.Vec3
+	for range maxRandomPointAttempts {
+		point = randomPointInPolygon(nm, selected, rng)
+		offset := point.Sub(center)
+		offset[1] = 0
+		if offset.LenSqr() <= radiusSq {
+			break
+		}
+	}
+	return point, selected, true
+}
+
+// edgePortals returns the parts of the polygon's edge that lead to neighboring
+// polygons which pass the filter. an edge inside a tile leads to at most one
+// polygon while edges on tile borders can be split between several
+func edgePortals(nm *CompiledNavMesh, ref PolyRef, edge int, filter *QueryFilter) []edgePortal {
+	tile := nm.Tiles[ref.Tile]
+	polygon := tile.Polygons[ref.Poly]
+
+	var portals []edgePortal
+	if neighbor := polygon.PolyNeighbors[edge]; neighbor != -1 {
+		if filter.PassFilter(tile.Polygons[neighbor].Area) {
+			left, right, _ := GetPortalVertIndices(tile, ref.Poly, neighbor)
+			portals = append(portals, edgePortal{Ref: PolyRef{Tile: ref.Tile, Poly: neighbor, Salt: ref.Salt}, Left: tile.Vertices[left], Right: tile.Vertices[right]})
+		}
+		return portals
+	}
+
+	for _, link := range polygon.Links {
+		if link.Edge != edge || !filter.PassFilter(areaAt(nm, link.Ref)) {
+			continue
+		}
+		portals = append(portals, edgePortal{Ref: link.Ref, Left: link.Left, Right: link.Right})
+	}
+	return portals
+}
+
+// edgeWallSegments returns the parts of the edge from va to vb that aren't
+// covered by the portals
+func edgeWallSegments(va, vb mgl64.Vec3, portals []edgePortal) [][2]mgl64.Vec3 {
+	type interval struct{ min, max float64 }
+	intervals := make([]interval, 0, len(portals))
+	for _, portal := range portals {
+		sl, sr := edgeParam(va, vb, portal.Left), edgeParam(va, vb, portal.Right)
+		intervals = append(intervals, interval{min: min(sl, sr), max: max(sl, sr)})
+	}
+	slices.SortFunc(intervals, func(a, b interval) int {
+		if a.min < b.min {
+			return -1
+		} else if a.min > b.min {
+			return 1
+		}
+		return 0
+	})
+
+	var segments [][2]mgl64.Vec3
+	lerp := func(t float64) mgl64.Vec3 { return va.Add(vb.Sub(va).Mul(t)) }
+	var covered float64
+	for _, interval := range intervals {
+		if interval.min-covered > edgeEpsilon {
+			segments = append(segments, [2]mgl64.Vec3{lerp(covered), lerp(interval.min)})
+		}
+		covered = max(covered, interval.max)
+	}
+	if 1-covered > edgeEpsilon {
+		segments = append(segments, [2]mgl64.Vec3{lerp(covered), vb})
+	}
+	return segments
+}
+
+// edgeParam returns how far along the edge from va to vb the point is on the xz
+// plane, from 0 at va to 1 at vb
+func edgeParam(va, vb, point mgl64.Vec3) float64 {
+	edgeX, edgeZ := vb.X()-va.X(), vb.Z()-va.Z()
+	lenSq := edgeX*edgeX + edgeZ*edgeZ
+	if lenSq == 0 {
+		return 0
+	}
+	return ((point.X()-va.X())*edgeX + (point.Z()-va.Z())*edgeZ) / lenSq
+}
+
+// wallNormal returns the normal of the polygon edge from va to vb on the xz
+// plane, facing into the polygon
+func wallNormal(va, vb mgl64.Vec3) mgl64.Vec3 {
+	normal := mgl64.Vec3{vb.Z() - va.Z(), 0, -(vb.X() - va.X())}
+	if normal.LenSqr() == 0 {
+		return normal
+	}
+	return normal.Normalize()
+}
+
+// intersectSegmentPoly2D clips the segment from p0 to p1 against the convex
+// polygon on the xz plane. tmin and tmax are how far along the segment it enters
+// and leaves the polygon and segMin and segMax are the edges it enters and
+// leaves through, or -1 if the segment starts or ends inside the polygon
+func intersectSegmentPoly2D(p0, p1 mgl64.Vec3, verts []mgl64.Vec3) (float64, float64, int, int, bool) {
+	tmin, tmax := 0.0, 1.0
+	segMin, segMax := -1, -1
+	dir := p1.Sub(p0)
+
+	for i, j := 0, len(verts)-1; i < len(verts); j, i = i, i+1 {
+		edge := verts[i].Sub(verts[j])
+		diff := p0.Sub(verts[j])
+		n := perp2D(edge, diff)
+		d := perp2D(dir, edge)
+		if math.Abs(d) < 1e-8 {
+			// the segment is parallel to the edge
+			if n < 0 {
+				return 0, 0, -1, -1, false
+			}
+			continue
+		}
+
+		t := n / d
+		if d < 0 {
+			// entering across the edge
+			if t > tmin {
+				tmin = t
+				segMin = j
+				if tmin > tmax {
+					return 0, 0, -1, -1, false
+				}
+			}
+		} else {
+			// leaving across the edge
+			if t < tmax {
+				tmax = t
+				segMax = j
+				if tmax < tmin {
+					return 0, 0, -1, -1, false
+				}
+			}
+		}
+	}
+
+	return tmin, tmax, segMin, segMax, true
+}
+
+func perp2D(u, v mgl64.Vec3) float64 {
+	return u.Z()*v.X() - u.X()*v.Z()
+}
+
+func polygonVertices(tile CTile, poly int) []mgl64.Vec3 {
+	indices := tile.Polygons[poly].Vertices
+	verts := make([]mgl64.Vec3, len(indices))
+	for i, index := range indices {
+		verts[i] = tile.Vertices[index]
+	}
+	return verts
+}
+
+// polygonArea2D returns the area of the convex polygon on the xz plane
+func polygonArea2D(verts []mgl64.Vec3) float64 {
+	var area float64
+	for i := 2; i < len(verts); i++ {
+		area += triangleArea2D(verts[0], verts[i-1], verts[i])
+	}
+	return area
+}
+
+func triangleArea2D(a, b, c mgl64.Vec3) float64 {
+	return math.Abs((b.X()-a.X())*(c.Z()-a.Z())-(c.X()-a.X())*(b.Z()-a.Z())) / 2
+}
+
+// randomPointInPolygon returns a point spread evenly over the polygon, with its
+// height taken from the detailed mesh
+func randomPointInPolygon(nm *CompiledNavMesh, ref PolyRef, rng *rand.Rand) mgl64.Vec3 {
+	tile := nm.Tiles[ref.Tile]
+	verts := polygonVertices(tile, ref.Poly)
+
+	// pick a triangle of the fan by area, then a point within it
+	threshold := rng.Float64() * polygonArea2D(verts)
+	tri := len(verts) - 1
+	var u float64 = 1
+	var accumulated float64
+	for i := 2; i < len(verts); i++ {
+		area := triangleArea2D(verts[0], verts[i-1], verts[i])
+		if threshold >= accumulated && threshold < accumulated+area {
+			u = (threshold - accumulated) / area
+			tri = i
+			break
+		}
+		accumulated += area
+	}
+
+	v := math.Sqrt(rng.Float64())
+	a := 1 - v
+	b := (1 - u) * v
+	c := u * v
+	point := verts[0].Mul(a).Add(verts[tri-1].Mul(b)).Add(verts[tri].Mul(c))
+	return projectPathPoint(tile, ref.Poly, point)
+}
@@ -0,0 +1,261 @@
+package navmesh
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+func TestRaycast(t *testing.T) {
+	g, w := WALKABLE_AREA, WATER_AREA
+	excludeWater := NewQueryFilter()
+	excludeWater.Exclude(WATER_AREA)
+
+	testCases := []struct {
+		name       string
+		nm         *CompiledNavMesh
+		filter     *QueryFilter
+		start      mgl64.Vec3
+		end        mgl64.Vec3
+		wantHit    bool
+		wantT      float64
+		wantPoly   PolyRef
+		wantNormal mgl64.Vec3
+		wantPath   []PolyRef
+	}{
+		{
+			name:     "clear",
+			nm:       gridNavMesh([][]AREA_TYPE{{g, g, g, g}}),
+			start:    mgl64.Vec3{0.5, 0, 0.5},
+			end:      mgl64.Vec3{3.5, 0, 0.5},
+			wantT:    1,
+			wantPoly: PolyRef{Tile: 0, Poly: 3},
+			wantPath: []PolyRef{{Tile: 0, Poly: 0}, {Tile: 0, Poly: 1}, {Tile: 0, Poly: 2}, {Tile: 0, Poly: 3}},
+		},
+		{
+			name:       "ledge",
+			nm:         ledgeNavMesh(),
+			start:      mgl64.Vec3{0.5, 0, 0.5},
+			end:        mgl64.Vec3{3.5, 0, 0.5},
+			wantHit:    true,
+			wantT:      0.5,
+			wantPoly:   PolyRef{Tile: 0, Poly: 1},
+			wantNormal: mgl64.Vec3{-1, 0, 0},
+			wantPath:   []PolyRef{{Tile: 0, Poly: 0}, {Tile: 0, Poly: 1}},
+		},
+		{
+			name:       "filtered",
+			nm:         gridNavMesh([][]AREA_TYPE{{g, g, w, g}}),
+			filter:     excludeWater,
+			start:      mgl64.Vec3{0.5, 0, 0.5},
+			end:        mgl64.Vec3{3.5, 0, 0.5},
+			wantHit:    true,
+			wantT:      0.5,
+			wantPoly:   PolyRef{Tile: 0, Poly: 1},
+			wantNormal: mgl64.Vec3{-1, 0, 0},
+			wantPath:   []PolyRef{{Tile: 0, Poly: 0}, {Tile: 0, Poly: 1}},
+		},
+		{
+			name:       "side wall",
+			nm:         gridNavMesh([][]AREA_TYPE{{g, g}}),
+			start:      mgl64.Vec3{0.5, 0, 0.5},
+			end:        mgl64.Vec3{0.5, 0, 2.5},
+			wantHit:    true,
+			wantT:      0.25,
+			wantPoly:   PolyRef{Tile: 0, Poly: 0},
+			wantNormal: mgl64.Vec3{0, 0, -1},
+			wantPath:   []PolyRef{{Tile: 0, Poly: 0}},
+		},
+		{
+			name:     "across tiles",
+			nm:       twoTileNavMesh(),
+			start:    mgl64.Vec3{0.5, 0, 0.75},
+			end:      mgl64.Vec3{1.5, 0, 0.75},
+			wantT:    1,
+			wantPoly: PolyRef{Tile: 1, Poly: 1},
+			wantPath: []PolyRef{{Tile: 0, Poly: 0}, {Tile: 1, Poly: 1}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hit, ok := Raycast(tc.nm, tc.start, tc.end, tc.filter)
+			if !ok {
+				t.Fatal("expected the ray to start on the nav mesh")
+			}
+			if hit.Hit != tc.wantHit {
+				t.Fatalf("hit = %v, want %v", hit.Hit, tc.wantHit)
+			}
+			if math.Abs(hit.T-tc.wantT) > 1e-9 {
+				t.Fatalf("t = %v, want %v", hit.T, tc.wantT)
+			}
+			if hit.Polygon != tc.wantPoly {
+				t.Fatalf("polygon = %v, want %v", hit.Polygon, tc.wantPoly)
+			}
+			if !hit.Normal.ApproxEqual(tc.wantNormal) {
+				t.Fatalf("normal = %v, want %v", hit.Normal, tc.wantNormal)
+			}
+			if !slices.Equal(hit.Path, tc.wantPath) {
+				t.Fatalf("path = %v, want %v", hit.Path, tc.wantPath)
+			}
+			if want := tc.start.Add(tc.end.Sub(tc.start).Mul(tc.wantT)); !hit.Point.ApproxEqual(want) {
+				t.Fatalf("point = %v, want %v", hit.Point, want)
+			}
+		})
+	}
+}
+
+func TestFindDistanceToWall(t *testing.T) {
+	g := WALKABLE_AREA
+	row := []AREA_TYPE{g, g, g, g, g}
+	nm := gridNavMesh([][]AREA_TYPE{row, row, row, row, row})
+
+	wall, ok := FindDistanceToWall(nm, mgl64.Vec3{1.25, 0, 2.5}, 5, nil)
+	if !ok {
+		t.Fatal("expected to find a wall")
+	}
+	if math.Abs(wall.Distance-1.25) > 1e-9 {
+		t.Fatalf("distance = %v, want 1.25", wall.Distance)
+	}
+	if want := (mgl64.Vec3{0, 0, 2.5}); !wall.Point.ApproxEqual(want) {
+		t.Fatalf("point = %v, want %v", wall.Point, want)
+	}
+	if want := (mgl64.Vec3{1, 0, 0}); !wall.Normal.ApproxEqual(want) {
+		t.Fatalf("normal = %v, want %v", wall.Normal, want)
+	}
+
+	if wall, ok := FindDistanceToWall(nm, mgl64.Vec3{2.5, 0, 2.5}, 2, nil); ok {
+		t.Fatalf("found a wall %v away outside the radius", wall.Distance)
+	}
+
+	// the edge between the disconnected cells is a wall
+	wall, ok = FindDistanceToWall(ledgeNavMesh(), mgl64.Vec3{1.75, 0, 0.5}, 5, nil)
+	if !ok || math.Abs(wall.Distance-0.25) > 1e-9 {
+		t.Fatalf("distance = %v, %v, want 0.25", wall.Distance, ok)
+	}
+}
+
+func TestMoveAlongSurface(t *testing.T) {
+	g := WALKABLE_AREA
+
+	testCases := []struct {
+		name     string
+		nm       *CompiledNavMesh
+		start    mgl64.Vec3
+		end      mgl64.Vec3
+		want     mgl64.Vec3
+		wantPath []PolyRef
+	}{
+		{
+			name:     "reachable",
+			nm:       gridNavMesh([][]AREA_TYPE{{g, g, g, g}}),
+			start:    mgl64.Vec3{0.5, 0, 0.5},
+			end:      mgl64.Vec3{2.5, 0, 0.5},
+			want:     mgl64.Vec3{2.5, 0, 0.5},
+			wantPath: []PolyRef{{Tile: 0, Poly: 0}, {Tile: 0, Poly: 1}, {Tile: 0, Poly: 2}},
+		},
+		{
+			name:     "slides along wall",
+			nm:       gridNavMesh([][]AREA_TYPE{{g, g, g, g}}),
+			start:    mgl64.Vec3{0.5, 0, 0.5},
+			end:      mgl64.Vec3{0.7, 0, 1.5},
+			want:     mgl64.Vec3{0.7, 0, 1},
+			wantPath: []PolyRef{{Tile: 0, Poly: 0}},
+		},
+		{
+			name:     "ledge",
+			nm:       ledgeNavMesh(),
+			start:    mgl64.Vec3{1.5, 0, 0.5},
+			end:      mgl64.Vec3{2.5, 0, 0.5},
+			want:     mgl64.Vec3{2, 0, 0.5},
+			wantPath: []PolyRef{{Tile: 0, Poly: 1}},
+		},
+		{
+			name:     "across tiles",
+			nm:       twoTileNavMesh(),
+			start:    mgl64.Vec3{0.5, 0, 0.25},
+			end:      mgl64.Vec3{1.5, 0, 0.75},
+			want:     mgl64.Vec3{1.5, 0, 0.75},
+			wantPath: []PolyRef{{Tile: 0, Poly: 0}, {Tile: 1, Poly: 1}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			position, path := MoveAlongSurface(tc.nm, tc.start, tc.end, nil)
+			if !position.ApproxEqual(tc.want) {
+				t.Fatalf("position = %v, want %v", position, tc.want)
+			}
+			if !slices.Equal(path, tc.wantPath) {
+				t.Fatalf("path = %v, want %v", path, tc.wantPath)
+			}
+		})
+	}
+}
+
+func TestFindRandomPoint(t *testing.T) {
+	g, w := WALKABLE_AREA, WATER_AREA
+	nm := gridNavMesh([][]AREA_TYPE{{g, g, w, g}})
+	excludeWater := NewQueryFilter()
+	excludeWater.Exclude(WATER_AREA)
+
+	rng := rand.New(rand.NewSource(1))
+	counts := map[PolyRef]int{}
+	for range 200 {
+		point, ref, ok := FindRandomPoint(nm, excludeWater, rng)
+		if !ok {
+			t.Fatal("expected a random point")
+		}
+		if _, nearest, over := FindNearestPolygon(nm, point, nil); nearest != ref || !over {
+			t.Fatalf("point %v isn't on its polygon %v", point, ref)
+		}
+		counts[ref]++
+	}
+
+	if counts[PolyRef{Tile: 0, Poly: 2}] != 0 {
+		t.Fatalf("picked %d points in the excluded water", counts[PolyRef{Tile: 0, Poly: 2}])
+	}
+	for _, poly := range []int{0, 1, 3} {
+		if counts[PolyRef{Tile: 0, Poly: poly}] == 0 {
+			t.Fatalf("never picked a point in polygon %d", poly)
+		}
+	}
+
+	first, _, _ := FindRandomPoint(nm, nil, rand.New(rand.NewSource(7)))
+	second, _, _ := FindRandomPoint(nm, nil, rand.New(rand.NewSource(7)))
+	if first != second {
+		t.Fatalf("same seed picked %v and %v", first, second)
+	}
+}
+
+func TestFindRandomPointAroundCircle(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	// the right side of the ledge can't be reached from the left
+	nm := ledgeNavMesh()
+	center := mgl64.Vec3{0.5, 0, 0.5}
+	for range 100 {
+		point, ref, ok := FindRandomPointAroundCircle(nm, center, 10, nil, rng)
+		if !ok {
+			t.Fatal("expected a random point")
+		}
+		if ref.Poly > 1 || point.X() > 2 {
+			t.Fatalf("picked %v on unreachable polygon %v", point, ref)
+		}
+	}
+
+	g := WALKABLE_AREA
+	row := []AREA_TYPE{g, g, g, g, g, g, g, g}
+	nm = gridNavMesh([][]AREA_TYPE{row, row, row, row, row, row, row, row})
+	center = mgl64.Vec3{4, 0, 4}
+	for range 100 {
+		point, _, _ := FindRandomPointAroundCircle(nm, center, 1.5, nil, rng)
+		// points can land on polygons that straddle the circle
+		if dist := point.Sub(center).Len(); dist > 1.5+math.Sqrt2 {
+			t.Fatalf("picked %v, %v from the center", point, dist)
+		}
+	}
+}
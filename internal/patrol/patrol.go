@@ -0,0 +1,129 @@
+package patrol
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/navmesh"
+)
+
+// Mode decides where a patrol goes after the last waypoint of its route
+type Mode string
+
+const (
+	// ModeLoop goes from the last waypoint back to the first
+	ModeLoop Mode = "LOOP"
+	// ModePingPong turns around at either end of the route
+	ModePingPong Mode = "PING_PONG"
+	// ModeOnce stops at the last waypoint
+	ModeOnce Mode = "ONCE"
+)
+
+var Modes = []Mode{ModeLoop, ModePingPong, ModeOnce}
+
+type Waypoint struct {
+	// ID identifies the waypoint to the caller e.g. the id of its entity
+	ID       int
+	Position mgl64.Vec3
+	// Wait is how long patrols wait at the waypoint before moving on
+	Wait time.Duration
+	// Action is played while waiting at the waypoint, empty for none
+	Action string
+}
+
+type Route struct {
+	Mode      Mode
+	Waypoints []Waypoint
+}
+
+// Cursor is how far along its route a patrol is
+type Cursor struct {
+	// Index is the index of the waypoint the patrol is heading to
+	Index int
+	// Reverse is set while a ping-pong patrol heads back towards the first
+	// waypoint
+	Reverse bool
+	// Done is set once a one-shot patrol has reached its last waypoint
+	Done bool
+}
+
+// Advance returns the cursor for the waypoint after the one the cursor is at
+func (r Route) Advance(cursor Cursor) Cursor {
+	n := len(r.Waypoints)
+	if n == 0 || cursor.Done {
+		cursor.Done = true
+		return cursor
+	}
+
+	switch r.Mode {
+	case ModePingPong:
+		if n == 1 {
+			return cursor
+		}
+		if cursor.Reverse && cursor.Index == 0 {
+			cursor.Reverse = false
+		} else if !cursor.Reverse && cursor.Index == n-1 {
+			cursor.Reverse = true
+		}
+		if cursor.Reverse {
+			cursor.Index--
+		} else {
+			cursor.Index++
+		}
+	case ModeOnce:
+		if cursor.Index >= n-1 {
+			cursor.Done = true
+		} else {
+			cursor.Index++
+		}
+	default:
+		cursor.Index = (cursor.Index + 1) % n
+	}
+	return cursor
+}
+
+// Validate checks that every waypoint of the route is within maxDistance of the
+// nav mesh and that patrols can path between the waypoints they go between,
+// including from the last waypoint back to the first for loops. every problem
+// that's found is returned
+func (r Route) Validate(nm *navmesh.CompiledNavMesh, filter *navmesh.QueryFilter, maxDistance float64) error {
+	if len(r.Waypoints) == 0 {
+		return errors.New("route has no waypoints")
+	}
+
+	var errs []error
+	polygons := make([]navmesh.PolyRef, len(r.Waypoints))
+	for i, waypoint := range r.Waypoints {
+		nearest, polygon, _ := navmesh.FindNearestPolygon(nm, waypoint.Position, filter)
+		if polygon == navmesh.InvalidPolyRef || nearest.Sub(waypoint.Position).Len() > maxDistance {
+			errs = append(errs, fmt.Errorf("waypoint %d is off the nav mesh", waypoint.ID))
+			polygon = navmesh.InvalidPolyRef
+		}
+		polygons[i] = polygon
+	}
+
+	var legs [][2]int
+	for i := range len(r.Waypoints) - 1 {
+		legs = append(legs, [2]int{i, i + 1})
+		if r.Mode == ModePingPong {
+			legs = append(legs, [2]int{i + 1, i})
+		}
+	}
+	if r.Mode == ModeLoop && len(r.Waypoints) > 1 {
+		legs = append(legs, [2]int{len(r.Waypoints) - 1, 0})
+	}
+
+	for _, leg := range legs {
+		from, to := leg[0], leg[1]
+		if polygons[from] == navmesh.InvalidPolyRef || polygons[to] == navmesh.InvalidPolyRef {
+			continue
+		}
+		path := navmesh.FindPath(nm, r.Waypoints[from].Position, r.Waypoints[to].Position, filter)
+		if len(path) == 0 || path[len(path)-1] != polygons[to] {
+			errs = append(errs, fmt.Errorf("waypoint %d can't be reached from waypoint %d", r.Waypoints[to].ID, r.Waypoints[from].ID))
+		}
+	}
+	return errors.Join(errs...)
+}
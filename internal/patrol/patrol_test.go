@@ -0,0 +1,135 @@
+package patrol
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/navmesh"
+)
+
+// gridNavMesh builds a nav mesh with a unit square polygon for every '#' in the
+// rows, row i covers z from i to i+1
+func gridNavMesh(rows []string) *navmesh.CompiledNavMesh {
+	width := len(rows[0])
+	vertex := func(x, z int) int { return x + z*(width+1) }
+	polygons := map[[2]int]int{}
+	for z, row := range rows {
+		for x, cell := range row {
+			if cell == '#' {
+				polygons[[2]int{x, z}] = len(polygons)
+			}
+		}
+	}
+	cell := func(x, z int) int {
+		if poly, ok := polygons[[2]int{x, z}]; ok {
+			return poly
+		}
+		return -1
+	}
+
+	var tile navmesh.CTile
+	for z := range len(rows) + 1 {
+		for x := range width + 1 {
+			tile.Vertices = append(tile.Vertices, mgl64.Vec3{float64(x), 0, float64(z)})
+		}
+	}
+	for z, row := range rows {
+		for x, c := range row {
+			if c != '#' {
+				continue
+			}
+			polygon := navmesh.CPolygon{
+				Vertices:      []int{vertex(x, z), vertex(x, z+1), vertex(x+1, z+1), vertex(x+1, z)},
+				PolyNeighbors: []int{cell(x-1, z), cell(x, z+1), cell(x+1, z), cell(x, z-1)},
+				Area:          navmesh.WALKABLE_AREA,
+			}
+			var verts []mgl64.Vec3
+			for _, v := range polygon.Vertices {
+				verts = append(verts, tile.Vertices[v])
+			}
+			tile.Polygons = append(tile.Polygons, polygon)
+			tile.DetailedVertices = append(tile.DetailedVertices, verts)
+			tile.DetailedPolygon = append(tile.DetailedPolygon, navmesh.CDetailedPolygon{Triangles: []navmesh.CDetailedTriangle{
+				{Vertices: [3]int{0, 1, 2}}, {Vertices: [3]int{0, 2, 3}},
+			}})
+		}
+	}
+	nm := &navmesh.CompiledNavMesh{Tiles: []navmesh.CTile{tile}}
+	navmesh.LinkTiles(nm)
+	return nm
+}
+
+func route(mode Mode, n int) Route {
+	r := Route{Mode: mode}
+	for i := range n {
+		r.Waypoints = append(r.Waypoints, Waypoint{ID: i, Position: mgl64.Vec3{float64(i) + 0.5, 0, 0.5}})
+	}
+	return r
+}
+
+func TestAdvance(t *testing.T) {
+	testCases := []struct {
+		mode Mode
+		want []int
+	}{
+		{mode: ModeLoop, want: []int{1, 2, 0, 1, 2, 0}},
+		{mode: ModePingPong, want: []int{1, 2, 1, 0, 1, 2}},
+		{mode: ModeOnce, want: []int{1, 2, 2, 2, 2, 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			r := route(tc.mode, 3)
+			var cursor Cursor
+			for i, want := range tc.want {
+				cursor = r.Advance(cursor)
+				if cursor.Index != want {
+					t.Fatalf("step %d index = %d, want %d", i, cursor.Index, want)
+				}
+			}
+			if done := tc.mode == ModeOnce; cursor.Done != done {
+				t.Fatalf("done = %v, want %v", cursor.Done, done)
+			}
+		})
+	}
+
+	if cursor := (Route{Mode: ModeLoop}).Advance(Cursor{}); !cursor.Done {
+		t.Fatal("expected an empty route to be done")
+	}
+	if cursor := route(ModePingPong, 1).Advance(Cursor{}); cursor.Index != 0 || cursor.Done {
+		t.Fatalf("cursor = %+v, want a single waypoint ping-pong to stay put", cursor)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	nm := gridNavMesh([]string{"###.#"})
+
+	if err := route(ModeLoop, 3).Validate(nm, nil, 0.5); err != nil {
+		t.Fatalf("expected a valid route, got %v", err)
+	}
+
+	// the fourth cell is missing, legs to and from its waypoint aren't checked
+	r := route(ModeOnce, 5)
+	err := r.Validate(nm, nil, 0.25)
+	if err == nil || err.Error() != "waypoint 3 is off the nav mesh" {
+		t.Fatalf("error = %v, want waypoint 3 off the nav mesh", err)
+	}
+
+	r = Route{Mode: ModeLoop, Waypoints: []Waypoint{
+		{ID: 0, Position: mgl64.Vec3{0.5, 0, 0.5}},
+		{ID: 1, Position: mgl64.Vec3{4.5, 0, 0.5}},
+	}}
+	err = r.Validate(nm, nil, 0.25)
+	var unreachable []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		unreachable = append(unreachable, e.Error())
+	}
+	if len(unreachable) != 2 {
+		t.Fatalf("errors = %v, want both legs of the loop unreachable", unreachable)
+	}
+
+	if err := (Route{}).Validate(nm, nil, 0.5); err == nil || errors.Unwrap(err) != nil {
+		t.Fatalf("error = %v, want an error for an empty route", err)
+	}
+}
@@ -0,0 +1,94 @@
+package perception
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+type Sense string
+
+const (
+	SenseSight   Sense = "SIGHT"
+	SenseHearing Sense = "HEARING"
+)
+
+// Record is what's remembered about a target, the last place and way it was
+// perceived
+type Record struct {
+	TargetID int
+	Position mgl64.Vec3
+	Sense    Sense
+	// Age is how long ago the target was last perceived
+	Age time.Duration
+}
+
+// InSight returns whether the target was seen since the memory was last
+// updated
+func (r Record) InSight() bool {
+	return r.Sense == SenseSight && r.Age == 0
+}
+
+// Memory is a short-term memory of targets, targets are forgotten once they
+// haven't been perceived for the memory's duration
+type Memory struct {
+	duration time.Duration
+	records  map[int]*Record
+}
+
+func NewMemory(duration time.Duration) *Memory {
+	return &Memory{duration: duration, records: map[int]*Record{}}
+}
+
+// Update ages the records by delta and forgets the ones older than the memory's
+// duration, it's called before the targets perceived this update are recorded
+func (m *Memory) Update(delta time.Duration) {
+	for id, record := range m.records {
+		record.Age += delta
+		if record.Age > m.duration {
+			delete(m.records, id)
+		}
+	}
+}
+
+// Perceive records the target at the position. a target that was seen this
+// update isn't overwritten by it being heard since sight is more precise
+func (m *Memory) Perceive(targetID int, position mgl64.Vec3, sense Sense) {
+	if record, ok := m.records[targetID]; ok && record.InSight() && sense != SenseSight {
+		return
+	}
+	m.records[targetID] = &Record{TargetID: targetID, Position: position, Sense: sense}
+}
+
+// Recall returns the record of the target if it's remembered
+func (m *Memory) Recall(targetID int) (Record, bool) {
+	record, ok := m.records[targetID]
+	if !ok {
+		return Record{}, false
+	}
+	return *record, true
+}
+
+func (m *Memory) Forget(targetID int) {
+	delete(m.records, targetID)
+}
+
+// Records returns the remembered targets, most recently perceived first and
+// then by id
+func (m *Memory) Records() []Record {
+	var records []Record
+	for _, id := range slices.Sorted(maps.Keys(m.records)) {
+		records = append(records, *m.records[id])
+	}
+	slices.SortStableFunc(records, func(a, b Record) int {
+		if a.Age < b.Age {
+			return -1
+		} else if a.Age > b.Age {
+			return 1
+		}
+		return 0
+	})
+	return records
+}
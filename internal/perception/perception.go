@@ -0,0 +1,38 @@
+package perception
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// InSightCone returns whether the target is within range of the eye and inside
+// the cone of the field of view, in radians, around the forward direction. it
+// doesn't check whether anything blocks the line of sight
+func InSightCone(eye, forward, target mgl64.Vec3, fieldOfView, sightRange float64) bool {
+	toTarget := target.Sub(eye)
+	distance := toTarget.Len()
+	if distance > sightRange {
+		return false
+	}
+	if distance == 0 || fieldOfView >= 2*math.Pi {
+		return true
+	}
+	if forward.LenSqr() == 0 {
+		return false
+	}
+	return toTarget.Mul(1/distance).Dot(forward.Normalize()) >= math.Cos(fieldOfView/2)
+}
+
+// Loudness returns how loud a noise is at the listener, it falls off linearly
+// from the loudness at its source to zero at its radius
+func Loudness(source, listener mgl64.Vec3, loudness, radius float64) float64 {
+	if radius <= 0 {
+		return 0
+	}
+	distance := listener.Sub(source).Len()
+	if distance >= radius {
+		return 0
+	}
+	return loudness * (1 - distance/radius)
+}
@@ -0,0 +1,92 @@
+package perception
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+func TestInSightCone(t *testing.T) {
+	eye := mgl64.Vec3{0, 1, 0}
+	forward := mgl64.Vec3{0, 0, -1}
+	fieldOfView := 90 * math.Pi / 180
+
+	testCases := []struct {
+		name   string
+		target mgl64.Vec3
+		want   bool
+	}{
+		{name: "ahead", target: mgl64.Vec3{0, 1, -5}, want: true},
+		{name: "edge of the cone", target: mgl64.Vec3{4.9, 1, -5}, want: true},
+		{name: "outside the cone", target: mgl64.Vec3{5.1, 1, -5}, want: false},
+		{name: "behind", target: mgl64.Vec3{0, 1, 5}, want: false},
+		{name: "out of range", target: mgl64.Vec3{0, 1, -11}, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := InSightCone(eye, forward, tc.target, fieldOfView, 10); got != tc.want {
+				t.Fatalf("in sight cone = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	if !InSightCone(eye, forward, mgl64.Vec3{0, 1, 5}, 2*math.Pi, 10) {
+		t.Fatal("expected a full field of view to see behind")
+	}
+}
+
+func TestLoudness(t *testing.T) {
+	source := mgl64.Vec3{0, 0, 0}
+	if got := Loudness(source, mgl64.Vec3{0, 0, 0}, 2, 10); got != 2 {
+		t.Fatalf("loudness at the source = %v, want 2", got)
+	}
+	if got := Loudness(source, mgl64.Vec3{5, 0, 0}, 2, 10); math.Abs(got-1) > 1e-9 {
+		t.Fatalf("loudness halfway = %v, want 1", got)
+	}
+	if got := Loudness(source, mgl64.Vec3{0, 0, 12}, 2, 10); got != 0 {
+		t.Fatalf("loudness outside the radius = %v, want 0", got)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	memory := NewMemory(2 * time.Second)
+	memory.Perceive(1, mgl64.Vec3{1, 0, 0}, SenseSight)
+	memory.Perceive(2, mgl64.Vec3{2, 0, 0}, SenseHearing)
+
+	if record, ok := memory.Recall(1); !ok || !record.InSight() {
+		t.Fatalf("record = %+v, %v, want target 1 in sight", record, ok)
+	}
+
+	// hearing a target doesn't overwrite seeing it on the same update
+	memory.Perceive(1, mgl64.Vec3{5, 0, 0}, SenseHearing)
+	if record, _ := memory.Recall(1); record.Position != (mgl64.Vec3{1, 0, 0}) {
+		t.Fatalf("position = %v, want the seen position", record.Position)
+	}
+
+	memory.Update(time.Second)
+	if record, _ := memory.Recall(1); record.InSight() || record.Age != time.Second {
+		t.Fatalf("record = %+v, want it aged out of sight", record)
+	}
+	memory.Perceive(2, mgl64.Vec3{3, 0, 0}, SenseHearing)
+
+	records := memory.Records()
+	if len(records) != 2 || records[0].TargetID != 2 || records[1].TargetID != 1 {
+		t.Fatalf("records = %+v, want the most recent first", records)
+	}
+
+	memory.Update(1500 * time.Millisecond)
+	if _, ok := memory.Recall(1); ok {
+		t.Fatal("expected target 1 to be forgotten")
+	}
+	if record, ok := memory.Recall(2); !ok || record.Position != (mgl64.Vec3{3, 0, 0}) {
+		t.Fatalf("record = %+v, %v, want target 2 at its last heard position", record, ok)
+	}
+
+	memory.Forget(2)
+	if len(memory.Records()) != 0 {
+		t.Fatal("expected nothing to be remembered")
+	}
+}
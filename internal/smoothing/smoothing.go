@@ -0,0 +1,82 @@
+package smoothing
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// decayRemainder is how much of an offset is left once it has decayed for its
+// full duration
+const decayRemainder float64 = 0.01
+
+// offsets smaller than these are dropped rather than decayed forever
+const (
+	minPositionOffset float64 = 1e-4
+	minRotationOffset float64 = 1e-4
+)
+
+// Offset is the visual error between where an entity is rendered and where it's
+// simulated. it's applied on top of the simulated transform when rendering so
+// corrections to the simulation can be eased in without moving colliders
+type Offset struct {
+	Position mgl64.Vec3
+	Rotation mgl64.Quat
+}
+
+// Correct returns the offset that keeps an entity rendered at the rendered
+// transform after its simulated transform was corrected
+func Correct(renderedPosition mgl64.Vec3, renderedRotation mgl64.Quat, simulatedPosition mgl64.Vec3, simulatedRotation mgl64.Quat) Offset {
+	return Offset{
+		Position: renderedPosition.Sub(simulatedPosition),
+		Rotation: renderedRotation.Mul(simulatedRotation.Inverse()).Normalize(),
+	}
+}
+
+// Apply returns the rendered transform for the simulated transform
+func (o Offset) Apply(position mgl64.Vec3, rotation mgl64.Quat) (mgl64.Vec3, mgl64.Quat) {
+	return position.Add(o.Position), o.rotation().Mul(rotation)
+}
+
+// Decay returns the offset after it has decayed for delta. offsets decay
+// exponentially, down to 1% of themselves after duration, so large corrections
+// are eased out quickly at first and then settle
+func (o Offset) Decay(delta, duration time.Duration) Offset {
+	if duration <= 0 {
+		return Offset{}
+	}
+
+	remainder := math.Pow(decayRemainder, delta.Seconds()/duration.Seconds())
+	decayed := Offset{
+		Position: o.Position.Mul(remainder),
+		Rotation: mgl64.QuatSlerp(mgl64.QuatIdent(), o.rotation(), remainder),
+	}
+
+	position, rotation := decayed.Magnitude()
+	if position < minPositionOffset && rotation < minRotationOffset {
+		return Offset{}
+	}
+	return decayed
+}
+
+// Magnitude returns the distance and the angle, in radians, the offset moves the
+// rendered transform by
+func (o Offset) Magnitude() (float64, float64) {
+	rotation := o.rotation()
+	angle := 2 * math.Acos(math.Min(math.Abs(rotation.W), 1))
+	return o.Position.Len(), angle
+}
+
+// IsZero returns whether the offset leaves the rendered transform unchanged
+func (o Offset) IsZero() bool {
+	return o == Offset{}
+}
+
+// rotation treats the zero value as no rotation
+func (o Offset) rotation() mgl64.Quat {
+	if o.Rotation == (mgl64.Quat{}) {
+		return mgl64.QuatIdent()
+	}
+	return o.Rotation
+}
@@ -0,0 +1,66 @@
+package smoothing
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+func TestCorrectKeepsRenderedTransform(t *testing.T) {
+	rendered := mgl64.Vec3{1, 0, 2}
+	renderedRotation := mgl64.QuatRotate(0.5, mgl64.Vec3{0, 1, 0})
+	simulated := mgl64.Vec3{1.5, 0, 1}
+	simulatedRotation := mgl64.QuatRotate(0.2, mgl64.Vec3{0, 1, 0})
+
+	offset := Correct(rendered, renderedRotation, simulated, simulatedRotation)
+	position, rotation := offset.Apply(simulated, simulatedRotation)
+	if !position.ApproxEqual(rendered) {
+		t.Fatalf("position = %v, want %v", position, rendered)
+	}
+	if !rotation.ApproxEqualThreshold(renderedRotation, 1e-9) {
+		t.Fatalf("rotation = %v, want %v", rotation, renderedRotation)
+	}
+
+	distance, angle := offset.Magnitude()
+	if math.Abs(distance-simulated.Sub(rendered).Len()) > 1e-9 || math.Abs(angle-0.3) > 1e-9 {
+		t.Fatalf("magnitude = %v, %v, want %v, 0.3", distance, angle, simulated.Sub(rendered).Len())
+	}
+}
+
+func TestDecay(t *testing.T) {
+	offset := Offset{Position: mgl64.Vec3{2, 0, 0}, Rotation: mgl64.QuatRotate(1, mgl64.Vec3{0, 1, 0})}
+
+	half := offset.Decay(100*time.Millisecond, 200*time.Millisecond)
+	if distance, angle := half.Magnitude(); math.Abs(distance-0.2) > 1e-9 || math.Abs(angle-0.1) > 1e-9 {
+		t.Fatalf("magnitude halfway = %v, %v, want 0.2, 0.1", distance, angle)
+	}
+
+	// decaying in steps ends up in the same place as decaying all at once
+	stepped := offset
+	for range 25 {
+		stepped = stepped.Decay(8*time.Millisecond, 200*time.Millisecond)
+	}
+	full := offset.Decay(200*time.Millisecond, 200*time.Millisecond)
+	if !stepped.Position.ApproxEqualThreshold(full.Position, 1e-9) || !full.Position.ApproxEqualThreshold(mgl64.Vec3{0.02, 0, 0}, 1e-9) {
+		t.Fatalf("positions = %v, %v, want %v", stepped.Position, full.Position, mgl64.Vec3{0.02, 0, 0})
+	}
+
+	if decayed := offset.Decay(2*time.Second, 200*time.Millisecond); !decayed.IsZero() {
+		t.Fatalf("offset = %v, want it dropped once negligible", decayed)
+	}
+	if decayed := offset.Decay(time.Millisecond, 0); !decayed.IsZero() {
+		t.Fatalf("offset = %v, want no smoothing without a duration", decayed)
+	}
+}
+
+func TestZeroOffset(t *testing.T) {
+	position, rotation := Offset{}.Apply(mgl64.Vec3{1, 2, 3}, mgl64.QuatIdent())
+	if position != (mgl64.Vec3{1, 2, 3}) || rotation != mgl64.QuatIdent() {
+		t.Fatalf("transform = %v, %v, want it unchanged", position, rotation)
+	}
+	if distance, angle := (Offset{}).Magnitude(); distance != 0 || angle != 0 {
+		t.Fatalf("magnitude = %v, %v, want 0", distance, angle)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"fmt"
 	"sort"
+	"strings"
 
 	iztanimation "github.com/kkevinchou/izzet/internal/animation"
 )
@@ -40,6 +41,10 @@ type GameContext struct {
 	Dead              bool
 	AimDownSights     bool
 	AimDownSightsFire bool
+	// Traversing is set while an agent moves across an off-mesh connection
+	Traversing bool
+	// Action is the action an AI's behavior tree is playing an animation for
+	Action string
 }
 
 //go:embed player_state_machine.yaml
@@ -61,7 +66,16 @@ func NewStateMachine(id StateMachineID) *iztanimation.AnimationStateMachine[Game
 // The engine offers baseline conditions like "clipCompleted" which are automatically
 // supported and can be referenced in config - the game level parser does not need to
 // handle it.
+//
+// "action:<name>" conditions check for the action an AI's behavior tree is
+// playing, e.g. "action:roar".
 func parseCondition(name string) iztanimation.Condition[GameContext] {
+	if action, ok := strings.CutPrefix(name, "action:"); ok {
+		return iztanimation.NewGameCondition(name, func(ctx GameContext) bool {
+			return ctx.Action == action
+		})
+	}
+
 	switch name {
 	case "moving":
 		return iztanimation.NewGameCondition(name, func(ctx GameContext) bool {
@@ -107,6 +121,14 @@ func parseCondition(name string) iztanimation.Condition[GameContext] {
 		return iztanimation.NewGameCondition(name, func(ctx GameContext) bool {
 			return ctx.Grounded
 		})
+	case "traversing":
+		return iztanimation.NewGameCondition(name, func(ctx GameContext) bool {
+			return ctx.Traversing
+		})
+	case "notTraversing":
+		return iztanimation.NewGameCondition(name, func(ctx GameContext) bool {
+			return !ctx.Traversing
+		})
 	default:
 		panic(fmt.Sprintf("unknown animation condition %q", name))
 	}
@@ -0,0 +1,73 @@
+package behavior
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/kkevinchou/izzet/internal/behaviortree"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/settings"
+	"github.com/kkevinchou/izzet/izzet/system"
+)
+
+// blackboard keys shared between leaves
+const (
+	// KeyTarget holds the id of the entity the tree is going after
+	KeyTarget string = "target"
+	// KeyLastKnownPosition holds the position a target that's being investigated
+	// was last perceived at
+	KeyLastKnownPosition string = "last_known_position"
+)
+
+// Context is what the leaves of a behavior tree act on, the entity running the
+// tree and the world it's in
+type Context struct {
+	Entity *entity.Entity
+	World  system.GameWorld
+}
+
+var (
+	configsMu sync.Mutex
+	configs   = map[string]behaviortree.Config{}
+)
+
+// LoadConfig loads the behavior with the name from _assets/behaviors, behaviors
+// are cached after they're first loaded
+func LoadConfig(name string) (behaviortree.Config, error) {
+	configsMu.Lock()
+	defer configsMu.Unlock()
+
+	if config, ok := configs[name]; ok {
+		return config, nil
+	}
+
+	file, err := os.Open(filepath.Join(settings.BuiltinAssetsDir, "behaviors", name+".yaml"))
+	if err != nil {
+		return behaviortree.Config{}, fmt.Errorf("failed to open behavior %q: %w", name, err)
+	}
+	defer file.Close()
+
+	config, err := behaviortree.LoadConfig(file)
+	if err != nil {
+		return behaviortree.Config{}, fmt.Errorf("failed to load behavior %q: %w", name, err)
+	}
+	configs[name] = config
+	return config, nil
+}
+
+// NewTree builds a new instance of the behavior with the name, every entity
+// running the behavior gets its own instance
+func NewTree(name string) (*behaviortree.Tree[Context], error) {
+	config, err := LoadConfig(name)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := behaviortree.Build(config.BehaviorTree, parseLeaf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build behavior %q: %w", name, err)
+	}
+	return behaviortree.NewTree(root), nil
+}
@@ -0,0 +1,113 @@
+package behavior
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kkevinchou/izzet/internal/behaviortree"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/settings"
+)
+
+func TestShippedBehaviors(t *testing.T) {
+	// behaviors are loaded relative to the root of the repo
+	t.Chdir("../..")
+
+	paths, err := filepath.Glob(filepath.Join(settings.BuiltinAssetsDir, "behaviors", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("expected shipped behaviors")
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".yaml")
+		t.Run(name, func(t *testing.T) {
+			config, err := LoadConfig(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.EntityType != name {
+				t.Fatalf("entity type = %q, want %q", config.EntityType, name)
+			}
+			if _, err := NewTree(name); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestParseLeaf(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "find target", config: "{type: find_target, key: enemy, range: 20}"},
+		{name: "find target bad range", config: "{type: find_target, range: far}", wantErr: true},
+		{name: "investigate", config: "{type: investigate, acceptance_radius: 2}"},
+		{name: "move to", config: "{type: move_to}"},
+		{name: "move to bad key", config: "{type: move_to, key: [a, b]}", wantErr: true},
+		{name: "attack", config: "{type: attack}"},
+		{name: "patrol", config: "{type: patrol, acceptance_radius: 0.5}"},
+		{name: "play animation", config: "{type: play_animation, action: rest, seconds: 2}"},
+		{name: "play animation without action", config: "{type: play_animation, seconds: 2}", wantErr: true},
+		{name: "play animation without duration", config: "{type: play_animation, action: rest}", wantErr: true},
+		{name: "unknown", config: "{type: dance}", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := behaviortree.LoadConfig(strings.NewReader("behavior_tree: " + tc.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = behaviortree.Build(config.BehaviorTree, parseLeaf)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("error = %v, want an error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlayAnimation(t *testing.T) {
+	config, err := behaviortree.LoadConfig(strings.NewReader(`
+behavior_tree:
+  type: timeout
+  seconds: 1
+  child:
+    type: play_animation
+    action: rest
+    seconds: 2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := behaviortree.Build(config.BehaviorTree, parseLeaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := entity.InstantiateBaseEntity("worker", 1)
+	e.AIComponent = &entity.AIComponent{}
+	tree := behaviortree.NewTree(root)
+	ctx := Context{Entity: e}
+
+	if status := tree.Tick(ctx, 600*time.Millisecond); status != behaviortree.StatusRunning {
+		t.Fatalf("status = %s, want %s", status, behaviortree.StatusRunning)
+	}
+	if e.AIComponent.Action != "rest" {
+		t.Fatalf("action = %q while playing, want rest", e.AIComponent.Action)
+	}
+
+	// the timeout interrupts the animation, which clears the action
+	if status := tree.Tick(ctx, 600*time.Millisecond); status != behaviortree.StatusFailure {
+		t.Fatalf("status = %s, want %s", status, behaviortree.StatusFailure)
+	}
+	if e.AIComponent.Action != "" {
+		t.Fatalf("action = %q after being interrupted, want none", e.AIComponent.Action)
+	}
+}
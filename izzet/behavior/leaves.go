@@ -0,0 +1,394 @@
+package behavior
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/behaviortree"
+	"github.com/kkevinchou/izzet/internal/navmesh"
+	"github.com/kkevinchou/izzet/internal/patrol"
+	"github.com/kkevinchou/izzet/izzet/entity"
+)
+
+const (
+	// defaultAcceptanceRadius is how close entities without an attack range get to
+	// where they're moving to
+	defaultAcceptanceRadius = 1
+	// repathDistance is how far a target has to move from the goal an entity is
+	// pathing to before it paths to the target again
+	repathDistance = 1
+)
+
+// parseLeaf builds the game's leaf nodes:
+//
+//   - find_target stores the nearest grounded player within range, if any, in
+//     the blackboard. entities with a PerceptionComponent only find the players
+//     they can see
+//   - investigate moves to where the most recently perceived target was last
+//     seen or heard
+//   - move_to paths to the target in the blackboard until it's within the
+//     acceptance radius
+//   - attack attacks the target in the blackboard while it's within range
+//   - patrol walks the entity's patrol route, waiting at each waypoint
+//   - play_animation sets an action that the animation state machine can react
+//     to for a number of seconds
+func parseLeaf(config behaviortree.NodeConfig) (behaviortree.Node[Context], error) {
+	switch config.Type {
+	case "find_target":
+		key, err := config.String("key", KeyTarget)
+		if err != nil {
+			return nil, err
+		}
+		searchRange, err := config.Float("range", math.MaxFloat64)
+		if err != nil {
+			return nil, err
+		}
+		return behaviortree.NewAction(func(ctx Context, bb *behaviortree.Blackboard, delta time.Duration) behaviortree.Status {
+			return findTarget(ctx, bb, key, searchRange)
+		}), nil
+	case "investigate":
+		acceptanceRadius, err := config.Float("acceptance_radius", defaultAcceptanceRadius)
+		if err != nil {
+			return nil, err
+		}
+		return &investigate{moveTo: moveTo{key: KeyLastKnownPosition, acceptanceRadius: acceptanceRadius}}, nil
+	case "move_to":
+		key, err := config.String("key", KeyTarget)
+		if err != nil {
+			return nil, err
+		}
+		acceptanceRadius, err := config.Float("acceptance_radius", 0)
+		if err != nil {
+			return nil, err
+		}
+		return &moveTo{key: key, acceptanceRadius: acceptanceRadius}, nil
+	case "attack":
+		key, err := config.String("key", KeyTarget)
+		if err != nil {
+			return nil, err
+		}
+		return &attack{key: key}, nil
+	case "patrol":
+		acceptanceRadius, err := config.Float("acceptance_radius", defaultAcceptanceRadius)
+		if err != nil {
+			return nil, err
+		}
+		return &patrolRoute{acceptanceRadius: acceptanceRadius}, nil
+	case "play_animation":
+		action, err := config.String("action", "")
+		if err != nil {
+			return nil, err
+		}
+		if action == "" {
+			return nil, errors.New("play_animation has no action")
+		}
+		duration, err := config.Seconds("seconds", 0)
+		if err != nil {
+			return nil, err
+		}
+		if duration <= 0 {
+			return nil, errors.New("play_animation needs a duration in seconds")
+		}
+		return &playAnimation{action: action, duration: duration}, nil
+	}
+	return nil, fmt.Errorf("unknown node type %q", config.Type)
+}
+
+func findTarget(ctx Context, bb *behaviortree.Blackboard, key string, searchRange float64) behaviortree.Status {
+	closestDist := searchRange
+	var closestPlayer *entity.Entity
+	for _, p := range ctx.World.Entities() {
+		if p.CharacterControllerComponent == nil || !p.Kinematic.Grounded || p.Deadge {
+			continue
+		}
+		if perception := ctx.Entity.PerceptionComponent; perception != nil {
+			if perception.Memory == nil {
+				continue
+			}
+			if record, ok := perception.Memory.Recall(p.GetID()); !ok || !record.InSight() {
+				continue
+			}
+		}
+		distToTarget := p.Position().Sub(ctx.Entity.Position()).Len()
+		if distToTarget <= closestDist {
+			closestDist = distToTarget
+			closestPlayer = p
+		}
+	}
+
+	if closestPlayer == nil {
+		bb.Delete(key)
+		return behaviortree.StatusFailure
+	}
+	bb.Set(key, closestPlayer.GetID())
+	return behaviortree.StatusSuccess
+}
+
+// target returns the live entity whose id is stored under the key
+func target(ctx Context, bb *behaviortree.Blackboard, key string) *entity.Entity {
+	id, ok := behaviortree.Value[int](bb, key)
+	if !ok {
+		return nil
+	}
+	e := ctx.World.GetEntityByID(id)
+	if e == nil || e.Deadge {
+		return nil
+	}
+	return e
+}
+
+// moveTo paths to a target, which is either an entity id or a position. it
+// succeeds once the entity is within the acceptance radius and fails if there's
+// no path to the target. the acceptance radius defaults to the entity's attack
+// range so it stops where it can attack
+type moveTo struct {
+	key              string
+	acceptanceRadius float64
+
+	// entity is the entity that's moving, its goal is cleared if the node is
+	// interrupted
+	entity *entity.Entity
+}
+
+func (n *moveTo) Tick(ctx Context, bb *behaviortree.Blackboard, delta time.Duration) behaviortree.Status {
+	navigation := ctx.Entity.NavigationComponent
+	if navigation == nil {
+		return behaviortree.StatusFailure
+	}
+
+	var goal mgl64.Vec3
+	if e := target(ctx, bb, n.key); e != nil {
+		goal = e.Position()
+	} else if position, ok := behaviortree.Value[mgl64.Vec3](bb, n.key); ok {
+		goal = position
+	} else {
+		return behaviortree.StatusFailure
+	}
+
+	acceptanceRadius := n.acceptanceRadius
+	if acceptanceRadius == 0 {
+		acceptanceRadius = defaultAcceptanceRadius
+		if ctx.Entity.AttackComponent != nil {
+			acceptanceRadius = ctx.Entity.AttackComponent.AttackRange
+		}
+	}
+	if goal.Sub(ctx.Entity.Position()).Len() <= acceptanceRadius {
+		navigation.ClearGoal()
+		n.entity = nil
+		return behaviortree.StatusSuccess
+	}
+
+	if n.entity == nil || goal.Sub(navigation.Goal).Len() > repathDistance {
+		n.entity = ctx.Entity
+		navigation.SetGoal(goal)
+		return behaviortree.StatusRunning
+	}
+	if !navigationRunning(navigation) {
+		return behaviortree.StatusFailure
+	}
+	return behaviortree.StatusRunning
+}
+
+func (n *moveTo) Reset() {
+	if n.entity != nil {
+		n.entity.NavigationComponent.ClearGoal()
+		n.entity = nil
+	}
+}
+
+// navigationRunning returns whether the entity is still making its way to its
+// goal, either pathing to it or waiting on a path
+func navigationRunning(navigation *entity.NavigationComponent) bool {
+	if navigation.PathDirty || navigation.PathStatus == navmesh.PathStatusPending {
+		return true
+	}
+	if navigation.PathStatus == navmesh.PathStatusFailed {
+		return false
+	}
+	return navigation.State != entity.Idle
+}
+
+// investigate moves to the last known position of the target that was
+// perceived most recently and forgets the target once it gets there, so the
+// next most recent target is investigated after it. it fails once there's no
+// target left to investigate
+type investigate struct {
+	moveTo moveTo
+}
+
+func (n *investigate) Tick(ctx Context, bb *behaviortree.Blackboard, delta time.Duration) behaviortree.Status {
+	perception := ctx.Entity.PerceptionComponent
+	if perception == nil || perception.Memory == nil {
+		return behaviortree.StatusFailure
+	}
+	records := perception.Memory.Records()
+	if len(records) == 0 {
+		n.Reset()
+		return behaviortree.StatusFailure
+	}
+
+	record := records[0]
+	bb.Set(KeyLastKnownPosition, record.Position)
+	status := n.moveTo.Tick(ctx, bb, delta)
+	if status != behaviortree.StatusRunning {
+		perception.Memory.Forget(record.TargetID)
+	}
+	return status
+}
+
+func (n *investigate) Reset() {
+	n.moveTo.Reset()
+}
+
+// attack attacks the target entity while it's in range, it fails once the
+// target is out of range or gone
+type attack struct {
+	key string
+
+	entity *entity.Entity
+}
+
+func (n *attack) Tick(ctx Context, bb *behaviortree.Blackboard, delta time.Duration) behaviortree.Status {
+	attackComponent := ctx.Entity.AttackComponent
+	if attackComponent == nil {
+		return behaviortree.StatusFailure
+	}
+
+	e := target(ctx, bb, n.key)
+	if e == nil || e.Position().Sub(ctx.Entity.Position()).Len() > attackComponent.AttackRange {
+		n.Reset()
+		return behaviortree.StatusFailure
+	}
+
+	n.entity = ctx.Entity
+	attackComponent.TargetID = e.GetID()
+	if ctx.Entity.NavigationComponent != nil {
+		ctx.Entity.NavigationComponent.ClearGoal()
+	}
+	return behaviortree.StatusRunning
+}
+
+func (n *attack) Reset() {
+	if n.entity != nil {
+		n.entity.AttackComponent.TargetID = -1
+		n.entity = nil
+	}
+}
+
+// patrolRoute walks the entity's patrol route. it paths to the waypoint the
+// patrol is heading to, waits there playing the waypoint's action and succeeds
+// once it moves on to the next waypoint. it fails if the entity has no route or
+// has finished it, and skips waypoints that can't be reached
+type patrolRoute struct {
+	acceptanceRadius float64
+
+	entity  *entity.Entity
+	pathing bool
+	// waiting is set once the waypoint is reached
+	waiting bool
+	elapsed time.Duration
+	action  string
+}
+
+func (n *patrolRoute) Tick(ctx Context, bb *behaviortree.Blackboard, delta time.Duration) behaviortree.Status {
+	config := ctx.Entity.AIComponent.PatrolConfig
+	navigation := ctx.Entity.NavigationComponent
+	if config == nil || config.Cursor.Done || navigation == nil {
+		return behaviortree.StatusFailure
+	}
+	route, err := ctx.World.PatrolRoute(config.RouteID)
+	if err != nil {
+		return behaviortree.StatusFailure
+	}
+	if config.Cursor.Index >= len(route.Waypoints) {
+		// the route lost waypoints since the patrol started
+		config.Cursor = patrol.Cursor{}
+	}
+
+	n.entity = ctx.Entity
+	waypoint := route.Waypoints[config.Cursor.Index]
+	if !n.waiting {
+		// waypoints are placed on the ground so only the horizontal distance
+		// counts
+		offset := waypoint.Position.Sub(ctx.Entity.Position())
+		offset[1] = 0
+		if offset.Len() > n.acceptanceRadius {
+			if !n.pathing || navigation.Goal != waypoint.Position {
+				n.pathing = true
+				navigation.SetGoal(waypoint.Position)
+				return behaviortree.StatusRunning
+			}
+			if !navigationRunning(navigation) {
+				config.Cursor = route.Advance(config.Cursor)
+				n.Reset()
+				return behaviortree.StatusFailure
+			}
+			return behaviortree.StatusRunning
+		}
+
+		navigation.ClearGoal()
+		n.pathing = false
+		n.waiting = true
+	}
+
+	if waypoint.Action != "" {
+		n.action = waypoint.Action
+		ctx.Entity.AIComponent.Action = waypoint.Action
+	}
+	n.elapsed += delta
+	if n.elapsed < waypoint.Wait {
+		return behaviortree.StatusRunning
+	}
+
+	config.Cursor = route.Advance(config.Cursor)
+	n.Reset()
+	return behaviortree.StatusSuccess
+}
+
+func (n *patrolRoute) Reset() {
+	if n.entity != nil {
+		if n.pathing {
+			n.entity.NavigationComponent.ClearGoal()
+		}
+		if n.action != "" && n.entity.AIComponent.Action == n.action {
+			n.entity.AIComponent.Action = ""
+		}
+	}
+	n.entity = nil
+	n.pathing = false
+	n.waiting = false
+	n.elapsed = 0
+	n.action = ""
+}
+
+// playAnimation sets the entity's action for the duration, animation state
+// machines pick it up with "action:<name>" conditions
+type playAnimation struct {
+	action   string
+	duration time.Duration
+	elapsed  time.Duration
+
+	entity *entity.Entity
+}
+
+func (n *playAnimation) Tick(ctx Context, bb *behaviortree.Blackboard, delta time.Duration) behaviortree.Status {
+	n.entity = ctx.Entity
+	ctx.Entity.AIComponent.Action = n.action
+	n.elapsed += delta
+	if n.elapsed >= n.duration {
+		n.Reset()
+		return behaviortree.StatusSuccess
+	}
+	return behaviortree.StatusRunning
+}
+
+func (n *playAnimation) Reset() {
+	if n.entity != nil && n.entity.AIComponent.Action == n.action {
+		n.entity.AIComponent.Action = ""
+	}
+	n.entity = nil
+	n.elapsed = 0
+}
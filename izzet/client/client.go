@@ -3,7 +3,6 @@ package client
 import (
 	"fmt"
 	"log/slog"
-	"net"
 	"os"
 	"path/filepath"
 	"time"
@@ -12,6 +11,8 @@ import (
 	"github.com/Zyko0/go-sdl3/ttf"
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/clocksync"
+	"github.com/kkevinchou/izzet/internal/collision/collider"
 	"github.com/kkevinchou/izzet/internal/input"
 	"github.com/kkevinchou/izzet/internal/iztlog"
 	"github.com/kkevinchou/izzet/internal/navmesh"
@@ -22,9 +23,12 @@ import (
 	"github.com/kkevinchou/izzet/izzet/client/editorcamera"
 	"github.com/kkevinchou/izzet/izzet/collisionobserver"
 	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/navmeshbuilder"
 	"github.com/kkevinchou/izzet/izzet/network"
+	"github.com/kkevinchou/izzet/izzet/project"
 	"github.com/kkevinchou/izzet/izzet/render"
 	"github.com/kkevinchou/izzet/izzet/runtimeconfig"
+	"github.com/kkevinchou/izzet/izzet/server"
 	"github.com/kkevinchou/izzet/izzet/serverstats"
 	"github.com/kkevinchou/izzet/izzet/settings"
 	"github.com/kkevinchou/izzet/izzet/system"
@@ -57,13 +61,14 @@ type Client struct {
 	appMode           appmode.Mode
 	collisionObserver *collisionobserver.CollisionObserver
 	stateBuffer       *clientsystem.StateBuffer
+	snapshotHistory   *clientsystem.SnapshotHistory
+	timeDilation      *clocksync.Dilation
 
 	runtimeConfig *runtimeconfig.RuntimeConfig
 
 	playerID        int
 	playerEntity    *entity.Entity
 	playerCamera    *entity.Entity
-	connection      net.Conn
 	networkMessages chan network.MessageTransport
 	commandFrame    int
 	clientConnected bool
@@ -71,16 +76,24 @@ type Client struct {
 	commandFrameHistory *clientsystem.CommandFrameHistory
 	asyncServerStarted  bool
 	asyncServerDone     chan bool
+	asyncServer         *server.Server
 	serverAddress       string
+	networkCodec        network.CodecType
+	networkTransport    network.TransportType
+	networkSimulation   settings.NetworkSimulationConfig
 
 	frameInput  input.Input
 	serverStats serverstats.ServerStats
 
 	selectedEntity *entity.Entity
 
-	project *Project
+	project *project.Project
 
-	navMesh                *navmesh.NavigationMesh
+	navMesh *navmesh.NavigationMesh
+	// bakedNavMesh is what's handed to the server and saved with the project, it's
+	// loaded with the project while navMesh is only set by building in the editor
+	bakedNavMesh           *navmeshbuilder.BakedNavMesh
+	navMeshEditBounds      []collider.BoundingBox
 	predictionDebugLogging bool
 }
 
@@ -109,12 +122,25 @@ func New(shaderDirectory string, config settings.Config, logsEnabled bool) *Clie
 
 	w, h := window.GetSize()
 
+	networkCodec, err := network.ParseCodecType(config.NetworkCodec)
+	if err != nil {
+		panic(err)
+	}
+	networkTransport, err := network.ParseTransportType(config.NetworkTransport)
+	if err != nil {
+		panic(err)
+	}
+
 	g := &Client{
-		asyncServerDone: make(chan bool),
-		window:          window,
-		appMode:         appmode.Editor,
-		platform:        sdlPlatform,
-		serverAddress:   config.ServerAddress,
+		asyncServerDone:   make(chan bool),
+		window:            window,
+		appMode:           appmode.Editor,
+		platform:          sdlPlatform,
+		serverAddress:     config.ServerAddress,
+		networkCodec:      networkCodec,
+		networkTransport:  networkTransport,
+		networkSimulation: config.NetworkSimulation,
+		timeDilation:      clocksync.NewDilation(settings.TimeDilationGain, settings.MaxTimeDilation),
 	}
 
 	if logsEnabled {
@@ -179,8 +205,16 @@ func (g *Client) Start() {
 		accumulator += delta
 		renderAccumulator += delta
 
+		// the simulation always steps by MSPerCommandFrame, dilating time only
+		// changes how often a step is taken so the server's input buffer for this
+		// client stays at its target depth
+		msPerCommandFrame := float64(settings.MSPerCommandFrame)
+		if g.IsConnected() {
+			msPerCommandFrame *= g.timeDilation.Scale(float64(g.RuntimeConfig().TargetInputBufferDepth))
+		}
+
 		numSimulatedFrames := 0
-		for accumulator >= float64(settings.MSPerCommandFrame) {
+		for accumulator >= msPerCommandFrame {
 			g.platform.NewFrame()
 			inputCollector := input.NewInputCollector()
 			g.platform.ProcessEvents(inputCollector)
@@ -197,7 +231,7 @@ func (g *Client) Start() {
 			g.world.IncrementCommandFrameCount()
 			commandFrameCountBeforeRender += 1
 
-			accumulator -= float64(settings.MSPerCommandFrame)
+			accumulator -= msPerCommandFrame
 			numSimulatedFrames++
 			if numSimulatedFrames >= settings.MaxCommandFramesPerLoop {
 				g.Logger().Info("ran into max command frames per loop", "max", settings.MaxCommandFramesPerLoop)
@@ -221,7 +255,7 @@ func (g *Client) Start() {
 			}
 		}
 
-		nextCommandFrameMs := float64(settings.MSPerCommandFrame) - accumulator
+		nextCommandFrameMs := msPerCommandFrame - accumulator
 		nextRenderFrameMs := msPerFrame - renderAccumulator
 		minNextFrameMs := min(nextCommandFrameMs, nextRenderFrameMs)
 		if minNextFrameMs > 0 {
@@ -259,6 +293,7 @@ func (g *Client) setupSystems() {
 	g.playModeSystems = append(g.playModeSystems, clientsystem.NewCharacterControllerSystem(g))
 	g.playModeSystems = append(g.playModeSystems, system.NewKinematicSystem(g))
 	g.playModeSystems = append(g.playModeSystems, system.NewCharacterOrientationSystem(g))
+	g.playModeSystems = append(g.playModeSystems, clientsystem.NewCorrectionSystem(g))
 	g.playModeSystems = append(g.playModeSystems, system.NewCameraSystem(g))
 	g.playModeSystems = append(g.playModeSystems, system.NewCombatSystem(g))
 	g.playModeSystems = append(g.playModeSystems, clientsystem.NewClientAnimationSystem(g))
@@ -5,13 +5,13 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"net"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/clocksync"
 	"github.com/kkevinchou/izzet/internal/collision/collider"
 	"github.com/kkevinchou/izzet/internal/input"
 	"github.com/kkevinchou/izzet/internal/iztlog"
@@ -25,9 +25,9 @@ import (
 	"github.com/kkevinchou/izzet/izzet/client/edithistory"
 	"github.com/kkevinchou/izzet/izzet/collisionobserver"
 	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/navmeshbuilder"
 	"github.com/kkevinchou/izzet/izzet/network"
 	"github.com/kkevinchou/izzet/izzet/render/context"
-	"github.com/kkevinchou/izzet/izzet/render/renderiface"
 	"github.com/kkevinchou/izzet/izzet/runtimeconfig"
 	"github.com/kkevinchou/izzet/izzet/serialization"
 	"github.com/kkevinchou/izzet/izzet/server"
@@ -94,12 +94,27 @@ func (g *Client) AppendEdit(edit edithistory.Edit) {
 
 // game world
 func (g *Client) Redo() {
-	g.editHistory.Redo()
+	g.applyHistoryEdit(g.editHistory.Next(), g.editHistory.Redo)
 }
 
 // game world
 func (g *Client) Undo() {
-	g.editHistory.Undo()
+	g.applyHistoryEdit(g.editHistory.Current(), g.editHistory.Undo)
+}
+
+// applyHistoryEdit undoes or redoes edit, rebuilding the nav mesh tiles under
+// the entity it transforms
+func (g *Client) applyHistoryEdit(edit edithistory.Edit, apply func() bool) {
+	entityEdit, ok := edit.(interface{ EditedEntity() *entity.Entity })
+	if !ok {
+		apply()
+		return
+	}
+
+	e := entityEdit.EditedEntity()
+	g.beginNavMeshEdit(e)
+	apply()
+	g.completeNavMeshEdit(e)
 }
 
 func (g *Client) StopLiveWorld() {
@@ -124,6 +139,9 @@ func (g *Client) RuntimeConfig() *runtimeconfig.RuntimeConfig {
 	return g.runtimeConfig
 }
 
+// playerJoinTimeout is how long Connect waits for the server to ack the join
+const playerJoinTimeout = 10 * time.Second
+
 func (g *Client) Connect() error {
 	if g.IsConnected() {
 		return nil
@@ -131,18 +149,19 @@ func (g *Client) Connect() error {
 	g.commandFrame = 0
 
 	iztlog.ClientLogger.Info("connecting to " + g.serverAddress)
-	conn, err := net.Dial("tcp", g.serverAddress)
+	client, err := network.Dial(g.networkTransport, g.serverAddress, g.networkCodec)
 	if err != nil {
 		return err
 	}
-	g.client = network.NewClient(conn)
-	messageTransport, err := g.client.Recv()
-	if err != nil {
-		return err
+	if g.networkSimulation.Enabled {
+		conditions := network.NetworkConditionsFromConfig(g.networkSimulation.Client)
+		iztlog.ClientLogger.Info("simulating network conditions", "conditions", fmt.Sprintf("%+v", conditions))
+		client = network.NewSimulatedClient(client, conditions, g.networkSimulation.Seed)
 	}
-
-	message, err := network.ExtractMessage[network.AckPlayerJoinMessage](messageTransport)
+	g.client = client
+	message, err := awaitPlayerJoin(client, playerJoinTimeout)
 	if err != nil {
+		client.Close()
 		return err
 	}
 	iztlog.ClientLogger.Info("connected to server", "project name", message.ProjectName)
@@ -152,7 +171,6 @@ func (g *Client) Connect() error {
 	g.appMode = appmode.Play
 
 	g.playerID = message.PlayerID
-	g.connection = conn
 	g.networkMessages = make(chan network.MessageTransport, 100)
 
 	g.initializeAppAndWorld(bytes.NewReader(message.SerializedWorld), message.ProjectName)
@@ -168,13 +186,14 @@ func (g *Client) Connect() error {
 
 	// TODO a done channel to close out the goroutine
 	go func() {
-		defer conn.Close()
+		defer client.Close()
 
 		for {
-			message, err := g.client.Recv()
+			message, err := client.Recv()
 			if err != nil {
 				if err == io.EOF {
-					continue
+					iztlog.ClientLogger.Info("connection closed by server")
+					return
 				}
 
 				iztlog.ClientLogger.Error("error reading incoming message, closing connection", "error", err.Error())
@@ -188,6 +207,30 @@ func (g *Client) Connect() error {
 	return nil
 }
 
+// awaitPlayerJoin reads messages until the server acks the join. unreliable
+// messages like game state updates can arrive before the ack, they're discarded
+// since there's no world to apply them to yet. the connection is closed if the
+// ack doesn't arrive within timeout
+func awaitPlayerJoin(client network.IzzetClient, timeout time.Duration) (network.AckPlayerJoinMessage, error) {
+	timer := time.AfterFunc(timeout, client.Close)
+	defer timer.Stop()
+
+	for {
+		messageTransport, err := client.Recv()
+		if err != nil {
+			if !timer.Stop() {
+				return network.AckPlayerJoinMessage{}, fmt.Errorf("timed out after %s waiting for the server to ack the join: %w", timeout, err)
+			}
+			return network.AckPlayerJoinMessage{}, err
+		}
+
+		if messageTransport.MessageType != network.MsgTypeAckPlayerJoin {
+			continue
+		}
+		return network.ExtractMessage[network.AckPlayerJoinMessage](messageTransport)
+	}
+}
+
 func (g *Client) NetworkMessagesChannel() chan network.MessageTransport {
 	return g.networkMessages
 }
@@ -203,9 +246,6 @@ func (g *Client) GetPlayerID() int {
 func (g *Client) IsConnected() bool {
 	return g.clientConnected
 }
-func (g *Client) GetPlayerConnection() net.Conn {
-	return g.connection
-}
 func (g *Client) SetPlayerEntity(entity *entity.Entity) {
 	g.playerEntity = entity
 }
@@ -242,9 +282,9 @@ func (g *Client) GetPlayer(playerID int) *network.Player {
 func (g *Client) StartAsyncServer() {
 	started := make(chan bool)
 
-	var compiledNavMesh *navmesh.CompiledNavMesh
-	if g.navMesh != nil {
-		compiledNavMesh = navmesh.CompileNavMesh(g.navMesh)
+	var bakedNavMesh navmeshbuilder.BakedNavMesh
+	if g.bakedNavMesh != nil {
+		bakedNavMesh = *g.bakedNavMesh
 	}
 
 	go func() {
@@ -266,9 +306,13 @@ func (g *Client) StartAsyncServer() {
 			g.assetManager.RootJoints,
 		)
 
-		serverApp.SetNavMesh(compiledNavMesh)
+		serverApp.SetNavMesh(bakedNavMesh)
+		serverApp.SetNetworkTransport(g.networkTransport)
+		serverApp.SetNetworkSimulation(g.networkSimulation)
+		g.asyncServer = serverApp
 		serverApp.Start(started, g.asyncServerDone)
 		g.asyncServerStarted = false
+		g.asyncServer = nil
 		fmt.Println("Server finished teardown")
 	}()
 
@@ -287,7 +331,7 @@ func (g *Client) AsyncServerStarted() bool {
 
 func (g *Client) DisconnectClient() {
 	if g.clientConnected {
-		g.connection.Close()
+		g.client.Close()
 		g.clientConnected = false
 		g.commandFrameHistory.Reset()
 		g.StopLiveWorld()
@@ -296,6 +340,37 @@ func (g *Client) DisconnectClient() {
 	g.SetMouseCaptured(false)
 }
 
+// NetworkConditions returns the conditions simulated on the client's connection,
+// ok is false if the connection isn't simulated
+func (g *Client) NetworkConditions() (network.NetworkConditions, bool) {
+	simulated, ok := g.client.(*network.SimulatedClient)
+	if !g.clientConnected || !ok {
+		return network.NetworkConditions{}, false
+	}
+	return simulated.Conditions(), true
+}
+
+func (g *Client) SetNetworkConditions(conditions network.NetworkConditions) {
+	if simulated, ok := g.client.(*network.SimulatedClient); ok {
+		simulated.SetConditions(conditions)
+	}
+}
+
+// ServerNetworkConditions returns the conditions the async server simulates on
+// each player's connection keyed by player id
+func (g *Client) ServerNetworkConditions() map[int]network.NetworkConditions {
+	if g.asyncServer == nil {
+		return nil
+	}
+	return g.asyncServer.PlayerNetworkConditions()
+}
+
+func (g *Client) SetServerNetworkConditions(playerID int, conditions network.NetworkConditions) {
+	if g.asyncServer != nil {
+		g.asyncServer.SetPlayerNetworkConditions(playerID, conditions)
+	}
+}
+
 func (g *Client) World() *world.GameWorld {
 	return g.world
 }
@@ -304,12 +379,22 @@ func (g *Client) StateBuffer() *clientsystem.StateBuffer {
 	return g.stateBuffer
 }
 
+func (g *Client) SnapshotHistory() *clientsystem.SnapshotHistory {
+	return g.snapshotHistory
+}
+
+func (g *Client) TimeDilation() *clocksync.Dilation {
+	return g.timeDilation
+}
+
 func (g *Client) initializeApp() {
 	g.stateBuffer = clientsystem.NewStateBuffer()
 	g.commandFrameHistory = clientsystem.NewCommandFrameHistory()
 	g.editHistory = edithistory.New()
 	g.collisionObserver = collisionobserver.NewCollisionObserver()
 	g.stateBuffer = clientsystem.NewStateBuffer()
+	g.snapshotHistory = clientsystem.NewSnapshotHistory()
+	g.timeDilation.Reset()
 	g.camera.Position = settings.EditorCameraStartPosition
 	g.camera.Rotation = mgl64.QuatIdent()
 }
@@ -460,104 +545,74 @@ func (g *Client) SelectedEntity() *entity.Entity {
 	return g.selectedEntity
 }
 
-func (g *Client) BuildNavMesh(app renderiface.App, iterationCount int, walkableHeight float32, climbableHeight float32, minRegionArea int, sampleDist float64, maxError float64) {
+func (g *Client) BuildNavMesh(config navmeshbuilder.Config) {
 	start := time.Now()
 	defer func() {
 		iztlog.ClientLogger.Info("built new nav mesh", "build time", time.Since(start).Seconds())
 	}()
 
-	cs := app.RuntimeConfig().NavigationMeshCellSize
-	ch := app.RuntimeConfig().NavigationMeshCellHeight
-	walkableRadius := app.RuntimeConfig().NavigationMeshAgentRadius
-
-	walkableRadiusVoxels := navmesh.WorldRadiusToVoxels(walkableRadius, cs)
-	walkableHeightVoxels := navmesh.WorldHeightToVoxels(walkableHeight, ch)
-	climbableHeightVoxels := navmesh.WorldClimbToVoxels(climbableHeight, ch)
-
-	minVertex := mgl64.Vec3{-200.0, -5.0, -200.0}
-	maxVertex := mgl64.Vec3{200.0, 80.0, 200.0}
-
-	hfWidth := int((maxVertex.X()-minVertex.X())/float64(cs) + 0.5)
-	hfHeight := int((maxVertex.Z()-minVertex.Z())/float64(cs) + 0.5)
-	nmbb := collider.BoundingBox{MinVertex: minVertex, MaxVertex: maxVertex}
-
-	hf := navmesh.NewHeightField(hfWidth, hfHeight, minVertex, maxVertex, float64(cs), float64(ch))
-	var debugLines [][2]mgl64.Vec3
-
-	world := app.World()
-	for _, e := range world.Entities() {
-		if e.MeshComponent == nil {
-			continue
-		}
-		if !e.HasBoundingBox() {
-			continue
-		}
-
-		ebb := e.BoundingBox()
-
-		if ebb.MaxVertex.X() < nmbb.MinVertex.X() || ebb.MinVertex.X() > nmbb.MaxVertex.X() {
-			continue
-		}
-		if ebb.MaxVertex.Y() < nmbb.MinVertex.Y() || ebb.MinVertex.Y() > nmbb.MaxVertex.Y() {
-			continue
-		}
-		if ebb.MaxVertex.Z() < nmbb.MinVertex.Z() || ebb.MinVertex.Z() > nmbb.MaxVertex.Z() {
-			continue
-		}
+	config.Logger = iztlog.ClientLogger
+	baked, navMesh := navmeshbuilder.BakeForEditor(g.world, g.assetManager, config)
+	g.navMesh = navMesh
+	g.bakedNavMesh = &baked
+}
 
-		primitives := app.AssetManager().GetPrimitives(e.MeshComponent.MeshHandle)
-		transform := utils.Mat4F64ToF32(entity.WorldTransform(e))
-		up := mgl64.Vec3{0, 1, 0}
+// beginNavMeshEdit remembers where an entity was before it's transformed in the
+// editor so the nav mesh tiles it leaves behind are rebuilt as well
+func (g *Client) beginNavMeshEdit(e *entity.Entity) {
+	g.navMeshEditBounds = navMeshGeometryBounds(e)
+}
 
-		for _, p := range primitives {
-			for i := 0; i < len(p.Primitive.Vertices); i += 3 {
-				v1 := utils.Vec3F32ToF64(transform.Mul4x1(p.Primitive.Vertices[i].Position.Vec4(1)).Vec3())
-				v2 := utils.Vec3F32ToF64(transform.Mul4x1(p.Primitive.Vertices[i+1].Position.Vec4(1)).Vec3())
-				v3 := utils.Vec3F32ToF64(transform.Mul4x1(p.Primitive.Vertices[i+2].Position.Vec4(1)).Vec3())
+// completeNavMeshEdit rebuilds the nav mesh tiles touched by an entity that was
+// transformed in the editor
+func (g *Client) completeNavMeshEdit(e *entity.Entity) {
+	bounds := append(g.navMeshEditBounds, navMeshGeometryBounds(e)...)
+	g.navMeshEditBounds = nil
+	g.rebuildNavMeshTiles(bounds)
+}
 
-				tv1 := v2.Sub(v1)
-				tv2 := v3.Sub(v2)
+// RebuildNavMeshUnder rebuilds the nav mesh tiles under entities that were
+// added to the world in the editor
+func (g *Client) RebuildNavMeshUnder(entities ...*entity.Entity) {
+	var bounds []collider.BoundingBox
+	for _, e := range entities {
+		bounds = append(bounds, navMeshGeometryBounds(e)...)
+	}
+	g.rebuildNavMeshTiles(bounds)
+}
 
-				normal := tv1.Cross(tv2).Normalize()
-				isUp := normal.Dot(up) >= 0.7
+// deleteEntity removes an entity from the world in the editor and rebuilds the
+// nav mesh tiles it leaves behind
+func (g *Client) deleteEntity(e *entity.Entity) {
+	bounds := navMeshGeometryBounds(e)
+	g.world.DeleteEntity(e.ID)
+	g.rebuildNavMeshTiles(bounds)
+}
 
-				navmesh.RasterizeTriangle(v1, v2, v3, float64(cs), float64(ch), hf, isUp, climbableHeightVoxels)
-			}
-		}
+// rebuildNavMeshTiles rebuilds the baked nav mesh tiles overlapping bounds
+// rather than baking the whole nav mesh again
+func (g *Client) rebuildNavMeshTiles(bounds []collider.BoundingBox) {
+	if g.bakedNavMesh == nil || len(bounds) == 0 {
+		return
 	}
 
-	if app.RuntimeConfig().NavigationMeshFilterLedgeSpans {
-		navmesh.FilterLedgeSpans(walkableHeightVoxels, climbableHeightVoxels, hf)
-	}
+	start := time.Now()
+	rebuilt := navmeshbuilder.RebuildTiles(*g.bakedNavMesh, g.world, g.assetManager, bounds)
+	g.bakedNavMesh = &rebuilt
+	iztlog.ClientLogger.Info("rebuilt nav mesh tiles", "build time", time.Since(start).Seconds())
+}
 
-	if app.RuntimeConfig().NavigationMeshFilterLowHeightSpans {
-		navmesh.FilterLowHeightSpans(walkableHeightVoxels, hf)
+// navMeshGeometryBounds returns the bounding boxes of the entity and its
+// descendants that the nav mesh is built from
+func navMeshGeometryBounds(e *entity.Entity) []collider.BoundingBox {
+	var bounds []collider.BoundingBox
+	if e.MeshComponent != nil && e.HasBoundingBox() {
+		bounds = append(bounds, e.BoundingBox())
 	}
-
-	chf := navmesh.NewCompactHeightField(walkableHeightVoxels, climbableHeightVoxels, hf)
-
-	navmesh.ErodeWalkableArea(chf, walkableRadiusVoxels)
-	navmesh.BuildDistanceField(chf)
-
-	navmesh.BuildRegions(chf, iterationCount, minRegionArea, 1)
-	contourSet := navmesh.BuildContours(chf, maxError, int(app.RuntimeConfig().NavigationmeshMaxEdgeLength))
-	mesh := navmesh.BuildPolyMesh(contourSet)
-	detailedMesh := navmesh.BuildDetailedPolyMesh(mesh, chf, app.RuntimeConfig())
-
-	nm := &navmesh.NavigationMesh{
-		HeightField:          hf,
-		CompactHeightField:   chf,
-		Volume:               nmbb,
-		BlurredDistances:     chf.Distances,
-		DebugLines:           debugLines,
-		Invalidated:          true,
-		InvalidatedTimestamp: int(time.Now().Unix()),
-		ContourSet:           contourSet,
-		Mesh:                 mesh,
-		DetailedMesh:         detailedMesh,
+	for _, child := range e.Children {
+		bounds = append(bounds, navMeshGeometryBounds(child)...)
 	}
-
-	g.navMesh = nm
+	return bounds
 }
 
 func (g *Client) NavMesh() *navmesh.NavigationMesh {
@@ -567,11 +622,11 @@ func (g *Client) NavMesh() *navmesh.NavigationMesh {
 func (g *Client) FindPath(start, goal mgl64.Vec3) {
 	g.navMesh.Invalidated = true
 	c := navmesh.CompileNavMesh(g.navMesh)
-	path := navmesh.FindPath(c, start, goal)
+	path := navmesh.FindPath(c, start, goal, nil)
 
 	navmesh.PATHPOLYGONS = make(map[int]bool)
 	for _, p := range path {
-		navmesh.PATHPOLYGONS[p] = true
+		navmesh.PATHPOLYGONS[p.Poly] = true
 	}
 }
 
@@ -587,6 +642,10 @@ func (g *Client) SetPredictionDebugLogging(value bool) {
 	g.predictionDebugLogging = value
 }
 
+func (g *Client) PredictedEntities() []*entity.Entity {
+	return clientsystem.PredictedEntities(g)
+}
+
 func (g *Client) QueueCreateMaterialTexture(id assets.MaterialID) {
 	g.renderSystem.QueueCreateMaterialTexture(id)
 }
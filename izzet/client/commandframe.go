@@ -87,7 +87,7 @@ func (g *Client) handleEditorInputCommands(frameInput input.Input) {
 		if event.Event == input.KeyboardEventUp {
 			selectedEntity := g.SelectedEntity()
 			if selectedEntity != nil {
-				g.world.DeleteEntity(selectedEntity.ID)
+				g.deleteEntity(selectedEntity)
 			}
 			g.SelectEntity(nil)
 		}
@@ -117,6 +117,7 @@ func (g *Client) handleEditorInputCommands(frameInput input.Input) {
 					id := entity.GetNextIDAndAdvance()
 					e.ID = id
 					g.world.AddEntity(e)
+					g.RebuildNavMeshUnder(e)
 					g.SelectEntity(e)
 				}
 			}
@@ -305,9 +306,11 @@ func (g *Client) handleGizmos(frameInput input.Input) {
 				g.AppendEdit(
 					edithistory.NewPositionEdit(gizmo.TranslationGizmo.ActivationPosition, e.GetLocalPosition(), e),
 				)
+				g.completeNavMeshEdit(e)
 			}
 			if gizmoEvent == gizmo.GizmoEventActivated {
 				gizmo.TranslationGizmo.ActivationPosition = e.GetLocalPosition()
+				g.beginNavMeshEdit(e)
 				gizmo.TranslationGizmo.LastSnapVector = e.GetLocalPosition()
 			}
 			gizmoHovered = gizmo.TranslationGizmo.HoveredEntityID != -1
@@ -361,9 +364,11 @@ func (g *Client) handleGizmos(frameInput input.Input) {
 				g.AppendEdit(
 					edithistory.NewRotationEdit(gizmo.TranslationGizmo.ActivationRotation, e.GetLocalRotation(), e),
 				)
+				g.completeNavMeshEdit(e)
 			}
 			if gizmoEvent == gizmo.GizmoEventActivated {
 				gizmo.RotationGizmo.ActivationRotation = e.GetLocalRotation()
+				g.beginNavMeshEdit(e)
 				// gizmo.TranslationGizmo.LastSnapVector = mgl64.Vec3{}
 			}
 			gizmoHovered = gizmo.RotationGizmo.HoveredEntityID != -1
@@ -380,9 +385,11 @@ func (g *Client) handleGizmos(frameInput input.Input) {
 				g.AppendEdit(
 					edithistory.NewScaleEdit(gizmo.ScaleGizmo.ActivationScale, e.Scale(), e),
 				)
+				g.completeNavMeshEdit(e)
 			}
 			if gizmoEvent == gizmo.GizmoEventActivated {
 				gizmo.ScaleGizmo.ActivationScale = e.Scale()
+				g.beginNavMeshEdit(e)
 			}
 			gizmoHovered = gizmo.ScaleGizmo.HoveredEntityID != -1
 		}
@@ -19,6 +19,10 @@ func NewPositionEdit(lastPosition, currentPosition mgl64.Vec3, entity *entity.En
 	}
 }
 
+func (e *PositionEdit) EditedEntity() *entity.Entity {
+	return e.Entity
+}
+
 func (e *PositionEdit) Undo() {
 	entity.SetLocalPosition(e.Entity, e.LastPosition)
 }
@@ -41,6 +45,10 @@ func NewRotationEdit(lastRotation, currentRotation mgl64.Quat, entity *entity.En
 	}
 }
 
+func (e *RotationEdit) EditedEntity() *entity.Entity {
+	return e.Entity
+}
+
 func (e *RotationEdit) Undo() {
 	e.Entity.SetLocalRotation(e.LastRotation)
 }
@@ -63,6 +71,10 @@ func NewScaleEdit(lastScale, currentScale mgl64.Vec3, entity *entity.Entity) *Sc
 	}
 }
 
+func (e *ScaleEdit) EditedEntity() *entity.Entity {
+	return e.Entity
+}
+
 func (e *ScaleEdit) Undo() {
 	entity.SetScale(e.Entity, e.LastScale)
 }
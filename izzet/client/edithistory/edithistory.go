@@ -53,6 +53,22 @@ func (eh *EditHistory) Redo() bool {
 	return true
 }
 
+// Current returns the edit that Undo would revert, nil if there isn't one
+func (eh *EditHistory) Current() Edit {
+	if eh.cursor == -1 {
+		return nil
+	}
+	return eh.editList[eh.cursor]
+}
+
+// Next returns the edit that Redo would apply, nil if there isn't one
+func (eh *EditHistory) Next() Edit {
+	if eh.cursor+1 >= len(eh.editList) {
+		return nil
+	}
+	return eh.editList[eh.cursor+1]
+}
+
 func (eh *EditHistory) Clear() {
 	eh.cursor = -1
 	eh.editList = nil
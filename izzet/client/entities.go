@@ -18,6 +18,8 @@ func (g *Client) CreateEntitiesFromDocument(d assets.Document, merged bool) *ent
 		for _, entity := range spawnedEntities {
 			g.world.AddEntity(entity)
 		}
+		// the first spawned entity is the parent of the rest
+		g.RebuildNavMeshUnder(spawnedEntities[0])
 		return spawnedEntities[0]
 	}
 
@@ -41,6 +43,7 @@ func (g *Client) CreateEntitiesFromDocument(d assets.Document, merged bool) *ent
 		e.Animation = entity.NewAnimationComponent(g.assetManager, handle, id, mode)
 	}
 
+	g.RebuildNavMeshUnder(e)
 	return e
 }
 
@@ -4,44 +4,23 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"io/fs"
 	"os"
-	"path"
 	"path/filepath"
 
 	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/iztlog"
 	"github.com/kkevinchou/izzet/izzet/apputils"
 	"github.com/kkevinchou/izzet/izzet/assets"
 	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/navmeshbuilder"
 	"github.com/kkevinchou/izzet/izzet/prefab"
+	"github.com/kkevinchou/izzet/izzet/project"
+	"github.com/kkevinchou/izzet/izzet/project/projectpaths"
 	"github.com/kkevinchou/izzet/izzet/settings"
 	"github.com/kkevinchou/izzet/izzet/world"
 )
 
-// Project contains engine data that's meant to be persisted and can be reloaded
-type Project struct {
-	WorldFile  string
-	AssetsFile string
-	Name       string
-}
-
-type DocumentJSON struct {
-	Document assets.Document
-}
-
-type MaterialsJSON struct {
-	MaterialAsset assets.Material
-}
-
-type PrefabsJSON struct {
-	PrefabAsset prefab.Prefab
-}
-
-type AssetsJSON struct {
-	Documents []DocumentJSON
-	Materials []MaterialsJSON
-	Prefabs   []PrefabsJSON
-}
-
 func (g *Client) InitializeProjectFolders(name string) error {
 	if name == "" {
 		return errors.New("name cannot be empty string")
@@ -81,11 +60,11 @@ func (g *Client) SaveProjectAs(name string) error {
 	}
 
 	contentDir := getContentDir(name)
-	worldFilePath := path.Join(settings.ProjectsDirectory, name, "world.json")
+	worldFilePath := projectpaths.WorldFilePath(name)
 	g.saveWorld(worldFilePath)
 	g.project.WorldFile = worldFilePath
 
-	assetsJSON := AssetsJSON{}
+	assetsJSON := project.AssetsJSON{}
 
 	// documents
 	for _, document := range g.AssetManager().GetDocuments() {
@@ -103,7 +82,7 @@ func (g *Client) SaveProjectAs(name string) error {
 		// in the event where we're saving a new project from the original, we want to overwrite the
 		// filepaths so that we reference the documents in the new project directory and not the old one.
 		newDocument.Filepath = filepath.ToSlash(filepath.Join(contentDir, filepath.Base(document.Filepath)))
-		assetsJSON.Documents = append(assetsJSON.Documents, DocumentJSON{
+		assetsJSON.Documents = append(assetsJSON.Documents, project.DocumentJSON{
 			Document: newDocument,
 		})
 
@@ -120,18 +99,18 @@ func (g *Client) SaveProjectAs(name string) error {
 	// materials
 
 	for _, material := range g.AssetManager().GetMaterials() {
-		assetsJSON.Materials = append(assetsJSON.Materials, MaterialsJSON{MaterialAsset: material})
+		assetsJSON.Materials = append(assetsJSON.Materials, project.MaterialsJSON{MaterialAsset: material})
 	}
 
 	// prefabs
 
 	for _, p := range prefab.Prefabs() {
-		assetsJSON.Prefabs = append(assetsJSON.Prefabs, PrefabsJSON{PrefabAsset: p})
+		assetsJSON.Prefabs = append(assetsJSON.Prefabs, project.PrefabsJSON{PrefabAsset: p})
 	}
 
 	// assets file
 
-	assetsFilePath := path.Join(settings.ProjectsDirectory, name, "assets.json")
+	assetsFilePath := projectpaths.AssetsFilePath(name)
 	assetsFile, err := os.OpenFile(assetsFilePath, os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		panic(err)
@@ -147,6 +126,16 @@ func (g *Client) SaveProjectAs(name string) error {
 
 	g.project.AssetsFile = assetsFilePath
 
+	// nav mesh
+
+	if g.bakedNavMesh != nil {
+		navMeshFilePath := projectpaths.NavMeshFilePath(name)
+		if err := navmeshbuilder.Save(navMeshFilePath, *g.bakedNavMesh); err != nil {
+			return err
+		}
+		g.project.NavMeshFile = navMeshFilePath
+	}
+
 	// write the project files
 
 	f, err := os.OpenFile(filepath.Join(settings.ProjectsDirectory, name, "main_project.izt"), os.O_CREATE|os.O_TRUNC, 0644)
@@ -167,7 +156,9 @@ func (g *Client) SaveProjectAs(name string) error {
 
 func (g *Client) NewProject(name string) {
 	g.InitializeProjectFolders(name)
-	g.project = &Project{Name: name}
+	g.project = &project.Project{Name: name}
+	g.navMesh = nil
+	g.bakedNavMesh = nil
 
 	g.assetManager = assets.NewAssetManager(true, g.Logger())
 	g.world = world.New()
@@ -206,15 +197,15 @@ func (g *Client) LoadProject(name string) bool {
 	}
 	defer projFile.Close()
 
-	var project Project
+	var p project.Project
 	decoder := json.NewDecoder(projFile)
-	err = decoder.Decode(&project)
+	err = decoder.Decode(&p)
 	if err != nil {
 		panic(err)
 	}
-	g.project = &project
+	g.project = &p
 
-	worldFile, err := os.Open(path.Join(settings.ProjectsDirectory, name, "world.json"))
+	worldFile, err := os.Open(projectpaths.WorldFilePath(name))
 	if err != nil {
 		panic(err)
 	}
@@ -222,46 +213,32 @@ func (g *Client) LoadProject(name string) bool {
 
 	g.world = world.New()
 	g.initializeAppAndWorld(worldFile, name)
+	g.loadNavMesh(name)
 
 	return true
 }
 
-func (g *Client) loadAssets(name string) {
-	assetsFilePath := path.Join(settings.ProjectsDirectory, name, "assets.json")
-	_, err := os.Stat(assetsFilePath)
-	if err != nil {
-		panic(err)
-	}
-
-	assetsFile, err := os.Open(assetsFilePath)
-	if err != nil {
-		panic(err)
-	}
-	defer assetsFile.Close()
+// loadNavMesh loads the project's baked nav mesh, one that was baked from a
+// different version of the world is discarded and needs to be built again
+func (g *Client) loadNavMesh(name string) {
+	g.navMesh = nil
+	g.bakedNavMesh = nil
 
-	var assetsJSON AssetsJSON
-	decoder := json.NewDecoder(assetsFile)
-	err = decoder.Decode(&assetsJSON)
+	baked, err := project.LoadNavMesh(name, g.world)
 	if err != nil {
-		panic(err)
+		if !errors.Is(err, fs.ErrNotExist) {
+			iztlog.ClientLogger.Warn("discarding project nav mesh", "reason", err)
+		}
+		return
 	}
+	baked.Config.Logger = iztlog.ClientLogger
+	g.bakedNavMesh = &baked
+}
 
+func (g *Client) loadAssets(name string) {
 	g.assetManager = assets.NewAssetManager(true, g.Logger())
-
-	for _, document := range assetsJSON.Documents {
-		g.assetManager.ReloadDocument(document.Document)
-	}
-
-	for _, material := range assetsJSON.Materials {
-		g.assetManager.CreateMaterialWithID(material.MaterialAsset.Name, material.MaterialAsset.Material, material.MaterialAsset.ID)
-	}
-
-	prefab.InitializePrefabs(g.assetManager)
-	for _, p := range assetsJSON.Prefabs {
-		err := prefab.RegisterPrefabWithID(p.PrefabAsset.ID, p.PrefabAsset.Name, p.PrefabAsset.Entities)
-		if err != nil {
-			panic(err)
-		}
+	if err := project.LoadAssets(name, g.assetManager); err != nil {
+		panic(err)
 	}
 }
 
@@ -1,8 +1,18 @@
 package entity
 
-import "github.com/go-gl/mathgl/mgl64"
+import (
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/patrol"
+)
 
 type AIComponent struct {
+	// BehaviorTree is the name of the behavior in _assets/behaviors the entity
+	// runs on the server, empty for none
+	BehaviorTree string
+	// Action is the action the behavior tree is playing an animation for, it's
+	// picked up by the "action:<name>" conditions of animation state machines
+	Action string
+
 	PatrolConfig   *PatrolConfig
 	RotationConfig *RotationConfig
 	TargetConfig   *TargetConfig
@@ -12,9 +22,11 @@ type TargetConfig struct {
 	Direction mgl64.Vec3
 }
 
+// PatrolConfig sends the entity along a patrol route
 type PatrolConfig struct {
-	Points []mgl64.Vec3
-	Index  int
+	// RouteID is the id of the entity with the route's PatrolRouteComponent
+	RouteID int
+	Cursor  patrol.Cursor
 }
 
 type RotationConfig struct {
@@ -1,7 +1,47 @@
 package entity
 
+import (
+	"time"
+
+	"github.com/kkevinchou/izzet/internal/damage"
+)
+
 type AttackComponent struct {
 	Attacking   bool
 	AttackRange float64
 	TargetID    int
+
+	Damage     int
+	DamageType damage.Type
+	// Duration is how many seconds an attack takes, another attack can't start
+	// until it's over
+	Duration float64
+	// HitWindowStart and HitWindowEnd are when the attack hits the target, in
+	// seconds since the attack started. the target is hit at most once per
+	// attack, if it's in range during the window
+	HitWindowStart float64
+	HitWindowEnd   float64
+
+	// Elapsed is the time since the current attack started
+	Elapsed time.Duration `json:"-"`
+	// Hit is set once the current attack has hit the target
+	Hit bool `json:"-"`
+}
+
+func NewAttackComponent(attackRange float64) *AttackComponent {
+	return &AttackComponent{
+		AttackRange:    attackRange,
+		TargetID:       InvalidEntityID,
+		Damage:         10,
+		DamageType:     damage.TypeMelee,
+		Duration:       1,
+		HitWindowStart: 0.3,
+		HitWindowEnd:   0.5,
+	}
+}
+
+// InHitWindow returns whether the current attack is in its hit window
+func (c *AttackComponent) InHitWindow() bool {
+	seconds := c.Elapsed.Seconds()
+	return c.Attacking && seconds >= c.HitWindowStart && seconds <= c.HitWindowEnd
 }
@@ -49,8 +49,16 @@ type Entity struct {
 	PlayerInput *PlayerInputComponent `json:",omitempty"`
 	AIComponent *AIComponent          `json:",omitempty"`
 
-	NavigationComponent *NavigationComponent `json:",omitempty"`
-	AttackComponent     *AttackComponent     `json:",omitempty"`
+	NavigationComponent  *NavigationComponent  `json:",omitempty"`
+	NavMeshAreaComponent *NavMeshAreaComponent `json:",omitempty"`
+	AttackComponent      *AttackComponent      `json:",omitempty"`
+	PerceptionComponent  *PerceptionComponent  `json:",omitempty"`
+
+	OffMeshConnectionComponent *OffMeshConnectionComponent `json:",omitempty"`
+	NavMeshObstacleComponent   *NavMeshObstacleComponent   `json:",omitempty"`
+
+	PatrolRouteComponent *PatrolRouteComponent `json:",omitempty"`
+	WaypointComponent    *WaypointComponent    `json:",omitempty"`
 
 	SpawnPointComponent    *SpawnPoint             `json:",omitempty"`
 	AimDownSightsComponent *AimDownSightsComponent `json:",omitempty"`
@@ -1,5 +1,20 @@
 package entity
 
+import "github.com/kkevinchou/izzet/internal/damage"
+
 type HealthComponent struct {
 	Amount int
+	Max    int
+	// Armor reduces damage of every type
+	Armor float64
+	// Resistances reduce damage of a type by a fraction
+	Resistances map[damage.Type]float64 `json:",omitempty"`
+}
+
+func NewHealthComponent(max int) *HealthComponent {
+	return &HealthComponent{Amount: max, Max: max}
+}
+
+func (c *HealthComponent) Modifiers() damage.Modifiers {
+	return damage.Modifiers{Armor: c.Armor, Resistances: c.Resistances}
 }
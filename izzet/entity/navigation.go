@@ -2,6 +2,8 @@ package entity
 
 import (
 	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/crowd"
+	"github.com/kkevinchou/izzet/internal/navmesh"
 )
 
 const (
@@ -10,11 +12,89 @@ const (
 
 type NavigationComponent struct {
 	Goal mgl64.Vec3
+	// Path is the straight path from the entity's position to the goal, it's
+	// refreshed as the entity moves and steers around other agents
 	Path []mgl64.Vec3
+	// PathConnections holds the index of the off-mesh connection that starts at
+	// each point of Path, or -1
+	PathConnections []int
+
+	PathDirty bool
+	// PathRequest is the id of the path request that was queued when the path
+	// was last marked dirty, and PathStatus is its status. the current path is
+	// followed until the new one is ready
+	PathRequest int
+	PathStatus  navmesh.PathStatus
 
-	PathDirty  bool
 	NextTarget int
 	State      PathfindingState
+	// Traversal is the off-mesh connection being traversed while the state is
+	// PathfindingStateTraversing
+	Traversal OffMeshTraversal
+
+	// QueryFilter decides which areas paths can go through and which are
+	// preferred, nil paths through every area at the same cost
+	QueryFilter *navmesh.QueryFilter `json:",omitempty"`
+	// AgentParams are the radius, max speed and priority the entity avoids other
+	// agents with. a zero max speed falls back to the kinematic speed
+	AgentParams crowd.AgentParams
+}
+
+// NavMeshAreaComponent marks the area type of the nav mesh built on an entity. by
+// default the area type applies to the walkable surfaces of the entity itself,
+// volumes instead mark the walkable area of other entities within their bounding
+// box and aren't part of the nav mesh themselves
+type NavMeshAreaComponent struct {
+	Area   navmesh.AREA_TYPE
+	Volume bool
+}
+
+// OffMeshConnectionComponent authors an off-mesh connection that starts at the
+// entity's position. End is relative to the entity so the connection moves and
+// rotates with it
+type OffMeshConnectionComponent struct {
+	End           mgl64.Vec3
+	Radius        float64
+	Bidirectional bool
+	Area          navmesh.AREA_TYPE
+	Cost          float64
+}
+
+// OffMeshConnection returns the entity's off-mesh connection in world space
+func (e *Entity) OffMeshConnection() navmesh.OffMeshConnection {
+	component := e.OffMeshConnectionComponent
+	start := e.Position()
+	return navmesh.OffMeshConnection{
+		Start:         start,
+		End:           start.Add(e.Rotation().Rotate(component.End)),
+		Radius:        component.Radius,
+		Bidirectional: component.Bidirectional,
+		Area:          component.Area,
+		Cost:          component.Cost,
+	}
+}
+
+// NavMeshObstacleComponent carves the entity out of the nav mesh at runtime
+// once it comes to rest. the position of Obstacle is relative to the entity
+type NavMeshObstacleComponent struct {
+	Obstacle navmesh.Obstacle
+}
+
+// NavMeshObstacle returns the entity's obstacle in world space
+func (e *Entity) NavMeshObstacle() navmesh.Obstacle {
+	obstacle := e.NavMeshObstacleComponent.Obstacle
+	obstacle.Position = e.Position().Add(obstacle.Position)
+	return obstacle
+}
+
+// OffMeshTraversal moves an entity from Start to End of an off-mesh connection
+// over Duration seconds
+type OffMeshTraversal struct {
+	Connection int
+	Start      mgl64.Vec3
+	End        mgl64.Vec3
+	Elapsed    float64
+	Duration   float64
 }
 
 func NewNavigationComponent() *NavigationComponent {
@@ -26,7 +106,15 @@ func (n *NavigationComponent) SetGoal(goal mgl64.Vec3) {
 	n.PathDirty = true
 }
 
+// ClearGoal stops pathing and drops pending path requests, agents that are
+// crossing an off-mesh connection finish crossing it first
 func (n *NavigationComponent) ClearGoal() {
+	n.PathStatus = ""
+	if n.State == PathfindingStateTraversing {
+		n.Path = n.Path[:n.NextTarget+1]
+		n.PathDirty = false
+		return
+	}
 	n.State = Idle
 }
 
@@ -35,4 +123,7 @@ type PathfindingState string
 var (
 	Idle                    PathfindingState = "IDLE"
 	PathfindingStatePathing PathfindingState = "PATHING"
+	// PathfindingStateTraversing is moving across an off-mesh connection, the path
+	// resumes once the end is reached
+	PathfindingStateTraversing PathfindingState = "TRAVERSING"
 )
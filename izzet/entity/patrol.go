@@ -0,0 +1,31 @@
+package entity
+
+import "github.com/kkevinchou/izzet/internal/patrol"
+
+// PatrolRouteComponent makes the entity a patrol route, the route is the chain
+// of waypoint entities starting at Start
+type PatrolRouteComponent struct {
+	Mode patrol.Mode
+	// Start is the id of the route's first waypoint, or InvalidEntityID
+	Start int
+}
+
+// WaypointComponent makes the entity a waypoint of a patrol route
+type WaypointComponent struct {
+	// Next is the id of the route's next waypoint, or InvalidEntityID for the
+	// last waypoint
+	Next int
+	// Wait is how many seconds patrols wait at the waypoint
+	Wait float64
+	// Action is the action patrols play an animation for while they wait, empty
+	// for none
+	Action string
+}
+
+func NewPatrolRouteComponent() *PatrolRouteComponent {
+	return &PatrolRouteComponent{Mode: patrol.ModeLoop, Start: InvalidEntityID}
+}
+
+func NewWaypointComponent() *WaypointComponent {
+	return &WaypointComponent{Next: InvalidEntityID}
+}
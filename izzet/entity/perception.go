@@ -0,0 +1,20 @@
+package entity
+
+import "github.com/kkevinchou/izzet/internal/perception"
+
+// PerceptionComponent lets an AI see targets within a cone in front of it that
+// aren't blocked by other colliders and hear noises loud enough to reach it.
+// what it perceives is kept in Memory for MemoryDuration seconds
+type PerceptionComponent struct {
+	SightRange float64
+	// FieldOfView is the angle of the sight cone in degrees
+	FieldOfView float64
+	// EyeHeight is how far above the entity's position it sees from
+	EyeHeight float64
+	// HearingThreshold is the quietest noise the entity hears, noises are at
+	// their loudest of 1 at their source
+	HearingThreshold float64
+	MemoryDuration   float64
+
+	Memory *perception.Memory `json:"-"`
+}
@@ -1,14 +1,38 @@
 package entity
 
 import (
-	"time"
-
 	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/smoothing"
 )
 
+// RenderBlend smooths out corrections to a predicted entity. the offset is the
+// visual error left over from a misprediction, it's drawn on top of the
+// simulated transform and decays over time so the entity eases into its
+// corrected position while its colliders stay where the simulation put them
 type RenderBlend struct {
-	StartTime          time.Time
-	StartFrame         int
-	Active             bool
-	BlendStartPosition mgl64.Vec3
+	Offset smoothing.Offset `json:"-"`
+}
+
+// RenderTransform is the world transform the entity is drawn with, its world
+// transform with the render blend offset applied
+func RenderTransform(entity *Entity) mgl64.Mat4 {
+	transform := WorldTransform(entity)
+	if entity.RenderBlend == nil || entity.RenderBlend.Offset.IsZero() {
+		return transform
+	}
+
+	// rotate about the entity's position and then move it by the offset
+	position := transform.Col(3).Vec3()
+	rendered, rotation := entity.RenderBlend.Offset.Apply(position, mgl64.QuatIdent())
+	toOrigin := mgl64.Translate3D(-position[0], -position[1], -position[2])
+	fromOrigin := mgl64.Translate3D(rendered[0], rendered[1], rendered[2])
+	return fromOrigin.Mul4(rotation.Mat4()).Mul4(toOrigin).Mul4(transform)
+}
+
+// RenderPosition is the position the entity is drawn at
+func RenderPosition(entity *Entity) mgl64.Vec3 {
+	if entity.RenderBlend == nil {
+		return entity.Position()
+	}
+	return entity.Position().Add(entity.RenderBlend.Offset.Position)
 }
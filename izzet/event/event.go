@@ -1,14 +1,15 @@
 package event
 
 import (
-	"net"
-
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/damage"
 	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/network"
 )
 
 type PlayerJoinEvent struct {
-	PlayerID   int
-	Connection net.Conn
+	PlayerID int
+	Client   network.IzzetClient
 }
 
 type PlayerDisconnectEvent struct {
@@ -22,3 +23,33 @@ type EntitySpawnEvent struct {
 type DestroyEntityEvent struct {
 	EntityID int
 }
+
+// NoiseEvent is a noise AI can hear, e.g. gunfire. it's loudest at its
+// position and falls off to nothing at its radius
+type NoiseEvent struct {
+	SourceID int
+	Position mgl64.Vec3
+	Loudness float64
+	Radius   float64
+}
+
+// DamageEvent is damage dealt to the target before its armor and resistances
+// are applied
+type DamageEvent struct {
+	SourceID int
+	TargetID int
+	Amount   int
+	Type     damage.Type
+	HitPoint mgl64.Vec3
+}
+
+type DeathEvent struct {
+	EntityID int
+	// KillerID is the source of the damage that killed the entity
+	KillerID int
+}
+
+// RespawnEvent brings a dead entity back at the spawn point with full health
+type RespawnEvent struct {
+	EntityID int
+}
@@ -5,6 +5,10 @@ type EventManager struct {
 	PlayerDisconnectTopic *Topic[PlayerDisconnectEvent]
 	EntitySpawnTopic      *Topic[EntitySpawnEvent]
 	DestroyEntityTopic    *Topic[DestroyEntityEvent]
+	NoiseTopic            *Topic[NoiseEvent]
+	DamageTopic           *Topic[DamageEvent]
+	DeathTopic            *Topic[DeathEvent]
+	RespawnTopic          *Topic[RespawnEvent]
 }
 
 func NewEventManager() *EventManager {
@@ -13,6 +17,10 @@ func NewEventManager() *EventManager {
 		PlayerDisconnectTopic: &Topic[PlayerDisconnectEvent]{},
 		EntitySpawnTopic:      &Topic[EntitySpawnEvent]{},
 		DestroyEntityTopic:    &Topic[DestroyEntityEvent]{},
+		NoiseTopic:            &Topic[NoiseEvent]{},
+		DamageTopic:           &Topic[DamageEvent]{},
+		DeathTopic:            &Topic[DeathEvent]{},
+		RespawnTopic:          &Topic[RespawnEvent]{},
 	}
 }
 
@@ -0,0 +1,118 @@
+// Package launch parses the command line and runs the modes that don't need a
+// window, it doesn't import the client so it builds and tests on headless machines
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kkevinchou/izzet/izzet/network"
+	"github.com/kkevinchou/izzet/izzet/project/projectpaths"
+	"github.com/kkevinchou/izzet/izzet/settings"
+)
+
+const (
+	ModeLocal    string = "LOCAL"
+	ModeClient   string = "CLIENT"
+	ModeServer   string = "SERVER"
+	ModeHeadless string = "HEADLESS"
+	ModeBake     string = "BAKENAVMESH"
+)
+
+type CommandLine struct {
+	Mode        string
+	LogsEnabled bool
+	ProjectName string
+}
+
+func ParseCommandLine(args []string) (CommandLine, error) {
+	result := CommandLine{Mode: ModeClient, LogsEnabled: true, ProjectName: settings.StartupProject}
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--logs=") {
+			value, err := strconv.ParseBool(strings.TrimPrefix(arg, "--logs="))
+			if err != nil {
+				return CommandLine{}, fmt.Errorf("unexpected --logs value %q", strings.TrimPrefix(arg, "--logs="))
+			}
+			result.LogsEnabled = value
+			continue
+		}
+
+		if strings.HasPrefix(arg, "--project=") {
+			result.ProjectName = strings.TrimPrefix(arg, "--project=")
+			continue
+		}
+
+		if strings.HasPrefix(arg, "--") {
+			return CommandLine{}, fmt.Errorf("unexpected flag %s", arg)
+		}
+
+		mode := strings.ToUpper(arg)
+		if mode != ModeServer && mode != ModeClient && mode != ModeHeadless && mode != ModeBake {
+			return CommandLine{}, fmt.Errorf("unexpected mode %s", mode)
+		}
+		result.Mode = mode
+	}
+
+	return result, nil
+}
+
+// HeadlessOptions are what a dedicated server is started with, they're resolved
+// up front so bad flags or config fail before the server starts
+type HeadlessOptions struct {
+	ProjectName       string
+	WorldFilePath     string
+	Transport         network.TransportType
+	NetworkSimulation settings.NetworkSimulationConfig
+}
+
+func ResolveHeadlessOptions(config settings.Config, projectName string) (HeadlessOptions, error) {
+	if projectName == "" {
+		return HeadlessOptions{}, errors.New("headless mode needs a project")
+	}
+
+	transport, err := network.ParseTransportType(config.NetworkTransport)
+	if err != nil {
+		return HeadlessOptions{}, err
+	}
+
+	worldFilePath := projectpaths.WorldFilePath(projectName)
+	if _, err := os.Stat(worldFilePath); err != nil {
+		return HeadlessOptions{}, fmt.Errorf("failed to find world for project %q: %w", projectName, err)
+	}
+
+	return HeadlessOptions{
+		ProjectName:       projectName,
+		WorldFilePath:     worldFilePath,
+		Transport:         transport,
+		NetworkSimulation: config.NetworkSimulation,
+	}, nil
+}
+
+// Server is a dedicated server, Start signals started once it's listening and
+// returns after done is signaled
+type Server interface {
+	Start(started chan bool, done chan bool)
+}
+
+// RunHeadless runs a dedicated server without a window or GL context until a
+// signal is received
+func RunHeadless(server Server, signals <-chan os.Signal) {
+	started := make(chan bool)
+	done := make(chan bool)
+	stopped := make(chan bool)
+	go func() {
+		server.Start(started, done)
+		stopped <- true
+	}()
+	<-started
+
+	sig := <-signals
+	fmt.Printf("received %s, shutting down\n", sig)
+	done <- true
+	<-stopped
+	fmt.Println("server shut down")
+}
@@ -0,0 +1,98 @@
+package launch
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/kkevinchou/izzet/izzet/network"
+	"github.com/kkevinchou/izzet/izzet/settings"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	testCases := []struct {
+		args []string
+		want CommandLine
+	}{
+		{nil, CommandLine{Mode: ModeClient, LogsEnabled: true, ProjectName: settings.StartupProject}},
+		{[]string{"headless", "--project=arena"}, CommandLine{Mode: ModeHeadless, LogsEnabled: true, ProjectName: "arena"}},
+		{[]string{"--logs=false", "Server"}, CommandLine{Mode: ModeServer, LogsEnabled: false, ProjectName: settings.StartupProject}},
+		{[]string{"bakenavmesh", "--project=", "--logs=1"}, CommandLine{Mode: ModeBake, LogsEnabled: true, ProjectName: ""}},
+	}
+	for _, tc := range testCases {
+		got, err := ParseCommandLine(tc.args)
+		if err != nil {
+			t.Fatalf("ParseCommandLine(%v) failed: %s", tc.args, err)
+		}
+		if got != tc.want {
+			t.Fatalf("ParseCommandLine(%v) got %+v, want %+v", tc.args, got, tc.want)
+		}
+	}
+
+	for _, args := range [][]string{{"--logs=maybe"}, {"--port=8080"}, {"editor"}} {
+		if _, err := ParseCommandLine(args); err == nil {
+			t.Fatalf("ParseCommandLine(%v) should fail", args)
+		}
+	}
+}
+
+func TestResolveHeadlessOptions(t *testing.T) {
+	t.Chdir(t.TempDir())
+	worldFilePath := filepath.Join(settings.ProjectsDirectory, "arena", "world.json")
+	if err := os.MkdirAll(filepath.Dir(worldFilePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(worldFilePath, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := settings.NewConfig()
+	config.NetworkTransport = "udp"
+	config.NetworkSimulation.Enabled = true
+
+	options, err := ResolveHeadlessOptions(config, "arena")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.ProjectName != "arena" || options.WorldFilePath != worldFilePath {
+		t.Fatalf("resolved %+v, want the arena project's world", options)
+	}
+	if options.Transport != network.TransportTypeUDP || !options.NetworkSimulation.Enabled {
+		t.Fatalf("resolved %+v, want the transport and network simulation from the config", options)
+	}
+
+	if _, err := ResolveHeadlessOptions(config, "missing"); err == nil {
+		t.Fatal("expected a project without a world file to fail")
+	}
+	if _, err := ResolveHeadlessOptions(config, ""); err == nil {
+		t.Fatal("expected an empty project name to fail")
+	}
+	config.NetworkTransport = "carrier pigeon"
+	if _, err := ResolveHeadlessOptions(config, "arena"); err == nil {
+		t.Fatal("expected an unknown transport to fail")
+	}
+}
+
+type fakeServer struct {
+	done chan bool
+}
+
+func (s *fakeServer) Start(started chan bool, done chan bool) {
+	started <- true
+	<-done
+	close(s.done)
+}
+
+func TestRunHeadless(t *testing.T) {
+	server := &fakeServer{done: make(chan bool)}
+	signals := make(chan os.Signal, 1)
+	signals <- syscall.SIGTERM
+
+	RunHeadless(server, signals)
+	select {
+	case <-server.done:
+	default:
+		t.Fatal("expected the server to be stopped before RunHeadless returns")
+	}
+}
@@ -0,0 +1,473 @@
+package navmeshbuilder
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/navmesh"
+	"github.com/kkevinchou/izzet/izzet/entity"
+)
+
+const (
+	navMeshFileMagic = "IZNM"
+	// NavMeshFileVersion is bumped whenever the file layout changes, files written
+	// with other versions are rejected and need to be baked again
+	NavMeshFileVersion uint32 = 5
+
+	// maxNavMeshFileCount bounds the length of any list in a nav mesh file so a
+	// corrupt file can't trigger huge allocations
+	maxNavMeshFileCount = 1 << 20
+)
+
+var (
+	ErrNotNavMeshFile          = errors.New("not a nav mesh file")
+	ErrUnsupportedNavMeshFile  = errors.New("unsupported nav mesh file version")
+	ErrMalformedNavMeshFile    = errors.New("malformed nav mesh file")
+	errNavMeshFileCountTooLong = fmt.Errorf("%w: list length exceeds %d", ErrMalformedNavMeshFile, maxNavMeshFileCount)
+)
+
+// BakedNavMesh is a compiled nav mesh along with what it was built from
+type BakedNavMesh struct {
+	Config Config
+	// WorldHash is the WorldHash of the world the nav mesh was built from
+	WorldHash string
+	NavMesh   *navmesh.CompiledNavMesh
+	// Obstacles are carved out of the nav mesh at runtime, they aren't saved
+	Obstacles []navmesh.Obstacle
+}
+
+// Stale reports whether the world's geometry has changed since the nav mesh
+// was built
+func (b BakedNavMesh) Stale(world World) bool {
+	return b.WorldHash != WorldHash(world)
+}
+
+// WorldHash fingerprints the geometry that nav meshes are built from, the mesh and
+// world transform of every entity that Build would voxelize along with the
+// off-mesh connections
+func WorldHash(world World) string {
+	var entities []*entity.Entity
+	for _, e := range world.Entities() {
+		if !isNavMeshGeometry(e) && e.OffMeshConnectionComponent == nil {
+			continue
+		}
+		entities = append(entities, e)
+	}
+	slices.SortFunc(entities, func(a, b *entity.Entity) int {
+		return a.GetID() - b.GetID()
+	})
+
+	h := sha256.New()
+	for _, e := range entities {
+		if e.OffMeshConnectionComponent != nil {
+			connection := e.OffMeshConnection()
+			binary.Write(h, binary.LittleEndian, connection.Start)
+			binary.Write(h, binary.LittleEndian, connection.End)
+			binary.Write(h, binary.LittleEndian, connection.Radius)
+			binary.Write(h, binary.LittleEndian, connection.Bidirectional)
+			binary.Write(h, binary.LittleEndian, int64(connection.Area))
+			binary.Write(h, binary.LittleEndian, connection.Cost)
+		}
+		if !isNavMeshGeometry(e) {
+			continue
+		}
+
+		handle, err := json.Marshal(e.MeshComponent.MeshHandle)
+		if err != nil {
+			panic(err)
+		}
+		h.Write(handle)
+		transform := entity.WorldTransform(e)
+		binary.Write(h, binary.LittleEndian, transform)
+		if e.NavMeshAreaComponent != nil {
+			binary.Write(h, binary.LittleEndian, int64(e.NavMeshAreaComponent.Area))
+			binary.Write(h, binary.LittleEndian, e.NavMeshAreaComponent.Volume)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Save writes a baked nav mesh to disk
+func Save(filepath string, baked BakedNavMesh) error {
+	f, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := &navMeshWriter{w: bufio.NewWriter(f)}
+	w.write([]byte(navMeshFileMagic))
+	w.writeUint32(NavMeshFileVersion)
+	w.writeString(baked.WorldHash)
+	w.writeConfig(baked.Config)
+
+	w.writeVec3(baked.NavMesh.Origin)
+	w.writeFloat64(baked.NavMesh.TileWidth)
+	w.writeFloat64(baked.NavMesh.WalkableClimb)
+	w.writeCount(len(baked.NavMesh.Tiles))
+	for _, tile := range baked.NavMesh.Tiles {
+		w.writeTile(tile)
+	}
+	w.writeCount(len(baked.NavMesh.OffMeshConnections))
+	for _, connection := range baked.NavMesh.OffMeshConnections {
+		w.writeOffMeshConnection(connection)
+	}
+
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// Load reads a nav mesh written by Save
+func Load(filepath string) (BakedNavMesh, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return BakedNavMesh{}, err
+	}
+	defer f.Close()
+
+	r := &navMeshReader{r: bufio.NewReader(f)}
+	magic := make([]byte, len(navMeshFileMagic))
+	r.read(magic)
+	if r.err != nil || string(magic) != navMeshFileMagic {
+		return BakedNavMesh{}, ErrNotNavMeshFile
+	}
+	if version := r.readUint32(); version != NavMeshFileVersion {
+		return BakedNavMesh{}, fmt.Errorf("%w: got %d, want %d", ErrUnsupportedNavMeshFile, version, NavMeshFileVersion)
+	}
+
+	var baked BakedNavMesh
+	baked.WorldHash = r.readString()
+	baked.Config = r.readConfig()
+
+	baked.NavMesh = &navmesh.CompiledNavMesh{}
+	baked.NavMesh.Origin = r.readVec3()
+	baked.NavMesh.TileWidth = r.readFloat64()
+	baked.NavMesh.WalkableClimb = r.readFloat64()
+	for range r.readCount() {
+		baked.NavMesh.Tiles = append(baked.NavMesh.Tiles, r.readTile())
+		if r.err != nil {
+			break
+		}
+	}
+	for range r.readCount() {
+		baked.NavMesh.OffMeshConnections = append(baked.NavMesh.OffMeshConnections, r.readOffMeshConnection())
+		if r.err != nil {
+			break
+		}
+	}
+
+	if r.err != nil {
+		if errors.Is(r.err, io.EOF) || errors.Is(r.err, io.ErrUnexpectedEOF) {
+			return BakedNavMesh{}, fmt.Errorf("%w: %w", ErrMalformedNavMeshFile, r.err)
+		}
+		return BakedNavMesh{}, r.err
+	}
+
+	navmesh.LinkTiles(baked.NavMesh)
+	return baked, nil
+}
+
+// navMeshWriter writes little endian values and holds on to the first error
+type navMeshWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (w *navMeshWriter) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.Write(b)
+}
+
+func (w *navMeshWriter) writeUint32(v uint32) {
+	w.write(binary.LittleEndian.AppendUint32(nil, v))
+}
+
+func (w *navMeshWriter) writeInt(v int) {
+	w.writeUint32(uint32(int32(v)))
+}
+
+func (w *navMeshWriter) writeCount(n int) {
+	w.writeUint32(uint32(n))
+}
+
+func (w *navMeshWriter) writeFloat32(v float32) {
+	w.writeUint32(math.Float32bits(v))
+}
+
+func (w *navMeshWriter) writeFloat64(v float64) {
+	w.write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)))
+}
+
+func (w *navMeshWriter) writeBool(v bool) {
+	if v {
+		w.write([]byte{1})
+	} else {
+		w.write([]byte{0})
+	}
+}
+
+func (w *navMeshWriter) writeString(s string) {
+	w.writeCount(len(s))
+	w.write([]byte(s))
+}
+
+func (w *navMeshWriter) writeVec3(v mgl64.Vec3) {
+	w.writeFloat64(v.X())
+	w.writeFloat64(v.Y())
+	w.writeFloat64(v.Z())
+}
+
+func (w *navMeshWriter) writeInts(values []int) {
+	w.writeCount(len(values))
+	for _, v := range values {
+		w.writeInt(v)
+	}
+}
+
+func (w *navMeshWriter) writeVec3s(values []mgl64.Vec3) {
+	w.writeCount(len(values))
+	for _, v := range values {
+		w.writeVec3(v)
+	}
+}
+
+func (w *navMeshWriter) writeConfig(c Config) {
+	w.writeVec3(c.Volume.MinVertex)
+	w.writeVec3(c.Volume.MaxVertex)
+	w.writeFloat32(c.CellSize)
+	w.writeFloat32(c.CellHeight)
+	w.writeFloat32(c.AgentRadius)
+	w.writeFloat32(c.WalkableHeight)
+	w.writeFloat32(c.ClimbableHeight)
+	w.writeInt(c.Iterations)
+	w.writeInt(c.MinRegionArea)
+	w.writeInt(c.MergeRegionArea)
+	w.writeFloat64(c.MaxError)
+	w.writeInt(c.MaxEdgeLength)
+	w.writeFloat64(c.SampleDist)
+	w.writeBool(c.FilterLedgeSpans)
+	w.writeBool(c.FilterLowHeightSpans)
+	w.writeInt(c.TileSize)
+}
+
+// links between tiles aren't written, they're rebuilt once the tiles are loaded
+func (w *navMeshWriter) writeTile(tile navmesh.CTile) {
+	w.writeInt(tile.X)
+	w.writeInt(tile.Z)
+	w.writeVec3s(tile.Vertices)
+
+	w.writeCount(len(tile.Polygons))
+	for _, p := range tile.Polygons {
+		w.writeInts(p.Vertices)
+		w.writeInts(p.PolyNeighbors)
+		w.writeInt(int(p.Area))
+	}
+
+	w.writeCount(len(tile.DetailedVertices))
+	for _, verts := range tile.DetailedVertices {
+		w.writeVec3s(verts)
+	}
+
+	w.writeCount(len(tile.DetailedPolygon))
+	for _, p := range tile.DetailedPolygon {
+		w.writeCount(len(p.Triangles))
+		for _, tri := range p.Triangles {
+			var onHull byte
+			for i := range 3 {
+				w.writeInt(tri.Vertices[i])
+				if tri.OnHull[i] {
+					onHull |= 1 << i
+				}
+			}
+			w.write([]byte{onHull})
+		}
+	}
+}
+
+// the polygons that off-mesh connections are attached to aren't written, they're
+// attached again when the tiles are linked
+func (w *navMeshWriter) writeOffMeshConnection(connection navmesh.OffMeshConnection) {
+	w.writeVec3(connection.Start)
+	w.writeVec3(connection.End)
+	w.writeFloat64(connection.Radius)
+	w.writeBool(connection.Bidirectional)
+	w.writeInt(int(connection.Area))
+	w.writeFloat64(connection.Cost)
+}
+
+// navMeshReader reads values written by navMeshWriter and holds on to the first
+// error, reads after an error return zero values
+type navMeshReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (r *navMeshReader) read(b []byte) {
+	if r.err != nil {
+		clear(b)
+		return
+	}
+	_, r.err = io.ReadFull(r.r, b)
+}
+
+func (r *navMeshReader) readUint32() uint32 {
+	var b [4]byte
+	r.read(b[:])
+	return binary.LittleEndian.Uint32(b[:])
+}
+
+func (r *navMeshReader) readInt() int {
+	return int(int32(r.readUint32()))
+}
+
+func (r *navMeshReader) readCount() int {
+	n := r.readUint32()
+	if n > maxNavMeshFileCount {
+		if r.err == nil {
+			r.err = errNavMeshFileCountTooLong
+		}
+		return 0
+	}
+	return int(n)
+}
+
+func (r *navMeshReader) readFloat32() float32 {
+	return math.Float32frombits(r.readUint32())
+}
+
+func (r *navMeshReader) readFloat64() float64 {
+	var b [8]byte
+	r.read(b[:])
+	return math.Float64frombits(binary.LittleEndian.Uint64(b[:]))
+}
+
+func (r *navMeshReader) readBool() bool {
+	var b [1]byte
+	r.read(b[:])
+	return b[0] != 0
+}
+
+func (r *navMeshReader) readString() string {
+	b := make([]byte, r.readCount())
+	r.read(b)
+	return string(b)
+}
+
+func (r *navMeshReader) readVec3() mgl64.Vec3 {
+	return mgl64.Vec3{r.readFloat64(), r.readFloat64(), r.readFloat64()}
+}
+
+// readInts and readVec3s return nil for empty lists to match how CompileNavMesh
+// builds them
+func (r *navMeshReader) readInts() []int {
+	n := r.readCount()
+	if n == 0 {
+		return nil
+	}
+	values := make([]int, n)
+	for i := range values {
+		values[i] = r.readInt()
+	}
+	return values
+}
+
+func (r *navMeshReader) readVec3s() []mgl64.Vec3 {
+	n := r.readCount()
+	if n == 0 {
+		return nil
+	}
+	values := make([]mgl64.Vec3, n)
+	for i := range values {
+		values[i] = r.readVec3()
+	}
+	return values
+}
+
+func (r *navMeshReader) readConfig() Config {
+	var c Config
+	c.Volume.MinVertex = r.readVec3()
+	c.Volume.MaxVertex = r.readVec3()
+	c.CellSize = r.readFloat32()
+	c.CellHeight = r.readFloat32()
+	c.AgentRadius = r.readFloat32()
+	c.WalkableHeight = r.readFloat32()
+	c.ClimbableHeight = r.readFloat32()
+	c.Iterations = r.readInt()
+	c.MinRegionArea = r.readInt()
+	c.MergeRegionArea = r.readInt()
+	c.MaxError = r.readFloat64()
+	c.MaxEdgeLength = r.readInt()
+	c.SampleDist = r.readFloat64()
+	c.FilterLedgeSpans = r.readBool()
+	c.FilterLowHeightSpans = r.readBool()
+	c.TileSize = r.readInt()
+	return c
+}
+
+func (r *navMeshReader) readTile() navmesh.CTile {
+	var tile navmesh.CTile
+	tile.X = r.readInt()
+	tile.Z = r.readInt()
+	tile.Vertices = r.readVec3s()
+
+	if n := r.readCount(); n > 0 {
+		tile.Polygons = make([]navmesh.CPolygon, n)
+		for i := range tile.Polygons {
+			tile.Polygons[i].Vertices = r.readInts()
+			tile.Polygons[i].PolyNeighbors = r.readInts()
+			tile.Polygons[i].Area = navmesh.AREA_TYPE(r.readInt())
+		}
+	}
+
+	tile.DetailedVertices = make([][]mgl64.Vec3, r.readCount())
+	for i := range tile.DetailedVertices {
+		tile.DetailedVertices[i] = r.readVec3s()
+	}
+
+	tile.DetailedPolygon = make([]navmesh.CDetailedPolygon, r.readCount())
+	for i := range tile.DetailedPolygon {
+		n := r.readCount()
+		if n == 0 {
+			continue
+		}
+		triangles := make([]navmesh.CDetailedTriangle, n)
+		for j := range triangles {
+			for k := range 3 {
+				triangles[j].Vertices[k] = r.readInt()
+			}
+			var onHull [1]byte
+			r.read(onHull[:])
+			for k := range 3 {
+				triangles[j].OnHull[k] = onHull[0]&(1<<k) != 0
+			}
+		}
+		tile.DetailedPolygon[i].Triangles = triangles
+	}
+
+	return tile
+}
+
+func (r *navMeshReader) readOffMeshConnection() navmesh.OffMeshConnection {
+	var connection navmesh.OffMeshConnection
+	connection.Start = r.readVec3()
+	connection.End = r.readVec3()
+	connection.Radius = r.readFloat64()
+	connection.Bidirectional = r.readBool()
+	connection.Area = navmesh.AREA_TYPE(r.readInt())
+	connection.Cost = r.readFloat64()
+	return connection
+}
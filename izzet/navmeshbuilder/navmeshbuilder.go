@@ -0,0 +1,371 @@
+package navmeshbuilder
+
+import (
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/collision/collider"
+	"github.com/kkevinchou/izzet/internal/navmesh"
+	"github.com/kkevinchou/izzet/internal/utils"
+	"github.com/kkevinchou/izzet/izzet/assets"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/runtimeconfig"
+)
+
+// Config holds the parameters of a navigation mesh build. distances are in world
+// units and converted to voxels internally
+type Config struct {
+	// Volume bounds the geometry that's voxelized
+	Volume collider.BoundingBox
+
+	CellSize        float32
+	CellHeight      float32
+	AgentRadius     float32
+	WalkableHeight  float32
+	ClimbableHeight float32
+
+	Iterations int
+	// MinRegionArea is the smallest number of cells an island of walkable area
+	// can have before it's removed
+	MinRegionArea int
+	// MergeRegionArea is the number of cells below which regions are merged
+	// into their neighbors
+	MergeRegionArea int
+	MaxError        float64
+	MaxEdgeLength   int
+	SampleDist      float64
+
+	FilterLedgeSpans     bool
+	FilterLowHeightSpans bool
+
+	// TileSize is the width of a tile in cells, the volume is baked as a single
+	// tile when it's zero
+	TileSize int
+
+	// Logger receives warnings from the build, slog.Default() when nil. it isn't
+	// saved with baked nav meshes
+	Logger *slog.Logger
+}
+
+func (c Config) logger() *slog.Logger {
+	if c.Logger == nil {
+		return slog.Default()
+	}
+	return c.Logger
+}
+
+// ConfigFromRuntimeConfig uses the navigation mesh settings exposed in the editor
+func ConfigFromRuntimeConfig(runtimeConfig *runtimeconfig.RuntimeConfig) Config {
+	return Config{
+		Volume: collider.BoundingBox{
+			MinVertex: mgl64.Vec3{-200.0, -5.0, -200.0},
+			MaxVertex: mgl64.Vec3{200.0, 80.0, 200.0},
+		},
+		CellSize:             runtimeConfig.NavigationMeshCellSize,
+		CellHeight:           runtimeConfig.NavigationMeshCellHeight,
+		AgentRadius:          runtimeConfig.NavigationMeshAgentRadius,
+		WalkableHeight:       runtimeConfig.NavigationMeshWalkableHeight,
+		ClimbableHeight:      runtimeConfig.NavigationMeshClimbableHeight,
+		Iterations:           int(runtimeConfig.NavigationMeshIterations),
+		MinRegionArea:        int(runtimeConfig.NavigationMeshMinRegionArea),
+		MergeRegionArea:      int(runtimeConfig.NavigationMeshMergeRegionArea),
+		MaxError:             float64(runtimeConfig.NavigationmeshMaxError),
+		MaxEdgeLength:        int(runtimeConfig.NavigationmeshMaxEdgeLength),
+		SampleDist:           float64(runtimeConfig.NavigationmeshSampleDist),
+		FilterLedgeSpans:     runtimeConfig.NavigationMeshFilterLedgeSpans,
+		FilterLowHeightSpans: runtimeConfig.NavigationMeshFilterLowHeightSpans,
+		TileSize:             int(runtimeConfig.NavigationMeshTileSize),
+	}
+}
+
+func DefaultConfig() Config {
+	return ConfigFromRuntimeConfig(runtimeconfig.DefaultRuntimeConfig())
+}
+
+type World interface {
+	Entities() []*entity.Entity
+}
+
+// Build voxelizes the walkable geometry of the world and builds a navigation mesh
+// of the whole volume from it. it only touches mesh data on the CPU so it can run
+// without a GL context
+func Build(world World, assetManager *assets.AssetManager, config Config) *navmesh.NavigationMesh {
+	return buildVolume(world, assetManager, config, config.Volume, 0, nil)
+}
+
+// buildVolume builds a navigation mesh of the volume with the obstacles carved
+// out of it, leaving out borderSize cells around its sides
+func buildVolume(world World, assetManager *assets.AssetManager, config Config, volume collider.BoundingBox, borderSize int, obstacles []navmesh.Obstacle) *navmesh.NavigationMesh {
+	cs := config.CellSize
+	ch := config.CellHeight
+
+	walkableRadiusVoxels := navmesh.WorldRadiusToVoxels(config.AgentRadius, cs)
+	walkableHeightVoxels := navmesh.WorldHeightToVoxels(config.WalkableHeight, ch)
+	climbableHeightVoxels := navmesh.WorldClimbToVoxels(config.ClimbableHeight, ch)
+
+	nmbb := volume
+	minVertex := nmbb.MinVertex
+	maxVertex := nmbb.MaxVertex
+
+	hfWidth := int((maxVertex.X()-minVertex.X())/float64(cs) + 0.5)
+	hfHeight := int((maxVertex.Z()-minVertex.Z())/float64(cs) + 0.5)
+
+	hf := navmesh.NewHeightField(hfWidth, hfHeight, minVertex, maxVertex, float64(cs), float64(ch))
+	var areaVolumes []areaVolume
+
+	for _, e := range world.Entities() {
+		if !isNavMeshGeometry(e) {
+			continue
+		}
+
+		ebb := e.BoundingBox()
+
+		if ebb.MaxVertex.X() < nmbb.MinVertex.X() || ebb.MinVertex.X() > nmbb.MaxVertex.X() {
+			continue
+		}
+		if ebb.MaxVertex.Y() < nmbb.MinVertex.Y() || ebb.MinVertex.Y() > nmbb.MaxVertex.Y() {
+			continue
+		}
+		if ebb.MaxVertex.Z() < nmbb.MinVertex.Z() || ebb.MinVertex.Z() > nmbb.MaxVertex.Z() {
+			continue
+		}
+
+		area := navmesh.WALKABLE_AREA
+		if e.NavMeshAreaComponent != nil {
+			if e.NavMeshAreaComponent.Volume {
+				areaVolumes = append(areaVolumes, areaVolume{box: ebb, area: e.NavMeshAreaComponent.Area})
+				continue
+			}
+			area = e.NavMeshAreaComponent.Area
+		}
+
+		primitives := assetManager.GetPrimitives(e.MeshComponent.MeshHandle)
+		transform := utils.Mat4F64ToF32(entity.WorldTransform(e))
+		up := mgl64.Vec3{0, 1, 0}
+
+		for _, p := range primitives {
+			for i := 0; i < len(p.Primitive.Vertices); i += 3 {
+				v1 := utils.Vec3F32ToF64(transform.Mul4x1(p.Primitive.Vertices[i].Position.Vec4(1)).Vec3())
+				v2 := utils.Vec3F32ToF64(transform.Mul4x1(p.Primitive.Vertices[i+1].Position.Vec4(1)).Vec3())
+				v3 := utils.Vec3F32ToF64(transform.Mul4x1(p.Primitive.Vertices[i+2].Position.Vec4(1)).Vec3())
+
+				tv1 := v2.Sub(v1)
+				tv2 := v3.Sub(v2)
+
+				normal := tv1.Cross(tv2).Normalize()
+				triangleArea := navmesh.NULL_AREA
+				if normal.Dot(up) >= 0.7 {
+					triangleArea = area
+				}
+
+				navmesh.RasterizeTriangle(v1, v2, v3, float64(cs), float64(ch), hf, triangleArea, climbableHeightVoxels)
+			}
+		}
+	}
+
+	if config.FilterLedgeSpans {
+		navmesh.FilterLedgeSpans(walkableHeightVoxels, climbableHeightVoxels, hf)
+	}
+
+	if config.FilterLowHeightSpans {
+		navmesh.FilterLowHeightSpans(walkableHeightVoxels, hf)
+	}
+
+	chf := navmesh.NewCompactHeightField(walkableHeightVoxels, climbableHeightVoxels, hf)
+
+	for _, obstacle := range obstacles {
+		navmesh.MarkObstacle(chf, obstacle)
+	}
+	navmesh.ErodeWalkableArea(chf, walkableRadiusVoxels)
+	for _, volume := range areaVolumes {
+		navmesh.MarkBoxArea(chf, volume.box, volume.area)
+	}
+	navmesh.ClipBorder(chf, borderSize)
+	navmesh.BuildDistanceField(chf)
+
+	navmesh.BuildRegions(chf, config.Iterations, config.MinRegionArea, config.MergeRegionArea)
+	contourSet := navmesh.BuildContours(chf, config.MaxError, config.MaxEdgeLength)
+	for _, warning := range contourSet.Warnings {
+		config.logger().Warn("nav mesh contour", "min", volume.MinVertex, "max", volume.MaxVertex, "reason", warning)
+	}
+	mesh := navmesh.BuildPolyMesh(contourSet)
+	detailedMesh := navmesh.BuildDetailedPolyMesh(mesh, chf, config.SampleDist, config.MaxError)
+
+	nm := &navmesh.NavigationMesh{
+		HeightField:          hf,
+		CompactHeightField:   chf,
+		Volume:               nmbb,
+		BlurredDistances:     chf.Distances,
+		Invalidated:          true,
+		InvalidatedTimestamp: int(time.Now().Unix()),
+		ContourSet:           contourSet,
+		Mesh:                 mesh,
+		DetailedMesh:         detailedMesh,
+	}
+
+	return nm
+}
+
+// isNavMeshGeometry returns whether the entity is voxelized into the nav mesh.
+// entities that move on their own, like characters and physics objects, are left
+// out and can be carved out as obstacles instead
+func isNavMeshGeometry(e *entity.Entity) bool {
+	if e.MeshComponent == nil || !e.HasBoundingBox() {
+		return false
+	}
+	dynamicBody := e.Physics != nil && !e.Static
+	return e.Kinematic == nil && !dynamicBody
+}
+
+// areaVolume marks the walkable area within a box with an area type
+type areaVolume struct {
+	box  collider.BoundingBox
+	area navmesh.AREA_TYPE
+}
+
+// Bake builds the navigation mesh and compiles it into the form used for path
+// finding at runtime
+func Bake(world World, assetManager *assets.AssetManager, config Config) BakedNavMesh {
+	return bake(world, assetManager, config, nil)
+}
+
+// BakeForEditor bakes the nav mesh that's handed to the server and saved with
+// the project, along with a navigation mesh of the whole volume that's only used
+// for debug drawing
+func BakeForEditor(world World, assetManager *assets.AssetManager, config Config) (BakedNavMesh, *navmesh.NavigationMesh) {
+	return Bake(world, assetManager, config), Build(world, assetManager, config)
+}
+
+func bake(world World, assetManager *assets.AssetManager, config Config, obstacles []navmesh.Obstacle) BakedNavMesh {
+	if config.TileSize <= 0 {
+		nm := navmesh.CompileNavMesh(buildVolume(world, assetManager, config, config.Volume, 0, obstacles))
+		nm.WalkableClimb = float64(config.ClimbableHeight)
+		nm.OffMeshConnections = offMeshConnections(world)
+		navmesh.LinkTiles(nm)
+		return BakedNavMesh{Config: config, WorldHash: WorldHash(world), NavMesh: nm, Obstacles: obstacles}
+	}
+
+	nm := &navmesh.CompiledNavMesh{
+		Origin:             config.Volume.MinVertex,
+		TileWidth:          tileWidth(config),
+		WalkableClimb:      float64(config.ClimbableHeight),
+		OffMeshConnections: offMeshConnections(world),
+	}
+
+	tilesX, tilesZ := tileGrid(config)
+	var tiles []navmesh.CTile
+	for z := range tilesZ {
+		for x := range tilesX {
+			tiles = append(tiles, buildTile(world, assetManager, config, x, z, obstacles))
+		}
+	}
+	nm.SetTiles(tiles)
+
+	return BakedNavMesh{Config: config, WorldHash: WorldHash(world), NavMesh: nm, Obstacles: obstacles}
+}
+
+// RebuildTiles bakes the tiles touched by any of the bounds again and reuses the
+// rest of the nav mesh. the bounds should cover where geometry used to be as well
+// as where it is now. nav meshes that aren't tiled are baked again in full. the
+// nav mesh's obstacles are carved out of the rebuilt tiles
+func RebuildTiles(baked BakedNavMesh, world World, assetManager *assets.AssetManager, bounds []collider.BoundingBox) BakedNavMesh {
+	config := baked.Config
+	if config.TileSize <= 0 || baked.NavMesh == nil || baked.NavMesh.TileWidth == 0 {
+		return bake(world, assetManager, config, baked.Obstacles)
+	}
+
+	// tiles are built with a border of padding, so geometry affects the tiles
+	// whose padding it's in as well
+	width := tileWidth(config)
+	padding := float64(borderSize(config)) * float64(config.CellSize)
+	origin := config.Volume.MinVertex
+	tilesX, tilesZ := tileGrid(config)
+
+	rebuild := map[[2]int]bool{}
+	for _, bb := range bounds {
+		if bb.MaxVertex.Y() < config.Volume.MinVertex.Y() || bb.MinVertex.Y() > config.Volume.MaxVertex.Y() {
+			continue
+		}
+
+		minX := int(math.Floor((bb.MinVertex.X() - padding - origin.X()) / width))
+		maxX := int(math.Floor((bb.MaxVertex.X() + padding - origin.X()) / width))
+		minZ := int(math.Floor((bb.MinVertex.Z() - padding - origin.Z()) / width))
+		maxZ := int(math.Floor((bb.MaxVertex.Z() + padding - origin.Z()) / width))
+
+		for z := max(minZ, 0); z <= min(maxZ, tilesZ-1); z++ {
+			for x := max(minX, 0); x <= min(maxX, tilesX-1); x++ {
+				rebuild[[2]int{x, z}] = true
+			}
+		}
+	}
+
+	var tiles []navmesh.CTile
+	for position := range rebuild {
+		tiles = append(tiles, buildTile(world, assetManager, config, position[0], position[1], baked.Obstacles))
+	}
+
+	nm := baked.NavMesh.Clone()
+	nm.OffMeshConnections = offMeshConnections(world)
+	nm.SetTiles(tiles)
+
+	return BakedNavMesh{Config: config, WorldHash: WorldHash(world), NavMesh: nm, Obstacles: baked.Obstacles}
+}
+
+// offMeshConnections collects the off-mesh connections authored on the world's
+// entities, ordered by entity id so bakes of the same world match
+func offMeshConnections(world World) []navmesh.OffMeshConnection {
+	var entities []*entity.Entity
+	for _, e := range world.Entities() {
+		if e.OffMeshConnectionComponent != nil {
+			entities = append(entities, e)
+		}
+	}
+	slices.SortFunc(entities, func(a, b *entity.Entity) int {
+		return a.GetID() - b.GetID()
+	})
+
+	var connections []navmesh.OffMeshConnection
+	for _, e := range entities {
+		connections = append(connections, e.OffMeshConnection())
+	}
+	return connections
+}
+
+// buildTile builds the tile at the grid position, the tile has no polygons if
+// there's nothing walkable within it
+func buildTile(world World, assetManager *assets.AssetManager, config Config, x, z int, obstacles []navmesh.Obstacle) navmesh.CTile {
+	border := borderSize(config)
+	width := tileWidth(config)
+	padding := float64(border) * float64(config.CellSize)
+	origin := config.Volume.MinVertex
+
+	volume := collider.BoundingBox{
+		MinVertex: mgl64.Vec3{origin.X() + float64(x)*width - padding, origin.Y(), origin.Z() + float64(z)*width - padding},
+		MaxVertex: mgl64.Vec3{origin.X() + float64(x+1)*width + padding, config.Volume.MaxVertex.Y(), origin.Z() + float64(z+1)*width + padding},
+	}
+
+	tile := navmesh.CompileTile(buildVolume(world, assetManager, config, volume, border, obstacles))
+	tile.X = x
+	tile.Z = z
+	return tile
+}
+
+func tileWidth(config Config) float64 {
+	return float64(config.TileSize) * float64(config.CellSize)
+}
+
+// tileGrid returns the number of tiles along x and z needed to cover the volume
+func tileGrid(config Config) (int, int) {
+	width := tileWidth(config)
+	size := config.Volume.MaxVertex.Sub(config.Volume.MinVertex)
+	return int(math.Ceil(size.X() / width)), int(math.Ceil(size.Z() / width))
+}
+
+// borderSize is the padding in cells around each tile, it needs to be larger than
+// the agent radius so walkable area isn't eroded along tile borders
+func borderSize(config Config) int {
+	return navmesh.WorldRadiusToVoxels(config.AgentRadius, config.CellSize) + 3
+}
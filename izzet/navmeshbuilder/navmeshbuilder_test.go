@@ -0,0 +1,533 @@
+package navmeshbuilder
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/collision/collider"
+	"github.com/kkevinchou/izzet/internal/navmesh"
+	"github.com/kkevinchou/izzet/izzet/assets"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/world"
+)
+
+// floorWorld is a world with a single 20x20 floor whose top is at y = 0
+func floorWorld() (*world.GameWorld, *assets.AssetManager) {
+	assetManager := assets.NewAssetManager(false, slog.New(slog.DiscardHandler))
+	w := world.New()
+	floor := entity.CreateCube(assetManager, 1)
+	entity.SetLocalPosition(floor, mgl64.Vec3{0, -1, 0})
+	entity.SetScale(floor, mgl64.Vec3{20, 2, 20})
+	w.AddEntity(floor)
+	return w, assetManager
+}
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.Volume = collider.BoundingBox{MinVertex: mgl64.Vec3{-15, -5, -15}, MaxVertex: mgl64.Vec3{15, 10, 15}}
+	config.CellSize = 0.25
+	config.CellHeight = 0.25
+	return config
+}
+
+func TestBakeFloor(t *testing.T) {
+	w, assetManager := floorWorld()
+	nm := Bake(w, assetManager, testConfig()).NavMesh
+
+	if len(nm.Tiles) != 1 || len(nm.Tiles[0].Polygons) == 0 {
+		t.Fatalf("expected a single tile with polygons, got %+v", nm.Tiles)
+	}
+	for _, v := range nm.Tiles[0].Vertices {
+		if v.Y() < -0.5 || v.Y() > 0.5 {
+			t.Fatalf("expected vertices on top of the floor, got %v", v)
+		}
+	}
+
+	path := navmesh.FindPath(nm, mgl64.Vec3{-7, 0, -7}, mgl64.Vec3{7, 0, 7}, nil)
+	if len(path) == 0 {
+		t.Fatal("expected a path across the floor")
+	}
+}
+
+func TestBakePillar(t *testing.T) {
+	for name, config := range map[string]Config{"single tile": testConfig(), "tiled": tiledTestConfig()} {
+		// the hollow inside of the pillar is voxelized as a small walkable island
+		// underneath its top, it's filtered out along with the top itself
+		config.MinRegionArea = 16
+
+		t.Run(name, func(t *testing.T) {
+			w, assetManager := floorWorld()
+			pillar := entity.CreateCube(assetManager, 1)
+			entity.SetLocalPosition(pillar, mgl64.Vec3{0, 2, 0})
+			entity.SetScale(pillar, mgl64.Vec3{2, 4, 2})
+			w.AddEntity(pillar)
+
+			nm := Bake(w, assetManager, config).NavMesh
+
+			// the walkable area around the pillar forms a ring, none of the
+			// polygons should cover the pillar itself
+			point, ref, over := navmesh.FindNearestPolygon(nm, mgl64.Vec3{0, 0, 0}, nil)
+			if ref == navmesh.InvalidPolyRef {
+				t.Fatal("expected a polygon near the pillar")
+			}
+			if over || (math.Abs(point.X()) < 1 && math.Abs(point.Z()) < 1) {
+				t.Fatalf("expected the nearest point to be outside of the pillar, got %v", point)
+			}
+
+			start := mgl64.Vec3{-5, 0, 0}
+			goal := mgl64.Vec3{5, 0, 0}
+			straightPath := navmesh.FindStraightPath(nm, start, goal, navmesh.FindPath(nm, start, goal, nil))
+			if len(straightPath) < 3 {
+				t.Fatalf("expected the path to go around the pillar, got %v", straightPath)
+			}
+		})
+	}
+}
+
+func TestBakeAreas(t *testing.T) {
+	w, assetManager := floorWorld()
+
+	// a road laid on top of the floor
+	road := entity.CreateCube(assetManager, 1)
+	entity.SetLocalPosition(road, mgl64.Vec3{-5, 0, 0})
+	entity.SetScale(road, mgl64.Vec3{4, 0.1, 20})
+	road.NavMeshAreaComponent = &entity.NavMeshAreaComponent{Area: navmesh.ROAD_AREA}
+	w.AddEntity(road)
+
+	// a volume marking the floor underneath it as water
+	water := entity.CreateCube(assetManager, 1)
+	entity.SetLocalPosition(water, mgl64.Vec3{5, 0, 0})
+	entity.SetScale(water, mgl64.Vec3{4, 2, 20})
+	water.NavMeshAreaComponent = &entity.NavMeshAreaComponent{Area: navmesh.WATER_AREA, Volume: true}
+	w.AddEntity(water)
+
+	nm := Bake(w, assetManager, testConfig()).NavMesh
+
+	testCases := []struct {
+		point mgl64.Vec3
+		want  navmesh.AREA_TYPE
+	}{
+		{point: mgl64.Vec3{-5, 0, 0}, want: navmesh.ROAD_AREA},
+		{point: mgl64.Vec3{5, 0, 0}, want: navmesh.WATER_AREA},
+		{point: mgl64.Vec3{0, 0, 0}, want: navmesh.WALKABLE_AREA},
+	}
+	for _, tc := range testCases {
+		_, ref, over := navmesh.FindNearestPolygon(nm, tc.point, nil)
+		if ref == navmesh.InvalidPolyRef || !over {
+			t.Fatalf("expected a polygon under %v", tc.point)
+		}
+		if area := nm.Tiles[ref.Tile].Polygons[ref.Poly].Area; area != tc.want {
+			t.Fatalf("expected area %v at %v, got %v", tc.want, tc.point, area)
+		}
+	}
+
+	// the volume itself isn't walkable, so nothing is built on top of it
+	for _, v := range nm.Tiles[0].Vertices {
+		if v.Y() > 0.5 {
+			t.Fatalf("expected no polygons on top of the water volume, got vertex %v", v)
+		}
+	}
+
+	filter := navmesh.NewQueryFilter()
+	filter.Exclude(navmesh.WATER_AREA)
+	for _, ref := range navmesh.FindPath(nm, mgl64.Vec3{0, 0, 0}, mgl64.Vec3{8, 0, 0}, filter) {
+		if nm.Tiles[ref.Tile].Polygons[ref.Poly].Area == navmesh.WATER_AREA {
+			t.Fatal("expected the path to stay out of the water")
+		}
+	}
+}
+
+// gapWorld is two floors along x separated by a two unit gap, joined by an
+// off-mesh connection jumping across it
+func gapWorld() (*world.GameWorld, *assets.AssetManager, *entity.Entity) {
+	assetManager := assets.NewAssetManager(false, slog.New(slog.DiscardHandler))
+	w := world.New()
+	for _, x := range []float64{-6, 6} {
+		floor := entity.CreateCube(assetManager, 1)
+		entity.SetLocalPosition(floor, mgl64.Vec3{x, -1, 0})
+		entity.SetScale(floor, mgl64.Vec3{10, 2, 20})
+		w.AddEntity(floor)
+	}
+
+	jump := entity.CreateEmptyEntity("jump")
+	entity.SetLocalPosition(jump, mgl64.Vec3{-1.5, 0, 0})
+	jump.OffMeshConnectionComponent = &entity.OffMeshConnectionComponent{End: mgl64.Vec3{3, 0, 0}, Radius: 1, Area: navmesh.WALKABLE_AREA}
+	w.AddEntity(jump)
+	return w, assetManager, jump
+}
+
+func TestBakeOffMeshConnections(t *testing.T) {
+	for name, config := range map[string]Config{"single tile": testConfig(), "tiled": tiledTestConfig()} {
+		t.Run(name, func(t *testing.T) {
+			w, assetManager, _ := gapWorld()
+			baked := Bake(w, assetManager, config)
+			nm := baked.NavMesh
+
+			if len(nm.OffMeshConnections) != 1 {
+				t.Fatalf("expected the connection to be baked, got %+v", nm.OffMeshConnections)
+			}
+			connection := nm.OffMeshConnections[0]
+			if connection.StartRef == navmesh.InvalidPolyRef || connection.EndRef == navmesh.InvalidPolyRef {
+				t.Fatalf("expected both ends of the connection to be attached, got %+v", connection)
+			}
+
+			start := mgl64.Vec3{-7, 0, 5}
+			goal := mgl64.Vec3{7, 0, 5}
+			path, connections := navmesh.FindStraightPathWithConnections(nm, start, goal, navmesh.FindPath(nm, start, goal, nil))
+			if len(path) != 4 || path[3].Sub(goal).Len() > 0.5 {
+				t.Fatalf("expected a path jumping across the gap, got %v", path)
+			}
+			if connections[1] != 0 || path[1].Sub(connection.StartPoint).Len() > 1e-6 || path[2].Sub(connection.EndPoint).Len() > 1e-6 {
+				t.Fatalf("expected the path to go through the connection, got %v with connections %v", path, connections)
+			}
+
+			// the connection is one way
+			for _, ref := range navmesh.FindPath(nm, goal, start, nil) {
+				if ref.IsOffMeshConnection() {
+					t.Fatal("expected no path back across the gap")
+				}
+			}
+
+			file := filepath.Join(t.TempDir(), "navmesh.bin")
+			if err := Save(file, baked); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := Load(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(loaded, baked) {
+				t.Fatal("loaded nav mesh differs from the saved one")
+			}
+		})
+	}
+}
+
+func TestBakeForEditorOffMeshConnections(t *testing.T) {
+	for name, config := range map[string]Config{"single tile": testConfig(), "tiled": tiledTestConfig()} {
+		t.Run(name, func(t *testing.T) {
+			w, assetManager, jump := gapWorld()
+			baked, _ := BakeForEditor(w, assetManager, config)
+			nm := baked.NavMesh
+
+			if len(nm.OffMeshConnections) != 1 {
+				t.Fatalf("expected the connection to be baked, got %+v", nm.OffMeshConnections)
+			}
+			connection := nm.OffMeshConnections[0]
+			if want := jump.OffMeshConnection(); connection.Start != want.Start || connection.End != want.End {
+				t.Fatalf("expected the connection authored on the entity, got %+v", connection)
+			}
+			if connection.StartRef == navmesh.InvalidPolyRef || connection.EndRef == navmesh.InvalidPolyRef {
+				t.Fatalf("expected both ends of the connection to be attached, got %+v", connection)
+			}
+
+			start := mgl64.Vec3{-7, 0, 5}
+			goal := mgl64.Vec3{7, 0, 5}
+			for _, ref := range navmesh.FindPath(nm, start, goal, nil) {
+				if ref.IsOffMeshConnection() {
+					return
+				}
+			}
+			t.Fatal("expected the path to jump across the gap")
+		})
+	}
+}
+
+func TestStaleOffMeshConnection(t *testing.T) {
+	w, assetManager, jump := gapWorld()
+	baked := Bake(w, assetManager, testConfig())
+
+	jump.OffMeshConnectionComponent.Bidirectional = true
+	if !baked.Stale(w) {
+		t.Fatal("expected changing the connection to make the nav mesh stale")
+	}
+}
+
+func tiledTestConfig() Config {
+	config := testConfig()
+	config.TileSize = 24
+	return config
+}
+
+func TestBakeTiled(t *testing.T) {
+	w, assetManager := floorWorld()
+	nm := Bake(w, assetManager, tiledTestConfig()).NavMesh
+
+	// the 20x20 floor spans 4x4 of the 6x6 tiles in the volume
+	if len(nm.Tiles) != 16 {
+		t.Fatalf("expected 16 tiles with polygons, got %d", len(nm.Tiles))
+	}
+
+	var links int
+	for _, tile := range nm.Tiles {
+		for _, polygon := range tile.Polygons {
+			links += len(polygon.Links)
+		}
+	}
+	if links == 0 {
+		t.Fatal("expected polygons to be linked across tiles")
+	}
+
+	start := mgl64.Vec3{-7, 0, -7}
+	goal := mgl64.Vec3{7, 0, 7}
+	path := navmesh.FindPath(nm, start, goal, nil)
+	tiles := map[int]bool{}
+	for _, ref := range path {
+		tiles[ref.Tile] = true
+	}
+	if len(tiles) < 3 {
+		t.Fatalf("expected the path to cross several tiles, got %v", path)
+	}
+
+	// the floor is open so the path should be a straight line across tile borders
+	straightPath := navmesh.FindStraightPath(nm, start, goal, path)
+	if len(straightPath) != 2 {
+		t.Fatalf("expected a straight path, got %v", straightPath)
+	}
+	if straightPath[1].Sub(goal).Len() > 0.5 {
+		t.Fatalf("expected the path to end at the goal, got %v", straightPath)
+	}
+}
+
+// unsalted copies the baked nav mesh with the tile salts cleared, rebuilt tiles
+// are bumped to a new salt that a fresh bake doesn't have
+func unsalted(baked BakedNavMesh) BakedNavMesh {
+	nm := *baked.NavMesh
+	nm.Tiles = slices.Clone(nm.Tiles)
+	for i := range nm.Tiles {
+		tile := &nm.Tiles[i]
+		tile.Salt = 0
+		tile.Polygons = slices.Clone(tile.Polygons)
+		for j := range tile.Polygons {
+			polygon := &tile.Polygons[j]
+			polygon.Links = slices.Clone(polygon.Links)
+			for k := range polygon.Links {
+				polygon.Links[k].Ref.Salt = 0
+			}
+		}
+	}
+	baked.NavMesh = &nm
+	return baked
+}
+
+func TestRebuildTiles(t *testing.T) {
+	w, assetManager := floorWorld()
+	config := tiledTestConfig()
+	baked := Bake(w, assetManager, config)
+	original := Bake(w, assetManager, config)
+
+	box := entity.CreateCube(assetManager, 1)
+	entity.SetLocalPosition(box, mgl64.Vec3{2, 1, 2})
+	entity.SetScale(box, mgl64.Vec3{2, 2, 2})
+	w.AddEntity(box)
+
+	if !baked.Stale(w) {
+		t.Fatal("expected adding a box to make the nav mesh stale")
+	}
+
+	rebuilt := RebuildTiles(baked, w, assetManager, []collider.BoundingBox{box.BoundingBox()})
+	if rebuilt.Stale(w) {
+		t.Fatal("expected the rebuilt nav mesh to be up to date")
+	}
+	if !reflect.DeepEqual(unsalted(rebuilt), Bake(w, assetManager, config)) {
+		t.Fatal("expected rebuilding the tiles under the box to match baking the whole nav mesh")
+	}
+
+	// the original nav mesh is left as is
+	if !reflect.DeepEqual(baked, original) {
+		t.Fatal("expected rebuilding to leave the original nav mesh untouched")
+	}
+}
+
+func TestBakeForEditor(t *testing.T) {
+	w, assetManager := floorWorld()
+	config := tiledTestConfig()
+	baked, debugMesh := BakeForEditor(w, assetManager, config)
+	if debugMesh == nil || debugMesh.Mesh == nil {
+		t.Fatal("expected a nav mesh of the whole volume for debug drawing")
+	}
+	if !reflect.DeepEqual(baked, Bake(w, assetManager, config)) {
+		t.Fatal("expected the editor's nav mesh to match a bake")
+	}
+
+	// edits in the editor rebuild tiles instead of baking the whole nav mesh again
+	box := entity.CreateCube(assetManager, 1)
+	entity.SetLocalPosition(box, mgl64.Vec3{2, 1, 2})
+	w.AddEntity(box)
+	rebuilt := RebuildTiles(baked, w, assetManager, []collider.BoundingBox{box.BoundingBox()})
+	if rebuilt.NavMesh.TileWidth != tileWidth(config) {
+		t.Fatalf("expected the rebuilt nav mesh to stay tiled, got a tile width of %f", rebuilt.NavMesh.TileWidth)
+	}
+	rebuiltTile := func(tile navmesh.CTile) bool { return tile.Salt != 0 }
+	if !slices.ContainsFunc(rebuilt.NavMesh.Tiles, rebuiltTile) || !slices.ContainsFunc(rebuilt.NavMesh.Tiles, func(tile navmesh.CTile) bool { return !rebuiltTile(tile) }) {
+		t.Fatal("expected only the tiles under the box to be rebuilt")
+	}
+}
+
+func TestObstacles(t *testing.T) {
+	w, assetManager := floorWorld()
+	config := tiledTestConfig()
+	baked := Bake(w, assetManager, config)
+
+	center := mgl64.Vec3{2, 0, 2}
+	obstacles := NewObstacles()
+	id := obstacles.Add(navmesh.Obstacle{Shape: navmesh.ObstacleShapeBox, Position: center.Add(mgl64.Vec3{0, 1, 0}), HalfExtents: mgl64.Vec3{1, 1, 1}})
+
+	carved, changed := obstacles.Rebuild(baked, w, assetManager)
+	if len(changed) != 1 {
+		t.Fatalf("expected the bounds of the added obstacle, got %v", changed)
+	}
+	if _, _, over := navmesh.FindNearestPolygon(carved.NavMesh, center, nil); over {
+		t.Fatal("expected no polygon under the obstacle")
+	}
+	if !reflect.DeepEqual(unsalted(carved), bake(w, assetManager, config, carved.Obstacles)) {
+		t.Fatal("expected carving the obstacle to match baking the nav mesh with it")
+	}
+	if carved.Stale(w) {
+		t.Fatal("expected obstacles to not make the nav mesh stale")
+	}
+
+	if _, changed := obstacles.Rebuild(carved, w, assetManager); changed != nil {
+		t.Fatalf("expected nothing to be rebuilt without changes, got %v", changed)
+	}
+
+	obstacles.Remove(id)
+	restored, _ := obstacles.Rebuild(carved, w, assetManager)
+	if _, _, over := navmesh.FindNearestPolygon(restored.NavMesh, center, nil); !over {
+		t.Fatal("expected the floor to be walkable once the obstacle is removed")
+	}
+	if !reflect.DeepEqual(unsalted(restored).NavMesh, baked.NavMesh) {
+		t.Fatal("expected removing the obstacle to restore the original nav mesh")
+	}
+}
+
+func TestObstaclesRebuildCap(t *testing.T) {
+	w, assetManager := floorWorld()
+	baked := Bake(w, assetManager, tiledTestConfig())
+
+	obstacles := NewObstacles()
+	for i := range maxChangesPerRebuild + 2 {
+		position := mgl64.Vec3{float64(i%4) * 3, 1, float64(i/4) * 3}
+		obstacles.Add(navmesh.Obstacle{Shape: navmesh.ObstacleShapeBox, Position: position, HalfExtents: mgl64.Vec3{0.5, 1, 0.5}})
+	}
+
+	baked, changed := obstacles.Rebuild(baked, w, assetManager)
+	if len(changed) != maxChangesPerRebuild {
+		t.Fatalf("rebuilt %d changes, want the first %d", len(changed), maxChangesPerRebuild)
+	}
+	baked, changed = obstacles.Rebuild(baked, w, assetManager)
+	if len(changed) != 2 {
+		t.Fatalf("rebuilt %d changes, want the remaining 2", len(changed))
+	}
+	if _, changed := obstacles.Rebuild(baked, w, assetManager); changed != nil {
+		t.Fatalf("expected nothing left to rebuild, got %v", changed)
+	}
+
+	obstacles.Add(navmesh.Obstacle{Shape: navmesh.ObstacleShapeBox, Position: mgl64.Vec3{0, 1, 0}, HalfExtents: mgl64.Vec3{0.5, 1, 0.5}})
+	obstacles.DiscardChanges()
+	if _, changed := obstacles.Rebuild(baked, w, assetManager); changed != nil {
+		t.Fatalf("expected discarded changes to not be rebuilt, got %v", changed)
+	}
+}
+
+func TestBakeSkipsMovingEntities(t *testing.T) {
+	w, assetManager := floorWorld()
+	baked := Bake(w, assetManager, testConfig())
+
+	crate := entity.CreateCube(assetManager, 1)
+	entity.SetLocalPosition(crate, mgl64.Vec3{2, 1, 2})
+	crate.Static = false
+	crate.Physics.Mass = 1
+	w.AddEntity(crate)
+
+	if baked.Stale(w) {
+		t.Fatal("expected physics objects to not be part of the nav mesh")
+	}
+	if !reflect.DeepEqual(Bake(w, assetManager, testConfig()), baked) {
+		t.Fatal("expected physics objects to not be voxelized")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	w, assetManager := floorWorld()
+	baked := Bake(w, assetManager, tiledTestConfig())
+
+	path := filepath.Join(t.TempDir(), "navmesh.bin")
+	if err := Save(path, baked); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, baked) {
+		t.Fatal("loaded nav mesh differs from the saved one")
+	}
+}
+
+func TestStale(t *testing.T) {
+	w, assetManager := floorWorld()
+	baked := Bake(w, assetManager, testConfig())
+	if baked.Stale(w) {
+		t.Fatal("expected a freshly baked nav mesh to not be stale")
+	}
+
+	floor := w.Entities()[0]
+
+	// entities without meshes aren't part of the nav mesh
+	w.AddEntity(entity.CreateEmptyEntity("empty"))
+	if baked.Stale(w) {
+		t.Fatal("expected adding an entity without a mesh to not make the nav mesh stale")
+	}
+
+	entity.SetLocalPosition(floor, mgl64.Vec3{0, -2, 0})
+	if !baked.Stale(w) {
+		t.Fatal("expected moving the floor to make the nav mesh stale")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	w, assetManager := floorWorld()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "navmesh.bin")
+	if err := Save(path, Bake(w, assetManager, testConfig())); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newVersion := append([]byte{}, data...)
+	newVersion[len(navMeshFileMagic)]++
+
+	testCases := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{name: "bad magic", data: []byte("{\"Tiles\": []}"), want: ErrNotNavMeshFile},
+		{name: "empty", data: nil, want: ErrNotNavMeshFile},
+		{name: "version mismatch", data: newVersion, want: ErrUnsupportedNavMeshFile},
+		{name: "truncated", data: data[:len(data)/2], want: ErrMalformedNavMeshFile},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.name+".bin")
+			if err := os.WriteFile(path, tc.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package navmeshbuilder
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/kkevinchou/izzet/internal/collision/collider"
+	"github.com/kkevinchou/izzet/internal/navmesh"
+	"github.com/kkevinchou/izzet/izzet/assets"
+)
+
+// maxChangesPerRebuild caps how many obstacle changes a single Rebuild carves so
+// that a burst of obstacles coming to rest is spread over several frames
+const maxChangesPerRebuild = 8
+
+// Obstacles keeps track of the obstacles carved out of a nav mesh at runtime.
+// changes are batched until Rebuild so several obstacles moving at once only
+// rebuild the tiles they touch a single time
+type Obstacles struct {
+	obstacles map[int]navmesh.Obstacle
+	nextID    int
+	// changed holds the bounds of the obstacles that were added, moved or removed
+	// since the last rebuild, including where moved obstacles used to be
+	changed []collider.BoundingBox
+}
+
+func NewObstacles() *Obstacles {
+	return &Obstacles{obstacles: map[int]navmesh.Obstacle{}, nextID: 1}
+}
+
+// Add adds an obstacle and returns its id, ids are never zero
+func (o *Obstacles) Add(obstacle navmesh.Obstacle) int {
+	id := o.nextID
+	o.nextID++
+	o.obstacles[id] = obstacle
+	o.changed = append(o.changed, obstacle.Bounds())
+	return id
+}
+
+// Set replaces the obstacle with the id, e.g. once it's moved
+func (o *Obstacles) Set(id int, obstacle navmesh.Obstacle) {
+	previous, ok := o.obstacles[id]
+	if !ok {
+		return
+	}
+	o.obstacles[id] = obstacle
+	o.changed = append(o.changed, previous.Bounds(), obstacle.Bounds())
+}
+
+// Remove removes the obstacle with the id
+func (o *Obstacles) Remove(id int) {
+	obstacle, ok := o.obstacles[id]
+	if !ok {
+		return
+	}
+	delete(o.obstacles, id)
+	o.changed = append(o.changed, obstacle.Bounds())
+}
+
+// Get returns the obstacle with the id
+func (o *Obstacles) Get(id int) (navmesh.Obstacle, bool) {
+	obstacle, ok := o.obstacles[id]
+	return obstacle, ok
+}
+
+// DiscardChanges drops the changes waiting to be rebuilt, e.g. when there's no
+// nav mesh to carve the obstacles out of
+func (o *Obstacles) DiscardChanges() {
+	o.changed = nil
+}
+
+// Rebuild carves the obstacles out of the nav mesh by rebuilding the tiles
+// touched by obstacles that changed since the last rebuild. at most
+// maxChangesPerRebuild changes are rebuilt at a time and the rest are left for
+// the next rebuild. it returns the rebuilt nav mesh along with the bounds of the
+// changes, which are empty if nothing changed
+func (o *Obstacles) Rebuild(baked BakedNavMesh, world World, assetManager *assets.AssetManager) (BakedNavMesh, []collider.BoundingBox) {
+	if len(o.changed) == 0 {
+		return baked, nil
+	}
+
+	n := min(len(o.changed), maxChangesPerRebuild)
+	changed := slices.Clone(o.changed[:n])
+	o.changed = slices.Delete(o.changed, 0, n)
+
+	// obstacles are carved in the order they were added so rebuilds of the same
+	// obstacles match
+	var obstacles []navmesh.Obstacle
+	for _, id := range slices.Sorted(maps.Keys(o.obstacles)) {
+		obstacles = append(obstacles, o.obstacles[id])
+	}
+	baked.Obstacles = obstacles
+
+	return RebuildTiles(baked, world, assetManager, changed), changed
+}
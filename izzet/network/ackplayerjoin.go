@@ -11,3 +11,19 @@ type AckPlayerJoinMessage struct {
 func (m AckPlayerJoinMessage) Type() MessageType {
 	return MsgTypeAckPlayerJoin
 }
+
+func (m AckPlayerJoinMessage) encodeBinary(w *wireWriter) {
+	w.writeString(m.ProjectName)
+	w.writeInt(m.PlayerID)
+	w.writeInt(m.PlayerEntityID)
+	w.writeInt(m.CameraEntityID)
+	w.writeBytes(m.SerializedWorld)
+}
+
+func (m *AckPlayerJoinMessage) decodeBinary(r *wireReader) {
+	m.ProjectName = r.readString()
+	m.PlayerID = r.readInt()
+	m.PlayerEntityID = r.readInt()
+	m.CameraEntityID = r.readInt()
+	m.SerializedWorld = r.readBytes()
+}
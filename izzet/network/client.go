@@ -1,15 +1,15 @@
 package network
 
 import (
-	"encoding/json"
 	"net"
 	"time"
 )
 
 type connectionImpl struct {
 	conn    net.Conn
-	encoder *json.Encoder
-	decoder *json.Decoder
+	codec   Codec
+	encoder TransportEncoder
+	decoder TransportDecoder
 }
 
 type IzzetClient interface {
@@ -18,16 +18,19 @@ type IzzetClient interface {
 	Close()
 }
 
-func NewClient(conn net.Conn) IzzetClient {
+// NewClient wraps a connection that has already completed the handshake and
+// negotiated codec
+func NewClient(conn net.Conn, codec Codec) IzzetClient {
 	return &connectionImpl{
 		conn:    conn,
-		encoder: json.NewEncoder(conn),
-		decoder: json.NewDecoder(conn),
+		codec:   codec,
+		encoder: codec.NewEncoder(conn),
+		decoder: codec.NewDecoder(conn),
 	}
 }
 
 func (c *connectionImpl) Send(message Message, frame int) error {
-	bytes, err := json.Marshal(message)
+	bytes, err := c.codec.Marshal(message)
 	if err != nil {
 		return err
 	}
@@ -47,8 +50,7 @@ func (c *connectionImpl) Send(message Message, frame int) error {
 }
 
 func (c *connectionImpl) Recv() (MessageTransport, error) {
-	var message MessageTransport
-	err := c.decoder.Decode(&message)
+	message, err := c.decoder.Decode()
 	if err != nil {
 		return MessageTransport{}, err
 	}
@@ -0,0 +1,266 @@
+package network
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxFrameSize bounds a single binary frame. AckPlayerJoinMessage carries the
+// serialized world so this is fairly generous
+const maxFrameSize int = 64 * 1024 * 1024
+
+type CodecType uint8
+
+const (
+	CodecTypeJSON   CodecType = 1
+	CodecTypeBinary CodecType = 2
+)
+
+func (t CodecType) String() string {
+	switch t {
+	case CodecTypeJSON:
+		return "json"
+	case CodecTypeBinary:
+		return "binary"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(t))
+}
+
+// ParseCodecType maps the codec name used in config.json to a CodecType
+func ParseCodecType(name string) (CodecType, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return CodecTypeJSON, nil
+	case "binary", "":
+		return CodecTypeBinary, nil
+	}
+	return 0, fmt.Errorf("unknown network codec %q", name)
+}
+
+// Codec controls how messages are laid out on the wire. Marshal/Unmarshal handle
+// message bodies while the encoder/decoder pair handle framing MessageTransports
+// onto a stream
+type Codec interface {
+	Type() CodecType
+	Marshal(message Message) ([]byte, error)
+	Unmarshal(body []byte, message any) error
+	NewEncoder(w io.Writer) TransportEncoder
+	NewDecoder(r io.Reader) TransportDecoder
+}
+
+type TransportEncoder interface {
+	Encode(messageTransport MessageTransport) error
+}
+
+type TransportDecoder interface {
+	Decode() (MessageTransport, error)
+}
+
+func NewCodec(codecType CodecType) (Codec, error) {
+	switch codecType {
+	case CodecTypeJSON:
+		return JSONCodec{}, nil
+	case CodecTypeBinary:
+		return BinaryCodec{}, nil
+	}
+	return nil, fmt.Errorf("unsupported codec %s", codecType)
+}
+
+// JSONCodec is the original wire format, it's much larger and slower than the
+// binary codec but is handy when debugging since the stream is human readable
+type JSONCodec struct{}
+
+func (c JSONCodec) Type() CodecType {
+	return CodecTypeJSON
+}
+
+func (c JSONCodec) Marshal(message Message) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+func (c JSONCodec) Unmarshal(body []byte, message any) error {
+	return json.Unmarshal(body, message)
+}
+
+func (c JSONCodec) NewEncoder(w io.Writer) TransportEncoder {
+	return &jsonTransportEncoder{encoder: json.NewEncoder(w)}
+}
+
+func (c JSONCodec) NewDecoder(r io.Reader) TransportDecoder {
+	return &jsonTransportDecoder{decoder: json.NewDecoder(r)}
+}
+
+type jsonTransportEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *jsonTransportEncoder) Encode(messageTransport MessageTransport) error {
+	return e.encoder.Encode(messageTransport)
+}
+
+type jsonTransportDecoder struct {
+	decoder *json.Decoder
+}
+
+func (d *jsonTransportDecoder) Decode() (MessageTransport, error) {
+	var messageTransport MessageTransport
+	if err := d.decoder.Decode(&messageTransport); err != nil {
+		return MessageTransport{}, err
+	}
+	messageTransport.codec = JSONCodec{}
+	return messageTransport, nil
+}
+
+// binaryMessage is implemented by every message that can be sent with the binary codec
+type binaryMessage interface {
+	encodeBinary(w *wireWriter)
+}
+
+// binaryMessageDecoder is implemented by pointers to messages that can be received
+// with the binary codec
+type binaryMessageDecoder interface {
+	decodeBinary(r *wireReader)
+}
+
+// BinaryCodec writes length prefixed frames with each message body encoded field
+// by field. frames are laid out as:
+//
+//	uint32 frame length (big endian, excluding these 4 bytes)
+//	uvarint message type
+//	varint sender id
+//	varint command frame
+//	varint timestamp in unix nanoseconds
+//	uvarint body length followed by the body
+type BinaryCodec struct{}
+
+func (c BinaryCodec) Type() CodecType {
+	return CodecTypeBinary
+}
+
+func (c BinaryCodec) Marshal(message Message) ([]byte, error) {
+	bm, ok := message.(binaryMessage)
+	if !ok {
+		return nil, fmt.Errorf("message type %d does not support binary encoding", message.Type())
+	}
+	w := &wireWriter{}
+	bm.encodeBinary(w)
+	return w.Bytes(), nil
+}
+
+func (c BinaryCodec) Unmarshal(body []byte, message any) error {
+	bm, ok := message.(binaryMessageDecoder)
+	if !ok {
+		return fmt.Errorf("%T does not support binary decoding", message)
+	}
+	r := newWireReader(body)
+	bm.decodeBinary(r)
+	return r.finish()
+}
+
+func (c BinaryCodec) NewEncoder(w io.Writer) TransportEncoder {
+	return &binaryTransportEncoder{writer: w}
+}
+
+func (c BinaryCodec) NewDecoder(r io.Reader) TransportDecoder {
+	return &binaryTransportDecoder{reader: bufio.NewReader(r)}
+}
+
+type binaryTransportEncoder struct {
+	writer io.Writer
+	buf    []byte
+}
+
+func (e *binaryTransportEncoder) Encode(messageTransport MessageTransport) error {
+	frame, err := appendBinaryFrame(e.buf[:0], messageTransport)
+	if err != nil {
+		return err
+	}
+	e.buf = frame
+	_, err = e.writer.Write(frame)
+	return err
+}
+
+type binaryTransportDecoder struct {
+	reader *bufio.Reader
+	buf    []byte
+}
+
+func (d *binaryTransportDecoder) Decode() (MessageTransport, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(d.reader, header[:]); err != nil {
+		return MessageTransport{}, err
+	}
+
+	frameSize := int(binary.BigEndian.Uint32(header[:]))
+	if frameSize > maxFrameSize {
+		return MessageTransport{}, fmt.Errorf("%w: frame size %d exceeds max frame size %d", ErrMalformedMessage, frameSize, maxFrameSize)
+	}
+
+	if cap(d.buf) < frameSize {
+		d.buf = make([]byte, frameSize)
+	}
+	frame := d.buf[:frameSize]
+	if _, err := io.ReadFull(d.reader, frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return MessageTransport{}, err
+	}
+
+	return decodeBinaryFrame(frame)
+}
+
+func appendBinaryFrame(buf []byte, messageTransport MessageTransport) ([]byte, error) {
+	w := &wireWriter{buf: append(buf, 0, 0, 0, 0)}
+	w.writeUvarint(uint64(messageTransport.MessageType))
+	w.writeInt(messageTransport.SenderID)
+	w.writeInt(messageTransport.CommandFrame)
+	w.writeInt64(timestampToUnixNano(messageTransport.Timestamp))
+	w.writeBytes(messageTransport.Body)
+
+	frame := w.Bytes()
+	frameSize := len(frame) - 4
+	if frameSize > maxFrameSize {
+		return nil, fmt.Errorf("frame size %d exceeds max frame size %d", frameSize, maxFrameSize)
+	}
+	binary.BigEndian.PutUint32(frame, uint32(frameSize))
+	return frame, nil
+}
+
+// decodeBinaryFrame decodes a frame, excluding its length prefix. the body is
+// copied so the frame buffer can be reused
+func decodeBinaryFrame(frame []byte) (MessageTransport, error) {
+	r := newWireReader(frame)
+	messageTransport := MessageTransport{
+		MessageType:  MessageType(r.readUvarint()),
+		SenderID:     r.readInt(),
+		CommandFrame: r.readInt(),
+		Timestamp:    unixNanoToTimestamp(r.readInt64()),
+		Body:         r.readBytes(),
+		codec:        BinaryCodec{},
+	}
+	if err := r.finish(); err != nil {
+		return MessageTransport{}, err
+	}
+	return messageTransport, nil
+}
+
+// the zero time can't be represented in unix nanoseconds so it's written as 0
+func timestampToUnixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func unixNanoToTimestamp(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
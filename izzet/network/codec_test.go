@@ -0,0 +1,327 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/input"
+	"github.com/kkevinchou/izzet/izzet/serverstats"
+)
+
+// testMessages contains a populated message for every MessageType
+func testMessages() map[MessageType]Message {
+	return map[MessageType]Message{
+		MsgTypeAcceptConnection: AcceptMessage{ID: 100001},
+		MsgTypeGameStateUpdate: GameStateUpdateMessage{
+			EntityStates: []EntityState{
+				{
+					EntityID:            12,
+					ChangedFields:       EntityStateFieldAll,
+					Position:            mgl64.Vec3{1.5, -2.25, 1000.125},
+					Rotation:            mgl64.QuatRotate(0.7, mgl64.Vec3{0, 1, 0}),
+					Velocity:            mgl64.Vec3{0.1, 0, -0.3},
+					AccumulatedVelocity: mgl64.Vec3{0, -60, 0},
+					Grounded:            true,
+					GravityEnabled:      true,
+					AnimationTransitions: []AnimationTransition{
+						{SourceState: "idle", DestinationState: "run", CommandFrame: 4301},
+					},
+				},
+				{
+					EntityID:      13,
+					ChangedFields: EntityStateFieldAll,
+					Rotation:      mgl64.QuatIdent(),
+					Deadge:        true,
+					MaxHealth:     100,
+				},
+				{
+					EntityID:      14,
+					ChangedFields: EntityStateFieldPosition | EntityStateFieldFlags | EntityStateFieldHealth,
+					Position:      mgl64.Vec3{-3, 0.5, 2},
+					Grounded:      true,
+					Health:        40,
+					MaxHealth:     100,
+				},
+			},
+			LastInputCommandFrame: 4290,
+			GlobalCommandFrame:    4310,
+			BaselineCommandFrame:  4300,
+			ServerStats: serverstats.ServerStats{Data: []serverstats.Stat{
+				{Name: "CFPS", Value: "125"},
+			}},
+			DestroyedEntities: []int{3, 7},
+			InputFeedback:     InputFeedback{BufferDepth: 3, DroppedInputs: 12, DuplicatedInputs: 5},
+		},
+		MsgTypePlayerInput: InputMessage{
+			Input: input.Input{
+				WindowEvent: input.WindowEvent{Resized: true},
+				KeyboardInput: input.KeyboardInput{
+					input.KeyboardKeyW:     {Key: input.KeyboardKeyW, Event: input.KeyboardEventNone},
+					input.KeyboardKeySpace: {Key: input.KeyboardKeySpace, Event: input.KeyboardEventDown},
+				},
+				MouseInput: input.MouseInput{
+					Position:         mgl64.Vec2{512, 360},
+					MouseWheelDelta:  -2,
+					MouseMotionEvent: input.MouseMotionEvent{XRel: 3, YRel: -1.5},
+					MouseButtonEvent: [3]input.MouseButtonEvent{input.MouseButtonEventDown, input.MouseButtonEventNone, input.MouseButtonEventUp},
+					MouseButtonState: [3]bool{true, false, false},
+				},
+				CameraRotation: mgl64.QuatRotate(-0.2, mgl64.Vec3{1, 0, 0}),
+			},
+			RedundantInputs: []input.Input{
+				{
+					KeyboardInput:  input.KeyboardInput{input.KeyboardKeyW: {Key: input.KeyboardKeyW, Event: input.KeyboardEventDown}},
+					CameraRotation: mgl64.QuatIdent(),
+				},
+				{CameraRotation: mgl64.QuatRotate(0.1, mgl64.Vec3{0, 1, 0})},
+			},
+			AckedGameStateFrame: 4300,
+			InterpolationDelay:  11,
+		},
+		MsgTypeCreateEntity: CreateEntityMessage{OwnerID: 4, EntityBytes: []byte(`{"ID":4}`)},
+		MsgTypePlayerJoin:   PlayerJoinMessage{PlayerID: 100002},
+		MsgTypeAckPlayerJoin: AckPlayerJoinMessage{
+			ProjectName:     "kitchen_miniature",
+			PlayerID:        100002,
+			PlayerEntityID:  40,
+			CameraEntityID:  41,
+			SerializedWorld: []byte(`{"Entities":[]}`),
+		},
+		MsgTypePing:          PingMessage{UnixTime: 1700000000123456789},
+		MsgTypeDestroyEntity: DestroyEntityMessage{EntityID: 41},
+		MsgTypeRPC: RPCMessage{
+			Pathfind:     &Pathfind{Goal: mgl64.Vec3{4, 0, -8}},
+			CreateEntity: &CreateEntityRPC{EntityType: "velociraptor", Patrol: true},
+			RessurectRPC: &RessurectRPC{ID: 40},
+		},
+	}
+}
+
+// extract decodes a transport into the concrete message type for its MessageType
+func extract(t MessageTransport) (Message, error) {
+	switch t.MessageType {
+	case MsgTypeAcceptConnection:
+		return ExtractMessage[AcceptMessage](t)
+	case MsgTypeGameStateUpdate:
+		return ExtractMessage[GameStateUpdateMessage](t)
+	case MsgTypePlayerInput:
+		return ExtractMessage[InputMessage](t)
+	case MsgTypeCreateEntity:
+		return ExtractMessage[CreateEntityMessage](t)
+	case MsgTypePlayerJoin:
+		return ExtractMessage[PlayerJoinMessage](t)
+	case MsgTypeAckPlayerJoin:
+		return ExtractMessage[AckPlayerJoinMessage](t)
+	case MsgTypePing:
+		return ExtractMessage[PingMessage](t)
+	case MsgTypeRPC:
+		return ExtractMessage[RPCMessage](t)
+	case MsgTypeDestroyEntity:
+		return ExtractMessage[DestroyEntityMessage](t)
+	}
+	return nil, errors.New("unknown message type")
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec{}, BinaryCodec{}} {
+		for messageType, message := range testMessages() {
+			var stream bytes.Buffer
+			body, err := codec.Marshal(message)
+			if err != nil {
+				t.Fatalf("[%s] failed to marshal message type %d: %s", codec.Type(), messageType, err)
+			}
+
+			sent := MessageTransport{
+				SenderID:     9,
+				MessageType:  messageType,
+				CommandFrame: 4310,
+				Timestamp:    time.Unix(0, 1700000000123456789),
+				Body:         body,
+			}
+			if err := codec.NewEncoder(&stream).Encode(sent); err != nil {
+				t.Fatalf("[%s] failed to encode message type %d: %s", codec.Type(), messageType, err)
+			}
+
+			received, err := codec.NewDecoder(&stream).Decode()
+			if err != nil {
+				t.Fatalf("[%s] failed to decode message type %d: %s", codec.Type(), messageType, err)
+			}
+
+			if received.SenderID != sent.SenderID || received.MessageType != sent.MessageType ||
+				received.CommandFrame != sent.CommandFrame || !received.Timestamp.Equal(sent.Timestamp) {
+				t.Fatalf("[%s] transport header mismatch, got %+v want %+v", codec.Type(), received, sent)
+			}
+
+			decoded, err := extract(received)
+			if err != nil {
+				t.Fatalf("[%s] failed to extract message type %d: %s", codec.Type(), messageType, err)
+			}
+			if !reflect.DeepEqual(decoded, message) {
+				t.Fatalf("[%s] message type %d mismatch\n got: %+v\nwant: %+v", codec.Type(), messageType, decoded, message)
+			}
+		}
+	}
+}
+
+func TestBinaryCodecIsSmallerThanJSON(t *testing.T) {
+	message := testMessages()[MsgTypeGameStateUpdate].(GameStateUpdateMessage)
+	for i := range 50 {
+		f := float64(i) / 3
+		message.EntityStates = append(message.EntityStates, EntityState{
+			EntityID:       100 + i,
+			ChangedFields:  EntityStateFieldAll,
+			Position:       mgl64.Vec3{f, 0.5 + f, -f * 7},
+			Rotation:       mgl64.QuatRotate(f, mgl64.Vec3{0, 1, 0}),
+			Velocity:       mgl64.Vec3{f / 11, 0, f / 13},
+			Grounded:       true,
+			GravityEnabled: true,
+		})
+	}
+
+	jsonBody, err := JSONCodec{}.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryBody, err := BinaryCodec{}.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(binaryBody)*2 > len(jsonBody) {
+		t.Fatalf("expected binary body (%d bytes) to be less than half the size of json body (%d bytes)", len(binaryBody), len(jsonBody))
+	}
+}
+
+func TestBinaryDecoderRejectsOversizedFrame(t *testing.T) {
+	stream := bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff})
+	_, err := BinaryCodec{}.NewDecoder(stream).Decode()
+	if !errors.Is(err, ErrMalformedMessage) {
+		t.Fatalf("expected ErrMalformedMessage, got %v", err)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	for _, codecType := range []CodecType{CodecTypeJSON, CodecTypeBinary} {
+		clientConn, serverConn := net.Pipe()
+
+		serverCodec := make(chan Codec, 1)
+		go func() {
+			codec, err := ServerHandshake(serverConn)
+			if err != nil {
+				t.Error(err)
+			}
+			serverCodec <- codec
+		}()
+
+		clientCodec, err := ClientHandshake(clientConn, codecType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sc := <-serverCodec
+		if sc == nil || sc.Type() != codecType || clientCodec.Type() != codecType {
+			t.Fatalf("expected both sides to negotiate %s", codecType)
+		}
+
+		client := NewClient(clientConn, clientCodec)
+		server := NewClient(serverConn, sc)
+		go client.Send(PingMessage{UnixTime: 42}, 7)
+
+		transport, err := server.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ping, err := ExtractMessage[PingMessage](transport)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ping.UnixTime != 42 || transport.CommandFrame != 7 {
+			t.Fatalf("unexpected ping %+v at frame %d", ping, transport.CommandFrame)
+		}
+
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestHandshakeVersionMismatch(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		_, err := ServerHandshake(serverConn)
+		serverErr <- err
+	}()
+
+	if err := writeHandshake(clientConn, handshake{Version: ProtocolVersion + 1, Codec: CodecTypeBinary}); err != nil {
+		t.Fatal(err)
+	}
+	response, err := readHandshake(clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Status != handshakeStatusVersionMismatch {
+		t.Fatalf("expected version mismatch status, got %d", response.Status)
+	}
+	if err := <-serverErr; !errors.Is(err, ErrProtocolVersionMismatch) {
+		t.Fatalf("expected ErrProtocolVersionMismatch, got %v", err)
+	}
+}
+
+// FuzzBinaryFrame feeds arbitrary frames through the binary decoder. decoding must
+// never panic, and anything that decodes successfully must re-encode to a message
+// that decodes to the same value
+func FuzzBinaryFrame(f *testing.F) {
+	codec := BinaryCodec{}
+	for messageType, message := range testMessages() {
+		body, err := codec.Marshal(message)
+		if err != nil {
+			f.Fatal(err)
+		}
+		frame, err := appendBinaryFrame(nil, MessageTransport{MessageType: messageType, CommandFrame: 1, Timestamp: time.Unix(0, 1), Body: body})
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(frame[4:])
+	}
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		transport, err := decodeBinaryFrame(frame)
+		if err != nil {
+			return
+		}
+
+		message, err := extract(transport)
+		if err != nil {
+			return
+		}
+
+		body, err := codec.Marshal(message)
+		if err != nil {
+			t.Fatalf("failed to re-encode decoded message: %s", err)
+		}
+		transport.Body = body
+
+		redecoded, err := extract(transport)
+		if err != nil {
+			t.Fatalf("failed to decode re-encoded message: %s", err)
+		}
+
+		// compare encoded bytes rather than values since fuzzed floats may be NaN
+		reencoded, err := codec.Marshal(redecoded)
+		if err != nil {
+			t.Fatalf("failed to re-encode decoded message: %s", err)
+		}
+		if !bytes.Equal(body, reencoded) {
+			t.Fatalf("re-encoded message mismatch\n got: %+v\nwant: %+v", redecoded, message)
+		}
+	})
+}
@@ -8,3 +8,13 @@ type CreateEntityMessage struct {
 func (m CreateEntityMessage) Type() MessageType {
 	return MsgTypeCreateEntity
 }
+
+func (m CreateEntityMessage) encodeBinary(w *wireWriter) {
+	w.writeInt(m.OwnerID)
+	w.writeBytes(m.EntityBytes)
+}
+
+func (m *CreateEntityMessage) decodeBinary(r *wireReader) {
+	m.OwnerID = r.readInt()
+	m.EntityBytes = r.readBytes()
+}
@@ -0,0 +1,19 @@
+package network
+
+// DestroyEntityMessage tells a client to remove an entity, either because it was
+// destroyed or because it's no longer relevant to the client
+type DestroyEntityMessage struct {
+	EntityID int
+}
+
+func (m DestroyEntityMessage) Type() MessageType {
+	return MsgTypeDestroyEntity
+}
+
+func (m DestroyEntityMessage) encodeBinary(w *wireWriter) {
+	w.writeInt(m.EntityID)
+}
+
+func (m *DestroyEntityMessage) decodeBinary(r *wireReader) {
+	m.EntityID = r.readInt()
+}
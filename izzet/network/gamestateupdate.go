@@ -6,7 +6,10 @@ import (
 )
 
 type EntityState struct {
-	EntityID             int
+	EntityID int
+	// ChangedFields are the fields that are set, the rest are carried over from
+	// the baseline when applying a delta
+	ChangedFields        EntityStateField
 	Position             mgl64.Vec3
 	Rotation             mgl64.Quat
 	Velocity             mgl64.Vec3
@@ -14,6 +17,8 @@ type EntityState struct {
 	Grounded             bool
 	GravityEnabled       bool
 	Deadge               bool
+	Health               int
+	MaxHealth            int
 	AnimationTransitions []AnimationTransition
 }
 
@@ -27,10 +32,191 @@ type GameStateUpdateMessage struct {
 	EntityStates          []EntityState
 	LastInputCommandFrame int
 	GlobalCommandFrame    int
-	ServerStats           serverstats.ServerStats
-	DestroyedEntities     []int
+	// BaselineCommandFrame is the GlobalCommandFrame of the snapshot this update is
+	// a delta against, 0 for full snapshots
+	BaselineCommandFrame int
+	ServerStats          serverstats.ServerStats
+	DestroyedEntities    []int
+	InputFeedback        InputFeedback
+}
+
+// InputFeedback tells a client how its inputs are arriving at the server so it
+// can speed up or slow down to keep the server's input buffer at a steady depth
+type InputFeedback struct {
+	// BufferDepth is the number of the client's inputs the server has buffered
+	// but not simulated yet
+	BufferDepth int
+	// DroppedInputs and DuplicatedInputs are running totals since the client
+	// joined so that lost updates don't lose counts
+	DroppedInputs    int
+	DuplicatedInputs int
 }
 
 func (m GameStateUpdateMessage) Type() MessageType {
 	return MsgTypeGameStateUpdate
 }
+
+const (
+	entityStateFlagGrounded byte = 1 << iota
+	entityStateFlagGravityEnabled
+	entityStateFlagDeadge
+)
+
+// smallest possible encoding of an EntityState, used to bound allocations when decoding.
+// deltas only include changed fields so this is just the id, field mask, and
+// animation transition count
+const minEncodedEntityStateSize int = 1 + 1 + 1
+
+func (m GameStateUpdateMessage) encodeBinary(w *wireWriter) {
+	w.writeUvarint(uint64(len(m.EntityStates)))
+	for _, state := range m.EntityStates {
+		state.encodeBinary(w)
+	}
+
+	w.writeInt(m.LastInputCommandFrame)
+	w.writeInt(m.GlobalCommandFrame)
+	w.writeInt(m.BaselineCommandFrame)
+
+	w.writeUvarint(uint64(len(m.ServerStats.Data)))
+	for _, stat := range m.ServerStats.Data {
+		w.writeString(stat.Name)
+		w.writeString(stat.Value)
+	}
+
+	w.writeUvarint(uint64(len(m.DestroyedEntities)))
+	for _, id := range m.DestroyedEntities {
+		w.writeInt(id)
+	}
+
+	w.writeInt(m.InputFeedback.BufferDepth)
+	w.writeInt(m.InputFeedback.DroppedInputs)
+	w.writeInt(m.InputFeedback.DuplicatedInputs)
+}
+
+func (m *GameStateUpdateMessage) decodeBinary(r *wireReader) {
+	if n := r.readLength(minEncodedEntityStateSize); n > 0 {
+		m.EntityStates = make([]EntityState, n)
+		for i := range m.EntityStates {
+			m.EntityStates[i].decodeBinary(r)
+		}
+	}
+
+	m.LastInputCommandFrame = r.readInt()
+	m.GlobalCommandFrame = r.readInt()
+	m.BaselineCommandFrame = r.readInt()
+
+	if n := r.readLength(2); n > 0 {
+		m.ServerStats.Data = make([]serverstats.Stat, n)
+		for i := range m.ServerStats.Data {
+			m.ServerStats.Data[i] = serverstats.Stat{Name: r.readString(), Value: r.readString()}
+		}
+	}
+
+	if n := r.readLength(1); n > 0 {
+		m.DestroyedEntities = make([]int, n)
+		for i := range m.DestroyedEntities {
+			m.DestroyedEntities[i] = r.readInt()
+		}
+	}
+
+	m.InputFeedback.BufferDepth = r.readInt()
+	m.InputFeedback.DroppedInputs = r.readInt()
+	m.InputFeedback.DuplicatedInputs = r.readInt()
+}
+
+// EncodedSize is the number of bytes the state takes up in a binary game state update
+func (s EntityState) EncodedSize() int {
+	w := &wireWriter{}
+	s.encodeBinary(w)
+	return len(w.buf)
+}
+
+// only the fields set in ChangedFields are written
+func (s EntityState) encodeBinary(w *wireWriter) {
+	w.writeInt(s.EntityID)
+	w.writeByte(byte(s.ChangedFields))
+
+	if s.ChangedFields&EntityStateFieldPosition != 0 {
+		w.writeVec3(s.Position)
+	}
+	if s.ChangedFields&EntityStateFieldRotation != 0 {
+		w.writeQuat(s.Rotation)
+	}
+	if s.ChangedFields&EntityStateFieldVelocity != 0 {
+		w.writeVec3(s.Velocity)
+	}
+	if s.ChangedFields&EntityStateFieldAccumulatedVelocity != 0 {
+		w.writeVec3(s.AccumulatedVelocity)
+	}
+	if s.ChangedFields&EntityStateFieldFlags != 0 {
+		var flags byte
+		if s.Grounded {
+			flags |= entityStateFlagGrounded
+		}
+		if s.GravityEnabled {
+			flags |= entityStateFlagGravityEnabled
+		}
+		if s.Deadge {
+			flags |= entityStateFlagDeadge
+		}
+		w.writeByte(flags)
+	}
+	if s.ChangedFields&EntityStateFieldHealth != 0 {
+		w.writeInt(s.Health)
+		w.writeInt(s.MaxHealth)
+	}
+
+	w.writeUvarint(uint64(len(s.AnimationTransitions)))
+	for _, transition := range s.AnimationTransitions {
+		w.writeString(transition.SourceState)
+		w.writeString(transition.DestinationState)
+		w.writeInt(transition.CommandFrame)
+	}
+}
+
+func (s *EntityState) decodeBinary(r *wireReader) {
+	s.EntityID = r.readInt()
+	s.ChangedFields = EntityStateField(r.readByte())
+	if s.ChangedFields&^EntityStateFieldAll != 0 {
+		r.fail("unknown entity state fields %b", s.ChangedFields)
+		return
+	}
+
+	if s.ChangedFields&EntityStateFieldPosition != 0 {
+		s.Position = r.readVec3()
+	}
+	if s.ChangedFields&EntityStateFieldRotation != 0 {
+		s.Rotation = r.readQuat()
+	}
+	if s.ChangedFields&EntityStateFieldVelocity != 0 {
+		s.Velocity = r.readVec3()
+	}
+	if s.ChangedFields&EntityStateFieldAccumulatedVelocity != 0 {
+		s.AccumulatedVelocity = r.readVec3()
+	}
+	if s.ChangedFields&EntityStateFieldFlags != 0 {
+		flags := r.readByte()
+		if flags&^(entityStateFlagGrounded|entityStateFlagGravityEnabled|entityStateFlagDeadge) != 0 {
+			r.fail("unknown entity state flags %b", flags)
+			return
+		}
+		s.Grounded = flags&entityStateFlagGrounded != 0
+		s.GravityEnabled = flags&entityStateFlagGravityEnabled != 0
+		s.Deadge = flags&entityStateFlagDeadge != 0
+	}
+	if s.ChangedFields&EntityStateFieldHealth != 0 {
+		s.Health = r.readInt()
+		s.MaxHealth = r.readInt()
+	}
+
+	if n := r.readLength(3); n > 0 {
+		s.AnimationTransitions = make([]AnimationTransition, n)
+		for i := range s.AnimationTransitions {
+			s.AnimationTransitions[i] = AnimationTransition{
+				SourceState:      r.readString(),
+				DestinationState: r.readString(),
+				CommandFrame:     r.readInt(),
+			}
+		}
+	}
+}
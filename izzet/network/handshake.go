@@ -0,0 +1,147 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// ProtocolVersion must be bumped whenever the wire format of a message changes
+const ProtocolVersion uint16 = 6
+
+const handshakeTimeout = 5 * time.Second
+
+var handshakeMagic = [4]byte{'I', 'Z', 'Z', 'T'}
+
+var ErrProtocolVersionMismatch = errors.New("protocol version mismatch")
+var ErrUnsupportedCodec = errors.New("unsupported codec")
+
+type handshakeStatus uint8
+
+const (
+	handshakeStatusOK handshakeStatus = iota
+	handshakeStatusVersionMismatch
+	handshakeStatusUnsupportedCodec
+)
+
+// handshakes are a fixed 8 bytes and are always the first thing written on a new
+// connection regardless of the codec that's negotiated
+//
+//	[4]byte magic
+//	uint16  protocol version (big endian)
+//	uint8   codec type
+//	uint8   status (only meaningful in the server's response)
+type handshake struct {
+	Version uint16
+	Codec   CodecType
+	Status  handshakeStatus
+}
+
+const handshakeSize int = 8
+
+func writeHandshake(w io.Writer, h handshake) error {
+	var buf [handshakeSize]byte
+	copy(buf[:4], handshakeMagic[:])
+	binary.BigEndian.PutUint16(buf[4:6], h.Version)
+	buf[6] = byte(h.Codec)
+	buf[7] = byte(h.Status)
+	_, err := w.Write(buf[:])
+	return err
+}
+
+func readHandshake(r io.Reader) (handshake, error) {
+	var buf [handshakeSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return handshake{}, err
+	}
+	if [4]byte(buf[:4]) != handshakeMagic {
+		return handshake{}, fmt.Errorf("%w: bad handshake magic %q", ErrMalformedMessage, buf[:4])
+	}
+	return handshake{
+		Version: binary.BigEndian.Uint16(buf[4:6]),
+		Codec:   CodecType(buf[6]),
+		Status:  handshakeStatus(buf[7]),
+	}, nil
+}
+
+// ClientHandshake announces the client's protocol version and requested codec
+// and waits for the server to accept them
+func ClientHandshake(conn net.Conn, codecType CodecType) (Codec, error) {
+	codec, err := NewCodec(codecType)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	if err := writeHandshake(conn, handshake{Version: ProtocolVersion, Codec: codecType}); err != nil {
+		return nil, err
+	}
+
+	response, err := readHandshake(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkHandshakeResponse(response, codecType); err != nil {
+		return nil, err
+	}
+	return codec, nil
+}
+
+// checkHandshakeResponse converts the status of a server's handshake response into an error
+func checkHandshakeResponse(response handshake, codecType CodecType) error {
+	switch response.Status {
+	case handshakeStatusOK:
+		return nil
+	case handshakeStatusVersionMismatch:
+		return fmt.Errorf("%w: client version %d, server version %d", ErrProtocolVersionMismatch, ProtocolVersion, response.Version)
+	case handshakeStatusUnsupportedCodec:
+		return fmt.Errorf("%w: %s", ErrUnsupportedCodec, codecType)
+	}
+	return fmt.Errorf("%w: unknown handshake status %d", ErrMalformedMessage, response.Status)
+}
+
+// ServerHandshake reads the client's handshake and responds with whether the
+// connection is accepted. on success the codec requested by the client is returned
+func ServerHandshake(conn net.Conn) (Codec, error) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	request, err := readHandshake(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, response, handshakeErr := acceptHandshake(request)
+	if err := writeHandshake(conn, response); err != nil {
+		return nil, err
+	}
+	if handshakeErr != nil {
+		return nil, handshakeErr
+	}
+
+	return codec, nil
+}
+
+// acceptHandshake validates a client's handshake request and builds the response
+// to send back. the response should be sent even if an error is returned so the
+// client knows why it was rejected
+func acceptHandshake(request handshake) (Codec, handshake, error) {
+	response := handshake{Version: ProtocolVersion, Codec: request.Codec, Status: handshakeStatusOK}
+
+	codec, err := NewCodec(request.Codec)
+	if request.Version != ProtocolVersion {
+		response.Status = handshakeStatusVersionMismatch
+		return nil, response, fmt.Errorf("%w: client version %d, server version %d", ErrProtocolVersionMismatch, request.Version, ProtocolVersion)
+	} else if err != nil {
+		response.Status = handshakeStatusUnsupportedCodec
+		return nil, response, fmt.Errorf("%w: %s", ErrUnsupportedCodec, request.Codec)
+	}
+
+	return codec, response, nil
+}
@@ -1,11 +1,117 @@
 package network
 
-import "github.com/kkevinchou/izzet/internal/input"
+import (
+	"slices"
+
+	"github.com/kkevinchou/izzet/internal/input"
+)
 
 type InputMessage struct {
 	Input input.Input
+	// RedundantInputs are the inputs from the command frames right before this
+	// one, most recent first. they're resent with every message so that inputs
+	// survive lost packets
+	RedundantInputs []input.Input
+	// AckedGameStateFrame is the GlobalCommandFrame of the most recent game state
+	// update the client has applied, the server deltas snapshots against it
+	AckedGameStateFrame int
+	// InterpolationDelay is how many command frames behind AckedGameStateFrame the
+	// client was rendering other entities, the server rewinds to that frame when
+	// checking the client's shots
+	InterpolationDelay int
 }
 
 func (m InputMessage) Type() MessageType {
 	return MsgTypePlayerInput
 }
+
+// smallest possible encoding of an input, used to bound allocations when
+// decoding. that's the resized flag, key count, mouse position, wheel delta,
+// mouse motion, button events and states, and camera rotation
+const minEncodedInputSize int = 1 + 1 + 16 + 1 + 16 + 3 + 3 + 32
+
+func (m InputMessage) encodeBinary(w *wireWriter) {
+	writeInput(w, m.Input)
+	w.writeUvarint(uint64(len(m.RedundantInputs)))
+	for _, in := range m.RedundantInputs {
+		writeInput(w, in)
+	}
+	w.writeInt(m.AckedGameStateFrame)
+	w.writeInt(m.InterpolationDelay)
+}
+
+func (m *InputMessage) decodeBinary(r *wireReader) {
+	readInput(r, &m.Input)
+	if n := r.readLength(minEncodedInputSize); n > 0 {
+		m.RedundantInputs = make([]input.Input, n)
+		for i := range m.RedundantInputs {
+			readInput(r, &m.RedundantInputs[i])
+		}
+	}
+	m.AckedGameStateFrame = r.readInt()
+	m.InterpolationDelay = r.readInt()
+}
+
+// Input.Commands are client local (quit, file drops, etc) and are not sent over the wire
+func writeInput(w *wireWriter, in input.Input) {
+	w.writeBool(in.WindowEvent.Resized)
+
+	keys := make([]input.KeyboardKey, 0, len(in.KeyboardInput))
+	for key := range in.KeyboardInput {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	w.writeUvarint(uint64(len(keys)))
+	for _, key := range keys {
+		state := in.KeyboardInput[key]
+		w.writeString(string(key))
+		w.writeString(string(state.Key))
+		w.writeString(string(state.Event))
+	}
+
+	mouse := in.MouseInput
+	w.writeVec2(mouse.Position)
+	w.writeInt(mouse.MouseWheelDelta)
+	w.writeFloat64(mouse.MouseMotionEvent.XRel)
+	w.writeFloat64(mouse.MouseMotionEvent.YRel)
+	for _, event := range mouse.MouseButtonEvent {
+		w.writeString(string(event))
+	}
+	for _, state := range mouse.MouseButtonState {
+		w.writeBool(state)
+	}
+
+	w.writeQuat(in.CameraRotation)
+}
+
+func readInput(r *wireReader, in *input.Input) {
+	in.WindowEvent.Resized = r.readBool()
+
+	// each key is at least 3 empty strings
+	keyCount := r.readLength(3)
+	if keyCount > 0 {
+		in.KeyboardInput = make(input.KeyboardInput, keyCount)
+	}
+	for range keyCount {
+		key := input.KeyboardKey(r.readString())
+		in.KeyboardInput[key] = input.KeyState{
+			Key:   input.KeyboardKey(r.readString()),
+			Event: input.KeyboardEvent(r.readString()),
+		}
+	}
+
+	mouse := &in.MouseInput
+	mouse.Position = r.readVec2()
+	mouse.MouseWheelDelta = r.readInt()
+	mouse.MouseMotionEvent.XRel = r.readFloat64()
+	mouse.MouseMotionEvent.YRel = r.readFloat64()
+	for i := range mouse.MouseButtonEvent {
+		mouse.MouseButtonEvent[i] = input.MouseButtonEvent(r.readString())
+	}
+	for i := range mouse.MouseButtonState {
+		mouse.MouseButtonState[i] = r.readBool()
+	}
+
+	in.CameraRotation = r.readQuat()
+}
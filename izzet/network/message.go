@@ -15,6 +15,7 @@ const (
 	MsgTypeAckPlayerJoin
 	MsgTypePing
 	MsgTypeRPC
+	MsgTypeDestroyEntity
 )
 
 type Message interface {
@@ -28,8 +29,23 @@ type MessageTransport struct {
 	Timestamp    time.Time
 
 	Body []byte
+
+	// codec is the codec the transport was received with, used to decode Body
+	codec Codec
 }
 
 type AcceptMessage struct {
 	ID int
 }
+
+func (m AcceptMessage) Type() MessageType {
+	return MsgTypeAcceptConnection
+}
+
+func (m AcceptMessage) encodeBinary(w *wireWriter) {
+	w.writeInt(m.ID)
+}
+
+func (m *AcceptMessage) decodeBinary(r *wireReader) {
+	m.ID = r.readInt()
+}
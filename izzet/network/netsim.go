@@ -0,0 +1,469 @@
+package network
+
+import (
+	"container/heap"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kkevinchou/izzet/izzet/settings"
+)
+
+// maxSimulatedResends caps how many times a lost reliable message is resent so
+// a loss rate of 1 doesn't stall the link forever
+const maxSimulatedResends = 8
+
+// NetworkConditions describes a simulated one way link. latency, jitter and the
+// bandwidth cap apply to every message. loss and duplication only apply to the
+// unreliable channel, reliable messages and stream writes are instead delayed by
+// a resend for every time they're lost and hold up everything queued behind them
+type NetworkConditions struct {
+	Latency time.Duration
+	// Jitter is the maximum deviation from Latency, sampled uniformly
+	Jitter time.Duration
+	// LossRate and DuplicateRate are probabilities in [0, 1]
+	LossRate      float64
+	DuplicateRate float64
+	// Bandwidth is in bytes per second, zero is unlimited
+	Bandwidth int
+}
+
+func NetworkConditionsFromConfig(config settings.NetworkConditionsConfig) NetworkConditions {
+	return NetworkConditions{
+		Latency:       time.Duration(config.LatencyMS * float64(time.Millisecond)),
+		Jitter:        time.Duration(config.JitterMS * float64(time.Millisecond)),
+		LossRate:      config.LossPercent / 100,
+		DuplicateRate: config.DuplicatePercent / 100,
+		Bandwidth:     int(config.BandwidthKbps * 1000 / 8),
+	}
+}
+
+type simulatedDelivery struct {
+	deliverAt time.Time
+	sequence  uint64
+	reliable  bool
+	deliver   func()
+}
+
+type deliveryQueue []simulatedDelivery
+
+func (q deliveryQueue) Len() int { return len(q) }
+func (q deliveryQueue) Less(i, j int) bool {
+	if q[i].deliverAt.Equal(q[j].deliverAt) {
+		return q[i].sequence < q[j].sequence
+	}
+	return q[i].deliverAt.Before(q[j].deliverAt)
+}
+func (q deliveryQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *deliveryQueue) Push(x any)   { *q = append(*q, x.(simulatedDelivery)) }
+func (q *deliveryQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// simulatedLink schedules deliveries in one direction. all random decisions are
+// made in send order from a seeded source so the same seed and traffic produce the
+// same losses and duplicates
+type simulatedLink struct {
+	mu         sync.Mutex
+	conditions NetworkConditions
+	random     *rand.Rand
+	pending    deliveryQueue
+	sequence   uint64
+
+	// linkFreeAt is when the bandwidth capped link finishes sending what's queued
+	linkFreeAt time.Time
+	// lastReliableAt keeps reliable deliveries in order
+	lastReliableAt time.Time
+	// lastUnreliable is the newest unreliable message delivered, older ones that
+	// arrive after it are dropped like the unreliable channel does
+	lastUnreliable uint64
+
+	wake chan struct{}
+	done chan struct{}
+}
+
+func newSimulatedLink(conditions NetworkConditions, seed int64, done chan struct{}) *simulatedLink {
+	l := &simulatedLink{
+		conditions: conditions,
+		random:     rand.New(rand.NewSource(seed)),
+		wake:       make(chan struct{}, 1),
+		done:       done,
+	}
+	go l.run()
+	return l
+}
+
+func (l *simulatedLink) setConditions(conditions NetworkConditions) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.conditions = conditions
+}
+
+func (l *simulatedLink) schedule(size int, reliable bool, deliver func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.conditions
+	now := time.Now()
+	l.sequence++
+
+	copies := 1
+	if !reliable {
+		if l.random.Float64() < c.LossRate {
+			return
+		}
+		if l.random.Float64() < c.DuplicateRate {
+			copies = 2
+		}
+	}
+
+	for range copies {
+		departure := now
+		if c.Bandwidth > 0 {
+			departure = latest(now, l.linkFreeAt)
+			l.linkFreeAt = departure.Add(time.Duration(size) * time.Second / time.Duration(c.Bandwidth))
+		}
+
+		delay := c.Latency
+		if c.Jitter > 0 {
+			delay += time.Duration((l.random.Float64()*2 - 1) * float64(c.Jitter))
+		}
+		if reliable {
+			for i := 0; i < maxSimulatedResends && l.random.Float64() < c.LossRate; i++ {
+				delay += reliableResendInterval
+			}
+		}
+
+		deliverAt := departure.Add(max(delay, 0))
+		if reliable {
+			deliverAt = latest(deliverAt, l.lastReliableAt)
+			l.lastReliableAt = deliverAt
+		}
+		heap.Push(&l.pending, simulatedDelivery{deliverAt: deliverAt, sequence: l.sequence, reliable: reliable, deliver: deliver})
+	}
+
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (l *simulatedLink) run() {
+	for {
+		now := time.Now()
+		var ready []simulatedDelivery
+		var timeout <-chan time.Time
+
+		l.mu.Lock()
+		for len(l.pending) > 0 && !l.pending[0].deliverAt.After(now) {
+			d := heap.Pop(&l.pending).(simulatedDelivery)
+			if !d.reliable {
+				if d.sequence < l.lastUnreliable {
+					continue
+				}
+				l.lastUnreliable = d.sequence
+			}
+			ready = append(ready, d)
+		}
+		if len(l.pending) > 0 {
+			timeout = time.After(l.pending[0].deliverAt.Sub(now))
+		}
+		l.mu.Unlock()
+
+		for _, d := range ready {
+			d.deliver()
+		}
+
+		select {
+		case <-l.wake:
+		case <-timeout:
+		case <-l.done:
+			return
+		}
+	}
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// SimulatedClient wraps an IzzetClient and applies NetworkConditions to messages
+// in both directions, so the round trip time is twice the configured latency
+type SimulatedClient struct {
+	client   IzzetClient
+	outgoing *simulatedLink
+	incoming *simulatedLink
+	received chan recvResult
+
+	mu         sync.Mutex
+	conditions NetworkConditions
+	sendErr    error
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSimulatedClient starts simulating conditions on client. the outgoing and
+// incoming links are seeded separately from seed
+func NewSimulatedClient(client IzzetClient, conditions NetworkConditions, seed int64) *SimulatedClient {
+	done := make(chan struct{})
+	c := &SimulatedClient{
+		client:     client,
+		outgoing:   newSimulatedLink(conditions, seed, done),
+		incoming:   newSimulatedLink(conditions, seed+1, done),
+		received:   make(chan recvResult, 1024),
+		conditions: conditions,
+		done:       done,
+	}
+	go c.readLoop()
+	return c
+}
+
+// SetConditions changes the simulated conditions, messages already in flight
+// keep the conditions they were sent with
+func (c *SimulatedClient) SetConditions(conditions NetworkConditions) {
+	c.mu.Lock()
+	c.conditions = conditions
+	c.mu.Unlock()
+	c.outgoing.setConditions(conditions)
+	c.incoming.setConditions(conditions)
+}
+
+func (c *SimulatedClient) Conditions() NetworkConditions {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conditions
+}
+
+// Send queues the message on the simulated link. errors from the underlying
+// client surface on the next call to Send
+func (c *SimulatedClient) Send(message Message, frame int) error {
+	c.mu.Lock()
+	err := c.sendErr
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	size := 0
+	if body, err := (BinaryCodec{}).Marshal(message); err == nil {
+		size = simulatedTransportSize(MessageTransport{MessageType: message.Type(), Body: body})
+	}
+
+	reliable := channelForMessageType(message.Type()) == channelReliable
+	c.outgoing.schedule(size, reliable, func() {
+		if err := c.client.Send(message, frame); err != nil {
+			c.mu.Lock()
+			c.sendErr = err
+			c.mu.Unlock()
+		}
+	})
+	return nil
+}
+
+func (c *SimulatedClient) Recv() (MessageTransport, error) {
+	select {
+	case result := <-c.received:
+		return result.transport, result.err
+	case <-c.done:
+		return MessageTransport{}, net.ErrClosed
+	}
+}
+
+func (c *SimulatedClient) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.client.Close()
+	})
+}
+
+func (c *SimulatedClient) readLoop() {
+	for {
+		transport, err := c.client.Recv()
+		if err != nil {
+			// errors are queued behind reliable messages so a disconnect isn't
+			// seen before the messages that preceded it
+			c.incoming.schedule(0, true, func() { c.push(recvResult{err: err}) })
+			return
+		}
+
+		reliable := channelForMessageType(transport.MessageType) == channelReliable
+		c.incoming.schedule(simulatedTransportSize(transport), reliable, func() { c.push(recvResult{transport: transport}) })
+	}
+}
+
+func (c *SimulatedClient) push(result recvResult) {
+	select {
+	case c.received <- result:
+	case <-c.done:
+	}
+}
+
+// simulatedTransportSize is the size of the transport on the wire with the binary
+// codec, used for bandwidth caps
+func simulatedTransportSize(transport MessageTransport) int {
+	frame, err := appendBinaryFrame(nil, transport)
+	if err != nil {
+		return len(transport.Body)
+	}
+	return len(frame)
+}
+
+type simulatedRead struct {
+	data []byte
+	err  error
+}
+
+// SimulatedConn wraps a stream connection and applies NetworkConditions to
+// writes and reads. a stream can't lose or duplicate bytes so loss shows up as
+// resend delays and duplication is ignored
+type SimulatedConn struct {
+	net.Conn
+	outgoing *simulatedLink
+	incoming *simulatedLink
+	received chan simulatedRead
+
+	mu           sync.Mutex
+	writeErr     error
+	readDeadline time.Time
+
+	readMu      sync.Mutex
+	pendingRead []byte
+	readErr     error
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSimulatedConn starts simulating conditions on conn. the outgoing and
+// incoming links are seeded separately from seed
+func NewSimulatedConn(conn net.Conn, conditions NetworkConditions, seed int64) *SimulatedConn {
+	done := make(chan struct{})
+	c := &SimulatedConn{
+		Conn:     conn,
+		outgoing: newSimulatedLink(conditions, seed, done),
+		incoming: newSimulatedLink(conditions, seed+1, done),
+		received: make(chan simulatedRead, 1024),
+		done:     done,
+	}
+	go c.readLoop()
+	return c
+}
+
+func (c *SimulatedConn) SetConditions(conditions NetworkConditions) {
+	c.outgoing.setConditions(conditions)
+	c.incoming.setConditions(conditions)
+}
+
+// Write queues b on the simulated link. errors from the underlying connection
+// surface on the next call to Write
+func (c *SimulatedConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	err := c.writeErr
+	c.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	data := append([]byte(nil), b...)
+	c.outgoing.schedule(len(data), true, func() {
+		if _, err := c.Conn.Write(data); err != nil {
+			c.mu.Lock()
+			c.writeErr = err
+			c.mu.Unlock()
+		}
+	})
+	return len(b), nil
+}
+
+func (c *SimulatedConn) Read(b []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	if len(c.pendingRead) == 0 {
+		if c.readErr != nil {
+			return 0, c.readErr
+		}
+
+		c.mu.Lock()
+		deadline := c.readDeadline
+		c.mu.Unlock()
+
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			timer := time.NewTimer(time.Until(deadline))
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		select {
+		case read := <-c.received:
+			if read.err != nil {
+				c.readErr = read.err
+				return 0, read.err
+			}
+			c.pendingRead = read.data
+		case <-timeout:
+			return 0, os.ErrDeadlineExceeded
+		case <-c.done:
+			return 0, net.ErrClosed
+		}
+	}
+
+	n := copy(b, c.pendingRead)
+	c.pendingRead = c.pendingRead[n:]
+	return n, nil
+}
+
+// SetDeadline sets the read deadline locally since reads are served from the
+// simulated link, write deadlines are passed through
+func (c *SimulatedConn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	return c.Conn.SetWriteDeadline(t)
+}
+
+func (c *SimulatedConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return nil
+}
+
+func (c *SimulatedConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = c.Conn.Close()
+	})
+	return err
+}
+
+func (c *SimulatedConn) readLoop() {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := c.Conn.Read(buf)
+		if n > 0 {
+			data := append([]byte(nil), buf[:n]...)
+			c.incoming.schedule(n, true, func() { c.push(simulatedRead{data: data}) })
+		}
+		if err != nil {
+			c.incoming.schedule(0, true, func() { c.push(simulatedRead{err: err}) })
+			return
+		}
+	}
+}
+
+func (c *SimulatedConn) push(read simulatedRead) {
+	select {
+	case c.received <- read:
+	case <-c.done:
+	}
+}
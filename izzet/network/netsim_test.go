@@ -0,0 +1,208 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kkevinchou/izzet/izzet/settings"
+)
+
+// simulatedPipe connects a simulated client to a plain client over an in memory pipe
+func simulatedPipe(t *testing.T, conditions NetworkConditions, seed int64) (*SimulatedClient, IzzetClient) {
+	a, b := net.Pipe()
+	simulated := NewSimulatedClient(NewClient(a, BinaryCodec{}), conditions, seed)
+	remote := NewClient(b, BinaryCodec{})
+	t.Cleanup(func() {
+		simulated.Close()
+		remote.Close()
+	})
+	return simulated, remote
+}
+
+// sendLossyInputs sends unreliable inputs followed by a reliable marker and
+// returns the frames that made it through
+func sendLossyInputs(t *testing.T, seed int64) []int {
+	simulated, remote := simulatedPipe(t, NetworkConditions{LossRate: 0.25, DuplicateRate: 0.1}, seed)
+
+	for frame := 1; frame <= 200; frame++ {
+		if err := simulated.Send(InputMessage{}, frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := simulated.Send(DestroyEntityMessage{EntityID: -1}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var frames []int
+	for {
+		transport := mustRecv(t, remote)
+		if transport.MessageType == MsgTypeDestroyEntity {
+			return frames
+		}
+		frames = append(frames, transport.CommandFrame)
+	}
+}
+
+func TestSimulatedClientDeterministicLoss(t *testing.T) {
+	first := sendLossyInputs(t, 7)
+	second := sendLossyInputs(t, 7)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("same seed delivered different messages\nfirst: %v\nsecond: %v", first, second)
+	}
+	if reflect.DeepEqual(first, sendLossyInputs(t, 8)) {
+		t.Fatal("expected a different seed to deliver different messages")
+	}
+
+	unique := map[int]bool{}
+	for _, frame := range first {
+		unique[frame] = true
+	}
+	if len(unique) < 100 || len(unique) > 190 {
+		t.Fatalf("expected roughly a quarter of 200 messages to be lost, got %d unique", len(unique))
+	}
+	if len(first) == len(unique) {
+		t.Fatal("expected some messages to be duplicated")
+	}
+}
+
+func TestSimulatedClientReliableLatency(t *testing.T) {
+	conditions := NetworkConditions{Latency: 20 * time.Millisecond, Jitter: 10 * time.Millisecond, LossRate: 0.2, DuplicateRate: 0.5}
+	simulated, remote := simulatedPipe(t, conditions, 1)
+
+	start := time.Now()
+	for i := range 50 {
+		if err := simulated.Send(DestroyEntityMessage{EntityID: i}, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := range 50 {
+		transport := mustRecv(t, remote)
+		if i == 0 && time.Since(start) < 10*time.Millisecond {
+			t.Fatalf("first message arrived after %s, expected at least 10ms of latency", time.Since(start))
+		}
+		if transport.CommandFrame != i {
+			t.Fatalf("got reliable message %d, want %d", transport.CommandFrame, i)
+		}
+	}
+
+	// conditions can be changed while the link is in use
+	simulated.SetConditions(NetworkConditions{Latency: 60 * time.Millisecond})
+	if simulated.Conditions().Latency != 60*time.Millisecond {
+		t.Fatalf("got latency %s after SetConditions", simulated.Conditions().Latency)
+	}
+	start = time.Now()
+	if err := simulated.Send(DestroyEntityMessage{EntityID: 50}, 50); err != nil {
+		t.Fatal(err)
+	}
+	mustRecv(t, remote)
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Fatalf("message arrived after %s, expected the new latency of 60ms", elapsed)
+	}
+}
+
+func TestSimulatedClientIncomingLatency(t *testing.T) {
+	simulated, remote := simulatedPipe(t, NetworkConditions{Latency: 30 * time.Millisecond}, 1)
+
+	start := time.Now()
+	if err := remote.Send(DestroyEntityMessage{EntityID: 1}, 1); err != nil {
+		t.Fatal(err)
+	}
+	mustRecv(t, simulated)
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("message arrived after %s, expected at least 30ms", elapsed)
+	}
+
+	remote.Close()
+	if _, err := simulated.Recv(); err == nil {
+		t.Fatal("expected the remote close to surface from Recv")
+	}
+}
+
+func TestSimulatedClientBandwidth(t *testing.T) {
+	simulated, remote := simulatedPipe(t, NetworkConditions{Bandwidth: 20000}, 1)
+
+	start := time.Now()
+	for i := range 5 {
+		if err := simulated.Send(AckPlayerJoinMessage{SerializedWorld: make([]byte, 1000)}, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range 5 {
+		mustRecv(t, remote)
+	}
+
+	// each message takes ~50ms to go out so the last one can't leave before 200ms
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("5KB went through a 20KB/s link in %s", elapsed)
+	}
+}
+
+func TestNetworkConditionsFromConfig(t *testing.T) {
+	conditions := NetworkConditionsFromConfig(settings.NetworkConditionsConfig{
+		LatencyMS:        100,
+		JitterMS:         20,
+		LossPercent:      5,
+		DuplicatePercent: 1,
+		BandwidthKbps:    64,
+	})
+	want := NetworkConditions{
+		Latency:       100 * time.Millisecond,
+		Jitter:        20 * time.Millisecond,
+		LossRate:      0.05,
+		DuplicateRate: 0.01,
+		Bandwidth:     8000,
+	}
+	if conditions != want {
+		t.Fatalf("got %+v, want %+v", conditions, want)
+	}
+}
+
+func TestSimulatedConn(t *testing.T) {
+	a, b := net.Pipe()
+	simulated := NewSimulatedConn(a, NetworkConditions{Latency: 15 * time.Millisecond, Jitter: 10 * time.Millisecond, LossRate: 0.3, DuplicateRate: 0.5}, 3)
+	defer simulated.Close()
+	defer b.Close()
+
+	var sent bytes.Buffer
+	for i := range 20 {
+		chunk := bytes.Repeat([]byte{byte(i)}, 100+i)
+		sent.Write(chunk)
+		if _, err := simulated.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	received := make([]byte, sent.Len())
+	b.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(b, received); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(received, sent.Bytes()) {
+		t.Fatal("stream was corrupted by the simulated link")
+	}
+
+	simulated.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, err := simulated.Read(make([]byte, 1))
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected a timeout reading past the deadline, got %v", err)
+	}
+}
+
+func mustRecv(t *testing.T, c IzzetClient) MessageTransport {
+	t.Helper()
+	r := recvWithTimeout(c, 5*time.Second)
+	if r.timedOut {
+		t.Fatal("timed out waiting for a message")
+	}
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	return r.transport
+}
@@ -1,10 +1,15 @@
 package network
 
-import "encoding/json"
-
+// ExtractMessage decodes the body of a message transport using the codec that
+// it was received with, transports constructed without a codec are assumed to be JSON
 func ExtractMessage[T any](messageTransport MessageTransport) (T, error) {
+	codec := messageTransport.codec
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
 	var message T
-	err := json.Unmarshal(messageTransport.Body, &message)
+	err := codec.Unmarshal(messageTransport.Body, &message)
 	if err != nil {
 		var empty T
 		return empty, err
@@ -7,3 +7,11 @@ type PingMessage struct {
 func (m PingMessage) Type() MessageType {
 	return MsgTypePing
 }
+
+func (m PingMessage) encodeBinary(w *wireWriter) {
+	w.writeInt64(m.UnixTime)
+}
+
+func (m *PingMessage) decodeBinary(r *wireReader) {
+	m.UnixTime = r.readInt64()
+}
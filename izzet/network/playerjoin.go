@@ -7,3 +7,11 @@ type PlayerJoinMessage struct {
 func (m PlayerJoinMessage) Type() MessageType {
 	return MsgTypePlayerJoin
 }
+
+func (m PlayerJoinMessage) encodeBinary(w *wireWriter) {
+	w.writeInt(m.PlayerID)
+}
+
+func (m *PlayerJoinMessage) decodeBinary(r *wireReader) {
+	m.PlayerID = r.readInt()
+}
@@ -1,15 +1,17 @@
 package network
 
-import (
-	"net"
-)
-
 type Player struct {
 	ID                         int
-	Connection                 net.Conn
 	InMessageChannel           chan MessageTransport
 	OutMessageChannel          chan MessageTransport
 	DisconnectChannel          chan bool
 	LastInputLocalCommandFrame int // local command frame from the client
-	Client                     IzzetClient
+	LastAckedGameStateFrame    int // global command frame of the last game state update the client applied
+	PerceivedCommandFrame      int // global command frame the client was rendering when it sent its current input
+	EntityID                   int
+	CameraEntityID             int
+	// RelevantEntities are the root entities the client has been told about,
+	// entities are created and destroyed on the client as they enter and leave
+	RelevantEntities map[int]bool
+	Client           IzzetClient
 }
@@ -24,3 +24,52 @@ type RessurectRPC struct {
 func (m RPCMessage) Type() MessageType {
 	return MsgTypeRPC
 }
+
+const (
+	rpcFlagPathfind byte = 1 << iota
+	rpcFlagCreateEntity
+	rpcFlagRessurect
+)
+
+func (m RPCMessage) encodeBinary(w *wireWriter) {
+	var flags byte
+	if m.Pathfind != nil {
+		flags |= rpcFlagPathfind
+	}
+	if m.CreateEntity != nil {
+		flags |= rpcFlagCreateEntity
+	}
+	if m.RessurectRPC != nil {
+		flags |= rpcFlagRessurect
+	}
+	w.writeByte(flags)
+
+	if m.Pathfind != nil {
+		w.writeVec3(m.Pathfind.Goal)
+	}
+	if m.CreateEntity != nil {
+		w.writeString(m.CreateEntity.EntityType)
+		w.writeBool(m.CreateEntity.Patrol)
+	}
+	if m.RessurectRPC != nil {
+		w.writeInt(m.RessurectRPC.ID)
+	}
+}
+
+func (m *RPCMessage) decodeBinary(r *wireReader) {
+	flags := r.readByte()
+	if flags&^(rpcFlagPathfind|rpcFlagCreateEntity|rpcFlagRessurect) != 0 {
+		r.fail("unknown rpc flags %b", flags)
+		return
+	}
+
+	if flags&rpcFlagPathfind != 0 {
+		m.Pathfind = &Pathfind{Goal: r.readVec3()}
+	}
+	if flags&rpcFlagCreateEntity != 0 {
+		m.CreateEntity = &CreateEntityRPC{EntityType: r.readString(), Patrol: r.readBool()}
+	}
+	if flags&rpcFlagRessurect != 0 {
+		m.RessurectRPC = &RessurectRPC{ID: r.readInt()}
+	}
+}
@@ -0,0 +1,177 @@
+package network
+
+import "fmt"
+
+// EntityStateField flags which fields of an EntityState are set. full snapshots
+// set every field while deltas only set the fields that changed from the baseline
+type EntityStateField uint8
+
+const (
+	EntityStateFieldPosition EntityStateField = 1 << iota
+	EntityStateFieldRotation
+	EntityStateFieldVelocity
+	EntityStateFieldAccumulatedVelocity
+	// EntityStateFieldFlags covers Grounded, GravityEnabled, and Deadge
+	EntityStateFieldFlags
+	// EntityStateFieldHealth covers Health and MaxHealth
+	EntityStateFieldHealth
+)
+
+const EntityStateFieldAll = EntityStateFieldPosition | EntityStateFieldRotation | EntityStateFieldVelocity |
+	EntityStateFieldAccumulatedVelocity | EntityStateFieldFlags | EntityStateFieldHealth
+
+// DiffEntityState returns the fields of current that differ from baseline
+func DiffEntityState(baseline, current EntityState) EntityStateField {
+	var changed EntityStateField
+	if current.Position != baseline.Position {
+		changed |= EntityStateFieldPosition
+	}
+	if current.Rotation != baseline.Rotation {
+		changed |= EntityStateFieldRotation
+	}
+	if current.Velocity != baseline.Velocity {
+		changed |= EntityStateFieldVelocity
+	}
+	if current.AccumulatedVelocity != baseline.AccumulatedVelocity {
+		changed |= EntityStateFieldAccumulatedVelocity
+	}
+	if current.Grounded != baseline.Grounded || current.GravityEnabled != baseline.GravityEnabled || current.Deadge != baseline.Deadge {
+		changed |= EntityStateFieldFlags
+	}
+	if current.Health != baseline.Health || current.MaxHealth != baseline.MaxHealth {
+		changed |= EntityStateFieldHealth
+	}
+	return changed
+}
+
+// ApplyEntityStateDelta overwrites the fields of baseline that are set in delta.
+// animation transitions are events rather than state so they're taken from the delta
+func ApplyEntityStateDelta(baseline, delta EntityState) EntityState {
+	result := baseline
+	result.EntityID = delta.EntityID
+	result.ChangedFields = EntityStateFieldAll
+	result.AnimationTransitions = delta.AnimationTransitions
+
+	if delta.ChangedFields&EntityStateFieldPosition != 0 {
+		result.Position = delta.Position
+	}
+	if delta.ChangedFields&EntityStateFieldRotation != 0 {
+		result.Rotation = delta.Rotation
+	}
+	if delta.ChangedFields&EntityStateFieldVelocity != 0 {
+		result.Velocity = delta.Velocity
+	}
+	if delta.ChangedFields&EntityStateFieldAccumulatedVelocity != 0 {
+		result.AccumulatedVelocity = delta.AccumulatedVelocity
+	}
+	if delta.ChangedFields&EntityStateFieldFlags != 0 {
+		result.Grounded = delta.Grounded
+		result.GravityEnabled = delta.GravityEnabled
+		result.Deadge = delta.Deadge
+	}
+	if delta.ChangedFields&EntityStateFieldHealth != 0 {
+		result.Health = delta.Health
+		result.MaxHealth = delta.MaxHealth
+	}
+	return result
+}
+
+// DeltaGameStateUpdate converts a full snapshot into a delta against baseline, the
+// entity states the remote acknowledged for baselineFrame. entities that haven't
+// changed are left out and entities missing from the snapshot are marked destroyed
+func DeltaGameStateUpdate(baseline []EntityState, baselineFrame int, update GameStateUpdateMessage) GameStateUpdateMessage {
+	baselineStates := make(map[int]EntityState, len(baseline))
+	for _, state := range baseline {
+		baselineStates[state.EntityID] = state
+	}
+
+	delta := update
+	delta.BaselineCommandFrame = baselineFrame
+	delta.EntityStates = nil
+	delta.DestroyedEntities = nil
+
+	current := make(map[int]bool, len(update.EntityStates))
+	for _, state := range update.EntityStates {
+		current[state.EntityID] = true
+
+		baselineState, ok := baselineStates[state.EntityID]
+		if !ok {
+			state.ChangedFields = EntityStateFieldAll
+			delta.EntityStates = append(delta.EntityStates, state)
+			continue
+		}
+
+		state.ChangedFields = DiffEntityState(baselineState, state)
+		if state.ChangedFields == 0 && len(state.AnimationTransitions) == 0 {
+			continue
+		}
+		delta.EntityStates = append(delta.EntityStates, state)
+	}
+
+	// destroyed entities are resent until the remote acks a baseline without them
+	// since the destroy events in any single update may be lost
+	destroyed := map[int]bool{}
+	for _, state := range baseline {
+		if !current[state.EntityID] {
+			destroyed[state.EntityID] = true
+			delta.DestroyedEntities = append(delta.DestroyedEntities, state.EntityID)
+		}
+	}
+	for _, id := range update.DestroyedEntities {
+		if !destroyed[id] {
+			delta.DestroyedEntities = append(delta.DestroyedEntities, id)
+		}
+	}
+
+	return delta
+}
+
+// ApplyGameStateDelta reconstructs the full snapshot for a delta given the entity
+// states of its baseline. full snapshots are returned as is
+func ApplyGameStateDelta(baseline []EntityState, baselineFrame int, delta GameStateUpdateMessage) (GameStateUpdateMessage, error) {
+	if delta.BaselineCommandFrame == 0 {
+		return delta, nil
+	}
+	if delta.BaselineCommandFrame != baselineFrame {
+		return GameStateUpdateMessage{}, fmt.Errorf("delta against baseline frame %d applied to baseline frame %d", delta.BaselineCommandFrame, baselineFrame)
+	}
+
+	deltaStates := make(map[int]EntityState, len(delta.EntityStates))
+	for _, state := range delta.EntityStates {
+		deltaStates[state.EntityID] = state
+	}
+	destroyed := make(map[int]bool, len(delta.DestroyedEntities))
+	for _, id := range delta.DestroyedEntities {
+		destroyed[id] = true
+	}
+
+	result := delta
+	result.BaselineCommandFrame = 0
+	result.EntityStates = make([]EntityState, 0, len(baseline)+len(delta.EntityStates))
+
+	inBaseline := make(map[int]bool, len(baseline))
+	for _, state := range baseline {
+		inBaseline[state.EntityID] = true
+		if destroyed[state.EntityID] {
+			continue
+		}
+
+		state.AnimationTransitions = nil
+		if deltaState, ok := deltaStates[state.EntityID]; ok {
+			state = ApplyEntityStateDelta(state, deltaState)
+		}
+		result.EntityStates = append(result.EntityStates, state)
+	}
+
+	for _, state := range delta.EntityStates {
+		if inBaseline[state.EntityID] {
+			continue
+		}
+		if state.ChangedFields != EntityStateFieldAll {
+			return GameStateUpdateMessage{}, fmt.Errorf("entity %d is missing from baseline frame %d but was sent as a partial delta", state.EntityID, baselineFrame)
+		}
+		result.EntityStates = append(result.EntityStates, state)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,127 @@
+package network
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+func fullState(id int, position mgl64.Vec3) EntityState {
+	return EntityState{
+		EntityID:       id,
+		ChangedFields:  EntityStateFieldAll,
+		Position:       position,
+		Rotation:       mgl64.QuatIdent(),
+		GravityEnabled: true,
+		Health:         100,
+		MaxHealth:      100,
+	}
+}
+
+func TestDeltaGameStateUpdateRoundTrip(t *testing.T) {
+	baseline := []EntityState{
+		fullState(1, mgl64.Vec3{0, 0, 0}),
+		fullState(2, mgl64.Vec3{5, 0, 5}),
+		fullState(3, mgl64.Vec3{-5, 0, -5}),
+	}
+
+	moved := fullState(1, mgl64.Vec3{0.5, 0, 0})
+	moved.Velocity = mgl64.Vec3{1, 0, 0}
+	dead := fullState(3, mgl64.Vec3{-5, 0, -5})
+	dead.Deadge = true
+	dead.Health = 0
+	spawned := fullState(4, mgl64.Vec3{1, 2, 3})
+
+	update := GameStateUpdateMessage{
+		EntityStates:          []EntityState{moved, fullState(2, mgl64.Vec3{5, 0, 5}), dead, spawned},
+		LastInputCommandFrame: 95,
+		GlobalCommandFrame:    110,
+	}
+
+	delta := DeltaGameStateUpdate(baseline, 100, update)
+	if delta.BaselineCommandFrame != 100 {
+		t.Fatalf("got baseline frame %d, want 100", delta.BaselineCommandFrame)
+	}
+
+	changed := map[int]EntityStateField{}
+	for _, state := range delta.EntityStates {
+		changed[state.EntityID] = state.ChangedFields
+	}
+	want := map[int]EntityStateField{
+		1: EntityStateFieldPosition | EntityStateFieldVelocity,
+		3: EntityStateFieldFlags | EntityStateFieldHealth,
+		4: EntityStateFieldAll,
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Fatalf("got changed fields %v, want %v", changed, want)
+	}
+
+	// send the delta through the binary codec so only the changed fields survive
+	body, err := BinaryCodec{}.Marshal(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received GameStateUpdateMessage
+	if err := (BinaryCodec{}).Unmarshal(body, &received); err != nil {
+		t.Fatal(err)
+	}
+
+	reconstructed, err := ApplyGameStateDelta(baseline, 100, received)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reconstructed.EntityStates, update.EntityStates) {
+		t.Fatalf("reconstructed states mismatch\n got: %+v\nwant: %+v", reconstructed.EntityStates, update.EntityStates)
+	}
+	if reconstructed.GlobalCommandFrame != 110 || reconstructed.LastInputCommandFrame != 95 || reconstructed.BaselineCommandFrame != 0 {
+		t.Fatalf("unexpected reconstructed header %+v", reconstructed)
+	}
+}
+
+func TestDeltaGameStateUpdateDestroyedEntities(t *testing.T) {
+	baseline := []EntityState{
+		fullState(1, mgl64.Vec3{}),
+		fullState(2, mgl64.Vec3{}),
+	}
+	update := GameStateUpdateMessage{
+		EntityStates:      []EntityState{fullState(1, mgl64.Vec3{})},
+		DestroyedEntities: []int{2, 7},
+	}
+
+	delta := DeltaGameStateUpdate(baseline, 10, update)
+	if len(delta.EntityStates) != 0 {
+		t.Fatalf("expected unchanged entities to be left out, got %+v", delta.EntityStates)
+	}
+	slices.Sort(delta.DestroyedEntities)
+	if !reflect.DeepEqual(delta.DestroyedEntities, []int{2, 7}) {
+		t.Fatalf("got destroyed entities %v, want [2 7]", delta.DestroyedEntities)
+	}
+
+	reconstructed, err := ApplyGameStateDelta(baseline, 10, delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reconstructed.EntityStates) != 1 || reconstructed.EntityStates[0].EntityID != 1 {
+		t.Fatalf("expected only entity 1 to remain, got %+v", reconstructed.EntityStates)
+	}
+}
+
+func TestApplyGameStateDeltaFullSnapshot(t *testing.T) {
+	update := GameStateUpdateMessage{EntityStates: []EntityState{fullState(1, mgl64.Vec3{1, 1, 1})}, GlobalCommandFrame: 20}
+	reconstructed, err := ApplyGameStateDelta(nil, 0, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reconstructed, update) {
+		t.Fatalf("expected full snapshots to be returned as is, got %+v", reconstructed)
+	}
+}
+
+func TestApplyGameStateDeltaWrongBaseline(t *testing.T) {
+	delta := DeltaGameStateUpdate(nil, 10, GameStateUpdateMessage{GlobalCommandFrame: 20})
+	if _, err := ApplyGameStateDelta(nil, 0, delta); err == nil {
+		t.Fatal("expected an error applying a delta to the wrong baseline")
+	}
+}
@@ -0,0 +1,161 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+type TransportType uint8
+
+const (
+	TransportTypeTCP TransportType = 1
+	TransportTypeUDP TransportType = 2
+)
+
+func (t TransportType) String() string {
+	switch t {
+	case TransportTypeTCP:
+		return "tcp"
+	case TransportTypeUDP:
+		return "udp"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(t))
+}
+
+// ParseTransportType maps the transport name used in config.json to a TransportType
+func ParseTransportType(name string) (TransportType, error) {
+	switch strings.ToLower(name) {
+	case "tcp", "":
+		return TransportTypeTCP, nil
+	case "udp":
+		return TransportTypeUDP, nil
+	}
+	return 0, fmt.Errorf("unknown network transport %q", name)
+}
+
+// Listener accepts clients that have completed the handshake. handshake failures
+// are returned from Accept without closing the listener, once the listener is
+// closed Accept returns net.ErrClosed
+type Listener interface {
+	Accept() (IzzetClient, error)
+	Close() error
+	Addr() net.Addr
+}
+
+func Listen(transportType TransportType, address string) (Listener, error) {
+	switch transportType {
+	case TransportTypeTCP:
+		return listenTCP(address)
+	case TransportTypeUDP:
+		return ListenUDP(address)
+	}
+	return nil, fmt.Errorf("unsupported transport %s", transportType)
+}
+
+// Dial connects to a server and performs the handshake, requesting codecType
+func Dial(transportType TransportType, address string, codecType CodecType) (IzzetClient, error) {
+	switch transportType {
+	case TransportTypeTCP:
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		codec, err := ClientHandshake(conn, codecType)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return NewClient(conn, codec), nil
+	case TransportTypeUDP:
+		return DialUDP(address, codecType)
+	}
+	return nil, fmt.Errorf("unsupported transport %s", transportType)
+}
+
+type acceptResult struct {
+	client IzzetClient
+	err    error
+}
+
+type tcpListener struct {
+	listener  net.Listener
+	accepted  chan acceptResult
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func listenTCP(address string) (*tcpListener, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &tcpListener{
+		listener: listener,
+		accepted: make(chan acceptResult),
+		done:     make(chan struct{}),
+	}
+	go l.acceptLoop()
+	return l, nil
+}
+
+func (l *tcpListener) acceptLoop() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) || !l.push(acceptResult{err: err}) {
+				return
+			}
+			continue
+		}
+
+		// handshake off of the accept loop so that a slow or misbehaving client
+		// doesn't block other connections
+		go func(conn net.Conn) {
+			codec, err := ServerHandshake(conn)
+			if err != nil {
+				conn.Close()
+				l.push(acceptResult{err: fmt.Errorf("failed handshake with %s: %w", conn.RemoteAddr(), err)})
+				return
+			}
+			if !l.push(acceptResult{client: NewClient(conn, codec)}) {
+				conn.Close()
+			}
+		}(conn)
+	}
+}
+
+// push hands a result to Accept, returning false if the listener has been closed
+func (l *tcpListener) push(result acceptResult) bool {
+	select {
+	case l.accepted <- result:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *tcpListener) Accept() (IzzetClient, error) {
+	select {
+	case result := <-l.accepted:
+		return result.client, result.err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *tcpListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.done)
+		err = l.listener.Close()
+	})
+	return err
+}
+
+func (l *tcpListener) Addr() net.Addr {
+	return l.listener.Addr()
+}
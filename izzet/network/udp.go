@@ -0,0 +1,871 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// udp packets are laid out as
+//
+//	uint32 protocol id (big endian, as are all fixed width integers below)
+//	uint8  packet type
+//
+// connect and accept packets are followed by a handshake. data packets follow with
+//
+//	uint16 packet sequence
+//	uint16 ack, the most recent packet sequence received from the remote
+//	uint32 ack bits, bit n is set if packet sequence ack-1-n was received
+//	uint8  flags, packetFlagAcks is set once the ack fields are valid
+//	uint8  message count
+//
+// and then each message
+//
+//	uint8  channel
+//	uint16 reliable id, fragment index and fragment count (reliable channel only)
+//	uvarint payload length followed by the payload
+//
+// payloads are binary frames without their length prefix. reliable messages that
+// don't fit in a single packet are split into fragments that each take a reliable
+// id, the receiver concatenates fragments back into a frame as they're delivered
+const udpProtocolID uint32 = 0x495a5550
+
+// ErrReceiverStalled is returned from Recv once a connection has been closed for
+// not keeping up with its incoming reliable messages
+var ErrReceiverStalled = errors.New("receiver stalled, incoming message buffer is full")
+
+type packetType uint8
+
+const (
+	packetTypeConnect packetType = iota + 1
+	packetTypeAccept
+	packetTypeData
+	packetTypeDisconnect
+)
+
+// packetFlagAcks marks the ack and ack bits of a data packet as valid. they're
+// meaningless until the sender has received a packet
+const packetFlagAcks uint8 = 1 << 0
+
+type channel uint8
+
+const (
+	// channelUnreliable messages are sent once and dropped if they arrive after a
+	// more recent unreliable message
+	channelUnreliable channel = iota
+	// channelReliable messages are resent until acked and delivered in order
+	channelReliable
+)
+
+const (
+	// maxUDPPacketSize keeps packets under typical MTUs, except for unreliable
+	// messages which are never fragmented
+	maxUDPPacketSize   int = 1200
+	maxUDPDatagramSize int = 65507
+	dataPacketHeader   int = 4 + 1 + 2 + 2 + 4 + 1 + 1
+	reliableEntrySize  int = 1 + 2 + 2 + 2 + 3
+
+	reliableFragmentSize   int = 1024
+	maxReliableInFlight    int = 256
+	reliableReceiveWindow  int = 1024
+	sentPacketBufferSize   int = 1024
+	maxMessagesPerPacket   int = 255
+	reliableResendInterval     = 100 * time.Millisecond
+
+	udpTickInterval         = 10 * time.Millisecond
+	udpKeepAliveInterval    = 100 * time.Millisecond
+	udpConnectionTimeout    = 10 * time.Second
+	udpConnectRetryInterval = 250 * time.Millisecond
+)
+
+// channelForMessageType picks the channel a message is sent on. inputs and state
+// updates are superseded every frame so there's no point resending them
+func channelForMessageType(messageType MessageType) channel {
+	switch messageType {
+	case MsgTypePlayerInput, MsgTypeGameStateUpdate, MsgTypePing:
+		return channelUnreliable
+	}
+	return channelReliable
+}
+
+// sequenceGreater reports whether sequence a is more recent than b, accounting
+// for wraparound
+func sequenceGreater(a, b uint16) bool {
+	return a != b && a-b < 1<<15
+}
+
+func appendControlPacket(buf []byte, t packetType, h handshake) []byte {
+	w := &wireWriter{buf: buf}
+	w.writeUint32(udpProtocolID)
+	w.writeByte(byte(t))
+	var hs bytes.Buffer
+	writeHandshake(&hs, h)
+	w.buf = append(w.buf, hs.Bytes()...)
+	return w.Bytes()
+}
+
+// parsePacketHeader returns the packet type and the remainder of the packet,
+// packets that don't belong to this protocol are rejected
+func parsePacketHeader(packet []byte) (packetType, []byte, bool) {
+	r := newWireReader(packet)
+	protocolID := r.readUint32()
+	t := packetType(r.readByte())
+	if r.Err() != nil || protocolID != udpProtocolID {
+		return 0, nil, false
+	}
+	if t < packetTypeConnect || t > packetTypeDisconnect {
+		return 0, nil, false
+	}
+	return t, r.buf, true
+}
+
+type sentPacket struct {
+	sequence  uint16
+	valid     bool
+	fragments []*outgoingFragment
+}
+
+type outgoingFragment struct {
+	id       uint16
+	index    uint16
+	count    uint16
+	payload  []byte
+	lastSent time.Time
+	acked    bool
+}
+
+type incomingFragment struct {
+	index   uint16
+	count   uint16
+	payload []byte
+}
+
+type recvResult struct {
+	transport MessageTransport
+	err       error
+}
+
+// udpConnection is an IzzetClient over a packet connection. clients own their
+// packet connection while server side connections share the listener's
+type udpConnection struct {
+	packetConn net.PacketConn
+	remoteAddr net.Addr
+	codec      Codec
+
+	incoming  chan recvResult
+	done      chan struct{}
+	closeOnce sync.Once
+	onClose   func()
+
+	// acceptResponse is resent if the client retries its connect packet
+	acceptResponse handshake
+
+	mu          sync.Mutex
+	closeErr    error
+	lastReceive time.Time
+	lastSend    time.Time
+	ackPending  bool
+
+	localSequence uint16
+	sentPackets   [sentPacketBufferSize]sentPacket
+
+	receivedAnyPacket bool
+	remoteSequence    uint16
+	remoteAckBits     uint32
+
+	receivedUnreliable     bool
+	lastUnreliableSequence uint16
+
+	nextReliableID uint16
+	reliableOutbox []*outgoingFragment
+
+	nextDeliverID  uint16
+	reliableInbox  map[uint16]incomingFragment
+	reassembly     []byte
+	reassemblyNext uint16
+}
+
+func newUDPConnection(packetConn net.PacketConn, remoteAddr net.Addr, codec Codec) *udpConnection {
+	c := &udpConnection{
+		packetConn:    packetConn,
+		remoteAddr:    remoteAddr,
+		codec:         codec,
+		incoming:      make(chan recvResult, 1024),
+		done:          make(chan struct{}),
+		lastReceive:   time.Now(),
+		reliableInbox: map[uint16]incomingFragment{},
+	}
+	go c.tick()
+	return c
+}
+
+func (c *udpConnection) Send(message Message, frame int) error {
+	body, err := c.codec.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	messageTransport := MessageTransport{
+		MessageType:  message.Type(),
+		Timestamp:    time.Now(),
+		Body:         body,
+		CommandFrame: frame,
+	}
+
+	encoded, err := appendBinaryFrame(nil, messageTransport)
+	if err != nil {
+		return err
+	}
+	payload := encoded[4:]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closeErr != nil {
+		return net.ErrClosed
+	}
+
+	ch := channelForMessageType(message.Type())
+	// unreliable messages aren't fragmented, anything that can't fit in a single
+	// datagram goes out on the reliable channel instead
+	if ch == channelUnreliable && dataPacketHeader+1+3+len(payload) > maxUDPDatagramSize {
+		ch = channelReliable
+	}
+
+	if ch == channelReliable {
+		if err := c.queueReliable(payload); err != nil {
+			return err
+		}
+		return c.flush(time.Now(), nil, false)
+	}
+	return c.flush(time.Now(), payload, false)
+}
+
+func (c *udpConnection) Recv() (MessageTransport, error) {
+	// drain anything that was received before the connection closed
+	select {
+	case result := <-c.incoming:
+		return result.transport, result.err
+	default:
+	}
+
+	select {
+	case result := <-c.incoming:
+		return result.transport, result.err
+	case <-c.done:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return MessageTransport{}, c.closeErr
+	}
+}
+
+func (c *udpConnection) Close() {
+	c.disconnect(net.ErrClosed)
+}
+
+// disconnect tells the remote the connection is closing and shuts it down, err
+// is returned from subsequent calls to Recv
+func (c *udpConnection) disconnect(err error) {
+	c.mu.Lock()
+	if c.closeErr == nil {
+		// best effort, the remote times out the connection if these are all lost
+		w := &wireWriter{}
+		w.writeUint32(udpProtocolID)
+		w.writeByte(byte(packetTypeDisconnect))
+		for range 3 {
+			c.packetConn.WriteTo(w.Bytes(), c.remoteAddr)
+		}
+	}
+	c.mu.Unlock()
+	c.shutdown(err)
+}
+
+// shutdown closes the connection, err is returned from subsequent calls to Recv
+func (c *udpConnection) shutdown(err error) {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.closeErr = err
+		c.mu.Unlock()
+		close(c.done)
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+}
+
+func (c *udpConnection) queueReliable(payload []byte) error {
+	count := (len(payload) + reliableFragmentSize - 1) / reliableFragmentSize
+	if count > 1<<16-1 {
+		return fmt.Errorf("message of %d bytes is too large to send reliably", len(payload))
+	}
+
+	for i := range count {
+		end := min((i+1)*reliableFragmentSize, len(payload))
+		c.reliableOutbox = append(c.reliableOutbox, &outgoingFragment{
+			id:      c.nextReliableID,
+			index:   uint16(i),
+			count:   uint16(count),
+			payload: payload[i*reliableFragmentSize : end],
+		})
+		c.nextReliableID++
+	}
+	return nil
+}
+
+// flush writes packets until there are no reliable fragments due to be sent.
+// unreliable is an optional payload to include in the first packet and keepAlive
+// forces a packet to be written so the remote receives our acks
+func (c *udpConnection) flush(now time.Time, unreliable []byte, keepAlive bool) error {
+	for len(c.reliableOutbox) > 0 && c.reliableOutbox[0].acked {
+		c.reliableOutbox = c.reliableOutbox[1:]
+	}
+	inFlight := c.reliableOutbox[:min(len(c.reliableOutbox), maxReliableInFlight)]
+
+	for first := true; ; first = false {
+		sequence := c.localSequence
+		w := &wireWriter{}
+		w.writeUint32(udpProtocolID)
+		w.writeByte(byte(packetTypeData))
+		w.writeUint16(sequence)
+		w.writeUint16(c.remoteSequence)
+		w.writeUint32(c.remoteAckBits)
+		var flags uint8
+		if c.receivedAnyPacket {
+			flags |= packetFlagAcks
+		}
+		w.writeByte(flags)
+		countOffset := len(w.buf)
+		w.writeByte(0)
+
+		messageCount := 0
+		if unreliable != nil {
+			w.writeByte(byte(channelUnreliable))
+			w.writeBytes(unreliable)
+			unreliable = nil
+			messageCount++
+		}
+
+		var fragments []*outgoingFragment
+		for _, f := range inFlight {
+			if f.acked || (!f.lastSent.IsZero() && now.Sub(f.lastSent) < reliableResendInterval) {
+				continue
+			}
+			if messageCount == maxMessagesPerPacket || (messageCount > 0 && len(w.buf)+reliableEntrySize+len(f.payload) > maxUDPPacketSize) {
+				break
+			}
+			w.writeByte(byte(channelReliable))
+			w.writeUint16(f.id)
+			w.writeUint16(f.index)
+			w.writeUint16(f.count)
+			w.writeBytes(f.payload)
+			f.lastSent = now
+			fragments = append(fragments, f)
+			messageCount++
+		}
+
+		if messageCount == 0 && !(first && keepAlive) {
+			return nil
+		}
+
+		packet := w.Bytes()
+		packet[countOffset] = byte(messageCount)
+		c.sentPackets[int(sequence)%sentPacketBufferSize] = sentPacket{sequence: sequence, valid: true, fragments: fragments}
+		c.localSequence++
+
+		if _, err := c.packetConn.WriteTo(packet, c.remoteAddr); err != nil {
+			return err
+		}
+		c.lastSend = now
+		c.ackPending = false
+	}
+}
+
+// tick resends reliable fragments, keeps acks flowing when there's nothing else
+// to send and times out the connection if the remote goes quiet
+func (c *udpConnection) tick() {
+	ticker := time.NewTicker(udpTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			c.mu.Lock()
+			if now.Sub(c.lastReceive) > udpConnectionTimeout {
+				c.mu.Unlock()
+				c.shutdown(io.EOF)
+				return
+			}
+			keepAlive := c.ackPending || now.Sub(c.lastSend) >= udpKeepAliveInterval
+			c.flush(now, nil, keepAlive)
+			c.mu.Unlock()
+		}
+	}
+}
+
+type receivedMessage struct {
+	channel channel
+	id      uint16
+	index   uint16
+	count   uint16
+	payload []byte
+}
+
+func (c *udpConnection) handleDataPacket(body []byte) {
+	r := newWireReader(body)
+	sequence := r.readUint16()
+	ack := r.readUint16()
+	ackBits := r.readUint32()
+	flags := r.readByte()
+	messageCount := int(r.readByte())
+
+	messages := make([]receivedMessage, 0, messageCount)
+	for range messageCount {
+		m := receivedMessage{channel: channel(r.readByte())}
+		switch m.channel {
+		case channelUnreliable:
+		case channelReliable:
+			m.id = r.readUint16()
+			m.index = r.readUint16()
+			m.count = r.readUint16()
+			if m.index >= m.count {
+				r.fail("fragment index %d out of range of %d", m.index, m.count)
+			}
+		default:
+			r.fail("unknown channel %d", m.channel)
+		}
+		m.payload = r.readBytes()
+		messages = append(messages, m)
+	}
+	if r.finish() != nil {
+		return
+	}
+
+	var delivered []recvResult
+	// reliable is whether each delivered message came over the reliable channel
+	var reliable []bool
+
+	c.mu.Lock()
+	c.lastReceive = time.Now()
+	duplicate := c.recordReceived(sequence)
+	if flags&packetFlagAcks != 0 {
+		c.processAcks(ack, ackBits)
+	}
+
+	for _, m := range messages {
+		switch m.channel {
+		case channelUnreliable:
+			if duplicate || (c.receivedUnreliable && !sequenceGreater(sequence, c.lastUnreliableSequence)) {
+				continue
+			}
+			c.receivedUnreliable = true
+			c.lastUnreliableSequence = sequence
+			delivered = append(delivered, c.decode(m.payload))
+		case channelReliable:
+			c.ackPending = true
+			delivered = c.receiveFragment(m, delivered)
+		}
+		for len(reliable) < len(delivered) {
+			reliable = append(reliable, m.channel == channelReliable)
+		}
+	}
+	c.mu.Unlock()
+
+	// server side connections are fed from the listener's read loop, so a
+	// connection that isn't draining its messages can't be waited on without
+	// stalling every other connection. unreliable messages are dropped, reliable
+	// messages have already been acked so the connection is closed instead
+	for i, result := range delivered {
+		select {
+		case c.incoming <- result:
+		case <-c.done:
+			return
+		default:
+			if reliable[i] {
+				c.disconnect(ErrReceiverStalled)
+				return
+			}
+		}
+	}
+}
+
+func (c *udpConnection) decode(frame []byte) recvResult {
+	messageTransport, err := decodeBinaryFrame(frame)
+	if err != nil {
+		return recvResult{err: err}
+	}
+	messageTransport.codec = c.codec
+	return recvResult{transport: messageTransport}
+}
+
+// recordReceived updates the ack state for a received packet sequence, returning
+// true if the packet was already received or is too old to tell
+func (c *udpConnection) recordReceived(sequence uint16) bool {
+	if !c.receivedAnyPacket {
+		c.receivedAnyPacket = true
+		c.remoteSequence = sequence
+		c.remoteAckBits = 0
+		return false
+	}
+
+	if sequenceGreater(sequence, c.remoteSequence) {
+		shift := sequence - c.remoteSequence
+		if shift <= 32 {
+			c.remoteAckBits = c.remoteAckBits<<shift | 1<<(shift-1)
+		} else {
+			c.remoteAckBits = 0
+		}
+		c.remoteSequence = sequence
+		return false
+	}
+
+	distance := c.remoteSequence - sequence
+	if distance == 0 || distance > 32 {
+		return true
+	}
+	bit := uint32(1) << (distance - 1)
+	duplicate := c.remoteAckBits&bit != 0
+	c.remoteAckBits |= bit
+	return duplicate
+}
+
+func (c *udpConnection) processAcks(ack uint16, ackBits uint32) {
+	c.ackPacket(ack)
+	for i := range 32 {
+		if ackBits&(1<<i) != 0 {
+			c.ackPacket(ack - 1 - uint16(i))
+		}
+	}
+}
+
+func (c *udpConnection) ackPacket(sequence uint16) {
+	sent := &c.sentPackets[int(sequence)%sentPacketBufferSize]
+	if !sent.valid || sent.sequence != sequence {
+		return
+	}
+	for _, f := range sent.fragments {
+		f.acked = true
+	}
+	*sent = sentPacket{}
+}
+
+// receiveFragment buffers a reliable fragment and delivers any complete messages
+// that are now in order
+func (c *udpConnection) receiveFragment(m receivedMessage, delivered []recvResult) []recvResult {
+	// already delivered fragments land outside of the window since ids wrap
+	if int(m.id-c.nextDeliverID) >= reliableReceiveWindow {
+		return delivered
+	}
+	if _, ok := c.reliableInbox[m.id]; ok {
+		return delivered
+	}
+	c.reliableInbox[m.id] = incomingFragment{index: m.index, count: m.count, payload: m.payload}
+
+	for {
+		f, ok := c.reliableInbox[c.nextDeliverID]
+		if !ok {
+			return delivered
+		}
+		delete(c.reliableInbox, c.nextDeliverID)
+		c.nextDeliverID++
+
+		if f.index == 0 {
+			c.reassembly = c.reassembly[:0]
+		} else if f.index != c.reassemblyNext {
+			c.reassembly = c.reassembly[:0]
+			c.reassemblyNext = 0
+			delivered = append(delivered, recvResult{err: fmt.Errorf("%w: unexpected fragment %d of %d", ErrMalformedMessage, f.index, f.count)})
+			continue
+		}
+
+		c.reassembly = append(c.reassembly, f.payload...)
+		c.reassemblyNext = f.index + 1
+		if c.reassemblyNext == f.count {
+			delivered = append(delivered, c.decode(c.reassembly))
+			c.reassembly = c.reassembly[:0]
+			c.reassemblyNext = 0
+		}
+	}
+}
+
+// readLoop reads packets for a client side connection which owns its packet connection
+func (c *udpConnection) readLoop() {
+	buf := make([]byte, maxUDPDatagramSize)
+	for {
+		n, addr, err := c.packetConn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			select {
+			case <-c.done:
+				return
+			default:
+				continue
+			}
+		}
+
+		if addr.String() != c.remoteAddr.String() {
+			continue
+		}
+
+		t, body, ok := parsePacketHeader(buf[:n])
+		if !ok {
+			continue
+		}
+		switch t {
+		case packetTypeData:
+			c.handleDataPacket(body)
+		case packetTypeDisconnect:
+			c.shutdown(io.EOF)
+		}
+	}
+}
+
+// DialUDP connects to a UDP server and performs the handshake, retrying the
+// connect packet until the server responds or the handshake times out
+func DialUDP(address string, codecType CodecType) (IzzetClient, error) {
+	remoteAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	packetConn, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dialUDP(packetConn, remoteAddr, codecType)
+	if err != nil {
+		packetConn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func dialUDP(packetConn net.PacketConn, remoteAddr net.Addr, codecType CodecType) (*udpConnection, error) {
+	codec, err := NewCodec(codecType)
+	if err != nil {
+		return nil, err
+	}
+
+	connect := appendControlPacket(nil, packetTypeConnect, handshake{Version: ProtocolVersion, Codec: codecType})
+	deadline := time.Now().Add(handshakeTimeout)
+	defer packetConn.SetReadDeadline(time.Time{})
+
+	buf := make([]byte, maxUDPDatagramSize)
+	for time.Now().Before(deadline) {
+		if _, err := packetConn.WriteTo(connect, remoteAddr); err != nil {
+			return nil, err
+		}
+
+		retry := time.Now().Add(udpConnectRetryInterval)
+		if retry.After(deadline) {
+			retry = deadline
+		}
+		packetConn.SetReadDeadline(retry)
+
+		for {
+			n, addr, err := packetConn.ReadFrom(buf)
+			if err != nil {
+				if errors.Is(err, os.ErrDeadlineExceeded) {
+					break
+				}
+				return nil, err
+			}
+
+			if addr.String() != remoteAddr.String() {
+				continue
+			}
+			t, body, ok := parsePacketHeader(buf[:n])
+			if !ok || t != packetTypeAccept {
+				continue
+			}
+			response, err := readHandshake(bytes.NewReader(body))
+			if err != nil {
+				continue
+			}
+			if err := checkHandshakeResponse(response, codecType); err != nil {
+				return nil, err
+			}
+
+			packetConn.SetReadDeadline(time.Time{})
+			conn := newUDPConnection(packetConn, remoteAddr, codec)
+			conn.onClose = func() { packetConn.Close() }
+			go conn.readLoop()
+			return conn, nil
+		}
+	}
+
+	return nil, fmt.Errorf("timed out connecting to %s", remoteAddr)
+}
+
+// UDPListener accepts UDP clients on a single packet connection, demultiplexing
+// incoming packets to connections by their remote address
+type UDPListener struct {
+	packetConn net.PacketConn
+
+	accepted  chan acceptResult
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu          sync.Mutex
+	connections map[string]*udpConnection
+}
+
+func ListenUDP(address string) (*UDPListener, error) {
+	packetConn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	return newUDPListener(packetConn), nil
+}
+
+func newUDPListener(packetConn net.PacketConn) *UDPListener {
+	l := &UDPListener{
+		packetConn:  packetConn,
+		accepted:    make(chan acceptResult, 16),
+		done:        make(chan struct{}),
+		connections: map[string]*udpConnection{},
+	}
+	go l.readLoop()
+	return l
+}
+
+func (l *UDPListener) readLoop() {
+	buf := make([]byte, maxUDPDatagramSize)
+	for {
+		n, addr, err := l.packetConn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			select {
+			case <-l.done:
+				return
+			default:
+				continue
+			}
+		}
+
+		t, body, ok := parsePacketHeader(buf[:n])
+		if !ok {
+			continue
+		}
+
+		key := addr.String()
+		l.mu.Lock()
+		conn := l.connections[key]
+		l.mu.Unlock()
+
+		switch t {
+		case packetTypeConnect:
+			l.handleConnect(addr, conn, body)
+		case packetTypeData:
+			if conn != nil {
+				conn.handleDataPacket(body)
+			}
+		case packetTypeDisconnect:
+			if conn != nil {
+				conn.shutdown(io.EOF)
+			}
+		}
+	}
+}
+
+func (l *UDPListener) handleConnect(addr net.Addr, conn *udpConnection, body []byte) {
+	// the client didn't receive our accept and retried
+	if conn != nil {
+		l.packetConn.WriteTo(appendControlPacket(nil, packetTypeAccept, conn.acceptResponse), addr)
+		return
+	}
+
+	request, err := readHandshake(bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	codec, response, err := acceptHandshake(request)
+	l.packetConn.WriteTo(appendControlPacket(nil, packetTypeAccept, response), addr)
+	if err != nil {
+		// dropped when the accept queue is full so that a peer spamming bad
+		// handshakes can't pile up blocked goroutines
+		select {
+		case l.accepted <- acceptResult{err: fmt.Errorf("failed handshake with %s: %w", addr, err)}:
+		default:
+		}
+		return
+	}
+
+	key := addr.String()
+	conn = newUDPConnection(l.packetConn, addr, codec)
+	conn.acceptResponse = response
+	conn.onClose = func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.connections[key] == conn {
+			delete(l.connections, key)
+		}
+	}
+
+	l.mu.Lock()
+	l.connections[key] = conn
+	l.mu.Unlock()
+
+	// pushed off of the read loop so that packets for other connections keep
+	// flowing while the new connection waits to be accepted
+	go func() {
+		if !l.push(acceptResult{client: conn}) {
+			conn.Close()
+		}
+	}()
+}
+
+// push hands a result to Accept, returning false if the listener has been closed
+func (l *UDPListener) push(result acceptResult) bool {
+	select {
+	case l.accepted <- result:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *UDPListener) Accept() (IzzetClient, error) {
+	select {
+	case result := <-l.accepted:
+		return result.client, result.err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *UDPListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.done)
+
+		l.mu.Lock()
+		var connections []*udpConnection
+		for _, conn := range l.connections {
+			connections = append(connections, conn)
+		}
+		l.mu.Unlock()
+
+		for _, conn := range connections {
+			conn.Close()
+		}
+		err = l.packetConn.Close()
+	})
+	return err
+}
+
+func (l *UDPListener) Addr() net.Addr {
+	return l.packetConn.LocalAddr()
+}
@@ -0,0 +1,367 @@
+package network
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lossyPacketConn simulates a bad link by dropping and delaying outgoing packets.
+// delayed packets arrive after packets sent later so they're also reordered
+type lossyPacketConn struct {
+	net.PacketConn
+
+	mu        sync.Mutex
+	rand      *rand.Rand
+	lossRate  float64
+	delayRate float64
+}
+
+func newLossyPacketConn(t *testing.T, seed int64, lossRate float64, delayRate float64) *lossyPacketConn {
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &lossyPacketConn{
+		PacketConn: packetConn,
+		rand:       rand.New(rand.NewSource(seed)),
+		lossRate:   lossRate,
+		delayRate:  delayRate,
+	}
+}
+
+func (c *lossyPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	c.mu.Lock()
+	drop := c.rand.Float64() < c.lossRate
+	delay := c.rand.Float64() < c.delayRate
+	c.mu.Unlock()
+
+	if drop {
+		return len(p), nil
+	}
+	if delay {
+		delayed := append([]byte(nil), p...)
+		time.AfterFunc(20*time.Millisecond, func() { c.PacketConn.WriteTo(delayed, addr) })
+		return len(p), nil
+	}
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+// connectLossy sets up a server and client connection over a lossy loopback link
+func connectLossy(t *testing.T, lossRate float64, delayRate float64) (IzzetClient, IzzetClient, func()) {
+	listener := newUDPListener(newLossyPacketConn(t, 1, lossRate, delayRate))
+	clientConn := newLossyPacketConn(t, 2, lossRate, delayRate)
+
+	client, err := dialUDP(clientConn, listener.Addr(), CodecTypeBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, server, func() {
+		client.Close()
+		listener.Close()
+	}
+}
+
+type recvTimeout struct {
+	transport MessageTransport
+	err       error
+	timedOut  bool
+}
+
+func recvWithTimeout(c IzzetClient, timeout time.Duration) recvTimeout {
+	result := make(chan recvTimeout, 1)
+	go func() {
+		transport, err := c.Recv()
+		result <- recvTimeout{transport: transport, err: err}
+	}()
+	select {
+	case r := <-result:
+		return r
+	case <-time.After(timeout):
+		return recvTimeout{timedOut: true}
+	}
+}
+
+func TestSequenceGreater(t *testing.T) {
+	testCases := []struct {
+		a, b uint16
+		want bool
+	}{
+		{1, 0, true},
+		{0, 1, false},
+		{5, 5, false},
+		{0, 65535, true},
+		{65535, 0, false},
+		{100, 65500, true},
+	}
+	for _, tc := range testCases {
+		if got := sequenceGreater(tc.a, tc.b); got != tc.want {
+			t.Fatalf("sequenceGreater(%d, %d) got %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestUDPReliableChannelOverLossyLink(t *testing.T) {
+	client, server, cleanup := connectLossy(t, 0.2, 0.1)
+	defer cleanup()
+
+	// large enough to be split into many fragments
+	world := bytes.Repeat([]byte("izzet"), 40000)
+	if err := server.Send(AckPlayerJoinMessage{ProjectName: "test", PlayerID: 100000, SerializedWorld: world}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	messageCount := 200
+	for i := range messageCount {
+		if err := server.Send(CreateEntityMessage{OwnerID: i}, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := recvWithTimeout(client, 10*time.Second)
+	if r.timedOut || r.err != nil {
+		t.Fatalf("failed to receive ack player join, timed out: %v, err: %v", r.timedOut, r.err)
+	}
+	ack, err := ExtractMessage[AckPlayerJoinMessage](r.transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ack.SerializedWorld, world) {
+		t.Fatalf("serialized world mismatch, got %d bytes want %d bytes", len(ack.SerializedWorld), len(world))
+	}
+
+	for i := range messageCount {
+		r := recvWithTimeout(client, 10*time.Second)
+		if r.timedOut || r.err != nil {
+			t.Fatalf("failed to receive message %d, timed out: %v, err: %v", i, r.timedOut, r.err)
+		}
+		message, err := ExtractMessage[CreateEntityMessage](r.transport)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if message.OwnerID != i || r.transport.CommandFrame != i {
+			t.Fatalf("got message %d at frame %d, want %d", message.OwnerID, r.transport.CommandFrame, i)
+		}
+	}
+}
+
+func TestUDPUnreliableChannelOverLossyLink(t *testing.T) {
+	client, server, cleanup := connectLossy(t, 0.2, 0.1)
+	defer cleanup()
+
+	messageCount := 200
+	for i := range messageCount {
+		if err := client.Send(InputMessage{}, i); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	lastFrame := -1
+	received := 0
+	for {
+		r := recvWithTimeout(server, 500*time.Millisecond)
+		if r.timedOut {
+			break
+		}
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		if r.transport.MessageType != MsgTypePlayerInput {
+			t.Fatalf("got message type %d, want %d", r.transport.MessageType, MsgTypePlayerInput)
+		}
+		if r.transport.CommandFrame <= lastFrame {
+			t.Fatalf("got frame %d after frame %d, unreliable messages should never arrive out of order", r.transport.CommandFrame, lastFrame)
+		}
+		lastFrame = r.transport.CommandFrame
+		received++
+	}
+
+	if received == 0 || received == messageCount {
+		t.Fatalf("received %d of %d unreliable messages, expected some but not all to arrive", received, messageCount)
+	}
+}
+
+func TestUDPDisconnect(t *testing.T) {
+	client, server, cleanup := connectLossy(t, 0, 0)
+	defer cleanup()
+
+	client.Close()
+	r := recvWithTimeout(server, 5*time.Second)
+	if r.timedOut || r.err != io.EOF {
+		t.Fatalf("expected io.EOF after the client disconnected, timed out: %v, err: %v", r.timedOut, r.err)
+	}
+}
+
+func TestUDPHandshakeVersionMismatch(t *testing.T) {
+	listener := newUDPListener(newLossyPacketConn(t, 1, 0, 0))
+	defer listener.Close()
+
+	clientConn := newLossyPacketConn(t, 2, 0, 0)
+	defer clientConn.Close()
+
+	connect := appendControlPacket(nil, packetTypeConnect, handshake{Version: ProtocolVersion + 1, Codec: CodecTypeBinary})
+	if _, err := clientConn.WriteTo(connect, listener.Addr()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := listener.Accept(); err == nil {
+		t.Fatal("expected the handshake to be rejected")
+	}
+}
+
+func TestUDPFailedHandshakesDoNotBlock(t *testing.T) {
+	listener := newUDPListener(newLossyPacketConn(t, 1, 0, 0))
+	defer listener.Close()
+
+	clientConn := newLossyPacketConn(t, 2, 0, 0)
+	defer clientConn.Close()
+
+	goroutines := runtime.NumGoroutine()
+
+	// nothing accepts, the queue fills and further failures are dropped
+	connect := appendControlPacket(nil, packetTypeConnect, handshake{Version: ProtocolVersion + 1, Codec: CodecTypeBinary})
+	attempts := cap(listener.accepted) * 4
+	for range attempts {
+		if _, err := clientConn.WriteTo(connect, listener.Addr()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(listener.accepted) < cap(listener.accepted) {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued %d of %d failed handshakes", len(listener.accepted), cap(listener.accepted))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// give the read loop time to work through the remaining packets
+	time.Sleep(50 * time.Millisecond)
+
+	if n := runtime.NumGoroutine(); n > goroutines+2 {
+		t.Fatalf("expected failed handshakes not to leave goroutines behind, went from %d to %d", goroutines, n)
+	}
+}
+
+func TestUDPStalledReceiverDoesNotBlockListener(t *testing.T) {
+	listener := newUDPListener(newLossyPacketConn(t, 1, 0, 0))
+	defer listener.Close()
+
+	dial := func(seed int64) (IzzetClient, *udpConnection) {
+		client, err := dialUDP(newLossyPacketConn(t, seed, 0, 0), listener.Addr(), CodecTypeBinary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client, server.(*udpConnection)
+	}
+
+	stalledClient, stalledServer := dial(2)
+	defer stalledClient.Close()
+	client, server := dial(3)
+	defer client.Close()
+
+	// nothing reads from the stalled connection, once its buffer is full further
+	// unreliable messages are dropped
+	deadline := time.Now().Add(5 * time.Second)
+	for len(stalledServer.incoming) < cap(stalledServer.incoming) {
+		if time.Now().After(deadline) {
+			t.Fatalf("buffered %d of %d messages", len(stalledServer.incoming), cap(stalledServer.incoming))
+		}
+		for range 64 {
+			stalledClient.Send(PingMessage{UnixTime: 1}, 1)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for range 64 {
+		stalledClient.Send(PingMessage{UnixTime: 1}, 1)
+	}
+
+	if err := client.Send(PingMessage{UnixTime: 2}, 1); err != nil {
+		t.Fatal(err)
+	}
+	r := recvWithTimeout(server, 5*time.Second)
+	if r.timedOut || r.err != nil {
+		t.Fatalf("expected other connections to keep receiving, timed out: %v, err: %v", r.timedOut, r.err)
+	}
+
+	// a reliable message can't be dropped so the stalled connection is closed
+	if err := stalledClient.Send(PlayerJoinMessage{PlayerID: 1}, 1); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stalledServer.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the stalled connection to be closed")
+	}
+	for len(stalledServer.incoming) > 0 {
+		stalledServer.Recv()
+	}
+	if _, err := stalledServer.Recv(); err != ErrReceiverStalled {
+		t.Fatalf("expected ErrReceiverStalled once drained, got %v", err)
+	}
+}
+
+// dropFirstDataPacketConn drops the first data packet written to it
+type dropFirstDataPacketConn struct {
+	net.PacketConn
+
+	mu      sync.Mutex
+	dropped bool
+}
+
+func (c *dropFirstDataPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	c.mu.Lock()
+	t, _, ok := parsePacketHeader(p)
+	drop := ok && t == packetTypeData && !c.dropped
+	if drop {
+		c.dropped = true
+	}
+	c.mu.Unlock()
+
+	if drop {
+		return len(p), nil
+	}
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+func TestUDPFirstPacketLost(t *testing.T) {
+	listener := newUDPListener(&dropFirstDataPacketConn{PacketConn: newLossyPacketConn(t, 1, 0, 0)})
+	defer listener.Close()
+
+	client, err := dialUDP(newLossyPacketConn(t, 2, 0, 0), listener.Addr(), CodecTypeBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the client hasn't received anything when it sends its first packets, those
+	// must not be taken as acks of the server's first packet
+	if err := server.Send(AckPlayerJoinMessage{ProjectName: "test", PlayerID: 1}, 0); err != nil {
+		t.Fatal(err)
+	}
+	r := recvWithTimeout(client, 5*time.Second)
+	if r.timedOut || r.err != nil {
+		t.Fatalf("expected the lost reliable message to be resent, timed out: %v, err: %v", r.timedOut, r.err)
+	}
+	if r.transport.MessageType != MsgTypeAckPlayerJoin {
+		t.Fatalf("expected an ack player join message, got %v", r.transport.MessageType)
+	}
+}
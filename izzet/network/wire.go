@@ -0,0 +1,261 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+var ErrMalformedMessage = errors.New("malformed message")
+
+// wireWriter appends primitives to a byte buffer, it's used by the binary codec
+// to encode message bodies field by field
+type wireWriter struct {
+	buf []byte
+}
+
+func (w *wireWriter) Bytes() []byte {
+	return w.buf
+}
+
+func (w *wireWriter) writeUvarint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *wireWriter) writeInt(v int) {
+	w.buf = binary.AppendVarint(w.buf, int64(v))
+}
+
+func (w *wireWriter) writeInt64(v int64) {
+	w.buf = binary.AppendVarint(w.buf, v)
+}
+
+func (w *wireWriter) writeByte(v byte) {
+	w.buf = append(w.buf, v)
+}
+
+func (w *wireWriter) writeBool(v bool) {
+	if v {
+		w.buf = append(w.buf, 1)
+	} else {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+func (w *wireWriter) writeUint16(v uint16) {
+	w.buf = binary.BigEndian.AppendUint16(w.buf, v)
+}
+
+func (w *wireWriter) writeUint32(v uint32) {
+	w.buf = binary.BigEndian.AppendUint32(w.buf, v)
+}
+
+func (w *wireWriter) writeFloat64(v float64) {
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(v))
+}
+
+func (w *wireWriter) writeVec2(v mgl64.Vec2) {
+	w.writeFloat64(v[0])
+	w.writeFloat64(v[1])
+}
+
+func (w *wireWriter) writeVec3(v mgl64.Vec3) {
+	w.writeFloat64(v[0])
+	w.writeFloat64(v[1])
+	w.writeFloat64(v[2])
+}
+
+func (w *wireWriter) writeQuat(q mgl64.Quat) {
+	w.writeFloat64(q.W)
+	w.writeVec3(q.V)
+}
+
+func (w *wireWriter) writeString(s string) {
+	w.writeUvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *wireWriter) writeBytes(b []byte) {
+	w.writeUvarint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+// wireReader reads primitives written by wireWriter. the first error encountered
+// is sticky, subsequent reads return zero values so decoders can read all their
+// fields and check Err() once at the end
+type wireReader struct {
+	buf []byte
+	err error
+}
+
+func newWireReader(buf []byte) *wireReader {
+	return &wireReader{buf: buf}
+}
+
+func (r *wireReader) Err() error {
+	return r.err
+}
+
+func (r *wireReader) fail(format string, args ...any) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: %s", ErrMalformedMessage, fmt.Sprintf(format, args...))
+	}
+}
+
+func (r *wireReader) readUvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail("bad uvarint")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *wireReader) readInt64() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.fail("bad varint")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *wireReader) readInt() int {
+	v := r.readInt64()
+	if v > math.MaxInt || v < math.MinInt {
+		r.fail("int %d overflows", v)
+		return 0
+	}
+	return int(v)
+}
+
+func (r *wireReader) readByte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) < 1 {
+		r.fail("unexpected end of buffer reading byte")
+		return 0
+	}
+	v := r.buf[0]
+	r.buf = r.buf[1:]
+	return v
+}
+
+func (r *wireReader) readBool() bool {
+	v := r.readByte()
+	if v > 1 {
+		r.fail("bad bool value %d", v)
+		return false
+	}
+	return v == 1
+}
+
+func (r *wireReader) readUint16() uint16 {
+	raw := r.readRaw(2)
+	if raw == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(raw)
+}
+
+func (r *wireReader) readUint32() uint32 {
+	raw := r.readRaw(4)
+	if raw == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(raw)
+}
+
+func (r *wireReader) readFloat64() float64 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) < 8 {
+		r.fail("unexpected end of buffer reading float64")
+		return 0
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(r.buf))
+	r.buf = r.buf[8:]
+	return v
+}
+
+func (r *wireReader) readVec2() mgl64.Vec2 {
+	return mgl64.Vec2{r.readFloat64(), r.readFloat64()}
+}
+
+func (r *wireReader) readVec3() mgl64.Vec3 {
+	return mgl64.Vec3{r.readFloat64(), r.readFloat64(), r.readFloat64()}
+}
+
+func (r *wireReader) readQuat() mgl64.Quat {
+	w := r.readFloat64()
+	return mgl64.Quat{W: w, V: r.readVec3()}
+}
+
+// readLength reads a collection length and validates it against the remaining buffer
+// size so that corrupt input can't trigger huge allocations. minElementSize is the
+// smallest number of bytes a single element can be encoded in
+func (r *wireReader) readLength(minElementSize int) int {
+	n := r.readUvarint()
+	if r.err != nil {
+		return 0
+	}
+	if minElementSize < 1 {
+		minElementSize = 1
+	}
+	if n > uint64(len(r.buf)/minElementSize) {
+		r.fail("length %d exceeds remaining buffer of %d bytes", n, len(r.buf))
+		return 0
+	}
+	return int(n)
+}
+
+func (r *wireReader) readRaw(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < n {
+		r.fail("unexpected end of buffer reading %d bytes", n)
+		return nil
+	}
+	v := r.buf[:n]
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *wireReader) readString() string {
+	n := r.readLength(1)
+	return string(r.readRaw(n))
+}
+
+func (r *wireReader) readBytes() []byte {
+	n := r.readLength(1)
+	if n == 0 {
+		return nil
+	}
+	raw := r.readRaw(n)
+	if raw == nil {
+		return nil
+	}
+	return append([]byte(nil), raw...)
+}
+
+// finish flags any trailing bytes that the decoder didn't consume
+func (r *wireReader) finish() error {
+	if r.err == nil && len(r.buf) > 0 {
+		r.fail("%d trailing bytes", len(r.buf))
+	}
+	return r.err
+}
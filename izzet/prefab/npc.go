@@ -3,15 +3,20 @@ package prefab
 import (
 	"fmt"
 	"math"
+	"strings"
 
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/kkevinchou/izzet/internal/collision/collider"
+	"github.com/kkevinchou/izzet/internal/crowd"
+	"github.com/kkevinchou/izzet/internal/navmesh"
 	"github.com/kkevinchou/izzet/izzet/animation"
 	"github.com/kkevinchou/izzet/izzet/assets"
 	"github.com/kkevinchou/izzet/izzet/entity"
 	"github.com/kkevinchou/izzet/izzet/settings"
 )
 
+const npcSpeed float64 = 7
+
 func createNPC(am *assets.AssetManager, entityType entity.EntityType) *entity.Entity {
 	var modelName string
 	var scale float64 = 1
@@ -27,9 +32,9 @@ func createNPC(am *assets.AssetManager, entityType entity.EntityType) *entity.En
 
 	meshHandle := am.GetSingleEntityMeshHandle(modelName)
 	e := entity.InstantiateBaseEntity(modelName, 0)
-	e.Kinematic = &entity.KinematicComponent{GravityEnabled: true, Speed: 7}
+	e.Kinematic = &entity.KinematicComponent{GravityEnabled: true, Speed: npcSpeed}
 	e.AimDownSightsComponent = &entity.AimDownSightsComponent{}
-	e.HealthComponent = &entity.HealthComponent{Amount: 100}
+	e.HealthComponent = entity.NewHealthComponent(100)
 
 	var radius float64 = settings.EntityCapsuleColliderRadius * (1 / scale)
 	var length float64 = settings.EntityCapsuleColliderLength * (1 / scale)
@@ -45,10 +50,63 @@ func createNPC(am *assets.AssetManager, entityType entity.EntityType) *entity.En
 	e.MeshComponent = &entity.MeshComponent{MeshHandle: meshHandle, Transform: mgl64.Rotate3DY(180 * math.Pi / 180).Mat4(), Visible: true, ShadowCasting: true}
 	handle := am.GetAnimationHandle(modelName)
 	e.Animation = entity.NewAnimationComponent(am, handle, animation.StateMachineIDVelociraptor, entity.AnimationModeStateMachine)
-	e.AttackComponent = &entity.AttackComponent{AttackRange: 3}
+	e.AttackComponent = entity.NewAttackComponent(3)
 	entity.SetScale(e, mgl64.Vec3{scale, scale, scale})
 
-	e.AIComponent = &entity.AIComponent{}
+	e.AIComponent = &entity.AIComponent{BehaviorTree: behaviorTree(entityType)}
+	e.PerceptionComponent = &entity.PerceptionComponent{
+		SightRange:       40,
+		FieldOfView:      120,
+		EyeHeight:        1.5,
+		HearingThreshold: 0.1,
+		MemoryDuration:   5,
+	}
 
 	return e
 }
+
+// behaviorTree returns the name of the behavior entities of the type run, every
+// npc type has a behavior named after it in _assets/behaviors
+func behaviorTree(entityType entity.EntityType) string {
+	return strings.ToLower(string(entityType))
+}
+
+// NPCPrefabID returns the prefab npcs of the type are instantiated from, unknown
+// types are velociraptors
+func NPCPrefabID(entityType entity.EntityType) PrefabID {
+	if entityType == entity.EntityTypeParasaurolophus {
+		return PrefabIDParasaurolophus
+	}
+	return PrefabIDVelociraptor
+}
+
+// NavigationQueryFilter returns the query filter entities of the type path with,
+// nil paths through every area at the same cost
+func NavigationQueryFilter(entityType entity.EntityType) *navmesh.QueryFilter {
+	if entityType == entity.EntityTypeVelociraptor {
+		// velociraptors don't swim
+		filter := navmesh.NewQueryFilter()
+		filter.Exclude(navmesh.WATER_AREA)
+		return filter
+	} else if entityType == entity.EntityTypeParasaurolophus {
+		// parasaurolophuses tag along with players as companions
+		return CompanionQueryFilter()
+	}
+	return nil
+}
+
+// NavigationAgentParams returns the crowd settings of entities of the type, they
+// avoid other agents with the radius of their collider
+func NavigationAgentParams(entityType entity.EntityType) crowd.AgentParams {
+	return crowd.AgentParams{Radius: settings.EntityCapsuleColliderRadius, MaxSpeed: npcSpeed, Priority: 1}
+}
+
+// CompanionQueryFilter is for entities that follow a player around, they stick
+// to roads where they can and avoid water
+func CompanionQueryFilter() *navmesh.QueryFilter {
+	filter := navmesh.NewQueryFilter()
+	filter.SetAreaCost(navmesh.ROAD_AREA, 0.5)
+	filter.SetAreaCost(navmesh.GRASS_AREA, 1.5)
+	filter.SetAreaCost(navmesh.WATER_AREA, 10)
+	return filter
+}
@@ -0,0 +1,110 @@
+package prefab
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/navmesh"
+	"github.com/kkevinchou/izzet/izzet/behavior"
+	"github.com/kkevinchou/izzet/izzet/entity"
+)
+
+func TestNPCBehaviors(t *testing.T) {
+	// behaviors are loaded relative to the root of the repo
+	t.Chdir("../..")
+
+	for _, entityType := range []entity.EntityType{entity.EntityTypeVelociraptor, entity.EntityTypeParasaurolophus} {
+		if _, err := behavior.NewTree(behaviorTree(entityType)); err != nil {
+			t.Fatalf("expected %s to have a behavior: %v", entityType, err)
+		}
+	}
+}
+
+// areaNavMesh builds a single tile nav mesh with a unit square polygon for every
+// cell in the rows, 'g' is grass, 'r' is road and '.' is empty. row i covers z
+// from i to i+1
+func areaNavMesh(rows []string) *navmesh.CompiledNavMesh {
+	width := len(rows[0])
+	vertex := func(x, z int) int { return x + z*(width+1) }
+	polygons := map[[2]int]int{}
+	for z, row := range rows {
+		for x, cell := range row {
+			if cell != '.' {
+				polygons[[2]int{x, z}] = len(polygons)
+			}
+		}
+	}
+	cell := func(x, z int) int {
+		if poly, ok := polygons[[2]int{x, z}]; ok {
+			return poly
+		}
+		return -1
+	}
+
+	var tile navmesh.CTile
+	for z := range len(rows) + 1 {
+		for x := range width + 1 {
+			tile.Vertices = append(tile.Vertices, mgl64.Vec3{float64(x), 0, float64(z)})
+		}
+	}
+	for z, row := range rows {
+		for x, c := range row {
+			if c == '.' {
+				continue
+			}
+			area := navmesh.GRASS_AREA
+			if c == 'r' {
+				area = navmesh.ROAD_AREA
+			}
+			polygon := navmesh.CPolygon{
+				Vertices:      []int{vertex(x, z), vertex(x, z+1), vertex(x+1, z+1), vertex(x+1, z)},
+				PolyNeighbors: []int{cell(x-1, z), cell(x, z+1), cell(x+1, z), cell(x, z-1)},
+				Area:          area,
+			}
+			var verts []mgl64.Vec3
+			for _, v := range polygon.Vertices {
+				verts = append(verts, tile.Vertices[v])
+			}
+			tile.Polygons = append(tile.Polygons, polygon)
+			tile.DetailedVertices = append(tile.DetailedVertices, verts)
+			tile.DetailedPolygon = append(tile.DetailedPolygon, navmesh.CDetailedPolygon{Triangles: []navmesh.CDetailedTriangle{
+				{Vertices: [3]int{0, 1, 2}}, {Vertices: [3]int{0, 2, 3}},
+			}})
+		}
+	}
+	nm := &navmesh.CompiledNavMesh{Tiles: []navmesh.CTile{tile}}
+	navmesh.LinkTiles(nm)
+	return nm
+}
+
+func pathAreas(nm *navmesh.CompiledNavMesh, path []navmesh.PolyRef) map[navmesh.AREA_TYPE]int {
+	areas := map[navmesh.AREA_TYPE]int{}
+	for _, ref := range path {
+		areas[nm.Tiles[ref.Tile].Polygons[ref.Poly].Area]++
+	}
+	return areas
+}
+
+func TestCompanionPrefersRoads(t *testing.T) {
+	// a straight grass route along the top and a longer road around the bottom
+	nm := areaNavMesh([]string{
+		"ggggggg",
+		"r.....r",
+		"rrrrrrr",
+	})
+	start := mgl64.Vec3{0.5, 0, 0.5}
+	goal := mgl64.Vec3{6.5, 0, 0.5}
+
+	if areas := pathAreas(nm, navmesh.FindPath(nm, start, goal, nil)); areas[navmesh.ROAD_AREA] != 0 {
+		t.Fatalf("expected the unfiltered path to take the shorter grass route, got areas %v", areas)
+	}
+
+	filter := NavigationQueryFilter(entity.EntityTypeParasaurolophus)
+	path := navmesh.FindPath(nm, start, goal, filter)
+	if len(path) == 0 {
+		t.Fatal("expected a path")
+	}
+	if areas := pathAreas(nm, path); areas[navmesh.GRASS_AREA] != 2 || areas[navmesh.ROAD_AREA] != 9 {
+		t.Fatalf("expected the companion to leave the grass for the road, got areas %v", areas)
+	}
+}
@@ -26,7 +26,7 @@ func createPlayer(am *assets.AssetManager) *entity.Entity {
 	e.Collider = entity.CreateCapsuleColliderComponent(entity.ColliderGroupFlagPlayer, entity.ColliderGroupFlagTerrain|entity.ColliderGroupFlagPlayer, capsule)
 	e.CharacterControllerComponent = &entity.CharacterControllerComponent{CameraEntityID: entity.InvalidEntityID}
 	e.AimDownSightsComponent = &entity.AimDownSightsComponent{}
-	e.HealthComponent = &entity.HealthComponent{Amount: 100}
+	e.HealthComponent = entity.NewHealthComponent(100)
 	meshHandle := am.GetSingleEntityMeshHandle("mannequin_m")
 
 	e.MeshComponent = &entity.MeshComponent{MeshHandle: meshHandle, Transform: mgl64.Rotate3DY(180 * math.Pi / 180).Mat4(), Visible: true, ShadowCasting: true, InvisibleToPlayerOwner: settings.FirstPersonCamera}
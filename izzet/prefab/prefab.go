@@ -30,8 +30,9 @@ type Asset struct {
 type PrefabID string
 
 var (
-	PrefabIDMannequin    PrefabID = "mannequin"
-	PrefabIDVelociraptor PrefabID = "velociraptor"
+	PrefabIDMannequin       PrefabID = "mannequin"
+	PrefabIDVelociraptor    PrefabID = "velociraptor"
+	PrefabIDParasaurolophus PrefabID = "parasaurolophus"
 )
 
 var PrefabRegistry map[PrefabID]Prefab
@@ -44,9 +45,11 @@ func InitializePrefabs(am *assets.AssetManager) {
 func CreateDefaultPrefabs(am *assets.AssetManager) {
 	player := createPlayer(am)
 	velociraptor := createNPC(am, entity.EntityTypeVelociraptor)
+	parasaurolophus := createNPC(am, entity.EntityTypeParasaurolophus)
 
 	_ = RegisterPrefabWithID(PrefabIDMannequin, "mannequin", []*entity.Entity{player})
 	_ = RegisterPrefabWithID(PrefabIDVelociraptor, "velociraptor", []*entity.Entity{velociraptor})
+	_ = RegisterPrefabWithID(PrefabIDParasaurolophus, "parasaurolophus", []*entity.Entity{parasaurolophus})
 }
 
 func RegisterPrefab(name string, ents []*entity.Entity) error {
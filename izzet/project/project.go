@@ -0,0 +1,102 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kkevinchou/izzet/izzet/assets"
+	"github.com/kkevinchou/izzet/izzet/navmeshbuilder"
+	"github.com/kkevinchou/izzet/izzet/prefab"
+	"github.com/kkevinchou/izzet/izzet/project/projectpaths"
+	"github.com/kkevinchou/izzet/izzet/serialization"
+	"github.com/kkevinchou/izzet/izzet/world"
+)
+
+// Project contains engine data that's meant to be persisted and can be reloaded
+type Project struct {
+	WorldFile   string
+	AssetsFile  string
+	NavMeshFile string
+	Name        string
+}
+
+var ErrStaleNavMesh = errors.New("nav mesh was baked from a different version of the world")
+
+type DocumentJSON struct {
+	Document assets.Document
+}
+
+type MaterialsJSON struct {
+	MaterialAsset assets.Material
+}
+
+type PrefabsJSON struct {
+	PrefabAsset prefab.Prefab
+}
+
+type AssetsJSON struct {
+	Documents []DocumentJSON
+	Materials []MaterialsJSON
+	Prefabs   []PrefabsJSON
+}
+
+// LoadNavMesh loads the project's baked nav mesh. if the world has changed since
+// it was baked, the stale nav mesh is returned along with ErrStaleNavMesh so its
+// config can be reused to bake it again
+func LoadNavMesh(name string, world navmeshbuilder.World) (navmeshbuilder.BakedNavMesh, error) {
+	baked, err := navmeshbuilder.Load(projectpaths.NavMeshFilePath(name))
+	if err != nil {
+		return navmeshbuilder.BakedNavMesh{}, err
+	}
+	if baked.Stale(world) {
+		return baked, ErrStaleNavMesh
+	}
+	return baked, nil
+}
+
+// LoadWorld loads the project's assets followed by its world, neither requires a
+// GL context
+func LoadWorld(name string, assetManager *assets.AssetManager) (*world.GameWorld, error) {
+	if err := LoadAssets(name, assetManager); err != nil {
+		return nil, err
+	}
+	return serialization.ReadFromFile(projectpaths.WorldFilePath(name), assetManager)
+}
+
+// LoadAssets registers the documents, materials and prefabs of the named project
+// with the asset manager. it's safe to use with an asset manager that doesn't
+// process visual assets, so the server can load projects without a GL context
+func LoadAssets(name string, assetManager *assets.AssetManager) error {
+	assetsFile, err := os.Open(projectpaths.AssetsFilePath(name))
+	if err != nil {
+		return err
+	}
+	defer assetsFile.Close()
+
+	var assetsJSON AssetsJSON
+	decoder := json.NewDecoder(assetsFile)
+	err = decoder.Decode(&assetsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to decode assets file: %w", err)
+	}
+
+	for _, document := range assetsJSON.Documents {
+		assetManager.ReloadDocument(document.Document)
+	}
+
+	for _, material := range assetsJSON.Materials {
+		assetManager.CreateMaterialWithID(material.MaterialAsset.Name, material.MaterialAsset.Material, material.MaterialAsset.ID)
+	}
+
+	prefab.InitializePrefabs(assetManager)
+	for _, p := range assetsJSON.Prefabs {
+		err := prefab.RegisterPrefabWithID(p.PrefabAsset.ID, p.PrefabAsset.Name, p.PrefabAsset.Entities)
+		if err != nil {
+			return fmt.Errorf("failed to register prefab %s: %w", p.PrefabAsset.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+// Package projectpaths locates the files a project is saved to. it's kept apart
+// from package project so tools that only need the paths don't link the asset
+// pipeline
+package projectpaths
+
+import (
+	"path"
+
+	"github.com/kkevinchou/izzet/izzet/settings"
+)
+
+func WorldFilePath(name string) string {
+	return path.Join(settings.ProjectsDirectory, name, "world.json")
+}
+
+func AssetsFilePath(name string) string {
+	return path.Join(settings.ProjectsDirectory, name, "assets.json")
+}
+
+func NavMeshFilePath(name string) string {
+	return path.Join(settings.ProjectsDirectory, name, "navmesh.bin")
+}
@@ -58,6 +58,7 @@ func drawPrefabCell(app renderiface.App, p prefab.Prefab) {
 			for _, e := range entities {
 				app.World().AddEntity(e)
 			}
+			app.RebuildNavMeshUnder(entities...)
 			app.SelectEntity(entities[0])
 			imgui.CloseCurrentPopup()
 		}
@@ -2,6 +2,7 @@ package menus
 
 import (
 	"github.com/AllenDang/cimgui-go/imgui"
+	"github.com/kkevinchou/izzet/izzet/navmeshbuilder"
 	"github.com/kkevinchou/izzet/izzet/render/renderiface"
 	"github.com/kkevinchou/izzet/izzet/render/windows"
 )
@@ -17,14 +18,7 @@ func create(app renderiface.App) {
 			windows.ShowCreatePrefabWindow(app)
 		}
 		if imgui.MenuItemBool("Build Nav Mesh") {
-			runtimeConfig := app.RuntimeConfig()
-			iterations := int(runtimeConfig.NavigationMeshIterations)
-			walkableHeight := runtimeConfig.NavigationMeshWalkableHeight
-			climbableHeight := runtimeConfig.NavigationMeshClimbableHeight
-			minRegionArea := int(runtimeConfig.NavigationMeshMinRegionArea)
-			sampleDist := float64(runtimeConfig.NavigationmeshSampleDist)
-			maxError := float64(runtimeConfig.NavigationmeshMaxError)
-			app.BuildNavMesh(app, iterations, walkableHeight, climbableHeight, minRegionArea, sampleDist, maxError)
+			app.BuildNavMesh(navmeshbuilder.ConfigFromRuntimeConfig(app.RuntimeConfig()))
 		}
 		if imgui.MenuItemBool("Bake Static Geometry") {
 			app.SetupBatchedStaticRendering()
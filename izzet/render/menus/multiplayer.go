@@ -2,8 +2,12 @@ package menus
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/AllenDang/cimgui-go/imgui"
+	"github.com/kkevinchou/izzet/izzet/network"
 	"github.com/kkevinchou/izzet/izzet/render/renderiface"
 )
 
@@ -28,6 +32,31 @@ func multiplayer(app renderiface.App) {
 			app.DisconnectClient()
 		}
 
+		if conditions, ok := app.NetworkConditions(); ok {
+			if imgui.BeginMenu("Network Conditions") {
+				if networkConditions(&conditions) {
+					app.SetNetworkConditions(conditions)
+				}
+				imgui.EndMenu()
+			}
+		}
+
+		if serverConditions := app.ServerNetworkConditions(); len(serverConditions) > 0 {
+			if imgui.BeginMenu("Server Network Conditions") {
+				playerIDs := slices.Sorted(maps.Keys(serverConditions))
+				for _, playerID := range playerIDs {
+					conditions := serverConditions[playerID]
+					if imgui.BeginMenu(fmt.Sprintf("Player %d", playerID)) {
+						if networkConditions(&conditions) {
+							app.SetServerNetworkConditions(playerID, conditions)
+						}
+						imgui.EndMenu()
+					}
+				}
+				imgui.EndMenu()
+			}
+		}
+
 		if imgui.MenuItemBoolV("Start Async Server", "", app.AsyncServerStarted(), !app.AsyncServerStarted()) {
 			// save project before attempting to start the server. There may be imported assets
 			// that have not been persisted to our project's disk yet which will cause them
@@ -46,3 +75,26 @@ func multiplayer(app renderiface.App) {
 		imgui.EndMenu()
 	}
 }
+
+// networkConditions edits simulated network conditions and returns whether they changed
+func networkConditions(conditions *network.NetworkConditions) bool {
+	latency := int32(conditions.Latency / time.Millisecond)
+	jitter := int32(conditions.Jitter / time.Millisecond)
+	loss := float32(conditions.LossRate * 100)
+	duplicate := float32(conditions.DuplicateRate * 100)
+	bandwidth := int32(conditions.Bandwidth / 1000)
+
+	changed := false
+	changed = imgui.SliderInt("Latency (ms)", &latency, 0, 500) || changed
+	changed = imgui.SliderInt("Jitter (ms)", &jitter, 0, 200) || changed
+	changed = imgui.SliderFloat("Loss (%)", &loss, 0, 50) || changed
+	changed = imgui.SliderFloat("Duplicate (%)", &duplicate, 0, 50) || changed
+	changed = imgui.SliderInt("Bandwidth (KB/s)", &bandwidth, 0, 1000) || changed
+
+	conditions.Latency = time.Duration(latency) * time.Millisecond
+	conditions.Jitter = time.Duration(jitter) * time.Millisecond
+	conditions.LossRate = float64(loss) / 100
+	conditions.DuplicateRate = float64(duplicate) / 100
+	conditions.Bandwidth = int(bandwidth) * 1000
+	return changed
+}
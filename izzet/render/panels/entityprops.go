@@ -10,7 +10,10 @@ import (
 
 	"github.com/AllenDang/cimgui-go/imgui"
 	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/damage"
 	"github.com/kkevinchou/izzet/internal/geometry"
+	"github.com/kkevinchou/izzet/internal/navmesh"
+	"github.com/kkevinchou/izzet/internal/patrol"
 	"github.com/kkevinchou/izzet/izzet/appmode"
 	"github.com/kkevinchou/izzet/izzet/entity"
 	"github.com/kkevinchou/izzet/izzet/render/renderiface"
@@ -25,12 +28,22 @@ var PhysicsComboOption ComponentComboOption = "Physics Component"
 var LightComboOption ComponentComboOption = "Light Component"
 var ImageComboOption ComponentComboOption = "Image Component"
 var SpawnPointComboOption ComponentComboOption = "Spawn Point Component"
+var NavMeshAreaComboOption ComponentComboOption = "Nav Mesh Area Component"
+var OffMeshConnectionComboOption ComponentComboOption = "Off Mesh Connection Component"
+var NavMeshObstacleComboOption ComponentComboOption = "Nav Mesh Obstacle Component"
+var PatrolRouteComboOption ComponentComboOption = "Patrol Route Component"
+var WaypointComboOption ComponentComboOption = "Waypoint Component"
 
 var componentComboOptions []ComponentComboOption = []ComponentComboOption{
 	PhysicsComboOption,
 	LightComboOption,
 	ImageComboOption,
 	SpawnPointComboOption,
+	NavMeshAreaComboOption,
+	OffMeshConnectionComboOption,
+	NavMeshObstacleComboOption,
+	PatrolRouteComboOption,
+	WaypointComboOption,
 }
 
 var (
@@ -199,6 +212,340 @@ func EntityProps(e *entity.Entity, app renderiface.App) {
 		}
 	}
 
+	if e.NavMeshAreaComponent != nil {
+		areaComponent := e.NavMeshAreaComponent
+		if imgui.CollapsingHeaderTreeNodeFlagsV("Nav Mesh Area Properties", imgui.TreeNodeFlagsNone) {
+			imgui.BeginTableV("", 2, imgui.TableFlagsBorders|imgui.TableFlagsResizable, imgui.Vec2{}, 0)
+			ui.InitColumns()
+
+			ui.RowV("Area", func() {
+				if imgui.BeginCombo("##area_combo", navMeshAreaName(areaComponent.Area)) {
+					for _, areaType := range navmesh.AreaTypes {
+						if imgui.SelectableBool(areaType.Name) {
+							areaComponent.Area = areaType.Area
+						}
+					}
+					imgui.EndCombo()
+				}
+			}, true)
+
+			ui.RowV("Volume", func() {
+				imgui.Checkbox("", &areaComponent.Volume)
+			}, true)
+
+			imgui.EndTable()
+			imgui.PushIDStr("remove nav mesh area")
+			if imgui.Button("Remove") {
+				e.NavMeshAreaComponent = nil
+			}
+			imgui.PopID()
+		}
+	}
+
+	if e.OffMeshConnectionComponent != nil {
+		connectionComponent := e.OffMeshConnectionComponent
+		if imgui.CollapsingHeaderTreeNodeFlagsV("Off Mesh Connection Properties", imgui.TreeNodeFlagsNone) {
+			imgui.BeginTableV("", 2, imgui.TableFlagsBorders|imgui.TableFlagsResizable, imgui.Vec2{}, 0)
+			ui.InitColumns()
+
+			ui.RowV("End", func() {
+				end := &connectionComponent.End
+				x, y, z := float32(end.X()), float32(end.Y()), float32(end.Z())
+				imgui.PushItemWidth(imgui.ContentRegionAvail().X / 3.0)
+				if imgui.InputFloatV("##x", &x, 0, 0, "%.2f", imgui.InputTextFlagsNone) {
+					end[0] = float64(x)
+				}
+				imgui.SameLine()
+				if imgui.InputFloatV("##y", &y, 0, 0, "%.2f", imgui.InputTextFlagsNone) {
+					end[1] = float64(y)
+				}
+				imgui.SameLine()
+				if imgui.InputFloatV("##z", &z, 0, 0, "%.2f", imgui.InputTextFlagsNone) {
+					end[2] = float64(z)
+				}
+				imgui.PopItemWidth()
+			}, true)
+
+			ui.RowV("Radius", func() {
+				radius := float32(connectionComponent.Radius)
+				if imgui.InputFloatV("", &radius, 0.1, 1, "%.2f", imgui.InputTextFlagsNone) {
+					connectionComponent.Radius = float64(radius)
+				}
+			}, true)
+
+			ui.RowV("Bidirectional", func() {
+				imgui.Checkbox("", &connectionComponent.Bidirectional)
+			}, true)
+
+			ui.RowV("Area", func() {
+				if imgui.BeginCombo("##connection_area_combo", navMeshAreaName(connectionComponent.Area)) {
+					for _, areaType := range navmesh.AreaTypes {
+						if imgui.SelectableBool(areaType.Name) {
+							connectionComponent.Area = areaType.Area
+						}
+					}
+					imgui.EndCombo()
+				}
+			}, true)
+
+			ui.RowV("Cost", func() {
+				cost := float32(connectionComponent.Cost)
+				if imgui.InputFloatV("", &cost, 0.1, 1, "%.2f", imgui.InputTextFlagsNone) {
+					connectionComponent.Cost = float64(cost)
+				}
+			}, true)
+
+			imgui.EndTable()
+			imgui.PushIDStr("remove off mesh connection")
+			if imgui.Button("Remove") {
+				e.OffMeshConnectionComponent = nil
+			}
+			imgui.PopID()
+		}
+	}
+
+	if e.NavMeshObstacleComponent != nil {
+		obstacle := &e.NavMeshObstacleComponent.Obstacle
+		if imgui.CollapsingHeaderTreeNodeFlagsV("Nav Mesh Obstacle Properties", imgui.TreeNodeFlagsNone) {
+			imgui.BeginTableV("", 2, imgui.TableFlagsBorders|imgui.TableFlagsResizable, imgui.Vec2{}, 0)
+			ui.InitColumns()
+
+			ui.RowV("Shape", func() {
+				if imgui.BeginCombo("##obstacle_shape_combo", string(obstacle.Shape)) {
+					for _, shape := range []navmesh.ObstacleShape{navmesh.ObstacleShapeCylinder, navmesh.ObstacleShapeBox} {
+						if imgui.SelectableBool(string(shape)) {
+							obstacle.Shape = shape
+						}
+					}
+					imgui.EndCombo()
+				}
+			}, true)
+
+			if obstacle.Shape == navmesh.ObstacleShapeBox {
+				ui.RowV("Half Extents", func() {
+					x, y, z := float32(obstacle.HalfExtents.X()), float32(obstacle.HalfExtents.Y()), float32(obstacle.HalfExtents.Z())
+					imgui.PushItemWidth(imgui.ContentRegionAvail().X / 3.0)
+					if imgui.InputFloatV("##x", &x, 0, 0, "%.2f", imgui.InputTextFlagsNone) {
+						obstacle.HalfExtents[0] = float64(x)
+					}
+					imgui.SameLine()
+					if imgui.InputFloatV("##y", &y, 0, 0, "%.2f", imgui.InputTextFlagsNone) {
+						obstacle.HalfExtents[1] = float64(y)
+					}
+					imgui.SameLine()
+					if imgui.InputFloatV("##z", &z, 0, 0, "%.2f", imgui.InputTextFlagsNone) {
+						obstacle.HalfExtents[2] = float64(z)
+					}
+					imgui.PopItemWidth()
+				}, true)
+			} else {
+				ui.RowV("Radius", func() {
+					radius := float32(obstacle.Radius)
+					if imgui.InputFloatV("", &radius, 0.1, 1, "%.2f", imgui.InputTextFlagsNone) {
+						obstacle.Radius = float64(radius)
+					}
+				}, true)
+
+				ui.RowV("Height", func() {
+					height := float32(obstacle.Height)
+					if imgui.InputFloatV("", &height, 0.1, 1, "%.2f", imgui.InputTextFlagsNone) {
+						obstacle.Height = float64(height)
+					}
+				}, true)
+			}
+
+			imgui.EndTable()
+			imgui.PushIDStr("remove nav mesh obstacle")
+			if imgui.Button("Remove") {
+				e.NavMeshObstacleComponent = nil
+			}
+			imgui.PopID()
+		}
+	}
+
+	if e.AIComponent != nil {
+		aiComponent := e.AIComponent
+		if imgui.CollapsingHeaderTreeNodeFlagsV("AI Properties", imgui.TreeNodeFlagsNone) {
+			imgui.BeginTableV("", 2, imgui.TableFlagsBorders|imgui.TableFlagsResizable, imgui.Vec2{}, 0)
+			ui.InitColumns()
+
+			ui.RowV("Behavior Tree", func() {
+				imgui.InputTextWithHint("", "none", &aiComponent.BehaviorTree, imgui.InputTextFlagsNone, nil)
+			}, true)
+
+			ui.RowV("Patrol Route", func() {
+				routeID := int32(entity.InvalidEntityID)
+				if aiComponent.PatrolConfig != nil {
+					routeID = int32(aiComponent.PatrolConfig.RouteID)
+				}
+				if imgui.InputInt("", &routeID) {
+					aiComponent.PatrolConfig = nil
+					if routeID != int32(entity.InvalidEntityID) {
+						aiComponent.PatrolConfig = &entity.PatrolConfig{RouteID: int(routeID)}
+					}
+				}
+			}, true)
+
+			imgui.EndTable()
+		}
+	}
+
+	if e.HealthComponent != nil {
+		healthComponent := e.HealthComponent
+		if imgui.CollapsingHeaderTreeNodeFlagsV("Health Properties", imgui.TreeNodeFlagsNone) {
+			imgui.BeginTableV("", 2, imgui.TableFlagsBorders|imgui.TableFlagsResizable, imgui.Vec2{}, 0)
+			ui.InitColumns()
+
+			uiTableRow("Health", fmt.Sprintf("%d / %d", healthComponent.Amount, healthComponent.Max))
+			ui.RowV("Max Health", func() {
+				maxHealth := int32(healthComponent.Max)
+				if imgui.InputInt("", &maxHealth) {
+					healthComponent.Max = max(int(maxHealth), 1)
+					healthComponent.Amount = min(healthComponent.Amount, healthComponent.Max)
+				}
+			}, true)
+
+			ui.RowV("Armor", func() {
+				armor := float32(healthComponent.Armor)
+				if imgui.InputFloatV("", &armor, 1, 10, "%.0f", imgui.InputTextFlagsNone) {
+					healthComponent.Armor = max(float64(armor), 0)
+				}
+			}, true)
+
+			for _, damageType := range damage.Types {
+				ui.RowV(fmt.Sprintf("%s Resistance", damageType), func() {
+					resistance := float32(healthComponent.Resistances[damageType])
+					if imgui.InputFloatV("", &resistance, 0.05, 0.1, "%.2f", imgui.InputTextFlagsNone) {
+						if healthComponent.Resistances == nil {
+							healthComponent.Resistances = map[damage.Type]float64{}
+						}
+						healthComponent.Resistances[damageType] = min(float64(resistance), 1)
+					}
+				}, true)
+			}
+
+			imgui.EndTable()
+		}
+	}
+
+	if e.AttackComponent != nil {
+		attackComponent := e.AttackComponent
+		if imgui.CollapsingHeaderTreeNodeFlagsV("Attack Properties", imgui.TreeNodeFlagsNone) {
+			imgui.BeginTableV("", 2, imgui.TableFlagsBorders|imgui.TableFlagsResizable, imgui.Vec2{}, 0)
+			ui.InitColumns()
+
+			ui.RowV("Damage", func() {
+				attackDamage := int32(attackComponent.Damage)
+				if imgui.InputInt("", &attackDamage) {
+					attackComponent.Damage = max(int(attackDamage), 0)
+				}
+			}, true)
+
+			ui.RowV("Damage Type", func() {
+				if imgui.BeginCombo("##damage_type_combo", string(attackComponent.DamageType)) {
+					for _, damageType := range damage.Types {
+						if imgui.SelectableBool(string(damageType)) {
+							attackComponent.DamageType = damageType
+						}
+					}
+					imgui.EndCombo()
+				}
+			}, true)
+
+			ui.RowV("Duration", func() {
+				duration := float32(attackComponent.Duration)
+				if imgui.InputFloatV("", &duration, 0.1, 1, "%.2f", imgui.InputTextFlagsNone) {
+					attackComponent.Duration = max(float64(duration), 0)
+				}
+			}, true)
+
+			ui.RowV("Hit Window", func() {
+				start, end := float32(attackComponent.HitWindowStart), float32(attackComponent.HitWindowEnd)
+				imgui.PushItemWidth(imgui.ContentRegionAvail().X / 2.0)
+				if imgui.InputFloatV("##start", &start, 0, 0, "%.2f", imgui.InputTextFlagsNone) {
+					attackComponent.HitWindowStart = max(float64(start), 0)
+				}
+				imgui.SameLine()
+				if imgui.InputFloatV("##end", &end, 0, 0, "%.2f", imgui.InputTextFlagsNone) {
+					attackComponent.HitWindowEnd = max(float64(end), attackComponent.HitWindowStart)
+				}
+				imgui.PopItemWidth()
+			}, true)
+
+			imgui.EndTable()
+		}
+	}
+
+	if e.PatrolRouteComponent != nil {
+		routeComponent := e.PatrolRouteComponent
+		if imgui.CollapsingHeaderTreeNodeFlagsV("Patrol Route Properties", imgui.TreeNodeFlagsNone) {
+			imgui.BeginTableV("", 2, imgui.TableFlagsBorders|imgui.TableFlagsResizable, imgui.Vec2{}, 0)
+			ui.InitColumns()
+
+			ui.RowV("Mode", func() {
+				if imgui.BeginCombo("##patrol_mode_combo", string(routeComponent.Mode)) {
+					for _, mode := range patrol.Modes {
+						if imgui.SelectableBool(string(mode)) {
+							routeComponent.Mode = mode
+						}
+					}
+					imgui.EndCombo()
+				}
+			}, true)
+
+			ui.RowV("First Waypoint", func() {
+				start := int32(routeComponent.Start)
+				if imgui.InputInt("", &start) {
+					routeComponent.Start = int(start)
+				}
+			}, true)
+
+			if _, err := app.World().PatrolRoute(e.GetID()); err != nil {
+				uiTableRow("Error", err.Error())
+			}
+
+			imgui.EndTable()
+			imgui.PushIDStr("remove patrol route")
+			if imgui.Button("Remove") {
+				e.PatrolRouteComponent = nil
+			}
+			imgui.PopID()
+		}
+	}
+
+	if e.WaypointComponent != nil {
+		waypointComponent := e.WaypointComponent
+		if imgui.CollapsingHeaderTreeNodeFlagsV("Waypoint Properties", imgui.TreeNodeFlagsNone) {
+			imgui.BeginTableV("", 2, imgui.TableFlagsBorders|imgui.TableFlagsResizable, imgui.Vec2{}, 0)
+			ui.InitColumns()
+
+			ui.RowV("Next Waypoint", func() {
+				next := int32(waypointComponent.Next)
+				if imgui.InputInt("", &next) {
+					waypointComponent.Next = int(next)
+				}
+			}, true)
+
+			ui.RowV("Wait", func() {
+				wait := float32(waypointComponent.Wait)
+				if imgui.InputFloatV("", &wait, 0.1, 1, "%.2f", imgui.InputTextFlagsNone) {
+					waypointComponent.Wait = max(float64(wait), 0)
+				}
+			}, true)
+
+			ui.RowV("Action", func() {
+				imgui.InputTextWithHint("", "none", &waypointComponent.Action, imgui.InputTextFlagsNone, nil)
+			}, true)
+
+			imgui.EndTable()
+			imgui.PushIDStr("remove waypoint")
+			if imgui.Button("Remove") {
+				e.WaypointComponent = nil
+			}
+			imgui.PopID()
+		}
+	}
+
 	originalMeshTriCount := 0
 
 	if e.MeshComponent != nil {
@@ -503,11 +850,36 @@ func EntityProps(e *entity.Entity, app renderiface.App) {
 				selectedEntity.SpawnPointComponent = &entity.SpawnPoint{}
 			} else if SelectedComponentComboOption == ImageComboOption {
 				selectedEntity.ImageComponent = entity.NewImageComponent("default.png", 1, true)
+			} else if SelectedComponentComboOption == NavMeshAreaComboOption {
+				selectedEntity.NavMeshAreaComponent = &entity.NavMeshAreaComponent{Area: navmesh.WALKABLE_AREA}
+			} else if SelectedComponentComboOption == NavMeshObstacleComboOption {
+				selectedEntity.NavMeshObstacleComponent = &entity.NavMeshObstacleComponent{
+					Obstacle: navmesh.Obstacle{Shape: navmesh.ObstacleShapeCylinder, Radius: 0.5, Height: 2},
+				}
+			} else if SelectedComponentComboOption == PatrolRouteComboOption {
+				selectedEntity.PatrolRouteComponent = entity.NewPatrolRouteComponent()
+			} else if SelectedComponentComboOption == WaypointComboOption {
+				selectedEntity.WaypointComponent = entity.NewWaypointComponent()
+			} else if SelectedComponentComboOption == OffMeshConnectionComboOption {
+				selectedEntity.OffMeshConnectionComponent = &entity.OffMeshConnectionComponent{
+					End:    mgl64.Vec3{0, 0, -2},
+					Radius: 1,
+					Area:   navmesh.WALKABLE_AREA,
+				}
 			}
 		}
 	}
 }
 
+func navMeshAreaName(area navmesh.AREA_TYPE) string {
+	for _, areaType := range navmesh.AreaTypes {
+		if areaType.Area == area {
+			return areaType.Name
+		}
+	}
+	return fmt.Sprintf("Area %d", area)
+}
+
 func uiTableInputPosition(e *entity.Entity, text *string) {
 	textCopy := *text
 	r := regexp.MustCompile(`\{(?P<x>-?\d+), (?P<y>-?\d+), (?P<z>-?\d+)\}`)
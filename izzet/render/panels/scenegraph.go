@@ -25,6 +25,7 @@ func SceneGraph(app renderiface.App) {
 			if imgui.Button("Add Cube") {
 				e := entity.CreateCube(app.AssetManager(), 1)
 				world.AddEntity(e)
+				app.RebuildNavMeshUnder(e)
 				app.SelectEntity(e)
 				imgui.CloseCurrentPopup()
 			}
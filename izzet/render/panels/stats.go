@@ -9,6 +9,7 @@ import (
 	"github.com/kkevinchou/izzet/internal/metrics"
 	"github.com/kkevinchou/izzet/izzet/render/renderiface"
 	"github.com/kkevinchou/izzet/izzet/render/ui"
+	"github.com/kkevinchou/izzet/izzet/settings"
 	"github.com/kkevinchou/izzet/izzet/telemetry"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -47,6 +48,42 @@ func Stats(app renderiface.App, renderContext RenderContext) {
 		})
 	}
 
+	if imgui.CollapsingHeaderTreeNodeFlagsV("Prediction", imgui.TreeNodeFlagsNone) {
+		ui.Table("", func() {
+			ui.CheckboxRow("Predict Kinematic Entities", &runtimeConfig.PredictKinematicEntities)
+			ui.SliderFloatRow("Prediction Radius", &runtimeConfig.PredictionRadius, 0, 100)
+			ui.LabelRow("Predicted Entities", fmt.Sprintf("%.1f", mr.AvgOver("predicted_entities", metricRange)))
+			ui.LabelRow("Mispredicted Entities", fmt.Sprintf("%.1f", mr.AvgOver("prediction_mispredicted_entities", metricRange)))
+			ui.LabelRow("Prediction Error", fmt.Sprintf("%.3f", mr.AvgOver("prediction_error", metricRange)))
+			ui.LabelRow("Replayed Frames", fmt.Sprintf("%.1f", mr.AvgOver("prediction_replayed_frames", metricRange)))
+			ui.SliderFloatRow("Correction Decay (ms)", &runtimeConfig.CorrectionDecayMilliseconds, 0, 1000)
+			ui.LabelRow("Correction", fmt.Sprintf("%.4f", mr.AvgOver("prediction_correction", metricRange)))
+			ui.LabelRow("Correction Rotation", fmt.Sprintf("%.3f", mr.AvgOver("prediction_correction_rotation", metricRange)))
+		})
+	}
+
+	if imgui.CollapsingHeaderTreeNodeFlagsV("Input Buffering", imgui.TreeNodeFlagsNone) {
+		ui.Table("", func() {
+			ui.SliderFloatRow("Target Buffer Depth", &runtimeConfig.TargetInputBufferDepth, 0, 10)
+			ui.SliderIntRow("Redundant Inputs", &runtimeConfig.RedundantInputs, 0, 10)
+			ui.LabelRow("Server Buffer Depth", fmt.Sprintf("%.1f", mr.AvgOver("input_buffer_depth", metricRange)))
+			ui.LabelRow("Time Dilation", fmt.Sprintf("%.3f", mr.AvgOver("time_dilation", metricRange)))
+			ui.LabelRow("Dropped Inputs", fmt.Sprintf("%.1f", mr.RatePerSec("input_dropped", metricRange)))
+			ui.LabelRow("Duplicated Inputs", fmt.Sprintf("%.1f", mr.RatePerSec("input_duplicated", metricRange)))
+		})
+	}
+
+	if imgui.CollapsingHeaderTreeNodeFlagsV("Interpolation", imgui.TreeNodeFlagsNone) {
+		ui.Table("", func() {
+			ui.SliderFloatRow("Delay (frames)", &runtimeConfig.InterpolationDelayFrames, 0, float32(settings.MaxInterpolationDelayFrames))
+			ui.CheckboxRow("Adapt To Jitter", &runtimeConfig.AdaptiveInterpolationDelay)
+			ui.LabelRow("Effective Delay", fmt.Sprintf("%.1f", mr.AvgOver("interpolation_delay", metricRange)))
+			ui.LabelRow("Jitter", fmt.Sprintf("%.2f", mr.AvgOver("interpolation_jitter", metricRange)))
+			ui.LabelRow("Extrapolated Frames", fmt.Sprintf("%.1f", mr.RatePerSec("interpolation_extrapolated", metricRange)))
+			ui.LabelRow("Buffer Overflows", fmt.Sprintf("%.1f", mr.RatePerSec("state_buffer_overflow", metricRange)))
+		})
+	}
+
 	// rendering metrics tracked from gpu
 	pairs, total := metricPairsByPrefix(mr, "render_gpu_")
 
@@ -5,6 +5,7 @@ import (
 
 	"github.com/AllenDang/cimgui-go/imgui"
 	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/izzet/navmeshbuilder"
 	"github.com/kkevinchou/izzet/izzet/render/renderiface"
 	"github.com/kkevinchou/izzet/izzet/render/ui"
 )
@@ -114,6 +115,12 @@ func WorldProps(app renderiface.App) {
 					runtimeConfig.NavigationMeshMinRegionArea = i
 				}
 			})
+			ui.Row("Merge Region Area", func() {
+				var i int32 = runtimeConfig.NavigationMeshMergeRegionArea
+				if imgui.InputInt("##value", &i) {
+					runtimeConfig.NavigationMeshMergeRegionArea = i
+				}
+			})
 			ui.Row("Max Error", func() {
 				var f float32 = float32(runtimeConfig.NavigationmeshMaxError)
 				if imgui.InputFloatV("##value", &f, 0.1, 0.1, "%.1f", imgui.InputTextFlagsNone) {
@@ -144,18 +151,18 @@ func WorldProps(app renderiface.App) {
 					runtimeConfig.NavigationmeshSampleDist = f
 				}
 			})
+			ui.Row("Tile Size (Cells)", func() {
+				var i int32 = runtimeConfig.NavigationMeshTileSize
+				if imgui.InputInt("##value", &i) {
+					runtimeConfig.NavigationMeshTileSize = i
+				}
+			})
 			ui.CheckboxRow("Filter Ledge Spans", &runtimeConfig.NavigationMeshFilterLedgeSpans)
 			ui.CheckboxRow("Filter Low Height Spans", &runtimeConfig.NavigationMeshFilterLowHeightSpans)
 		})
 
 		if imgui.Button("Build") {
-			iterations := int(runtimeConfig.NavigationMeshIterations)
-			walkableHeight := runtimeConfig.NavigationMeshWalkableHeight
-			climbableHeight := runtimeConfig.NavigationMeshClimbableHeight
-			minRegionArea := int(runtimeConfig.NavigationMeshMinRegionArea)
-			maxError := float64(runtimeConfig.NavigationmeshMaxError)
-			sampleDist := float64(runtimeConfig.NavigationmeshSampleDist)
-			app.BuildNavMesh(app, iterations, walkableHeight, climbableHeight, minRegionArea, sampleDist, maxError)
+			app.BuildNavMesh(navmeshbuilder.ConfigFromRuntimeConfig(runtimeConfig))
 		}
 	}
 
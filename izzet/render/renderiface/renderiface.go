@@ -10,6 +10,8 @@ import (
 	"github.com/kkevinchou/izzet/izzet/assets"
 	"github.com/kkevinchou/izzet/izzet/collisionobserver"
 	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/navmeshbuilder"
+	"github.com/kkevinchou/izzet/izzet/network"
 	"github.com/kkevinchou/izzet/izzet/runtimeconfig"
 	"github.com/kkevinchou/izzet/izzet/serverstats"
 	"github.com/kkevinchou/izzet/izzet/world"
@@ -41,6 +43,10 @@ type App interface {
 	DisconnectAsyncServer()
 	AsyncServerStarted() bool
 	DisconnectClient()
+	NetworkConditions() (network.NetworkConditions, bool)
+	SetNetworkConditions(conditions network.NetworkConditions)
+	ServerNetworkConditions() map[int]network.NetworkConditions
+	SetServerNetworkConditions(playerID int, conditions network.NetworkConditions)
 
 	GetServerStats() serverstats.ServerStats
 	SaveProject() error
@@ -54,8 +60,9 @@ type App interface {
 	SelectEntity(entity *entity.Entity)
 	SelectedEntity() *entity.Entity
 	CreateEntitiesFromDocument(d assets.Document, merged bool) *entity.Entity
+	RebuildNavMeshUnder(entities ...*entity.Entity)
 	DeleteDocument(d assets.Document) []int
-	BuildNavMesh(App, int, float32, float32, int, float64, float64)
+	BuildNavMesh(config navmeshbuilder.Config)
 	NavMesh() *navmesh.NavigationMesh
 	World() *world.GameWorld
 
@@ -621,6 +621,20 @@ func (p *MainRenderPass) drawAnnotations(viewerContext context.ViewerContext, re
 					rutils.DrawLineGroup(fmt.Sprintf("%d_%v_%v", entity.ID, entity.Position(), dir), shader, lines, 0.05, color)
 				}
 			}
+
+			if entity.OffMeshConnectionComponent != nil {
+				connection := entity.OffMeshConnection()
+				lines := [][2]mgl64.Vec3{{connection.Start, connection.End}}
+
+				shader := p.sm.GetShaderProgram("flat")
+				color := mgl64.Vec3{33.0 / 255, 200.0 / 255, 252.0 / 255}
+				shader.Use()
+				shader.SetUniformMat4("model", utils.Mat4F64ToF32(mgl64.Ident4()))
+				shader.SetUniformMat4("view", utils.Mat4F64ToF32(viewerContext.ViewMatrix))
+				shader.SetUniformMat4("projection", utils.Mat4F64ToF32(viewerContext.ProjectionMatrix))
+
+				rutils.DrawLineGroup(fmt.Sprintf("offmesh_%d_%v_%v", entity.ID, connection.Start, connection.End), shader, lines, 0.05, color)
+			}
 		}
 	}
 
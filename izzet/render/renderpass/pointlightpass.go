@@ -133,7 +133,7 @@ func (p *PointLightRenderPass) Render(
 			p.shader.SetUniformInt("isAnimated", 0)
 		}
 
-		modelMatrix := entity.WorldTransform(e)
+		modelMatrix := entity.RenderTransform(e)
 		m32ModelMatrix := utils.Mat4F64ToF32(modelMatrix)
 
 		primitives := p.app.AssetManager().GetPrimitives(e.MeshComponent.MeshHandle)
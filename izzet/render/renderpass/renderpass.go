@@ -3,7 +3,6 @@ package renderpass
 import (
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
@@ -12,13 +11,11 @@ import (
 	"github.com/kkevinchou/izzet/internal/modelspec"
 	"github.com/kkevinchou/izzet/internal/shaders"
 	"github.com/kkevinchou/izzet/internal/utils"
-	"github.com/kkevinchou/izzet/izzet/apputils"
 	"github.com/kkevinchou/izzet/izzet/assets"
 	"github.com/kkevinchou/izzet/izzet/entity"
 	"github.com/kkevinchou/izzet/izzet/render/context"
 	"github.com/kkevinchou/izzet/izzet/render/renderiface"
 	"github.com/kkevinchou/izzet/izzet/render/rutils"
-	"github.com/kkevinchou/izzet/izzet/settings"
 	"github.com/kkevinchou/izzet/izzet/telemetry"
 )
 
@@ -197,7 +194,7 @@ func renderGeometryWithoutColor(
 			shader.SetUniformInt("isAnimated", 0)
 		}
 
-		modelMatrix := entity.WorldTransform(e)
+		modelMatrix := entity.RenderTransform(e)
 		m32ModelMatrix := utils.Mat4F64ToF32(modelMatrix)
 
 		primitives := app.AssetManager().GetPrimitives(e.MeshComponent.MeshHandle)
@@ -421,27 +418,11 @@ func drawModel(
 		shader.SetUniformVec3("scale", utils.Vec3F64ToF32(e.Scale()))
 		shader.SetUniformInt("alphaMode", int32(alphaMode))
 
-		modelMatrix := entity.WorldTransform(e)
+		// draw at the smoothed transform rather than the simulated one so
+		// misprediction corrections are eased in
+		modelMatrix := entity.RenderTransform(e)
 		var modelMat mgl32.Mat4
 
-		// apply smooth blending between mispredicted position and actual real position
-		if e.RenderBlend != nil && e.RenderBlend.Active {
-			deltaMs := time.Since(e.RenderBlend.StartTime).Milliseconds()
-			t := apputils.RenderBlendMath(deltaMs)
-
-			blendedPosition := e.Position().Sub(e.RenderBlend.BlendStartPosition).Mul(t).Add(e.RenderBlend.BlendStartPosition)
-
-			translationMatrix := mgl64.Translate3D(blendedPosition[0], blendedPosition[1], blendedPosition[2])
-			rotationMatrix := e.GetLocalRotation().Mat4()
-			scale := e.Scale()
-			scaleMatrix := mgl64.Scale3D(scale.X(), scale.Y(), scale.Z())
-			modelMatrix = translationMatrix.Mul4(rotationMatrix).Mul4(scaleMatrix)
-
-			if deltaMs >= int64(settings.RenderBlendDurationMilliseconds) {
-				e.RenderBlend.Active = false
-			}
-		}
-
 		modelMat = utils.Mat4F64ToF32(modelMatrix).Mul4(utils.Mat4F64ToF32(e.MeshComponent.Transform))
 
 		shader.SetUniformMat4("model", modelMat)
@@ -220,13 +220,13 @@ func buildPrefabTemplate(app renderiface.App, prefabName string) *entity.Entity
 		}
 	}
 	if activePrefabEditor.IncludeHealth {
-		template.HealthComponent = &entity.HealthComponent{Amount: int(activePrefabEditor.HealthAmount)}
+		template.HealthComponent = entity.NewHealthComponent(int(activePrefabEditor.HealthAmount))
 	}
 	if activePrefabEditor.IncludeAimDownSights {
 		template.AimDownSightsComponent = &entity.AimDownSightsComponent{}
 	}
 	if activePrefabEditor.IncludeAttack {
-		template.AttackComponent = &entity.AttackComponent{AttackRange: float64(activePrefabEditor.AttackRange)}
+		template.AttackComponent = entity.NewAttackComponent(float64(activePrefabEditor.AttackRange))
 	}
 	if activePrefabEditor.IncludeAI {
 		template.AIComponent = &entity.AIComponent{}
@@ -72,6 +72,31 @@ type RuntimeConfig struct {
 	PhysicsCollisionCheckCount     int32
 	PhysicsConfirmedCollisionCount int32
 
+	// Prediction
+	// PredictKinematicEntities predicts kinematic entities within PredictionRadius
+	// of the player along with the player, except ones driven by other players,
+	// AI or navigation. the rest are interpolated
+	PredictKinematicEntities bool
+	PredictionRadius         float32
+	// CorrectionDecayMilliseconds is how long it takes for a misprediction
+	// correction to be smoothed out, 0 snaps straight to the corrected transform
+	CorrectionDecayMilliseconds float32
+
+	// Input Buffering
+	// TargetInputBufferDepth is how many of the client's inputs the server should
+	// have buffered, the client speeds up or slows down to keep it there
+	TargetInputBufferDepth float32
+	// RedundantInputs is how many previous inputs are resent with each input
+	RedundantInputs int32
+
+	// Interpolation
+	// InterpolationDelayFrames is how many command frames behind the server
+	// interpolated entities are rendered, it should cover the time between game
+	// state updates. AdaptiveInterpolationDelay adds to it when updates arrive
+	// with jitter
+	InterpolationDelayFrames   float32
+	AdaptiveInterpolationDelay bool
+
 	// Editing
 	SnapSize            float64
 	RotationSnapSize    int32
@@ -88,6 +113,7 @@ type RuntimeConfig struct {
 	NavigationMeshWalkableHeight       float32
 	NavigationMeshClimbableHeight      float32
 	NavigationMeshMinRegionArea        int32
+	NavigationMeshMergeRegionArea      int32
 	NavigationMeshAgentRadius          float32
 	NavigationMeshCellSize             float32
 	NavigationMeshCellHeight           float32
@@ -96,6 +122,7 @@ type RuntimeConfig struct {
 	NavigationmeshSampleDist           float32
 	NavigationMeshFilterLedgeSpans     bool
 	NavigationMeshFilterLowHeightSpans bool
+	NavigationMeshTileSize             int32
 
 	NavigationMeshStart      int32
 	NavigationMeshStartPoint mgl64.Vec3
@@ -218,10 +245,21 @@ func DefaultRuntimeConfig() *RuntimeConfig {
 		ShowColliders:            false,
 		ShowTextureViewer:        false,
 
+		PredictKinematicEntities:    false,
+		PredictionRadius:            20,
+		CorrectionDecayMilliseconds: 200,
+
+		TargetInputBufferDepth: 2,
+		RedundantInputs:        3,
+
+		InterpolationDelayFrames:   float32(settings.NumFramesPerGameStateUpdate + 2),
+		AdaptiveInterpolationDelay: true,
+
 		NavigationMeshIterations:           2500,
 		NavigationMeshWalkableHeight:       float32(settings.EntityCapsuleColliderLength + (2 * settings.EntityCapsuleColliderRadius)),
 		NavigationMeshClimbableHeight:      0.3,
 		NavigationMeshMinRegionArea:        4,
+		NavigationMeshMergeRegionArea:      400,
 		NavigationMeshAgentRadius:          float32(settings.EntityCapsuleColliderRadius),
 		NavigationMeshCellSize:             0.1,
 		NavigationMeshCellHeight:           0.1,
@@ -230,6 +268,7 @@ func DefaultRuntimeConfig() *RuntimeConfig {
 		NavigationmeshSampleDist:           1,
 		NavigationMeshFilterLedgeSpans:     true,
 		NavigationMeshFilterLowHeightSpans: true,
+		NavigationMeshTileSize:             256,
 
 		NavigationMeshStart: 0,
 		NavigationMeshGoal:  1,
@@ -25,9 +25,21 @@ func DeserializeEntity(bytes []byte, assetManager *assets.AssetManager) (*entity
 	return &e, err
 }
 
+// defaultMaxHealth is the maximum health of entities that were saved before
+// health had a maximum and with no health left
+const defaultMaxHealth = 100
+
 func initDeserializedEntity(e *entity.Entity, am *assets.AssetManager) {
 	e.DirtyTransformFlag = true
 
+	// worlds saved before health had a maximum respawn entities at full health
+	if e.HealthComponent != nil && e.HealthComponent.Max == 0 {
+		e.HealthComponent.Max = e.HealthComponent.Amount
+		if e.HealthComponent.Max <= 0 {
+			e.HealthComponent.Max = defaultMaxHealth
+		}
+	}
+
 	// reinitialize the animation player and state machine
 	if e.Animation != nil {
 		var state string
@@ -0,0 +1,27 @@
+package serialization
+
+import "testing"
+
+func TestDeserializeEntityDefaultsMaxHealth(t *testing.T) {
+	testCases := []struct {
+		name    string
+		health  string
+		wantMax int
+	}{
+		{name: "saved without a maximum", health: `{"Amount": 80}`, wantMax: 80},
+		{name: "saved dead without a maximum", health: `{"Amount": 0}`, wantMax: defaultMaxHealth},
+		{name: "saved with a maximum", health: `{"Amount": 20, "Max": 50}`, wantMax: 50},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := DeserializeEntity([]byte(`{"ID": 1, "HealthComponent": `+tc.health+`}`), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.HealthComponent.Max != tc.wantMax {
+				t.Fatalf("max health = %d, want %d", e.HealthComponent.Max, tc.wantMax)
+			}
+		})
+	}
+}
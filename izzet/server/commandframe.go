@@ -24,8 +24,8 @@ func (g *Server) handlePlayerConnections() {
 	select {
 	case connection := <-g.newConnections:
 		g.eventManager.PlayerJoinTopic.Write(event.PlayerJoinEvent{
-			PlayerID:   connection.PlayerID,
-			Connection: connection.Connection,
+			PlayerID: connection.PlayerID,
+			Client:   connection.Client,
 		})
 	default:
 		return
@@ -2,16 +2,23 @@ package inputbuffer
 
 import (
 	"log/slog"
+	"slices"
 
 	"github.com/kkevinchou/izzet/internal/input"
 	"github.com/kkevinchou/izzet/internal/iztlog"
 )
 
-const maxBufferedInput int = 2
+// maxBufferedInput bounds how far ahead of the server a player's inputs can be
+// buffered, the oldest inputs are dropped past it. clients dilate time to keep
+// their buffers well below this
+const maxBufferedInput int = 32
 
 type BufferedInput struct {
 	Input             input.Input
 	LocalCommandFrame int
+	// PerceivedCommandFrame is the global command frame of the game state the
+	// client was rendering when it sent the input
+	PerceivedCommandFrame int
 }
 
 type InputBuffer struct {
@@ -19,10 +26,28 @@ type InputBuffer struct {
 	app           App
 }
 
+// PlayerBuffer holds a player's inputs that haven't been simulated yet, ordered
+// by the client's command frame
 type PlayerBuffer struct {
-	count  int
 	inputs []BufferedInput
-	cursor int
+
+	pulledAny  bool
+	lastPulled BufferedInput
+
+	dropped    int
+	duplicated int
+}
+
+// Stats describe how well a player's inputs are keeping up with the server
+type Stats struct {
+	// Depth is the number of inputs waiting to be simulated
+	Depth int
+	// DroppedInputs is the number of inputs that were never simulated because
+	// they were lost, arrived too late, or overflowed the buffer
+	DroppedInputs int
+	// DuplicatedInputs is the number of frames the buffer ran dry and the last
+	// input was simulated again
+	DuplicatedInputs int
 }
 
 type App interface {
@@ -35,41 +60,70 @@ func New(app App) *InputBuffer {
 }
 
 func (b *InputBuffer) RegisterPlayer(playerID int) {
-	b.playerBuffers[playerID] = &PlayerBuffer{inputs: make([]BufferedInput, maxBufferedInput)}
+	b.playerBuffers[playerID] = &PlayerBuffer{}
 }
 
 func (b *InputBuffer) DeregisterPlayer(playerID int) {
 	delete(b.playerBuffers, playerID)
 }
 
-func (b *InputBuffer) PushInput(localCommandFrame int, playerID int, frameInput input.Input) {
+// PushInput buffers an input. clients resend their recent inputs to survive
+// packet loss so inputs that are already buffered or simulated are ignored
+func (b *InputBuffer) PushInput(playerID int, bufferedInput BufferedInput) {
 	buffer := b.playerBuffers[playerID]
+	if buffer.pulledAny && bufferedInput.LocalCommandFrame <= buffer.lastPulled.LocalCommandFrame {
+		return
+	}
+
+	index, found := slices.BinarySearchFunc(buffer.inputs, bufferedInput.LocalCommandFrame, func(buffered BufferedInput, frame int) int {
+		return buffered.LocalCommandFrame - frame
+	})
+	if found {
+		return
+	}
+	buffer.inputs = slices.Insert(buffer.inputs, index, bufferedInput)
 
-	buffer.inputs[buffer.count%maxBufferedInput] = BufferedInput{LocalCommandFrame: localCommandFrame, Input: frameInput}
-	buffer.count++
-	if buffer.count-buffer.cursor > maxBufferedInput {
-		buffer.cursor++
+	// the skipped frame is counted as dropped when the next input is pulled
+	if len(buffer.inputs) > maxBufferedInput {
+		buffer.inputs = slices.Delete(buffer.inputs, 0, 1)
 	}
 }
 
+// PullInput returns the next input to simulate for the player. when the buffer
+// has run dry the last input is repeated
 func (b *InputBuffer) PullInput(playerID int) BufferedInput {
 	buffer := b.playerBuffers[playerID]
-	if buffer.count == 0 {
-		return BufferedInput{}
+	if len(buffer.inputs) == 0 {
+		if !buffer.pulledAny {
+			return BufferedInput{}
+		}
+		buffer.duplicated++
+		iztlog.ServerLogger.Info("read stale input", "player id", playerID, "input command frame", buffer.lastPulled.LocalCommandFrame)
+		return buffer.lastPulled
 	}
 
-	stale := false
-	if buffer.cursor >= buffer.count && buffer.count != 0 {
-		stale = true
-		buffer.cursor = buffer.count - 1
-	}
-
-	bufferedInput := buffer.inputs[buffer.cursor%maxBufferedInput]
-	buffer.cursor++
+	bufferedInput := buffer.inputs[0]
+	buffer.inputs = slices.Delete(buffer.inputs, 0, 1)
 
-	if stale {
-		iztlog.ServerLogger.Info("read stale input", "player id", playerID, "input command frame", bufferedInput.LocalCommandFrame)
+	if buffer.pulledAny {
+		buffer.dropped += max(0, bufferedInput.LocalCommandFrame-buffer.lastPulled.LocalCommandFrame-1)
 	}
+	buffer.pulledAny = true
+	buffer.lastPulled = bufferedInput
 
 	return bufferedInput
 }
+
+// Stats returns the player's buffer depth along with the total number of inputs
+// dropped and duplicated since the player joined
+func (b *InputBuffer) Stats(playerID int) Stats {
+	buffer, ok := b.playerBuffers[playerID]
+	if !ok {
+		return Stats{}
+	}
+	return Stats{
+		Depth:            len(buffer.inputs),
+		DroppedInputs:    buffer.dropped,
+		DuplicatedInputs: buffer.duplicated,
+	}
+}
@@ -0,0 +1,126 @@
+package inputbuffer
+
+import (
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+type testApp struct{}
+
+func (testApp) Logger() *slog.Logger { return slog.Default() }
+func (testApp) CommandFrame() int    { return 0 }
+
+const playerID = 1
+
+func newTestBuffer() *InputBuffer {
+	b := New(testApp{})
+	b.RegisterPlayer(playerID)
+	return b
+}
+
+func push(b *InputBuffer, frames ...int) {
+	for _, frame := range frames {
+		b.PushInput(playerID, BufferedInput{LocalCommandFrame: frame})
+	}
+}
+
+func pullFrames(b *InputBuffer, n int) []int {
+	var frames []int
+	for range n {
+		frames = append(frames, b.PullInput(playerID).LocalCommandFrame)
+	}
+	return frames
+}
+
+func expectFrames(t *testing.T, got []int, want ...int) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected frames %v, got %v", want, got)
+	}
+}
+
+func expectStats(t *testing.T, b *InputBuffer, want Stats) {
+	t.Helper()
+	if got := b.Stats(playerID); got != want {
+		t.Fatalf("expected stats %+v, got %+v", want, got)
+	}
+}
+
+func TestOutOfOrderAndRedundantInputs(t *testing.T) {
+	b := newTestBuffer()
+
+	// redundant inputs arrive alongside each new one, possibly out of order
+	push(b, 3, 1, 2)
+	push(b, 4, 3, 2)
+	push(b, 2, 1)
+	expectStats(t, b, Stats{Depth: 4})
+
+	expectFrames(t, pullFrames(b, 4), 1, 2, 3, 4)
+	expectStats(t, b, Stats{})
+}
+
+func TestPushAtOrBeforeLastPulled(t *testing.T) {
+	b := newTestBuffer()
+	push(b, 5)
+	expectFrames(t, pullFrames(b, 1), 5)
+
+	// already simulated or too late to be
+	push(b, 5, 4, 1)
+	expectStats(t, b, Stats{})
+
+	push(b, 6)
+	expectFrames(t, pullFrames(b, 1), 6)
+	expectStats(t, b, Stats{})
+}
+
+func TestGapsCountAsDropped(t *testing.T) {
+	b := newTestBuffer()
+	push(b, 1, 2, 5)
+	expectFrames(t, pullFrames(b, 3), 1, 2, 5)
+	expectStats(t, b, Stats{DroppedInputs: 2})
+}
+
+func TestOverflowDropsOldest(t *testing.T) {
+	b := newTestBuffer()
+	push(b, 1)
+	expectFrames(t, pullFrames(b, 1), 1)
+
+	for frame := 2; frame < 2+maxBufferedInput+3; frame++ {
+		push(b, frame)
+	}
+	expectStats(t, b, Stats{Depth: maxBufferedInput})
+
+	// frames 2 through 4 overflowed and are counted once the gap is pulled past
+	expectFrames(t, pullFrames(b, 1), 5)
+	expectStats(t, b, Stats{Depth: maxBufferedInput - 1, DroppedInputs: 3})
+}
+
+func TestRepeatLastInputWhenDry(t *testing.T) {
+	b := newTestBuffer()
+
+	// nothing to repeat before the first input arrives
+	if got := b.PullInput(playerID); got.LocalCommandFrame != 0 || got.PerceivedCommandFrame != 0 {
+		t.Fatalf("expected an empty input before any were pushed, got %+v", got)
+	}
+	expectStats(t, b, Stats{})
+
+	b.PushInput(playerID, BufferedInput{LocalCommandFrame: 1, PerceivedCommandFrame: 10})
+	expectFrames(t, pullFrames(b, 3), 1, 1, 1)
+	if got := b.PullInput(playerID); got.PerceivedCommandFrame != 10 {
+		t.Fatalf("expected the repeated input to be the last one pulled, got %+v", got)
+	}
+	expectStats(t, b, Stats{DuplicatedInputs: 3})
+
+	// the repeated frames were simulated in place of the ones that arrive late
+	push(b, 2, 3)
+	expectFrames(t, pullFrames(b, 2), 2, 3)
+	expectStats(t, b, Stats{DuplicatedInputs: 3})
+}
+
+func TestStatsUnknownPlayer(t *testing.T) {
+	b := newTestBuffer()
+	push(b, 1, 2)
+	b.DeregisterPlayer(playerID)
+	expectStats(t, b, Stats{})
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/navmesh"
 	"github.com/kkevinchou/izzet/izzet/entity"
 	"github.com/kkevinchou/izzet/izzet/event"
 )
@@ -25,6 +26,12 @@ func (g *Server) SpawnPhysicsCube(contactPoint mgl64.Vec3) {
 		LinearDamping:  0.05,
 		AngularDamping: 0.1,
 	}
+	cube.NavMeshObstacleComponent = &entity.NavMeshObstacleComponent{
+		Obstacle: navmesh.Obstacle{
+			Shape:       navmesh.ObstacleShapeBox,
+			HalfExtents: mgl64.Vec3{physicsCubeSize / 2, physicsCubeSize / 2, physicsCubeSize / 2},
+		},
+	}
 	entity.SetLocalPosition(cube, position)
 
 	g.EventsManager().EntitySpawnTopic.Write(event.EntitySpawnEvent{Entity: cube})
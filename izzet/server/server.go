@@ -1,12 +1,14 @@
 package server
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/kkevinchou/izzet/internal/input"
@@ -16,7 +18,9 @@ import (
 	"github.com/kkevinchou/izzet/izzet/collisionobserver"
 	"github.com/kkevinchou/izzet/izzet/entity"
 	"github.com/kkevinchou/izzet/izzet/event"
+	"github.com/kkevinchou/izzet/izzet/navmeshbuilder"
 	"github.com/kkevinchou/izzet/izzet/network"
+	"github.com/kkevinchou/izzet/izzet/project"
 	"github.com/kkevinchou/izzet/izzet/runtimeconfig"
 	"github.com/kkevinchou/izzet/izzet/serialization"
 	"github.com/kkevinchou/izzet/izzet/server/inputbuffer"
@@ -38,6 +42,7 @@ type Server struct {
 
 	systems           []system.System
 	collisionObserver *collisionobserver.CollisionObserver
+	lagCompensation   *serversystem.LagCompensationSystem
 
 	runtimeConfig *runtimeconfig.RuntimeConfig
 
@@ -50,31 +55,138 @@ type Server struct {
 	playerInput  map[int]input.Input
 	eventManager *event.EventManager
 
-	navMesh *navmesh.CompiledNavMesh
+	bakedNavMesh     navmeshbuilder.BakedNavMesh
+	navMeshObstacles *navmeshbuilder.Obstacles
 
 	projectName            string
 	predictionDebugLogging bool
+
+	transport         network.TransportType
+	networkSimulation settings.NetworkSimulationConfig
+
+	// simulatedClients are the players' simulated connections, they're adjusted
+	// from outside the server loop so they're guarded separately from players
+	simulatedClientsMu sync.Mutex
+	simulatedClients   map[int]*network.SimulatedClient
 }
 
+// NewWithFile loads the project's assets followed by the world at filepath,
+// neither requires a GL context
 func NewWithFile(filepath string, projectName string) *Server {
 	s := NewWithWorld(nil, projectName)
+	if err := project.LoadAssets(projectName, s.assetManager); err != nil {
+		panic(err)
+	}
 	world, err := serialization.ReadFromFile(filepath, s.assetManager)
 	if err != nil {
 		panic(err)
 	}
 	s.world = world
+	s.loadNavMesh()
+	s.validatePatrolRoutes()
 	return s
 }
 
+// loadNavMesh loads the project's baked nav mesh, baking a new one if it's
+// missing or was baked from a different version of the world
+func (g *Server) loadNavMesh() {
+	baked, err := project.LoadNavMesh(g.projectName, g.world)
+	if err == nil {
+		baked.Config.Logger = iztlog.ServerLogger
+		g.bakedNavMesh = baked
+		return
+	}
+
+	config := navmeshbuilder.DefaultConfig()
+	if errors.Is(err, project.ErrStaleNavMesh) {
+		config = baked.Config
+	}
+	config.Logger = iztlog.ServerLogger
+	if !errors.Is(err, fs.ErrNotExist) {
+		iztlog.ServerLogger.Warn("rebuilding nav mesh", "reason", err)
+	}
+
+	start := time.Now()
+	g.bakedNavMesh = navmeshbuilder.Bake(g.world, g.assetManager, config)
+	iztlog.ServerLogger.Info("built nav mesh", "build time", time.Since(start).Seconds())
+}
+
+// maxWaypointNavMeshDistance is how far waypoints can be from the nav mesh,
+// they're placed on the ground which the nav mesh sits a little above
+const maxWaypointNavMeshDistance = 1
+
+// validatePatrolRoutes warns about patrol routes that patrols can't walk, e.g.
+// because a waypoint is off the nav mesh or can't be reached from the one
+// before it
+func (g *Server) validatePatrolRoutes() {
+	for _, err := range patrolRouteErrors(g.world, g.NavMesh()) {
+		iztlog.ServerLogger.Warn("invalid patrol route", "entity id", err.routeID, "patrol entity id", err.patrolID, "reason", err.err)
+	}
+}
+
+type patrolRouteError struct {
+	routeID int
+	// patrolID is the entity patrolling the route the error is for, or
+	// entity.InvalidEntityID for routes nothing patrols
+	patrolID int
+	err      error
+}
+
+// patrolRouteErrors validates each patrol route with the query filter of every
+// entity that patrols it, since e.g. a velociraptor can't take a leg through
+// water that the nav mesh itself allows. routes that nothing patrols yet are
+// validated without a filter
+func patrolRouteErrors(w *world.GameWorld, nm *navmesh.CompiledNavMesh) []patrolRouteError {
+	patrols := map[int][]*entity.Entity{}
+	for _, e := range w.Entities() {
+		if e.AIComponent != nil && e.AIComponent.PatrolConfig != nil {
+			routeID := e.AIComponent.PatrolConfig.RouteID
+			patrols[routeID] = append(patrols[routeID], e)
+		}
+	}
+
+	var errs []patrolRouteError
+	for _, e := range w.Entities() {
+		if e.PatrolRouteComponent == nil {
+			continue
+		}
+		route, err := w.PatrolRoute(e.GetID())
+		if err != nil {
+			errs = append(errs, patrolRouteError{routeID: e.GetID(), patrolID: entity.InvalidEntityID, err: err})
+			continue
+		}
+
+		if len(patrols[e.GetID()]) == 0 {
+			if err := route.Validate(nm, nil, maxWaypointNavMeshDistance); err != nil {
+				errs = append(errs, patrolRouteError{routeID: e.GetID(), patrolID: entity.InvalidEntityID, err: err})
+			}
+			continue
+		}
+		for _, patrol := range patrols[e.GetID()] {
+			var filter *navmesh.QueryFilter
+			if patrol.NavigationComponent != nil {
+				filter = patrol.NavigationComponent.QueryFilter
+			}
+			if err := route.Validate(nm, filter, maxWaypointNavMeshDistance); err != nil {
+				errs = append(errs, patrolRouteError{routeID: e.GetID(), patrolID: patrol.GetID(), err: err})
+			}
+		}
+	}
+	return errs
+}
+
 func NewWithWorld(world *world.GameWorld, projectName string) *Server {
 	start := time.Now()
 
 	initSeed()
 	g := &Server{
-		players:      map[int]*network.Player{},
-		playerInput:  map[int]input.Input{},
-		eventManager: event.NewEventManager(),
-		projectName:  projectName,
+		players:          map[int]*network.Player{},
+		playerInput:      map[int]input.Input{},
+		eventManager:     event.NewEventManager(),
+		navMeshObstacles: navmeshbuilder.NewObstacles(),
+		projectName:      projectName,
+		transport:        network.TransportTypeTCP,
+		simulatedClients: map[int]*network.SimulatedClient{},
 	}
 
 	logHandlerOptions := &slog.HandlerOptions{
@@ -107,19 +219,23 @@ func NewWithWorld(world *world.GameWorld, projectName string) *Server {
 	g.systems = append(g.systems, serversystem.NewReceiverSystem(g))
 	g.systems = append(g.systems, serversystem.NewInputSystem(g))
 	g.systems = append(g.systems, serversystem.NewCharacterControllerSystem(g))
+	g.systems = append(g.systems, serversystem.NewPerceptionSystem(g))
 	g.systems = append(g.systems, serversystem.NewAISystemSystem(g))
-	g.systems = append(g.systems, serversystem.NewBrainSystem(g))
+	g.systems = append(g.systems, serversystem.NewNavMeshObstacleSystem(g))
 	g.systems = append(g.systems, serversystem.NewNavigationSystem(g))
 	g.systems = append(g.systems, system.NewKinematicSystem(g))
 	g.systems = append(g.systems, system.NewCharacterOrientationSystem(g))
 	g.systems = append(g.systems, serversystem.NewAttackSystem(g))
 	g.systems = append(g.systems, system.NewCameraSystem(g))
 	g.systems = append(g.systems, system.NewCombatSystem(g))
+	g.systems = append(g.systems, serversystem.NewDamageSystem(g))
 	g.systems = append(g.systems, serversystem.NewServerAnimationSystem(g))
 	g.systems = append(g.systems, serversystem.NewMiscSystem(g))
 	g.systems = append(g.systems, system.NewCleanupSystem(g))
 	g.systems = append(g.systems, serversystem.NewEventsSystem(g))
 	g.systems = append(g.systems, serversystem.NewPhysicsSystem(g))
+	g.lagCompensation = serversystem.NewLagCompensationSystem(g)
+	g.systems = append(g.systems, g.lagCompensation)
 	g.systems = append(g.systems, serversystem.NewReplicationSystem(g))
 
 	fmt.Println(time.Since(start), "to start up systems")
@@ -167,6 +283,7 @@ func (g *Server) Start(started chan bool, done chan bool) {
 		select {
 		case <-done:
 			listener.Close()
+			g.disconnectPlayers()
 			return
 		default:
 			break
@@ -174,6 +291,15 @@ func (g *Server) Start(started chan bool, done chan bool) {
 	}
 }
 
+// disconnectPlayers closes every player's connection so clients see the server
+// go away rather than timing out
+func (g *Server) disconnectPlayers() {
+	for id, player := range g.players {
+		player.Client.Close()
+		g.DeregisterPlayer(id)
+	}
+}
+
 func New(shaderDirectory string, projectName string) *Server {
 	world := world.New()
 	return NewWithWorld(world, projectName)
@@ -185,26 +311,26 @@ func initSeed() {
 }
 
 type NewConnection struct {
-	PlayerID   int
-	Connection net.Conn
+	PlayerID int
+	Client   network.IzzetClient
 }
 
-func (s *Server) listen() (net.Listener, error) {
+func (s *Server) listen() (network.Listener, error) {
 	host := "0.0.0.0"
 	port := "7878"
-	listener, err := net.Listen("tcp", host+":"+port)
+	listener, err := network.Listen(s.transport, host+":"+port)
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Println("listening on " + host + ":" + port)
+	fmt.Println("listening on " + host + ":" + port + " over " + s.transport.String())
 
 	go func() {
 		playerIDGenerator := 100000
 		for {
-			conn, err := listener.Accept()
+			client, err := listener.Accept()
 			if err != nil {
-				if strings.Contains(err.Error(), "use of closed network connection") {
+				if errors.Is(err, net.ErrClosed) {
 					return
 				}
 				fmt.Println("error accepting a connection on the listener:", err.Error())
@@ -213,8 +339,7 @@ func (s *Server) listen() (net.Listener, error) {
 
 			id := playerIDGenerator
 			playerIDGenerator += 1
-
-			s.newConnections <- NewConnection{PlayerID: id, Connection: conn}
+			s.newConnections <- NewConnection{PlayerID: id, Client: client}
 		}
 	}()
 
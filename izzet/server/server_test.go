@@ -0,0 +1,93 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/navmesh"
+	"github.com/kkevinchou/izzet/internal/patrol"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/prefab"
+	"github.com/kkevinchou/izzet/izzet/world"
+)
+
+// rowNavMesh builds a single tile nav mesh with a unit square polygon for each
+// area along x
+func rowNavMesh(areas ...navmesh.AREA_TYPE) *navmesh.CompiledNavMesh {
+	var tile navmesh.CTile
+	for z := range 2 {
+		for x := range len(areas) + 1 {
+			tile.Vertices = append(tile.Vertices, mgl64.Vec3{float64(x), 0, float64(z)})
+		}
+	}
+	top := len(areas) + 1
+	for x, area := range areas {
+		left, right := x-1, x+1
+		if right == len(areas) {
+			right = -1
+		}
+		polygon := navmesh.CPolygon{
+			Vertices:      []int{x, x + top, x + top + 1, x + 1},
+			PolyNeighbors: []int{left, -1, right, -1},
+			Area:          area,
+		}
+		var verts []mgl64.Vec3
+		for _, v := range polygon.Vertices {
+			verts = append(verts, tile.Vertices[v])
+		}
+		tile.Polygons = append(tile.Polygons, polygon)
+		tile.DetailedVertices = append(tile.DetailedVertices, verts)
+		tile.DetailedPolygon = append(tile.DetailedPolygon, navmesh.CDetailedPolygon{Triangles: []navmesh.CDetailedTriangle{
+			{Vertices: [3]int{0, 1, 2}}, {Vertices: [3]int{0, 2, 3}},
+		}})
+	}
+	nm := &navmesh.CompiledNavMesh{Tiles: []navmesh.CTile{tile}}
+	navmesh.LinkTiles(nm)
+	return nm
+}
+
+func TestPatrolRouteErrors(t *testing.T) {
+	// the only way between the waypoints is through the water in between
+	nm := rowNavMesh(navmesh.WALKABLE_AREA, navmesh.WATER_AREA, navmesh.WALKABLE_AREA)
+	w := world.New()
+
+	route := entity.InstantiateBaseEntity("route", 1)
+	route.PatrolRouteComponent = entity.NewPatrolRouteComponent()
+	route.PatrolRouteComponent.Mode = patrol.ModeOnce
+	route.PatrolRouteComponent.Start = 2
+	w.AddEntity(route)
+
+	for i, x := range []float64{0.5, 2.5} {
+		waypoint := entity.InstantiateBaseEntity("waypoint", 2+i)
+		waypoint.WaypointComponent = entity.NewWaypointComponent()
+		if i == 0 {
+			waypoint.WaypointComponent.Next = 3
+		}
+		entity.SetLocalPosition(waypoint, mgl64.Vec3{x, 0, 0.5})
+		w.AddEntity(waypoint)
+	}
+
+	if errs := patrolRouteErrors(w, nm); len(errs) != 0 {
+		t.Fatalf("expected a route nothing patrols to be walkable without a filter, got %v", errs)
+	}
+
+	swimmer := entity.InstantiateBaseEntity("swimmer", 4)
+	swimmer.AIComponent = &entity.AIComponent{PatrolConfig: &entity.PatrolConfig{RouteID: 1}}
+	swimmer.NavigationComponent = &entity.NavigationComponent{}
+	w.AddEntity(swimmer)
+	if errs := patrolRouteErrors(w, nm); len(errs) != 0 {
+		t.Fatalf("expected the route to be walkable for a patrol that swims, got %v", errs)
+	}
+
+	velociraptor := entity.InstantiateBaseEntity("velociraptor", 5)
+	velociraptor.AIComponent = &entity.AIComponent{PatrolConfig: &entity.PatrolConfig{RouteID: 1}}
+	velociraptor.NavigationComponent = &entity.NavigationComponent{QueryFilter: prefab.NavigationQueryFilter(entity.EntityTypeVelociraptor)}
+	w.AddEntity(velociraptor)
+	errs := patrolRouteErrors(w, nm)
+	if len(errs) != 1 || errs[0].routeID != 1 || errs[0].patrolID != 5 {
+		t.Fatalf("expected the water leg to be unwalkable for the velociraptor, got %v", errs)
+	}
+	if want := "waypoint 3 can't be reached from waypoint 2"; errs[0].err.Error() != want {
+		t.Fatalf("error = %v, want %s", errs[0].err, want)
+	}
+}
@@ -2,12 +2,14 @@ package server
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"net"
-	"strings"
+	"strconv"
+	"time"
 
+	"github.com/kkevinchou/izzet/internal/collision/collider"
 	"github.com/kkevinchou/izzet/internal/input"
 	"github.com/kkevinchou/izzet/internal/iztlog"
 	"github.com/kkevinchou/izzet/internal/modelspec"
@@ -16,10 +18,12 @@ import (
 	"github.com/kkevinchou/izzet/izzet/collisionobserver"
 	"github.com/kkevinchou/izzet/izzet/entity"
 	"github.com/kkevinchou/izzet/izzet/event"
+	"github.com/kkevinchou/izzet/izzet/navmeshbuilder"
 	"github.com/kkevinchou/izzet/izzet/network"
 	"github.com/kkevinchou/izzet/izzet/runtimeconfig"
 	"github.com/kkevinchou/izzet/izzet/serialization"
 	"github.com/kkevinchou/izzet/izzet/server/inputbuffer"
+	"github.com/kkevinchou/izzet/izzet/settings"
 	"github.com/kkevinchou/izzet/izzet/world"
 )
 
@@ -27,13 +31,13 @@ func (g *Server) GetPlayers() map[int]*network.Player {
 	return g.players
 }
 
-func (g *Server) RegisterPlayer(playerID int, connection net.Conn) *network.Player {
+func (g *Server) RegisterPlayer(playerID int, c network.IzzetClient) *network.Player {
 	inMessageChannel := make(chan network.MessageTransport, 100)
 	disconnectChannel := make(chan bool, 1)
-	c := network.NewClient(connection)
 	g.inputBuffer.RegisterPlayer(playerID)
+	c = g.simulateNetworkConditions(playerID, c)
 	g.players[playerID] = &network.Player{
-		ID: playerID, Connection: connection,
+		ID:                playerID,
 		InMessageChannel:  inMessageChannel,
 		OutMessageChannel: make(chan network.MessageTransport, 100),
 		DisconnectChannel: disconnectChannel,
@@ -44,21 +48,20 @@ func (g *Server) RegisterPlayer(playerID int, connection net.Conn) *network.Play
 		for {
 			message, err := g.players[playerID].Client.Recv()
 			if err != nil {
-				fmt.Println(fmt.Errorf("error decoding message from player %d - %w", id, err))
-				// f.Write([]byte(fmt.Sprintf("%s - %d - FAILED TO DECODE\n", time.Now().Format("2006-01-02 15:04:05"), message.CommandFrame)))
-				if strings.Contains(err.Error(), "An existing connection was forcibly closed") ||
-					strings.Contains(err.Error(), "An established connection was aborted by the software in your host machine") ||
-					err == io.EOF {
-
-					if err == io.EOF {
-						fmt.Println("Got EOF from remote player", id)
-					}
-					fmt.Println("connection closed by remote player", id)
-					client.Close()
-					discCh <- true
-					return
+				// a message that failed to decode is skipped, any other error
+				// means the connection is closed
+				if errors.Is(err, network.ErrMalformedMessage) {
+					fmt.Println(fmt.Errorf("error decoding message from player %d - %w", id, err))
+					continue
 				}
-				continue
+
+				if errors.Is(err, io.EOF) {
+					fmt.Println("Got EOF from remote player", id)
+				}
+				fmt.Println(fmt.Errorf("connection closed by remote player %d - %w", id, err))
+				client.Close()
+				discCh <- true
+				return
 			}
 			ch <- message
 		}
@@ -70,6 +73,10 @@ func (g *Server) RegisterPlayer(playerID int, connection net.Conn) *network.Play
 func (g *Server) DeregisterPlayer(playerID int) {
 	g.inputBuffer.DeregisterPlayer(playerID)
 	delete(g.players, playerID)
+
+	g.simulatedClientsMu.Lock()
+	delete(g.simulatedClients, playerID)
+	g.simulatedClientsMu.Unlock()
 }
 
 func (g *Server) CommandFrame() int {
@@ -122,6 +129,12 @@ func (g *Server) EventsManager() *event.EventManager {
 	return g.eventManager
 }
 
+// RewindToPerceivedFrame moves hittable entities back to where the player
+// controlling the shooter saw them, the returned func moves them back
+func (g *Server) RewindToPerceivedFrame(shooterID int) ([]*entity.Entity, func()) {
+	return g.lagCompensation.Rewind(g.world, shooterID)
+}
+
 func (g *Server) SystemNames() []string {
 	var names []string
 	for _, s := range g.systems {
@@ -138,12 +151,98 @@ func (g *Server) AssetManager() *assets.AssetManager {
 	return g.assetManager
 }
 
-func (g *Server) SetNavMesh(nm *navmesh.CompiledNavMesh) {
-	g.navMesh = nm
+func (g *Server) SetNavMesh(baked navmeshbuilder.BakedNavMesh) {
+	baked.Config.Logger = iztlog.ServerLogger
+	g.bakedNavMesh = baked
+	g.validatePatrolRoutes()
+}
+
+// SetNetworkTransport selects the transport clients connect over, it must be
+// called before Start
+func (g *Server) SetNetworkTransport(transport network.TransportType) {
+	g.transport = transport
+}
+
+// SetNetworkSimulation degrades the connections of players that join after it's
+// called, see settings.NetworkSimulationConfig
+func (g *Server) SetNetworkSimulation(config settings.NetworkSimulationConfig) {
+	g.networkSimulation = config
+}
+
+func (g *Server) simulateNetworkConditions(playerID int, c network.IzzetClient) network.IzzetClient {
+	config := g.networkSimulation
+	if !config.Enabled {
+		return c
+	}
+
+	conditionsConfig, ok := config.Players[strconv.Itoa(playerID)]
+	if !ok {
+		conditionsConfig = config.Default
+	}
+	conditions := network.NetworkConditionsFromConfig(conditionsConfig)
+	iztlog.ServerLogger.Info("simulating network conditions", "player id", playerID, "conditions", fmt.Sprintf("%+v", conditions))
+
+	// each simulated client seeds two links so players are spaced apart by two
+	simulated := network.NewSimulatedClient(c, conditions, config.Seed+2*int64(playerID))
+
+	g.simulatedClientsMu.Lock()
+	g.simulatedClients[playerID] = simulated
+	g.simulatedClientsMu.Unlock()
+	return simulated
+}
+
+// PlayerNetworkConditions returns the conditions simulated on each player's
+// connection keyed by player id, players without a simulated connection are left out
+func (g *Server) PlayerNetworkConditions() map[int]network.NetworkConditions {
+	g.simulatedClientsMu.Lock()
+	defer g.simulatedClientsMu.Unlock()
+
+	conditions := make(map[int]network.NetworkConditions, len(g.simulatedClients))
+	for playerID, simulated := range g.simulatedClients {
+		conditions[playerID] = simulated.Conditions()
+	}
+	return conditions
+}
+
+// SetPlayerNetworkConditions changes the conditions simulated on a player's
+// connection while the server is running
+func (g *Server) SetPlayerNetworkConditions(playerID int, conditions network.NetworkConditions) {
+	g.simulatedClientsMu.Lock()
+	simulated, ok := g.simulatedClients[playerID]
+	g.simulatedClientsMu.Unlock()
+
+	if ok {
+		simulated.SetConditions(conditions)
+		iztlog.ServerLogger.Info("changed simulated network conditions", "player id", playerID, "conditions", fmt.Sprintf("%+v", conditions))
+	}
 }
 
 func (g *Server) NavMesh() *navmesh.CompiledNavMesh {
-	return g.navMesh
+	return g.bakedNavMesh.NavMesh
+}
+
+func (g *Server) NavMeshObstacles() *navmeshbuilder.Obstacles {
+	return g.navMeshObstacles
+}
+
+// RebuildNavMeshObstacles carves the obstacles that changed since the last
+// rebuild out of the nav mesh and returns the bounds of the changes. the work is
+// capped per call so changes may take several frames to all be carved
+func (g *Server) RebuildNavMeshObstacles() []collider.BoundingBox {
+	if g.bakedNavMesh.NavMesh == nil {
+		// there's nothing to carve the obstacles out of
+		g.navMeshObstacles.DiscardChanges()
+		return nil
+	}
+
+	start := time.Now()
+	baked, changed := g.navMeshObstacles.Rebuild(g.bakedNavMesh, g.world, g.assetManager)
+	if len(changed) == 0 {
+		return nil
+	}
+	g.bakedNavMesh = baked
+	iztlog.ServerLogger.Info("rebuilt nav mesh obstacles", "build time", time.Since(start).Seconds())
+	return changed
 }
 
 func (g *Server) CopyLoadedAnimations(
@@ -10,15 +10,43 @@ type Config struct {
 	Fullscreen    bool
 	Profile       bool
 	ServerAddress string `json:"server_address"`
+	// NetworkCodec is the wire format requested when connecting to a server, "binary" or "json"
+	NetworkCodec string `json:"network_codec"`
+	// NetworkTransport is the transport used between the client and server, "tcp" or "udp"
+	NetworkTransport string `json:"network_transport"`
+	// NetworkSimulation degrades connections to reproduce bad networks locally
+	NetworkSimulation NetworkSimulationConfig `json:"network_simulation"`
+}
+
+type NetworkSimulationConfig struct {
+	Enabled bool  `json:"enabled"`
+	Seed    int64 `json:"seed"`
+	// Client is applied by the client to its own connection
+	Client NetworkConditionsConfig `json:"client"`
+	// Players is applied by the server to each player's connection, keyed by
+	// player id. players without an entry use Default
+	Default NetworkConditionsConfig            `json:"default"`
+	Players map[string]NetworkConditionsConfig `json:"players"`
+}
+
+type NetworkConditionsConfig struct {
+	LatencyMS        float64 `json:"latency_ms"`
+	JitterMS         float64 `json:"jitter_ms"`
+	LossPercent      float64 `json:"loss_percent"`
+	DuplicatePercent float64 `json:"duplicate_percent"`
+	// BandwidthKbps is in kilobits per second, zero is unlimited
+	BandwidthKbps float64 `json:"bandwidth_kbps"`
 }
 
 func NewConfig() Config {
 	return Config{
-		Width:         0,
-		Height:        0,
-		Fullscreen:    false,
-		Profile:       false,
-		ServerAddress: "localhost:7878",
+		Width:            0,
+		Height:           0,
+		Fullscreen:       false,
+		Profile:          false,
+		ServerAddress:    "localhost:7878",
+		NetworkCodec:     "binary",
+		NetworkTransport: "tcp",
 	}
 }
 
@@ -30,6 +58,27 @@ const (
 	MaxEntityCount int = 100000
 
 	NumFramesPerGameStateUpdate int = 10
+	// MaxGameStateBaselines is the number of game state snapshots kept around to
+	// delta compress against, older acks fall back to full snapshots
+	MaxGameStateBaselines int = 32
+	// GameStateUpdateByteBudget caps the encoded entity states in a single game state
+	// update, lower priority entities are deferred to later updates when it's exceeded
+	GameStateUpdateByteBudget int = 4096
+	// MaxLagCompensationFrames is the furthest back the server rewinds hittable
+	// entities to check a player's shots against what they saw, ~250ms
+	MaxLagCompensationFrames int = 32
+	// MaxInterpolationDelayFrames caps how far behind the server interpolated
+	// entities are rendered, any further and the server can't rewind shots to
+	// what the player saw
+	MaxInterpolationDelayFrames int = MaxLagCompensationFrames
+	// MaxExtrapolationFrames is how long interpolated entities keep moving along
+	// their last velocity when snapshots are late, ~100ms
+	MaxExtrapolationFrames int = 12
+
+	// entities within RelevancyRadius of a player's entity or camera are replicated
+	// to them, they stop being replicated once they're beyond RelevancyLeaveRadius
+	RelevancyRadius      float64 = 150
+	RelevancyLeaveRadius float64 = 200
 
 	// FPS is the number of rendered frames per second, separate from command frames
 	FPS         int     = 144
@@ -42,6 +91,11 @@ const (
 	// the maximum number of command frames to execute in a single loop to prevent the spiral of death
 	MaxCommandFramesPerLoop int = 10
 
+	// clients stretch their command frames by TimeDilationGain per frame of
+	// input buffered on the server away from the target, up to MaxTimeDilation
+	TimeDilationGain float64 = 0.01
+	MaxTimeDilation  float64 = 0.05
+
 	// Animation
 	MaxAnimationJointWeights = 4
 
@@ -58,7 +112,9 @@ const (
 	GizmoDistanceFactor float64 = 8
 
 	MaxCommandFrameBufferSize int = 100000
-	MaxStateBufferSize        int = 100
+	// MaxStateBufferSize is the number of game state snapshots buffered for
+	// interpolation, the oldest are dropped past it
+	MaxStateBufferSize int = 100
 
 	FirstPersonCamera          bool    = false
 	CameraEntityFollowDistance float64 = 5
@@ -75,8 +131,10 @@ const (
 	CameraSlowSpeed          float64 = 2
 	AccelerationDueToGravity float64 = 60 // units per second
 
-	BuiltinAssetsDir                string  = "_assets"
-	RenderBlendDurationMilliseconds float64 = 3000
+	BuiltinAssetsDir string = "_assets"
+
+	// corrections further than this are snapped to rather than smoothed
+	MaxSmoothedCorrectionDistance float64 = 5
 
 	// Gizmos
 	ScaleSensitivity        float64 = 0.8
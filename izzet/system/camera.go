@@ -7,7 +7,6 @@ import (
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/kkevinchou/izzet/internal/collision/checks"
 	"github.com/kkevinchou/izzet/internal/collision/collider"
-	"github.com/kkevinchou/izzet/izzet/apputils"
 	"github.com/kkevinchou/izzet/izzet/entity"
 	"github.com/kkevinchou/izzet/izzet/settings"
 )
@@ -59,12 +58,8 @@ func (s *CameraSystem) update(delta time.Duration, world GameWorld, camera *enti
 		return
 	}
 
-	position := target.Position()
-	if target.RenderBlend.Active {
-		deltaMs := time.Since(target.RenderBlend.StartTime).Milliseconds()
-		t := apputils.RenderBlendMath(deltaMs)
-		position = position.Sub(target.RenderBlend.BlendStartPosition).Mul(t).Add(target.RenderBlend.BlendStartPosition)
-	}
+	// follow the target where it's drawn so the camera doesn't pop on corrections
+	position := entity.RenderPosition(target)
 
 	// pivot is the 3d point that the camera will rotate around
 	pivot := position.Add(mgl64.Vec3{0, 1.75, 0})
@@ -2,8 +2,8 @@ package clientsystem
 
 import (
 	"log/slog"
-	"net"
 
+	"github.com/kkevinchou/izzet/internal/clocksync"
 	"github.com/kkevinchou/izzet/internal/input"
 	"github.com/kkevinchou/izzet/izzet/assets"
 	"github.com/kkevinchou/izzet/izzet/entity"
@@ -23,12 +23,13 @@ type App interface {
 	IsClient() bool
 	IsServer() bool
 	Logger() *slog.Logger
-	GetPlayerConnection() net.Conn
 	GetPlayerEntity() *entity.Entity
 	GetPlayerCamera() *entity.Entity
 	GetCommandFrameHistory() *CommandFrameHistory
 	Client() network.IzzetClient
 	StateBuffer() *StateBuffer
+	SnapshotHistory() *SnapshotHistory
+	TimeDilation() *clocksync.Dilation
 	GetFrameInput() input.Input
 	GetFrameInputPtr() *input.Input
 	SetServerStats(stats serverstats.ServerStats)
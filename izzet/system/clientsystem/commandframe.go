@@ -6,6 +6,7 @@ import (
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/kkevinchou/izzet/internal/input"
 	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/network"
 	"github.com/kkevinchou/izzet/izzet/settings"
 )
 
@@ -19,10 +20,26 @@ type PostCommandFrameState struct {
 	GravityEnabled      bool
 }
 
+// entityState returns the predicted state in the form of a server state so an
+// entity can be rolled back to it
+func (s PostCommandFrameState) entityState() network.EntityState {
+	return network.EntityState{
+		EntityID:            s.ID,
+		Position:            s.Position,
+		Rotation:            s.Rotation,
+		Velocity:            s.Velocity,
+		AccumulatedVelocity: s.AccumulatedVelocity,
+		Grounded:            s.Grounded,
+		GravityEnabled:      s.GravityEnabled,
+	}
+}
+
 type CommandFrame struct {
 	FrameNumber int
 	FrameInput  input.Input
-	PostCFState PostCommandFrameState
+	// PostCFStates are the states of the predicted entities at the end of the
+	// command frame, by entity id
+	PostCFStates map[int]PostCommandFrameState
 }
 
 type CommandFrameHistory struct {
@@ -35,23 +52,28 @@ func NewCommandFrameHistory() *CommandFrameHistory {
 	return &CommandFrameHistory{CommandFrameCursor: 0}
 }
 
-func (h *CommandFrameHistory) AddCommandFrame(frameNumber int, frameInput input.Input, player *entity.Entity) {
+// AddCommandFrame records the input of the command frame along with the states
+// of the predicted entities after it was simulated
+func (h *CommandFrameHistory) AddCommandFrame(frameNumber int, frameInput input.Input, predicted []*entity.Entity) {
 	if h.CommandFrameCount == settings.MaxCommandFrameBufferSize {
 		panic("command frame buffer size exceeded")
 	}
 
 	cf := CommandFrame{
-		FrameNumber: frameNumber,
-		FrameInput:  frameInput,
-		PostCFState: PostCommandFrameState{
-			ID:                  player.GetID(),
-			Position:            player.LocalPosition,
-			Rotation:            player.LocalRotation,
-			Velocity:            player.Kinematic.Velocity,
-			AccumulatedVelocity: player.Kinematic.AccumulatedVelocity,
-			Grounded:            player.Kinematic.Grounded,
-			GravityEnabled:      player.Kinematic.GravityEnabled,
-		},
+		FrameNumber:  frameNumber,
+		FrameInput:   frameInput,
+		PostCFStates: make(map[int]PostCommandFrameState, len(predicted)),
+	}
+	for _, e := range predicted {
+		cf.PostCFStates[e.GetID()] = PostCommandFrameState{
+			ID:                  e.GetID(),
+			Position:            e.LocalPosition,
+			Rotation:            e.LocalRotation,
+			Velocity:            e.Kinematic.Velocity,
+			AccumulatedVelocity: e.Kinematic.AccumulatedVelocity,
+			Grounded:            e.Kinematic.Grounded,
+			GravityEnabled:      e.Kinematic.GravityEnabled,
+		}
 	}
 
 	h.CommandFrames[(h.CommandFrameCursor+h.CommandFrameCount)%settings.MaxCommandFrameBufferSize] = cf
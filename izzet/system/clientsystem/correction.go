@@ -0,0 +1,92 @@
+package clientsystem
+
+import (
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/smoothing"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/settings"
+	"github.com/kkevinchou/izzet/izzet/system"
+	"github.com/kkevinchou/izzet/izzet/telemetry"
+)
+
+// CorrectionSystem decays the visual error left over from mispredictions so
+// predicted entities ease into their corrected transforms
+type CorrectionSystem struct {
+	app App
+}
+
+func NewCorrectionSystem(app App) *CorrectionSystem {
+	return &CorrectionSystem{app: app}
+}
+
+func (s *CorrectionSystem) Name() string {
+	return "CorrectionSystem"
+}
+
+func (s *CorrectionSystem) Update(delta time.Duration, world system.GameWorld) {
+	duration := time.Duration(s.app.RuntimeConfig().CorrectionDecayMilliseconds) * time.Millisecond
+
+	// how far the rendered transforms were moved towards the simulated ones
+	// this frame
+	var positionCorrection, rotationCorrection float64
+	for _, e := range world.Entities() {
+		if e.RenderBlend == nil || e.RenderBlend.Offset.IsZero() {
+			continue
+		}
+
+		before := e.RenderBlend.Offset
+		e.RenderBlend.Offset = before.Decay(delta, duration)
+
+		positionBefore, rotationBefore := before.Magnitude()
+		positionAfter, rotationAfter := e.RenderBlend.Offset.Magnitude()
+		positionCorrection += positionBefore - positionAfter
+		rotationCorrection += rotationBefore - rotationAfter
+	}
+
+	mr := telemetry.ClientRegistry()
+	mr.Inc("prediction_correction", positionCorrection)
+	mr.Inc("prediction_correction_rotation", mgl64.RadToDeg(rotationCorrection))
+}
+
+type renderedTransform struct {
+	position mgl64.Vec3
+	rotation mgl64.Quat
+}
+
+// renderedTransforms returns where each of the entities is currently drawn
+func renderedTransforms(entities []*entity.Entity) map[int]renderedTransform {
+	transforms := make(map[int]renderedTransform, len(entities))
+	for _, e := range entities {
+		var offset smoothing.Offset
+		if e.RenderBlend != nil {
+			offset = e.RenderBlend.Offset
+		}
+		position, rotation := offset.Apply(e.Position(), e.Rotation())
+		transforms[e.GetID()] = renderedTransform{position: position, rotation: rotation}
+	}
+	return transforms
+}
+
+// smoothCorrections keeps the entities drawn where they were before a replay
+// moved them, the difference is carried as a render offset that the correction
+// system decays. corrections that are too large to pass off as smoothing, like
+// teleports, are snapped to
+func smoothCorrections(entities []*entity.Entity, rendered map[int]renderedTransform) {
+	for _, e := range entities {
+		before, ok := rendered[e.GetID()]
+		if !ok {
+			continue
+		}
+		if e.RenderBlend == nil {
+			e.RenderBlend = &entity.RenderBlend{}
+		}
+
+		offset := smoothing.Correct(before.position, before.rotation, e.Position(), e.Rotation())
+		if distance, _ := offset.Magnitude(); distance > settings.MaxSmoothedCorrectionDistance {
+			offset = smoothing.Offset{}
+		}
+		e.RenderBlend.Offset = offset
+	}
+}
@@ -3,6 +3,7 @@ package clientsystem
 import (
 	"fmt"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/go-gl/mathgl/mgl64"
@@ -23,6 +24,16 @@ import (
 type InputSystem struct {
 	app App
 	f   *os.File
+
+	// recentInputs are the inputs sent for the most recent command frames, most
+	// recent first, they're resent with each input in case earlier messages were
+	// lost. they're cleared when the command frame restarts on connecting
+	recentInputs []sentInput
+}
+
+type sentInput struct {
+	commandFrame int
+	input        input.Input
 }
 
 func NewInputSystem(app App) *InputSystem {
@@ -147,8 +158,34 @@ func computeCameraRotation(frameInput input.Input, camera *entity.Entity, sensit
 }
 
 func (s *InputSystem) handleSendInputToServer(frameInput *input.Input) {
+	commandFrame := s.app.CommandFrame()
+	redundantCount := int(s.app.RuntimeConfig().RedundantInputs)
+	if len(s.recentInputs) > 0 && s.recentInputs[0].commandFrame >= commandFrame {
+		// the inputs were sent over a previous connection
+		s.recentInputs = nil
+	}
+
+	// the server reads redundant inputs as belonging to the frames right before
+	// this one, so stop at the first gap
+	var redundantInputs []input.Input
+	for i, sent := range s.recentInputs {
+		if i >= redundantCount || sent.commandFrame != commandFrame-1-i {
+			break
+		}
+		redundantInputs = append(redundantInputs, sent.input)
+	}
+
+	latestFrame := s.app.SnapshotHistory().LatestFrame()
 	inputMessage := network.InputMessage{
-		Input: *frameInput,
+		Input:               *frameInput,
+		RedundantInputs:     redundantInputs,
+		AckedGameStateFrame: latestFrame,
+		InterpolationDelay:  s.app.StateBuffer().InterpolationDelay(latestFrame),
+	}
+
+	s.recentInputs = slices.Insert(s.recentInputs, 0, sentInput{commandFrame: commandFrame, input: *frameInput})
+	if len(s.recentInputs) > redundantCount {
+		s.recentInputs = s.recentInputs[:redundantCount]
 	}
 
 	err := s.app.Client().Send(inputMessage, s.app.CommandFrame())
@@ -22,20 +22,29 @@ func (s *PostFrameSystem) Name() string {
 func (s *PostFrameSystem) Update(delta time.Duration, world system.GameWorld) {
 	sb := s.app.StateBuffer()
 	playerEntity := s.app.GetPlayerEntity()
-	if bi, ok := sb.Pull(s.app.CommandFrame()); ok {
+	predicted := PredictedEntities(s.app)
+	predictedIDs := make(map[int]bool, len(predicted))
+	for _, e := range predicted {
+		predictedIDs[e.GetID()] = true
+	}
+
+	if bi, ok := sb.Pull(s.app.CommandFrame(), s.app.RuntimeConfig()); ok {
 		for _, bs := range bi.EntityStates {
 			e := world.GetEntityByID(bs.EntityID)
 			if e == nil {
 				continue
 			}
 
-			if bs.EntityID == playerEntity.ID || bs.EntityID == playerEntity.CharacterControllerComponent.CameraEntityID {
+			if bs.EntityID == playerEntity.CharacterControllerComponent.CameraEntityID {
 				continue
 			}
 
-			entity.SetLocalPosition(e, bs.Position)
-			e.SetLocalRotation(bs.Rotation)
-			if e.Animation != nil {
+			// predicted entities are simulated by the client rather than interpolated
+			if !predictedIDs[bs.EntityID] {
+				entity.SetLocalPosition(e, bs.Position)
+				e.SetLocalRotation(bs.Rotation)
+			}
+			if e.Animation != nil && bs.EntityID != playerEntity.ID {
 				e.Animation.ReplicatedAnimationTransition = bs.AnimationTransition
 			}
 		}
@@ -43,5 +52,5 @@ func (s *PostFrameSystem) Update(delta time.Duration, world system.GameWorld) {
 
 	history := s.app.GetCommandFrameHistory()
 	// fmt.Printf("CLIENT ACTUAL - [%d] - %v\n", s.app.CommandFrame(), entity.GetLocalPosition(s.app.GetPlayerEntity()))
-	history.AddCommandFrame(s.app.CommandFrame(), s.app.GetFrameInput(), s.app.GetPlayerEntity())
+	history.AddCommandFrame(s.app.CommandFrame(), s.app.GetFrameInput(), predicted)
 }
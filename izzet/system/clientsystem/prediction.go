@@ -0,0 +1,85 @@
+package clientsystem
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/network"
+	"github.com/kkevinchou/izzet/izzet/runtimeconfig"
+)
+
+// PredictedEntities returns the entities the client simulates ahead of the
+// server, the player's entity and, when enabled in the runtime config, the
+// kinematic entities within the prediction radius of it that aren't driven by
+// other players, AI or navigation. the rest of the world is interpolated
+// between game state updates
+func PredictedEntities(app App) []*entity.Entity {
+	return predictedEntities(app.GetPlayerEntity(), app.World().Entities(), app.RuntimeConfig())
+}
+
+func predictedEntities(player *entity.Entity, entities []*entity.Entity, config *runtimeconfig.RuntimeConfig) []*entity.Entity {
+	if player == nil {
+		return nil
+	}
+
+	predicted := []*entity.Entity{player}
+	if !config.PredictKinematicEntities {
+		return predicted
+	}
+
+	radius := float64(config.PredictionRadius)
+	for _, e := range entities {
+		if e.GetID() == player.GetID() || e.Static || e.Parent != nil || e.Kinematic == nil || e.Deadge {
+			continue
+		}
+		// entities steered by AI or navigation change their move intent on the
+		// server in ways the client can't replay, predicting them only mispredicts
+		if e.AIComponent != nil || e.NavigationComponent != nil {
+			continue
+		}
+		// the same goes for other players, the client never sees their inputs
+		if e.CharacterControllerComponent != nil {
+			continue
+		}
+		if e.Position().Sub(player.Position()).Len() > radius {
+			continue
+		}
+		predicted = append(predicted, e)
+	}
+	return predicted
+}
+
+// mispredictions returns the ids of the entities whose predicted state in the
+// command frame doesn't match the server's, along with the largest distance
+// between a predicted and server position
+func mispredictions(cf CommandFrame, serverStates []network.EntityState) ([]int, float64) {
+	var mispredicted []int
+	var maxError float64
+	for _, state := range serverStates {
+		predicted, ok := cf.PostCFStates[state.EntityID]
+		if !ok || predictedStateMatchesServer(predicted, state) {
+			continue
+		}
+		mispredicted = append(mispredicted, state.EntityID)
+		maxError = max(maxError, predicted.Position.Sub(state.Position).Len())
+	}
+	return mispredicted, maxError
+}
+
+// rollback sets the entity to its state in a game state update. only the
+// player's inputs are known to the client so other entities are predicted to
+// keep moving the way the server last saw them move
+func rollback(e *entity.Entity, state network.EntityState, isPlayer bool) {
+	entity.SetLocalPosition(e, state.Position)
+	e.SetLocalRotation(state.Rotation)
+	e.Kinematic.Velocity = state.Velocity
+	e.Kinematic.AccumulatedVelocity = state.AccumulatedVelocity
+	e.Kinematic.Grounded = state.Grounded
+	e.Kinematic.GravityEnabled = state.GravityEnabled
+
+	if !isPlayer {
+		e.Kinematic.MoveIntent = mgl64.Vec3{}
+		if e.Kinematic.Speed > 0 {
+			e.Kinematic.MoveIntent = state.Velocity.Mul(1 / e.Kinematic.Speed)
+		}
+	}
+}
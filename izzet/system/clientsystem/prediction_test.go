@@ -0,0 +1,144 @@
+package clientsystem
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/network"
+	"github.com/kkevinchou/izzet/izzet/runtimeconfig"
+)
+
+func newKinematicEntity(id int, position mgl64.Vec3) *entity.Entity {
+	e := entity.InstantiateBaseEntity("kinematic", id)
+	e.Kinematic = &entity.KinematicComponent{}
+	entity.SetLocalPosition(e, position)
+	return e
+}
+
+func entityIDs(entities []*entity.Entity) []int {
+	var ids []int
+	for _, e := range entities {
+		ids = append(ids, e.GetID())
+	}
+	return ids
+}
+
+func TestPredictedEntities(t *testing.T) {
+	player := newKinematicEntity(1, mgl64.Vec3{})
+	near := newKinematicEntity(2, mgl64.Vec3{5, 0, 0})
+	far := newKinematicEntity(3, mgl64.Vec3{50, 0, 0})
+
+	static := newKinematicEntity(4, mgl64.Vec3{1, 0, 0})
+	static.Static = true
+
+	nonKinematic := entity.InstantiateBaseEntity("prop", 5)
+	entity.SetLocalPosition(nonKinematic, mgl64.Vec3{1, 0, 0})
+
+	ai := newKinematicEntity(6, mgl64.Vec3{1, 0, 0})
+	ai.AIComponent = &entity.AIComponent{}
+
+	navigating := newKinematicEntity(7, mgl64.Vec3{1, 0, 0})
+	navigating.NavigationComponent = &entity.NavigationComponent{}
+
+	entities := []*entity.Entity{near, far, static, nonKinematic, ai, navigating, player}
+
+	config := runtimeconfig.DefaultRuntimeConfig()
+	config.PredictKinematicEntities = true
+	config.PredictionRadius = 10
+
+	got := entityIDs(predictedEntities(player, entities, config))
+	if want := []int{1, 2}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v to be predicted, got %v", want, got)
+	}
+
+	config.PredictionRadius = 100
+	got = entityIDs(predictedEntities(player, entities, config))
+	if want := []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v to be predicted in the larger radius, got %v", want, got)
+	}
+
+	// the player is predicted even outside of its own radius and with
+	// prediction of other entities turned off
+	config.PredictionRadius = 0
+	got = entityIDs(predictedEntities(player, entities, config))
+	if want := []int{1}; !slices.Equal(got, want) {
+		t.Fatalf("expected only the player to be predicted with no radius, got %v", got)
+	}
+
+	config.PredictionRadius = 100
+	config.PredictKinematicEntities = false
+	got = entityIDs(predictedEntities(player, entities, config))
+	if want := []int{1}; !slices.Equal(got, want) {
+		t.Fatalf("expected only the player to be predicted when disabled, got %v", got)
+	}
+
+	if got := predictedEntities(nil, entities, config); got != nil {
+		t.Fatalf("expected nothing to be predicted without a player, got %v", entityIDs(got))
+	}
+}
+
+func TestPredictedEntitiesSkipsRemotePlayers(t *testing.T) {
+	player := newKinematicEntity(1, mgl64.Vec3{})
+	player.CharacterControllerComponent = &entity.CharacterControllerComponent{}
+	remotePlayer := newKinematicEntity(2, mgl64.Vec3{5, 0, 0})
+	remotePlayer.CharacterControllerComponent = &entity.CharacterControllerComponent{}
+	entities := []*entity.Entity{player, remotePlayer}
+
+	config := runtimeconfig.DefaultRuntimeConfig()
+	if config.PredictKinematicEntities {
+		t.Fatal("expected prediction of other entities to be off by default")
+	}
+
+	config.PredictKinematicEntities = true
+	config.PredictionRadius = 10
+	got := entityIDs(predictedEntities(player, entities, config))
+	if want := []int{1}; !slices.Equal(got, want) {
+		t.Fatalf("expected the remote player to be interpolated rather than predicted, got %v", got)
+	}
+}
+
+func TestMispredictions(t *testing.T) {
+	cf := CommandFrame{
+		PostCFStates: map[int]PostCommandFrameState{
+			1: {ID: 1, Position: mgl64.Vec3{1, 0, 0}},
+			2: {ID: 2, Position: mgl64.Vec3{2, 0, 0}},
+			3: {ID: 3, Position: mgl64.Vec3{3, 0, 0}, Grounded: true},
+		},
+	}
+
+	serverStates := []network.EntityState{
+		// within the threshold
+		{EntityID: 1, Position: mgl64.Vec3{1.0005, 0, 0}},
+		// off by half a unit
+		{EntityID: 2, Position: mgl64.Vec3{2.5, 0, 0}},
+		// matching position but not grounded on the server
+		{EntityID: 3, Position: mgl64.Vec3{3, 0, 0}},
+		// not predicted
+		{EntityID: 4, Position: mgl64.Vec3{100, 0, 0}},
+	}
+
+	mispredicted, maxError := mispredictions(cf, serverStates)
+	if want := []int{2, 3}; !slices.Equal(mispredicted, want) {
+		t.Fatalf("expected %v to be mispredicted, got %v", want, mispredicted)
+	}
+	if maxError < 0.5-1e-9 || maxError > 0.5+1e-9 {
+		t.Fatalf("expected a max error of 0.5, got %v", maxError)
+	}
+
+	serverStates[1].Position = mgl64.Vec3{2, 0, 0.002}
+	serverStates[2].Grounded = true
+	mispredicted, maxError = mispredictions(cf, serverStates)
+	if want := []int{2}; !slices.Equal(mispredicted, want) {
+		t.Fatalf("expected an error just over the threshold to mispredict, got %v", mispredicted)
+	}
+	if maxError < 0.002-1e-9 || maxError > 0.002+1e-9 {
+		t.Fatalf("expected a max error of 0.002, got %v", maxError)
+	}
+
+	serverStates[1].Position = mgl64.Vec3{2, 0, 0}
+	if mispredicted, maxError := mispredictions(cf, serverStates); mispredicted != nil || maxError != 0 {
+		t.Fatalf("expected no mispredictions, got %v with error %v", mispredicted, maxError)
+	}
+}
@@ -1,7 +1,6 @@
 package clientsystem
 
 import (
-	"encoding/json"
 	"fmt"
 	"time"
 
@@ -14,6 +13,9 @@ import (
 
 type ReceiverSystem struct {
 	app App
+
+	// the server's running input feedback totals as of the last update
+	inputFeedback network.InputFeedback
 }
 
 func NewReceiverSystem(app App) *ReceiverSystem {
@@ -31,13 +33,22 @@ func (s *ReceiverSystem) Update(delta time.Duration, world system.GameWorld) {
 		select {
 		case message := <-s.app.NetworkMessagesChannel():
 			if message.MessageType == network.MsgTypeGameStateUpdate {
-				var gamestateUpdateMessage network.GameStateUpdateMessage
-				err := json.Unmarshal(message.Body, &gamestateUpdateMessage)
+				gamestateUpdateMessage, err := network.ExtractMessage[network.GameStateUpdateMessage](message)
 				if err != nil {
 					fmt.Println(fmt.Errorf("failed to deserialize message %w", err))
 					continue
 				}
 
+				// updates may be deltas against an earlier snapshot, everything
+				// past this point works with the reconstructed full state
+				gamestateUpdateMessage, err = s.app.SnapshotHistory().Apply(gamestateUpdateMessage)
+				if err != nil {
+					fmt.Println(fmt.Errorf("failed to apply game state delta %w", err))
+					continue
+				}
+
+				s.handleInputFeedback(gamestateUpdateMessage.InputFeedback)
+
 				// this is an edge case where the player has joined, and is receiving
 				// a game state update but hasn't had input processed by the server yet.
 				// this results in a LastInputCommandFrame of 0, which will not be found
@@ -48,9 +59,6 @@ func (s *ReceiverSystem) Update(delta time.Duration, world system.GameWorld) {
 
 				s.app.SetServerStats(gamestateUpdateMessage.ServerStats)
 
-				playerEntityID := s.app.GetPlayerEntity().GetID()
-				var serverEntityState network.EntityState
-
 				for _, entityState := range gamestateUpdateMessage.EntityStates {
 					e := world.GetEntityByID(entityState.EntityID)
 					if e == nil {
@@ -59,10 +67,9 @@ func (s *ReceiverSystem) Update(delta time.Duration, world system.GameWorld) {
 
 					// TODO - move this into statebuffer handling
 					e.Deadge = entityState.Deadge
-
-					if e.GetID() == playerEntityID {
-						serverEntityState = entityState
-						continue
+					if e.HealthComponent != nil {
+						e.HealthComponent.Amount = entityState.Health
+						e.HealthComponent.Max = entityState.MaxHealth
 					}
 				}
 
@@ -80,36 +87,33 @@ func (s *ReceiverSystem) Update(delta time.Duration, world system.GameWorld) {
 				if err != nil {
 					panic(err)
 				}
-				if predictedStateMatchesServer(cf.PostCFState, serverEntityState) {
+
+				predicted := PredictedEntities(s.app)
+				mr.Inc("predicted_entities", float64(len(predicted)))
+
+				mispredicted, predictionError := mispredictions(cf, gamestateUpdateMessage.EntityStates)
+				if len(mispredicted) == 0 {
 					mr.Inc("prediction_hit", 1)
-					// 		gamestateUpdateMessage.LastInputCommandFrame,
-					// 	)
-					// }
 					cfHistory.ClearUntilFrameNumber(gamestateUpdateMessage.LastInputCommandFrame)
-					player := s.app.GetPlayerEntity()
-					player.RenderBlend.Active = false
 				} else {
 					mr.Inc("prediction_miss", 1)
-					player := s.app.GetPlayerEntity()
-					s.app.Logger().Info("prediction miss", "last input command frame", gamestateUpdateMessage.LastInputCommandFrame)
-
-					// if s.app.PredictionDebugLogging() {
-					// 	fmt.Printf("\t - Predictiton Miss [Frame: %d] [Client: %s] [Server: %s]\n",
-					// 		gamestateUpdateMessage.LastInputCommandFrame,
-					// 		apputils.FormatVec(state.Position),
-					// 		apputils.FormatVec(serverTransform.Position),
-					// 	)
-					// }
-
-					player.RenderBlend.StartTime = time.Now()
-					player.RenderBlend.BlendStartPosition = player.Position()
-					if err := replay(s.app, player, gamestateUpdateMessage, cfHistory, world); err != nil {
+					mr.Inc("prediction_mispredicted_entities", float64(len(mispredicted)))
+					mr.Inc("prediction_error", predictionError)
+					s.app.Logger().Info(
+						"prediction miss",
+						"last input command frame", gamestateUpdateMessage.LastInputCommandFrame,
+						"mispredicted entities", mispredicted,
+						"position error", predictionError,
+					)
+
+					rendered := renderedTransforms(predicted)
+					if err := replay(s.app, predicted, gamestateUpdateMessage, cfHistory); err != nil {
 						panic(err)
 					}
+					smoothCorrections(predicted, rendered)
 				}
 			} else if message.MessageType == network.MsgTypeCreateEntity {
-				var createEntityMessage network.CreateEntityMessage
-				err := json.Unmarshal(message.Body, &createEntityMessage)
+				createEntityMessage, err := network.ExtractMessage[network.CreateEntityMessage](message)
 				if err != nil {
 					fmt.Println(fmt.Errorf("failed to deserialize message %w", err))
 					continue
@@ -121,6 +125,20 @@ func (s *ReceiverSystem) Update(delta time.Duration, world system.GameWorld) {
 					continue
 				}
 				world.AddEntity(e)
+			} else if message.MessageType == network.MsgTypeDestroyEntity {
+				destroyEntityMessage, err := network.ExtractMessage[network.DestroyEntityMessage](message)
+				if err != nil {
+					fmt.Println(fmt.Errorf("failed to deserialize message %w", err))
+					continue
+				}
+
+				// the player's own entities are always relevant, this would only
+				// happen if they were destroyed on the server
+				player := s.app.GetPlayerEntity()
+				if destroyEntityMessage.EntityID == player.GetID() || destroyEntityMessage.EntityID == player.CharacterControllerComponent.CameraEntityID {
+					continue
+				}
+				world.DeleteEntity(destroyEntityMessage.EntityID)
 			} else if message.MessageType == network.MsgTypePing {
 				pingMessage, err := network.ExtractMessage[network.PingMessage](message)
 				if err != nil {
@@ -150,3 +168,17 @@ func predictedStateMatchesServer(predicted PostCommandFrameState, server network
 
 	return predicted.Grounded == server.Grounded && predicted.GravityEnabled == server.GravityEnabled
 }
+
+// handleInputFeedback tracks the server's buffer of our inputs so time dilation
+// can keep it at its target depth
+func (s *ReceiverSystem) handleInputFeedback(feedback network.InputFeedback) {
+	timeDilation := s.app.TimeDilation()
+	timeDilation.Observe(feedback.BufferDepth)
+
+	mr := telemetry.ClientRegistry()
+	mr.Inc("time_dilation", timeDilation.Scale(float64(s.app.RuntimeConfig().TargetInputBufferDepth)))
+	mr.Inc("input_buffer_depth", float64(feedback.BufferDepth))
+	mr.Inc("input_dropped", float64(max(0, feedback.DroppedInputs-s.inputFeedback.DroppedInputs)))
+	mr.Inc("input_duplicated", float64(max(0, feedback.DuplicatedInputs-s.inputFeedback.DuplicatedInputs)))
+	s.inputFeedback = feedback
+}
@@ -6,11 +6,17 @@ import (
 	"github.com/kkevinchou/izzet/izzet/entity"
 	"github.com/kkevinchou/izzet/izzet/network"
 	"github.com/kkevinchou/izzet/izzet/settings"
-	"github.com/kkevinchou/izzet/izzet/system"
 	"github.com/kkevinchou/izzet/izzet/system/shared"
+	"github.com/kkevinchou/izzet/izzet/telemetry"
 )
 
-func replay(app App, e *entity.Entity, gamestateUpdateMessage network.GameStateUpdateMessage, cfHistory *CommandFrameHistory, world system.GameWorld) error {
+// replay rolls the predicted entities back to their states in the game state
+// update and re-simulates them together up to the current command frame so
+// collisions between them are predicted the same way the server resolved them.
+// predicted entities that weren't in the update, e.g. deferred by the server's
+// byte budget, are rolled back to their own predicted state instead, and ones
+// with no state to roll back to aren't replayed
+func replay(app shared.App, predicted []*entity.Entity, gamestateUpdateMessage network.GameStateUpdateMessage, cfHistory *CommandFrameHistory) error {
 	commandFrames, err := cfHistory.GetAllFramesStartingFrom(gamestateUpdateMessage.LastInputCommandFrame)
 	if err != nil {
 		return err
@@ -20,40 +26,41 @@ func replay(app App, e *entity.Entity, gamestateUpdateMessage network.GameStateU
 		panic("the first frame we fetch should match the last input command frame")
 	}
 
-	for _, transform := range gamestateUpdateMessage.EntityStates {
-		// special case for the player for now
-		if transform.EntityID != e.GetID() {
-			continue
-		}
-
-		entity.SetLocalPosition(e, transform.Position)
-		e.SetLocalRotation(transform.Rotation)
-		e.Kinematic.Velocity = transform.Velocity
-		e.Kinematic.AccumulatedVelocity = transform.AccumulatedVelocity
-		e.Kinematic.Grounded = transform.Grounded
-		e.Kinematic.GravityEnabled = transform.GravityEnabled
+	serverStates := make(map[int]network.EntityState, len(gamestateUpdateMessage.EntityStates))
+	for _, state := range gamestateUpdateMessage.EntityStates {
+		serverStates[state.EntityID] = state
+	}
 
-		// if app.PredictionDebugLogging() {
-		// 	fmt.Printf("\t - Intialized Entity [Current Frame: %d] [Replay Frame: %d] [Position: %s]\n", app.CommandFrame(), gamestateUpdateMessage.LastInputCommandFrame, apputils.FormatVec(transform.Position))
-		// }
+	player := app.GetPlayerEntity()
+	replayed := make([]*entity.Entity, 0, len(predicted))
+	for _, e := range predicted {
+		state, ok := serverStates[e.GetID()]
+		if !ok {
+			var predictedState PostCommandFrameState
+			if predictedState, ok = commandFrames[0].PostCFStates[e.GetID()]; !ok {
+				continue
+			}
+			state = predictedState.entityState()
+		}
+		rollback(e, state, e.GetID() == player.GetID())
+		replayed = append(replayed, e)
 	}
 
 	cfHistory.Reset()
-	cfHistory.AddCommandFrame(gamestateUpdateMessage.LastInputCommandFrame, commandFrames[0].FrameInput, e)
-
-	if len(commandFrames) == 1 {
-		return nil
-	}
+	cfHistory.AddCommandFrame(gamestateUpdateMessage.LastInputCommandFrame, commandFrames[0].FrameInput, replayed)
+	telemetry.ClientRegistry().Inc("prediction_replayed_frames", float64(len(commandFrames)-1))
 
+	delta := time.Duration(settings.MSPerCommandFrame) * time.Millisecond
 	for i := 1; i < len(commandFrames); i++ {
 		commandFrame := commandFrames[i]
 
-		// reset entity positions, (if they exist on the client)
-		// rerun spatial partioning over these entities ?
+		// only the predicted entities move during the replay, the rest of the
+		// world stays where it was last indexed
+		app.World().ReindexEntities(replayed)
 
-		shared.UpdateCharacterController(time.Duration(settings.MSPerCommandFrame)*time.Millisecond, commandFrame.FrameInput, e)
-		shared.KinematicStepSingle(time.Duration(settings.MSPerCommandFrame)*time.Millisecond, e, app.World(), app)
-		cfHistory.AddCommandFrame(commandFrame.FrameNumber, commandFrame.FrameInput, e)
+		shared.UpdateCharacterController(delta, commandFrame.FrameInput, player)
+		shared.KinematicStep(delta, replayed, app.World(), app)
+		cfHistory.AddCommandFrame(commandFrame.FrameNumber, commandFrame.FrameInput, replayed)
 	}
 	return nil
 }
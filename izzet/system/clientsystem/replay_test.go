@@ -0,0 +1,109 @@
+package clientsystem
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/collision/collider"
+	"github.com/kkevinchou/izzet/internal/input"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/network"
+	"github.com/kkevinchou/izzet/izzet/settings"
+	"github.com/kkevinchou/izzet/izzet/system/shared"
+	"github.com/kkevinchou/izzet/izzet/world"
+)
+
+type replayApp struct {
+	world  *world.GameWorld
+	player *entity.Entity
+}
+
+func (a *replayApp) CommandFrame() int               { return 0 }
+func (a *replayApp) IsClient() bool                  { return true }
+func (a *replayApp) IsServer() bool                  { return false }
+func (a *replayApp) Logger() *slog.Logger            { return slog.Default() }
+func (a *replayApp) World() *world.GameWorld         { return a.world }
+func (a *replayApp) GetPlayerEntity() *entity.Entity { return a.player }
+
+func newMovingCapsuleEntity(id int, position mgl64.Vec3, moveIntent mgl64.Vec3) *entity.Entity {
+	e := newKinematicEntity(id, position)
+	capsule := collider.NewCapsule(mgl64.Vec3{0, 1.5, 0}, mgl64.Vec3{0, 0.5, 0}, 0.5)
+	e.Collider = entity.CreateCapsuleColliderComponent(entity.ColliderGroupFlagPlayer, entity.ColliderGroupFlagTerrain|entity.ColliderGroupFlagPlayer, capsule)
+	e.Kinematic.Speed = 1
+	e.Kinematic.MoveIntent = moveIntent
+	e.Kinematic.Velocity = moveIntent
+	return e
+}
+
+func TestReplayEntityMissingFromUpdate(t *testing.T) {
+	player := newMovingCapsuleEntity(1, mgl64.Vec3{}, mgl64.Vec3{})
+	player.CharacterControllerComponent = &entity.CharacterControllerComponent{}
+	player.AimDownSightsComponent = &entity.AimDownSightsComponent{}
+	inUpdate := newMovingCapsuleEntity(2, mgl64.Vec3{10, 0, 0}, mgl64.Vec3{1, 0, 0})
+	deferred := newMovingCapsuleEntity(3, mgl64.Vec3{20, 0, 0}, mgl64.Vec3{1, 0, 0})
+
+	w := world.New()
+	for _, e := range []*entity.Entity{player, inUpdate, deferred} {
+		w.AddEntity(e)
+	}
+	w.ReindexSpatialEntities()
+	app := &replayApp{world: w, player: player}
+	predicted := []*entity.Entity{player, inUpdate, deferred}
+
+	// predict a few frames ahead of the last input the server has seen
+	delta := time.Duration(settings.MSPerCommandFrame) * time.Millisecond
+	cfHistory := NewCommandFrameHistory()
+	cfHistory.AddCommandFrame(10, input.Input{}, predicted)
+	for frame := 11; frame <= 13; frame++ {
+		w.ReindexSpatialEntities()
+		shared.UpdateCharacterController(delta, input.Input{}, player)
+		shared.KinematicStep(delta, predicted, w, app)
+		cfHistory.AddCommandFrame(frame, input.Input{}, predicted)
+	}
+	deferredPosition := deferred.Position()
+	step := 3 * delta.Seconds()
+	if want := (mgl64.Vec3{20 + step, 0, 0}); !deferredPosition.ApproxEqual(want) {
+		t.Fatalf("expected the deferred entity to be predicted to %v, got %v", want, deferredPosition)
+	}
+
+	// the server corrects one entity and defers the other past its byte budget
+	update := network.GameStateUpdateMessage{
+		LastInputCommandFrame: 10,
+		EntityStates: []network.EntityState{
+			{EntityID: 1, Rotation: mgl64.QuatIdent()},
+			{EntityID: 2, Position: mgl64.Vec3{11, 0, 0}, Rotation: mgl64.QuatIdent(), Velocity: mgl64.Vec3{1, 0, 0}},
+		},
+	}
+	if err := replay(app, predicted, update, cfHistory); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (mgl64.Vec3{11 + step, 0, 0}); !inUpdate.Position().ApproxEqual(want) {
+		t.Fatalf("expected the corrected entity to be replayed to %v, got %v", want, inUpdate.Position())
+	}
+	if !deferred.Position().ApproxEqual(deferredPosition) {
+		t.Fatalf("expected the entity missing from the update to be replayed from its own prediction to %v, got %v", deferredPosition, deferred.Position())
+	}
+
+	cf, err := cfHistory.GetFrame(13)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state, ok := cf.PostCFStates[deferred.GetID()]; !ok || !state.Position.ApproxEqual(deferredPosition) {
+		t.Fatalf("expected the replayed history to keep the deferred entity at %v, got %v", deferredPosition, state.Position)
+	}
+
+	// an entity that was just predicted has no state to roll back to and is left
+	// where it is
+	entered := newMovingCapsuleEntity(4, mgl64.Vec3{30, 0, 0}, mgl64.Vec3{1, 0, 0})
+	w.AddEntity(entered)
+	predicted = append(predicted, entered)
+	if err := replay(app, predicted, update, cfHistory); err != nil {
+		t.Fatal(err)
+	}
+	if want := (mgl64.Vec3{30, 0, 0}); !entered.Position().ApproxEqual(want) {
+		t.Fatalf("expected the newly predicted entity to stay at %v, got %v", want, entered.Position())
+	}
+}
@@ -0,0 +1,57 @@
+package clientsystem
+
+import (
+	"fmt"
+
+	"github.com/kkevinchou/izzet/izzet/network"
+	"github.com/kkevinchou/izzet/izzet/settings"
+)
+
+// SnapshotHistory keeps the entity states of recent game state updates with their
+// deltas applied. the server delta compresses updates against the latest snapshot
+// the client acks so these are needed to reconstruct the full state
+type SnapshotHistory struct {
+	snapshots   map[int][]network.EntityState
+	latestFrame int
+}
+
+func NewSnapshotHistory() *SnapshotHistory {
+	return &SnapshotHistory{snapshots: map[int][]network.EntityState{}}
+}
+
+// Apply reconstructs the full game state for an update and records it as a
+// baseline for future updates
+func (h *SnapshotHistory) Apply(update network.GameStateUpdateMessage) (network.GameStateUpdateMessage, error) {
+	baseline, ok := h.snapshots[update.BaselineCommandFrame]
+	if update.BaselineCommandFrame != 0 && !ok {
+		return network.GameStateUpdateMessage{}, fmt.Errorf("missing baseline for frame %d", update.BaselineCommandFrame)
+	}
+
+	full, err := network.ApplyGameStateDelta(baseline, update.BaselineCommandFrame, update)
+	if err != nil {
+		return network.GameStateUpdateMessage{}, err
+	}
+
+	snapshot := make([]network.EntityState, len(full.EntityStates))
+	for i, entityState := range full.EntityStates {
+		entityState.AnimationTransitions = nil
+		snapshot[i] = entityState
+	}
+	h.snapshots[full.GlobalCommandFrame] = snapshot
+	h.latestFrame = max(h.latestFrame, full.GlobalCommandFrame)
+
+	oldestFrame := h.latestFrame - settings.MaxGameStateBaselines*settings.NumFramesPerGameStateUpdate
+	for frame := range h.snapshots {
+		if frame < oldestFrame {
+			delete(h.snapshots, frame)
+		}
+	}
+
+	return full, nil
+}
+
+// LatestFrame is the global command frame of the most recent snapshot, this is
+// acked back to the server
+func (h *SnapshotHistory) LatestFrame() int {
+	return h.latestFrame
+}
@@ -1,23 +1,53 @@
 package clientsystem
 
 import (
-	"fmt"
+	"math"
+	"slices"
 
 	"github.com/go-gl/mathgl/mgl64"
 	iztanimation "github.com/kkevinchou/izzet/internal/animation"
+	"github.com/kkevinchou/izzet/internal/clocksync"
 	"github.com/kkevinchou/izzet/izzet/network"
+	"github.com/kkevinchou/izzet/izzet/runtimeconfig"
 	"github.com/kkevinchou/izzet/izzet/settings"
+	"github.com/kkevinchou/izzet/izzet/telemetry"
 )
 
+// StateBuffer holds the game state snapshots received from the server and
+// produces the transforms of interpolated entities. entities are rendered a
+// delay behind the estimated server frame so there's usually a snapshot on
+// either side to interpolate between. the delay grows with the jitter of
+// snapshot arrivals, and when snapshots are late entities are extrapolated along
+// their last velocity for a short while rather than stopping
 type StateBuffer struct {
-	frames       [settings.MaxStateBufferSize]Frame
-	prevGSUpdate network.GameStateUpdateMessage
-	cursor       int
-	count        int
+	// snapshots are ordered by global command frame, the first is the most recent
+	// snapshot at or before the render time
+	snapshots   []snapshot
+	transitions animationTransitionLookup
+	arrivals    *clocksync.Arrivals
+
+	// renderTime is the global command frame entities are being rendered at, it's
+	// fractional since it's steered towards the target delay
+	renderTime float64
+	rendering  bool
+	// renderedFrame is the global command frame of the last frame that was pulled
+	renderedFrame int
+	// pendingDestroyedEntities are the destroyed entities of snapshots that were
+	// discarded before being rendered, they're sent with the next pulled frame
+	pendingDestroyedEntities []int
+}
+
+type snapshot struct {
+	globalCommandFrame int
+	entityStates       map[int]network.EntityState
+	destroyedEntities  []int
+	// destroyedSent is set once the destroyed entities have been sent in a frame
+	destroyedSent bool
 }
 
 type Frame struct {
-	EntityStates []EntityState
+	GlobalCommandFrame int
+	EntityStates       []EntityState
 }
 
 type EntityState struct {
@@ -33,117 +63,211 @@ type EntityState struct {
 
 type animationTransitionLookup map[int]map[int]iztanimation.AnimationTransition
 
+const (
+	// the render clock is sped up or slowed down by renderClockGain per frame it's
+	// off from the target delay, by no more than renderClockMaxCorrection
+	renderClockGain          float64 = 0.05
+	renderClockMaxCorrection float64 = 0.1
+	// jitterDelayMultiplier is how many jitters of extra delay are added when the
+	// delay is adaptive
+	jitterDelayMultiplier float64 = 2
+)
+
 func NewStateBuffer() *StateBuffer {
-	return &StateBuffer{prevGSUpdate: network.GameStateUpdateMessage{LastInputCommandFrame: -1}}
+	return &StateBuffer{transitions: animationTransitionLookup{}, arrivals: clocksync.NewArrivals()}
 }
 
+// Push adds a game state update that arrived on localCommandFrame. updates older
+// than the latest one are dropped, and the oldest snapshots are dropped once
+// the buffer is full
 func (sb *StateBuffer) Push(updateMsg network.GameStateUpdateMessage, localCommandFrame int) {
-	if sb.prevGSUpdate.LastInputCommandFrame == -1 {
-		sb.prevGSUpdate = updateMsg
+	if n := len(sb.snapshots); n > 0 && updateMsg.GlobalCommandFrame <= sb.snapshots[n-1].globalCommandFrame {
 		return
 	}
+	sb.arrivals.Observe(updateMsg.GlobalCommandFrame, localCommandFrame)
 
-	blendEnd := map[int]EntityState{}
-	blendStart := map[int]EntityState{}
+	entityStates := make(map[int]network.EntityState, len(updateMsg.EntityStates))
+	for _, state := range updateMsg.EntityStates {
+		entityStates[state.EntityID] = state
+	}
+	sb.snapshots = append(sb.snapshots, snapshot{
+		globalCommandFrame: updateMsg.GlobalCommandFrame,
+		entityStates:       entityStates,
+		destroyedEntities:  updateMsg.DestroyedEntities,
+	})
+	sb.transitions.add(updateMsg.EntityStates)
+
+	if overflow := len(sb.snapshots) - settings.MaxStateBufferSize; overflow > 0 {
+		sb.discard(overflow)
+		telemetry.ClientRegistry().Inc("state_buffer_overflow", float64(overflow))
+	}
+}
 
-	for _, entity := range updateMsg.EntityStates {
-		blendEnd[entity.EntityID] = EntityState{
-			EntityID: entity.EntityID,
-			Position: entity.Position,
-			Rotation: entity.Rotation,
+// discard drops the n oldest snapshots, keeping their unsent destroyed entities
+// so they're still sent with the next frame
+func (sb *StateBuffer) discard(n int) {
+	for _, snapshot := range sb.snapshots[:n] {
+		if !snapshot.destroyedSent {
+			sb.pendingDestroyedEntities = append(sb.pendingDestroyedEntities, snapshot.destroyedEntities...)
 		}
 	}
+	sb.snapshots = slices.Delete(sb.snapshots, 0, n)
+}
 
-	if sb.count >= 1 {
-		// if we have interpolated frames remaining, we essentially
-		// toss out the remaining interpolated frames and start generating
-		// new ones from the new incoming game state update
+// Pull advances the render clock by a command frame and returns the states of
+// entities at the new render time
+func (sb *StateBuffer) Pull(localCommandFrame int, config *runtimeconfig.RuntimeConfig) (Frame, bool) {
+	if len(sb.snapshots) == 0 {
+		return Frame{}, false
+	}
 
-		for _, entity := range sb.frames[sb.cursor].EntityStates {
-			blendStart[entity.EntityID] = EntityState{
-				EntityID: entity.EntityID,
-				Position: entity.Position,
-				Rotation: entity.Rotation,
-			}
-		}
+	delay := float64(config.InterpolationDelayFrames)
+	if config.AdaptiveInterpolationDelay {
+		delay += jitterDelayMultiplier * sb.arrivals.Jitter()
+	}
+	delay = min(delay, float64(settings.MaxInterpolationDelayFrames))
 
-		sb.count = 1
+	target := sb.arrivals.ServerFrame(localCommandFrame) - delay
+	if !sb.rendering || math.Abs(target-sb.renderTime) > float64(settings.MaxInterpolationDelayFrames) {
+		sb.renderTime = target
+		sb.rendering = true
 	} else {
-		for _, entity := range sb.prevGSUpdate.EntityStates {
-			blendStart[entity.EntityID] = EntityState{
-				EntityID: entity.EntityID,
-				Position: entity.Position,
-				Rotation: entity.Rotation,
-			}
-		}
+		// ease the render clock towards the target so entities don't skip
+		sb.renderTime++
+		correction := (target - sb.renderTime) * renderClockGain
+		sb.renderTime += max(-renderClockMaxCorrection, min(renderClockMaxCorrection, correction))
 	}
 
-	// case where entity exists in the current game state update, but not the previous one
-	for _, endEntity := range blendEnd {
-		if _, ok := blendStart[endEntity.EntityID]; !ok {
-			blendStart[endEntity.EntityID] = blendEnd[endEntity.EntityID]
-		}
-	}
+	mr := telemetry.ClientRegistry()
+	mr.Inc("interpolation_delay", delay)
+	mr.Inc("interpolation_jitter", sb.arrivals.Jitter())
 
-	// case where entity exists in the last game state update, but not the current one
-	for _, startEntity := range blendStart {
-		if _, ok := blendEnd[startEntity.EntityID]; !ok {
-			blendEnd[startEntity.EntityID] = blendStart[startEntity.EntityID]
-		}
+	// drop the snapshots that are entirely behind the render time
+	for len(sb.snapshots) > 1 && float64(sb.snapshots[1].globalCommandFrame) <= sb.renderTime {
+		sb.discard(1)
 	}
 
-	sb.writeInterpolatedStates(updateMsg, blendStart, blendEnd)
-
-	sb.prevGSUpdate = updateMsg
+	frame := sb.sample(sb.renderTime)
+	sb.appendDestroyedEntities(&frame)
+	sb.attachAnimationTransitions(&frame, sb.renderedFrame)
+	sb.renderedFrame = frame.GlobalCommandFrame
+	return frame, true
 }
 
-func (sb *StateBuffer) writeInterpolatedStates(updateMsg network.GameStateUpdateMessage, blendStart map[int]EntityState, blendEnd map[int]EntityState) {
-	numFrames := updateMsg.GlobalCommandFrame - sb.prevGSUpdate.GlobalCommandFrame
-	cfStep := float64(1) / float64(numFrames)
+// sample returns the states of entities at renderTime, interpolated between the
+// snapshots on either side of it or extrapolated past the latest one
+func (sb *StateBuffer) sample(renderTime float64) Frame {
+	start := sb.snapshots[0]
+	end := start
+	if len(sb.snapshots) > 1 {
+		end = sb.snapshots[1]
+	}
 
-	transitionLookup := newAnimationTransitionLookup(updateMsg.EntityStates)
+	// hold on the oldest snapshot until the render time catches up with it
+	renderTime = max(renderTime, float64(start.globalCommandFrame))
+	frame := Frame{GlobalCommandFrame: int(math.Floor(renderTime))}
 
-	for i := 1; i <= numFrames; i++ {
-		frame := Frame{}
-		commandFrame := sb.prevGSUpdate.GlobalCommandFrame + i
+	if end.globalCommandFrame == start.globalCommandFrame {
+		extrapolation := min(renderTime-float64(start.globalCommandFrame), float64(settings.MaxExtrapolationFrames))
+		if extrapolation > 0 {
+			telemetry.ClientRegistry().Inc("interpolation_extrapolated", 1)
+		}
+		seconds := extrapolation * float64(settings.MSPerCommandFrame) / 1000
+		for id, state := range start.entityStates {
+			velocity := state.Velocity.Add(state.AccumulatedVelocity)
+			frame.EntityStates = append(frame.EntityStates, EntityState{
+				GlobalCommandFrame: frame.GlobalCommandFrame,
+				EntityID:           id,
+				Position:           state.Position.Add(velocity.Mul(seconds)),
+				Rotation:           state.Rotation,
+			})
+		}
+		return frame
+	}
 
-		for id := range blendStart {
-			endSnapshot := blendEnd[id]
-			startSnapshot := blendStart[id]
+	t := (renderTime - float64(start.globalCommandFrame)) / float64(end.globalCommandFrame-start.globalCommandFrame)
+	for id, endState := range end.entityStates {
+		// entities that are new in the end snapshot hold at their end state
+		startState, ok := start.entityStates[id]
+		if !ok {
+			startState = endState
+		}
+		frame.EntityStates = append(frame.EntityStates, EntityState{
+			GlobalCommandFrame: frame.GlobalCommandFrame,
+			EntityID:           id,
+			Position:           endState.Position.Sub(startState.Position).Mul(t).Add(startState.Position),
+			Rotation:           QInterpolate64(startState.Rotation, endState.Rotation, t),
+		})
+	}
 
-			bs := EntityState{
-				GlobalCommandFrame: commandFrame,
-				EntityID:           id,
-				Position:           endSnapshot.Position.Sub(startSnapshot.Position).Mul(float64(i) * cfStep).Add(startSnapshot.Position),
-				Rotation:           QInterpolate64(startSnapshot.Rotation, endSnapshot.Rotation, float64(i)*cfStep),
-			}
+	// entities missing from the end snapshot hold at their start state
+	for id, startState := range start.entityStates {
+		if _, ok := end.entityStates[id]; ok {
+			continue
+		}
+		frame.EntityStates = append(frame.EntityStates, EntityState{
+			GlobalCommandFrame: frame.GlobalCommandFrame,
+			EntityID:           id,
+			Position:           startState.Position,
+			Rotation:           startState.Rotation,
+		})
+	}
 
-			if transition, ok := transitionLookup.At(commandFrame, id); ok {
-				bs.AnimationTransition = &transition
-			}
+	return frame
+}
 
-			frame.EntityStates = append(frame.EntityStates, bs)
+// appendDestroyedEntities adds the entities destroyed in the snapshots the
+// render time has reached, and in the snapshots discarded since the last frame,
+// to the frame. each snapshot's destroyed entities are only sent once
+func (sb *StateBuffer) appendDestroyedEntities(frame *Frame) {
+	destroyed := sb.pendingDestroyedEntities
+	sb.pendingDestroyedEntities = nil
+	for i := range sb.snapshots {
+		if float64(sb.snapshots[i].globalCommandFrame) > sb.renderTime {
+			break
+		}
+		if !sb.snapshots[i].destroyedSent {
+			destroyed = append(destroyed, sb.snapshots[i].destroyedEntities...)
+			sb.snapshots[i].destroyedSent = true
 		}
+	}
+	for _, id := range destroyed {
+		frame.EntityStates = append(frame.EntityStates, EntityState{EntityID: id, Deadge: true})
+	}
+}
 
-		for _, id := range updateMsg.DestroyedEntities {
-			bs := EntityState{
-				EntityID: id,
-				Deadge:   true,
+// attachAnimationTransitions sets the animation transitions that happened since
+// the previously rendered frame. the render clock doesn't step exactly one frame
+// at a time so transitions aren't matched on a single frame
+func (sb *StateBuffer) attachAnimationTransitions(frame *Frame, previousFrame int) {
+	latest := map[int]int{}
+	for commandFrame, transitions := range sb.transitions {
+		if commandFrame > frame.GlobalCommandFrame {
+			continue
+		}
+		if commandFrame > previousFrame {
+			for entityID := range transitions {
+				latest[entityID] = max(latest[entityID], commandFrame)
 			}
-			frame.EntityStates = append(frame.EntityStates, bs)
 		}
+	}
 
-		sb.frames[(sb.cursor+sb.count)%settings.MaxStateBufferSize] = frame
-		if sb.count == settings.MaxStateBufferSize {
-			panic(fmt.Sprintf("buffer has filled with max capacity %d", settings.MaxStateBufferSize))
+	for i := range frame.EntityStates {
+		state := &frame.EntityStates[i]
+		if commandFrame, ok := latest[state.EntityID]; ok {
+			transition := sb.transitions[commandFrame][state.EntityID]
+			state.AnimationTransition = &transition
 		}
-		sb.count += 1
 	}
-}
 
-func newAnimationTransitionLookup(entityStates []network.EntityState) animationTransitionLookup {
-	lookup := animationTransitionLookup{}
+	for commandFrame := range sb.transitions {
+		if commandFrame <= frame.GlobalCommandFrame {
+			delete(sb.transitions, commandFrame)
+		}
+	}
+}
 
+func (lookup animationTransitionLookup) add(entityStates []network.EntityState) {
 	for _, state := range entityStates {
 		for _, transition := range state.AnimationTransitions {
 			if _, ok := lookup[transition.CommandFrame]; !ok {
@@ -156,29 +280,15 @@ func newAnimationTransitionLookup(entityStates []network.EntityState) animationT
 			}
 		}
 	}
-
-	return lookup
 }
 
-func (lookup animationTransitionLookup) At(commandFrame, entityID int) (iztanimation.AnimationTransition, bool) {
-	transitions, ok := lookup[commandFrame]
-	if !ok {
-		return iztanimation.AnimationTransition{}, false
+// InterpolationDelay is how many command frames the entities being rendered are
+// behind the latest game state update
+func (sb *StateBuffer) InterpolationDelay(latestFrame int) int {
+	if sb.renderedFrame == 0 {
+		return 0
 	}
-
-	transition, ok := transitions[entityID]
-	return transition, ok
-}
-
-func (sb *StateBuffer) Pull(localCommandFrame int) (Frame, bool) {
-	if sb.count == 0 {
-		return Frame{}, false
-	}
-
-	frame := sb.frames[sb.cursor]
-	sb.cursor = (sb.cursor + 1) % settings.MaxStateBufferSize
-	sb.count -= 1
-	return frame, true
+	return max(latestFrame-sb.renderedFrame, 0)
 }
 
 // Quaternion interpolation, reimplemented from: https://github.com/TheThinMatrix/OpenGL-Animation/blob/dde792fe29767192bcb60d30ac3e82d6bcff1110/Animation/animation/Quaternion.java#L158
@@ -0,0 +1,242 @@
+package clientsystem
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/izzet/network"
+	"github.com/kkevinchou/izzet/izzet/runtimeconfig"
+	"github.com/kkevinchou/izzet/izzet/settings"
+)
+
+const interpolatedEntityID = 1
+
+// gameStateUpdate has the interpolated entity at x = frame, moving at velocity
+func gameStateUpdate(frame int, velocity mgl64.Vec3, destroyed ...int) network.GameStateUpdateMessage {
+	return network.GameStateUpdateMessage{
+		GlobalCommandFrame: frame,
+		EntityStates: []network.EntityState{{
+			EntityID: interpolatedEntityID,
+			Position: mgl64.Vec3{float64(frame), 0, 0},
+			Rotation: mgl64.QuatIdent(),
+			Velocity: velocity,
+		}},
+		DestroyedEntities: destroyed,
+	}
+}
+
+// pushFrames pushes updates that each arrive on the local command frame matching
+// their global command frame, so the render time is the local frame minus the
+// delay
+func pushFrames(sb *StateBuffer, frames ...int) {
+	for _, frame := range frames {
+		sb.Push(gameStateUpdate(frame, mgl64.Vec3{}), frame)
+	}
+}
+
+func fixedDelayConfig(delay float32) *runtimeconfig.RuntimeConfig {
+	config := runtimeconfig.DefaultRuntimeConfig()
+	config.InterpolationDelayFrames = delay
+	config.AdaptiveInterpolationDelay = false
+	return config
+}
+
+func entityPosition(t *testing.T, frame Frame) mgl64.Vec3 {
+	t.Helper()
+	for _, state := range frame.EntityStates {
+		if state.EntityID == interpolatedEntityID {
+			return state.Position
+		}
+	}
+	t.Fatalf("entity %d is missing from frame %d", interpolatedEntityID, frame.GlobalCommandFrame)
+	return mgl64.Vec3{}
+}
+
+func destroyedEntities(frame Frame) []int {
+	var ids []int
+	for _, state := range frame.EntityStates {
+		if state.Deadge {
+			ids = append(ids, state.EntityID)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestStateBufferInterpolates(t *testing.T) {
+	sb := NewStateBuffer()
+	pushFrames(sb, 100, 110)
+
+	frame, ok := sb.Pull(115, fixedDelayConfig(10))
+	if !ok {
+		t.Fatal("expected a frame")
+	}
+	if frame.GlobalCommandFrame != 105 {
+		t.Fatalf("expected to render frame 105, got %d", frame.GlobalCommandFrame)
+	}
+	if x := entityPosition(t, frame).X(); !approxEqual(x, 105) {
+		t.Fatalf("expected the entity halfway between snapshots at x = 105, got %v", x)
+	}
+}
+
+func TestStateBufferPushOverflow(t *testing.T) {
+	sb := NewStateBuffer()
+	overflow := 5
+	for i := range settings.MaxStateBufferSize + overflow {
+		frame := 100 + i
+		sb.Push(gameStateUpdate(frame, mgl64.Vec3{}, frame), frame)
+	}
+
+	if len(sb.snapshots) != settings.MaxStateBufferSize {
+		t.Fatalf("expected %d buffered snapshots, got %d", settings.MaxStateBufferSize, len(sb.snapshots))
+	}
+	if first := sb.snapshots[0].globalCommandFrame; first != 100+overflow {
+		t.Fatalf("expected the oldest snapshots to be dropped, the first is %d", first)
+	}
+
+	// updates at or before the latest are ignored
+	latest := 100 + settings.MaxStateBufferSize + overflow - 1
+	pushFrames(sb, latest, latest-1)
+	if len(sb.snapshots) != settings.MaxStateBufferSize || sb.snapshots[len(sb.snapshots)-1].globalCommandFrame != latest {
+		t.Fatal("expected stale updates to be ignored")
+	}
+
+	// render right at the first buffered snapshot so the destroyed entities of
+	// the dropped ones are only sent because they were dropped
+	frame, _ := sb.Pull(100+overflow, fixedDelayConfig(0))
+	want := []int{100, 101, 102, 103, 104, 105, 106}
+	if got := destroyedEntities(frame); !slices.Equal(got, want) {
+		t.Fatalf("expected destroyed entities %v, got %v", want, got)
+	}
+}
+
+func TestStateBufferExtrapolation(t *testing.T) {
+	config := fixedDelayConfig(0)
+	velocity := mgl64.Vec3{1000, 0, 0}
+	unitsPerFrame := velocity.X() * float64(settings.MSPerCommandFrame) / 1000
+
+	sb := NewStateBuffer()
+	sb.Push(gameStateUpdate(100, velocity), 100)
+	frame, _ := sb.Pull(105, config)
+	if x := entityPosition(t, frame).X(); !approxEqual(x, 100+5*unitsPerFrame) {
+		t.Fatalf("expected the entity to be extrapolated 5 frames to %v, got %v", 100+5*unitsPerFrame, x)
+	}
+
+	// extrapolation stops after MaxExtrapolationFrames
+	sb = NewStateBuffer()
+	sb.Push(gameStateUpdate(100, velocity), 100)
+	frame, _ = sb.Pull(100+settings.MaxExtrapolationFrames+8, config)
+	capped := 100 + float64(settings.MaxExtrapolationFrames)*unitsPerFrame
+	if x := entityPosition(t, frame).X(); !approxEqual(x, capped) {
+		t.Fatalf("expected extrapolation to be capped at %v, got %v", capped, x)
+	}
+	frame, _ = sb.Pull(100+settings.MaxExtrapolationFrames+9, config)
+	if x := entityPosition(t, frame).X(); !approxEqual(x, capped) {
+		t.Fatalf("expected extrapolation to stay capped at %v, got %v", capped, x)
+	}
+}
+
+func TestStateBufferRenderClock(t *testing.T) {
+	sb := NewStateBuffer()
+	pushFrames(sb, 100, 110, 120)
+
+	// the first pull snaps to the target
+	sb.Pull(120, fixedDelayConfig(12))
+	if !approxEqual(sb.renderTime, 108) {
+		t.Fatalf("expected the render time to snap to 108, got %v", sb.renderTime)
+	}
+
+	// on target the clock advances a frame at a time
+	sb.Pull(121, fixedDelayConfig(12))
+	if !approxEqual(sb.renderTime, 109) {
+		t.Fatalf("expected the render time to advance to 109, got %v", sb.renderTime)
+	}
+
+	// a frame behind the target eases towards it by the gain
+	sb.Pull(122, fixedDelayConfig(11))
+	if want := 110 + renderClockGain; !approxEqual(sb.renderTime, want) {
+		t.Fatalf("expected the render time to ease to %v, got %v", want, sb.renderTime)
+	}
+
+	// far behind the target the correction is capped
+	sb.Pull(123, fixedDelayConfig(1))
+	if want := 111 + renderClockGain + renderClockMaxCorrection; !approxEqual(sb.renderTime, want) {
+		t.Fatalf("expected the render time correction to be capped at %v, got %v", want, sb.renderTime)
+	}
+
+	// past MaxInterpolationDelayFrames from the target it snaps
+	sb.Pull(123+settings.MaxInterpolationDelayFrames+10, fixedDelayConfig(1))
+	if want := float64(123 + settings.MaxInterpolationDelayFrames + 9); !approxEqual(sb.renderTime, want) {
+		t.Fatalf("expected the render time to snap to %v, got %v", want, sb.renderTime)
+	}
+}
+
+func TestStateBufferInterpolationDelay(t *testing.T) {
+	sb := NewStateBuffer()
+	if delay := sb.InterpolationDelay(100); delay != 0 {
+		t.Fatalf("expected no delay before rendering, got %d", delay)
+	}
+
+	pushFrames(sb, 100, 110)
+	sb.Pull(115, fixedDelayConfig(10))
+	if delay := sb.InterpolationDelay(110); delay != 5 {
+		t.Fatalf("expected a delay of 5 frames, got %d", delay)
+	}
+	if delay := sb.InterpolationDelay(100); delay != 0 {
+		t.Fatalf("expected the delay not to go negative, got %d", delay)
+	}
+
+	// the delay is capped even when the configured delay is larger
+	sb = NewStateBuffer()
+	pushFrames(sb, 100, 200)
+	sb.Pull(200, fixedDelayConfig(float32(settings.MaxInterpolationDelayFrames+20)))
+	if delay := sb.InterpolationDelay(200); delay != settings.MaxInterpolationDelayFrames {
+		t.Fatalf("expected the delay to be capped at %d, got %d", settings.MaxInterpolationDelayFrames, delay)
+	}
+}
+
+func TestStateBufferDestroyedEntitiesInSkippedSnapshots(t *testing.T) {
+	config := fixedDelayConfig(10)
+	sb := NewStateBuffer()
+	for i, frame := range []int{100, 110, 120, 130} {
+		sb.Push(gameStateUpdate(frame, mgl64.Vec3{}, 1000+i), frame)
+	}
+
+	// rendering at 125 skips past the first two snapshots entirely
+	frame, _ := sb.Pull(135, config)
+	if got, want := destroyedEntities(frame), []int{1000, 1001, 1002}; !slices.Equal(got, want) {
+		t.Fatalf("expected destroyed entities %v, got %v", want, got)
+	}
+
+	// they're only sent once
+	frame, _ = sb.Pull(136, config)
+	if got := destroyedEntities(frame); got != nil {
+		t.Fatalf("expected no destroyed entities to be sent again, got %v", got)
+	}
+
+	// entities destroyed in the snapshot being interpolated towards are only
+	// sent once the render time reaches it
+	for localFrame := 137; localFrame < 140; localFrame++ {
+		frame, _ = sb.Pull(localFrame, config)
+		if got := destroyedEntities(frame); got != nil {
+			t.Fatalf("expected no destroyed entities before frame 130, got %v at %v", got, frame.GlobalCommandFrame)
+		}
+	}
+	frame, _ = sb.Pull(140, config)
+	if got, want := destroyedEntities(frame), []int{1003}; !slices.Equal(got, want) {
+		t.Fatalf("expected destroyed entities %v at frame 130, got %v", want, got)
+	}
+
+	// destroyed entities are sent while extrapolating past the latest snapshot
+	sb.Push(gameStateUpdate(140, mgl64.Vec3{}, 1004), 140)
+	frame, _ = sb.Pull(140+settings.MaxInterpolationDelayFrames, config)
+	if got, want := destroyedEntities(frame), []int{1004}; !slices.Equal(got, want) {
+		t.Fatalf("expected destroyed entities %v while extrapolating, got %v", want, got)
+	}
+}
@@ -6,11 +6,16 @@ import (
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/kkevinchou/izzet/internal/collision"
 	"github.com/kkevinchou/izzet/internal/collision/collider"
+	"github.com/kkevinchou/izzet/internal/damage"
 	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/event"
 )
 
 const (
 	maxBulletDistance float64 = 300
+	// gunfireNoiseRadius is how far away AI can hear gunfire
+	gunfireNoiseRadius float64 = 60
+	bulletDamage       int     = 50
 )
 
 type CombatSystem struct {
@@ -21,6 +26,14 @@ type physicsSpawner interface {
 	SpawnPhysicsCube(contactPoint mgl64.Vec3)
 }
 
+type eventsManager interface {
+	EventsManager() *event.EventManager
+}
+
+type lagCompensator interface {
+	RewindToPerceivedFrame(shooterID int) ([]*entity.Entity, func())
+}
+
 func NewCombatSystem(app App) *CombatSystem {
 	return &CombatSystem{app: app}
 }
@@ -36,6 +49,17 @@ func (s *CombatSystem) Update(delta time.Duration, world GameWorld) {
 		}
 		camera := world.GetEntityByID(e.CharacterControllerComponent.CameraEntityID)
 
+		if s.app.IsServer() {
+			if manager, ok := s.app.(eventsManager); ok {
+				manager.EventsManager().NoiseTopic.Write(event.NoiseEvent{
+					SourceID: e.GetID(),
+					Position: e.Position(),
+					Loudness: 1,
+					Radius:   gunfireNoiseRadius,
+				})
+			}
+		}
+
 		bulletRange := camera.LocalRotation.Rotate(mgl64.Vec3{0, 0, -1}).Normalize().Mul(maxBulletDistance)
 		position := camera.Position()
 
@@ -47,7 +71,19 @@ func (s *CombatSystem) Update(delta time.Duration, world GameWorld) {
 			hitTargets = append(hitTargets, world.GetEntityByID(e.GetID()))
 		}
 
-		if hitEntityID, _, hit := collision.ClosestHit(line, hitTargets); hit {
+		// check the shot against where targets were on the shooter's screen
+		restore := func() {}
+		if s.app.IsServer() {
+			if compensator, ok := s.app.(lagCompensator); ok {
+				var rewound []*entity.Entity
+				rewound, restore = compensator.RewindToPerceivedFrame(e.GetID())
+				hitTargets = append(hitTargets, rewound...)
+			}
+		}
+		hitEntityID, hitPoint, hit := collision.ClosestHit(line, hitTargets)
+		restore()
+
+		if hit {
 			// if s.app.IsServer() {
 			// 	if spawner, ok := s.app.(physicsSpawner); ok {
 			// 		spawner.SpawnPhysicsCube(hitPoint)
@@ -57,9 +93,14 @@ func (s *CombatSystem) Update(delta time.Duration, world GameWorld) {
 			hitEntity := world.GetEntityByID(hitEntityID)
 			if hitEntity.HealthComponent != nil {
 				if s.app.IsServer() {
-					hitEntity.HealthComponent.Amount -= 50
-					if hitEntity.HealthComponent.Amount <= 0 {
-						hitEntity.Deadge = true
+					if manager, ok := s.app.(eventsManager); ok {
+						manager.EventsManager().DamageTopic.Write(event.DamageEvent{
+							SourceID: e.GetID(),
+							TargetID: hitEntityID,
+							Amount:   bulletDamage,
+							Type:     damage.TypeBallistic,
+							HitPoint: hitPoint,
+						})
 					}
 				} else {
 					s.app.AssetManager().Play("hit-pip")
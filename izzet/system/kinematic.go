@@ -11,6 +11,10 @@ type KinematicSystem struct {
 	app App
 }
 
+type predictor interface {
+	PredictedEntities() []*entity.Entity
+}
+
 func NewKinematicSystem(app App) *KinematicSystem {
 	return &KinematicSystem{app: app}
 }
@@ -23,6 +27,9 @@ func (s *KinematicSystem) Update(delta time.Duration, world GameWorld) {
 	var ents []*entity.Entity
 	if s.app.IsClient() {
 		ents = []*entity.Entity{s.app.GetPlayerEntity()}
+		if p, ok := s.app.(predictor); ok {
+			ents = p.PredictedEntities()
+		}
 	} else {
 		ents = world.Entities()
 	}
@@ -3,87 +3,70 @@ package serversystem
 import (
 	"time"
 
+	"github.com/kkevinchou/izzet/internal/behaviortree"
+	"github.com/kkevinchou/izzet/internal/iztlog"
+	"github.com/kkevinchou/izzet/izzet/behavior"
 	"github.com/kkevinchou/izzet/izzet/system"
 )
 
-const (
-	travelThreshold = 0.5
-)
-
 type AISystem struct {
-	app App
+	app   App
+	trees map[int]*aiTree
+}
+
+// aiTree is an entity's instance of its behavior tree, tree is nil if the
+// behavior failed to load so it isn't loaded again every frame
+type aiTree struct {
+	name string
+	tree *behaviortree.Tree[behavior.Context]
 }
 
 func NewAISystemSystem(app App) *AISystem {
-	return &AISystem{app: app}
+	return &AISystem{app: app, trees: map[int]*aiTree{}}
 }
 
 func (s *AISystem) Name() string {
 	return "AISystem"
 }
 
+// Update ticks the behavior trees of entities with an AIComponent, the trees of
+// dead entities are interrupted
 func (s *AISystem) Update(delta time.Duration, world system.GameWorld) {
-	// for _, e := range world.Entities() {
-	// 	aiComponent := e.AIComponent
-	// 	if aiComponent == nil {
-	// 		continue
-	// 	}
-
-	// 	if e.Deadge {
-	// 		continue
-	// 	}
+	entityIDs := map[int]bool{}
+	for _, e := range world.Entities() {
+		aiComponent := e.AIComponent
+		if aiComponent == nil || aiComponent.BehaviorTree == "" {
+			continue
+		}
+		entityIDs[e.GetID()] = true
 
-	// 	e.Kinematic.MoveIntent = mgl64.Vec3{}
+		instance := s.trees[e.GetID()]
+		if instance == nil || instance.name != aiComponent.BehaviorTree {
+			if instance != nil && instance.tree != nil {
+				instance.tree.Reset()
+			}
+			instance = &aiTree{name: aiComponent.BehaviorTree}
+			tree, err := behavior.NewTree(aiComponent.BehaviorTree)
+			if err != nil {
+				iztlog.ServerLogger.Error("failed to create behavior tree", "entity id", e.GetID(), "error", err)
+			}
+			instance.tree = tree
+			s.trees[e.GetID()] = instance
+		}
+		if instance.tree == nil {
+			continue
+		}
 
-	// 	position := e.Position()
+		if e.Deadge {
+			instance.tree.Reset()
+			continue
+		}
+		instance.tree.Tick(behavior.Context{Entity: e, World: world}, delta)
+	}
 
-	// 	if aiComponent.PatrolConfig != nil {
-	// 		target := aiComponent.PatrolConfig.Points[aiComponent.PatrolConfig.Index]
-	// 		if position.Sub(target).Len() < 1 {
-	// 			aiComponent.PatrolConfig.Index = (aiComponent.PatrolConfig.Index + 1) % len(aiComponent.PatrolConfig.Points)
-	// 			target = aiComponent.PatrolConfig.Points[aiComponent.PatrolConfig.Index]
-	// 		}
-	// 		dir := target.Sub(position).Normalize()
-	// 		e.Kinematic.MoveIntent = dir
-	// 	}
-
-	// 	if aiComponent.RotationConfig != nil {
-	// 		r := e.GetLocalRotation()
-	// 		finalRotation := aiComponent.RotationConfig.Quat.Mul(r)
-	// 		frameRotation := utils.QInterpolate64(r, finalRotation, float64(delta.Milliseconds())/1000)
-	// 		e.SetLocalRotation(frameRotation)
-	// 	}
-
-	// 	if aiComponent.TargetConfig != nil {
-	// 		target := getTarget(world)
-	// 		if target != nil {
-	// 			dir := target.Position().Sub(e.Position())
-	// 			dir[1] = 0
-	// 			if dir.LenSqr() > 0 {
-	// 				dir = dir.Normalize()
-	// 				e.Kinematic.MoveIntent = dir
-
-	// 				if !utils.Vec3IsZero(dir) {
-	// 					newRotation := mgl64.QuatBetweenVectors(mgl64.Vec3{0, 0, -1}, dir)
-	// 					e.SetLocalRotation(newRotation)
-	// 				}
-	// 			}
-	// 		}
-	// 	}
-	// }
+	for id := range s.trees {
+		if !entityIDs[id] {
+			delete(s.trees, id)
+		}
+	}
 }
-
-// func getTarget(world system.GameWorld) *entity.Entity {
-// 	var target *entity.Entity
-// 	for _, camera := range world.Entities() {
-// 		if camera.PlayerInput == nil {
-// 			continue
-// 		}
-// 		if camera.CameraComponent.Target == nil {
-// 			continue
-// 		}
-// 		target = world.GetEntityByID(*camera.CameraComponent.Target)
-// 		break
-// 	}
-// 	return target
-// }
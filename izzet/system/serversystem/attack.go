@@ -5,6 +5,7 @@ import (
 
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/kkevinchou/izzet/internal/utils"
+	"github.com/kkevinchou/izzet/izzet/event"
 	"github.com/kkevinchou/izzet/izzet/system"
 )
 
@@ -20,24 +21,55 @@ func (s *AttackSystem) Name() string {
 	return "AttackSystem"
 }
 
+// Update starts attacks on targets in range and plays them out, an attack damages
+// its target once if the target is in range during the attack's hit window
 func (s *AttackSystem) Update(delta time.Duration, world system.GameWorld) {
 	for _, e := range world.Entities() {
-		if e.AttackComponent == nil || e.Deadge {
+		attack := e.AttackComponent
+		if attack == nil {
 			continue
 		}
-		e.AttackComponent.Attacking = false
-		if e.AttackComponent.TargetID <= 0 {
+		if e.Deadge {
+			attack.Attacking = false
+			continue
+		}
+
+		if attack.Attacking {
+			attack.Elapsed += delta
+			if attack.Elapsed.Seconds() >= attack.Duration {
+				attack.Attacking = false
+			}
+		}
+
+		target := world.GetEntityByID(attack.TargetID)
+		if target == nil || target.Deadge {
 			continue
 		}
 
-		target := world.GetEntityByID(e.AttackComponent.TargetID)
 		vecToTarget := target.Position().Sub(e.Position())
 		vecToTargetXZ := mgl64.Vec3{vecToTarget.X(), 0, vecToTarget.Z()}
+		if vecToTarget.Len() > attack.AttackRange || utils.Vec3IsZero(vecToTargetXZ) {
+			continue
+		}
+
+		newRotation := mgl64.QuatBetweenVectors(mgl64.Vec3{0, 0, -1}, vecToTargetXZ)
+		e.SetLocalRotation(newRotation)
+
+		if !attack.Attacking {
+			attack.Attacking = true
+			attack.Elapsed = 0
+			attack.Hit = false
+		}
 
-		if vecToTarget.Len() <= e.AttackComponent.AttackRange && !utils.Vec3IsZero(vecToTargetXZ) {
-			newRotation := mgl64.QuatBetweenVectors(mgl64.Vec3{0, 0, -1}, vecToTargetXZ)
-			e.AttackComponent.Attacking = true
-			e.SetLocalRotation(newRotation)
+		if !attack.Hit && attack.Damage > 0 && attack.InHitWindow() {
+			attack.Hit = true
+			s.app.EventsManager().DamageTopic.Write(event.DamageEvent{
+				SourceID: e.GetID(),
+				TargetID: target.GetID(),
+				Amount:   attack.Damage,
+				Type:     attack.DamageType,
+				HitPoint: perceptionTarget(target),
+			})
 		}
 	}
 }
@@ -0,0 +1,68 @@
+package serversystem
+
+import (
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/damage"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/event"
+	"github.com/kkevinchou/izzet/izzet/system"
+)
+
+type DamageSystem struct {
+	app             App
+	damageConsumer  *event.Consumer[event.DamageEvent]
+	respawnConsumer *event.Consumer[event.RespawnEvent]
+}
+
+func NewDamageSystem(app App) *DamageSystem {
+	return &DamageSystem{
+		app:             app,
+		damageConsumer:  event.NewConsumer(app.EventsManager().DamageTopic),
+		respawnConsumer: event.NewConsumer(app.EventsManager().RespawnTopic),
+	}
+}
+
+func (s *DamageSystem) Name() string {
+	return "DamageSystem"
+}
+
+// Update applies the damage dealt this frame to the health of its targets after
+// their armor and resistances. targets that run out of health die. dead entities
+// that respawn come back at the spawn point with full health
+func (s *DamageSystem) Update(delta time.Duration, world system.GameWorld) {
+	for _, respawnEvent := range s.respawnConsumer.ReadNewEvents() {
+		e := world.GetEntityByID(respawnEvent.EntityID)
+		if e == nil || e.HealthComponent == nil || !e.Deadge {
+			continue
+		}
+
+		e.HealthComponent.Amount = e.HealthComponent.Max
+		e.Deadge = false
+		if spawnPoint := world.GetSpawnPoint(); spawnPoint != nil {
+			entity.SetLocalPosition(e, spawnPoint.Position())
+		}
+		if e.Kinematic != nil {
+			e.Kinematic.Velocity = mgl64.Vec3{}
+			e.Kinematic.AccumulatedVelocity = mgl64.Vec3{}
+		}
+	}
+
+	for _, damageEvent := range s.damageConsumer.ReadNewEvents() {
+		target := world.GetEntityByID(damageEvent.TargetID)
+		if target == nil || target.HealthComponent == nil || target.Deadge {
+			continue
+		}
+
+		health := target.HealthComponent
+		amount := damage.Apply(damageEvent.Amount, damageEvent.Type, health.Modifiers())
+		health.Amount = max(health.Amount-amount, 0)
+		if health.Amount > 0 {
+			continue
+		}
+
+		target.Deadge = true
+		s.app.EventsManager().DeathTopic.Write(event.DeathEvent{EntityID: target.GetID(), KillerID: damageEvent.SourceID})
+	}
+}
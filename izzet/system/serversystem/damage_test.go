@@ -0,0 +1,70 @@
+package serversystem
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/damage"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/event"
+	"github.com/kkevinchou/izzet/izzet/world"
+)
+
+// eventsApp only has an event manager, the rest of App panics if it's used
+type eventsApp struct {
+	App
+	eventsManager *event.EventManager
+}
+
+func (a eventsApp) EventsManager() *event.EventManager {
+	return a.eventsManager
+}
+
+func TestDamageDeathAndRespawn(t *testing.T) {
+	app := eventsApp{eventsManager: event.NewEventManager()}
+	deaths := event.NewConsumer(app.eventsManager.DeathTopic)
+	s := NewDamageSystem(app)
+
+	w := world.New()
+	spawnPoint := entity.InstantiateBaseEntity("spawn point", 1)
+	spawnPoint.SpawnPointComponent = &entity.SpawnPoint{}
+	entity.SetLocalPosition(spawnPoint, mgl64.Vec3{10, 0, 10})
+	w.AddEntity(spawnPoint)
+
+	e := entity.InstantiateBaseEntity("target", 2)
+	e.HealthComponent = entity.NewHealthComponent(100)
+	e.Kinematic = &entity.KinematicComponent{AccumulatedVelocity: mgl64.Vec3{0, -5, 0}}
+	w.AddEntity(e)
+
+	damageEvent := event.DamageEvent{SourceID: 3, TargetID: 2, Amount: 60, Type: damage.TypeMelee}
+	app.eventsManager.DamageTopic.Write(damageEvent)
+	s.Update(0, w)
+	if e.HealthComponent.Amount != 40 || e.Deadge {
+		t.Fatalf("expected 40 health and alive, got %d and deadge %t", e.HealthComponent.Amount, e.Deadge)
+	}
+
+	// damage past zero kills the entity once
+	app.eventsManager.DamageTopic.Write(damageEvent)
+	app.eventsManager.DamageTopic.Write(damageEvent)
+	s.Update(0, w)
+	if e.HealthComponent.Amount != 0 || !e.Deadge {
+		t.Fatalf("expected the entity to be dead with no health, got %d and deadge %t", e.HealthComponent.Amount, e.Deadge)
+	}
+	if got := deaths.ReadNewEvents(); len(got) != 1 || got[0] != (event.DeathEvent{EntityID: 2, KillerID: 3}) {
+		t.Fatalf("expected a single death event, got %v", got)
+	}
+
+	// respawning only applies to dead entities
+	app.eventsManager.RespawnTopic.Write(event.RespawnEvent{EntityID: 2})
+	app.eventsManager.RespawnTopic.Write(event.RespawnEvent{EntityID: 1})
+	s.Update(0, w)
+	if e.HealthComponent.Amount != 100 || e.Deadge {
+		t.Fatalf("expected the entity to respawn with full health, got %d and deadge %t", e.HealthComponent.Amount, e.Deadge)
+	}
+	if e.Position() != spawnPoint.Position() {
+		t.Fatalf("expected the entity to respawn at the spawn point, got %v", e.Position())
+	}
+	if e.Kinematic.AccumulatedVelocity != (mgl64.Vec3{}) {
+		t.Fatalf("expected the entity to respawn at rest, got %v", e.Kinematic.AccumulatedVelocity)
+	}
+}
@@ -37,7 +37,7 @@ func (s *EventsSystem) Name() string {
 
 func (s *EventsSystem) Update(delta time.Duration, world system.GameWorld) {
 	for _, e := range s.playerJoinConsumer.ReadNewEvents() {
-		player := s.app.RegisterPlayer(e.PlayerID, e.Connection)
+		player := s.app.RegisterPlayer(e.PlayerID, e.Client)
 
 		playerEntity := prefab.Instantiate(prefab.PrefabIDMannequin, s.app.AssetManager())[0]
 		spawnPoint := world.GetSpawnPoint()
@@ -62,8 +62,18 @@ func (s *EventsSystem) Update(delta time.Duration, world system.GameWorld) {
 			panic(err)
 		}
 
-		s.notifyEntityCreation(camera)
-		s.notifyEntityCreation(playerEntity)
+		// the client starts with everything in the serialized world, the replication
+		// system destroys whatever isn't relevant on its next update. other players
+		// are told about the new entities once they become relevant to them
+		player.EntityID = playerEntity.ID
+		player.CameraEntityID = camera.ID
+		player.RelevantEntities = map[int]bool{}
+		for _, e := range world.Entities() {
+			if !e.Static && e.Parent == nil {
+				player.RelevantEntities[e.ID] = true
+			}
+		}
+
 		iztlog.ServerLogger.Info("player joined", "player id", e.PlayerID, "camera id", camera.GetID(), "player entity id", playerEntity.GetID())
 	}
 
@@ -72,21 +82,12 @@ func (s *EventsSystem) Update(delta time.Duration, world system.GameWorld) {
 		s.app.DeregisterPlayer(e.PlayerID)
 	}
 
+	// spawned entities are sent to clients by the replication system as they
+	// become relevant
 	for _, e := range s.entitySpawnConsumer.ReadNewEvents() {
 		world.AddEntity(e.Entity)
-		s.notifyEntityCreation(e.Entity)
-	}
-}
-
-func (s *EventsSystem) notifyEntityCreation(entity *entity.Entity) {
-	entityMessage, err := createEntityMessage(0, entity)
-	if err != nil {
-		panic(err)
-	}
-	for _, player := range s.app.GetPlayers() {
-		player.Client.Send(entityMessage, s.app.CommandFrame())
+		iztlog.ServerLogger.Info("spawned entity", "entity id", e.Entity.GetID())
 	}
-	iztlog.ServerLogger.Info("spawned entity", "entity id", entity.GetID())
 }
 
 func createEntityMessage(playerID int, entity *entity.Entity) (network.CreateEntityMessage, error) {
@@ -25,5 +25,6 @@ func (s *InputSystem) Update(delta time.Duration, world system.GameWorld) {
 		s.app.SetPlayerInput(player.ID, bufferedInput.Input)
 		player := s.app.GetPlayer(player.ID)
 		player.LastInputLocalCommandFrame = bufferedInput.LocalCommandFrame
+		player.PerceivedCommandFrame = bufferedInput.PerceivedCommandFrame
 	}
 }
@@ -0,0 +1,94 @@
+package serversystem
+
+import (
+	"time"
+
+	"github.com/kkevinchou/izzet/internal/lagcomp"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/settings"
+	"github.com/kkevinchou/izzet/izzet/system"
+)
+
+// LagCompensationSystem records where hittable entities were at the end of each
+// command frame so shots can be checked against the world as the shooter saw it
+type LagCompensationSystem struct {
+	app     App
+	history *lagcomp.History
+}
+
+func NewLagCompensationSystem(app App) *LagCompensationSystem {
+	return &LagCompensationSystem{
+		app:     app,
+		history: lagcomp.NewHistory(settings.MaxLagCompensationFrames + 1),
+	}
+}
+
+func (s *LagCompensationSystem) Name() string {
+	return "LagCompensationSystem"
+}
+
+// Update records the transforms of the root entities with colliders, children
+// are rewound along with their root
+func (s *LagCompensationSystem) Update(delta time.Duration, world system.GameWorld) {
+	transforms := map[int]lagcomp.Transform{}
+	for _, e := range world.Entities() {
+		if e.Static || e.Parent != nil || e.Collider == nil {
+			continue
+		}
+		transforms[e.GetID()] = rewindableEntity{e}.Transform()
+	}
+	s.history.Record(s.app.CommandFrame(), transforms)
+}
+
+// Rewind moves hittable entities back to where they were in the game state the
+// player controlling the shooter was rendering when it sent its current input,
+// up to settings.MaxLagCompensationFrames back. the shooter isn't moved. the
+// rewound entities are returned along with a func that moves them back
+func (s *LagCompensationSystem) Rewind(world system.GameWorld, shooterID int) ([]*entity.Entity, func()) {
+	var perceivedFrame int
+	for _, player := range s.app.GetPlayers() {
+		if player.EntityID == shooterID {
+			perceivedFrame = player.PerceivedCommandFrame
+		}
+	}
+	// the player hasn't rendered any game state yet
+	if perceivedFrame <= 0 {
+		return nil, func() {}
+	}
+
+	rewindFrame := lagcomp.RewindFrame(s.app.CommandFrame(), perceivedFrame, settings.MaxLagCompensationFrames)
+	frame, ok := s.history.Frame(rewindFrame)
+	if !ok {
+		return nil, func() {}
+	}
+
+	var candidates []rewindableEntity
+	for id := range frame.Transforms {
+		if id == shooterID {
+			continue
+		}
+		if e := world.GetEntityByID(id); e != nil {
+			candidates = append(candidates, rewindableEntity{e})
+		}
+	}
+
+	rewound, restore := lagcomp.Rewind(frame, candidates)
+	entities := make([]*entity.Entity, len(rewound))
+	for i, e := range rewound {
+		entities[i] = e.Entity
+	}
+	return entities, restore
+}
+
+type rewindableEntity struct {
+	*entity.Entity
+}
+
+func (e rewindableEntity) Transform() lagcomp.Transform {
+	return lagcomp.Transform{Position: e.GetLocalPosition(), Rotation: e.GetLocalRotation()}
+}
+
+func (e rewindableEntity) SetTransform(transform lagcomp.Transform) {
+	entity.SetLocalPosition(e.Entity, transform.Position)
+	e.SetLocalRotation(transform.Rotation)
+}
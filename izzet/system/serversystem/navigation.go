@@ -1,35 +1,61 @@
 package serversystem
 
 import (
-	"math"
 	"time"
 
 	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/crowd"
 	"github.com/kkevinchou/izzet/internal/navmesh"
-	"github.com/kkevinchou/izzet/internal/utils"
 	"github.com/kkevinchou/izzet/izzet/entity"
 	"github.com/kkevinchou/izzet/izzet/system"
 )
 
 type NavigationSystem struct {
-	app App
+	app   App
+	crowd *crowd.Crowd
+	paths *navmesh.PathQueue
+	// pathRequests maps entity ids to their pending path request so requests of
+	// removed entities can be released
+	pathRequests map[int]int
 }
 
 const (
-	neighborSeparationRadius   = 3.0
-	neighborSeparationStrength = 0.85
-	neighborSeparationEpsilon  = 0.0001
+	// minOffMeshTraversalDuration is the shortest time in seconds that crossing
+	// an off-mesh connection takes
+	minOffMeshTraversalDuration = 0.25
+	// minFacingSpeed is the speed an agent has to be moving at before it turns to
+	// face where it's going
+	minFacingSpeed = 0.1
+	// pathNodeBudget is how many nodes path searches can expand per frame, the
+	// rest carry over to the next frame
+	pathNodeBudget = 1024
 )
 
 func NewNavigationSystem(app App) *NavigationSystem {
-	return &NavigationSystem{app: app}
+	return &NavigationSystem{
+		app:   app,
+		crowd: crowd.New(crowd.DefaultConfig()),
+		paths: navmesh.NewPathQueue(pathNodeBudget),
+
+		pathRequests: map[int]int{},
+	}
 }
 
 func (s *NavigationSystem) Name() string {
 	return "NavigationSystem"
 }
 
+// Update moves entities with a NavigationComponent to their goals as a crowd so
+// they steer around each other rather than pushing through. paths are searched
+// through a queue with a per frame budget so entities whose paths go dirty on
+// the same frame don't all search on it
 func (s *NavigationSystem) Update(delta time.Duration, world system.GameWorld) {
+	nm := s.app.NavMesh()
+	s.crowd.SetNavMesh(nm)
+	s.paths.SetNavMesh(nm)
+
+	var entities []*entity.Entity
+	agentIDs := map[int]bool{}
 	for _, e := range world.Entities() {
 		navigationComponent := e.NavigationComponent
 		if navigationComponent == nil || e.Kinematic == nil {
@@ -37,144 +63,200 @@ func (s *NavigationSystem) Update(delta time.Duration, world system.GameWorld) {
 		}
 
 		e.Kinematic.MoveIntent = mgl64.Vec3{}
+		if nm == nil {
+			continue
+		}
+
+		entities = append(entities, e)
+		agentIDs[e.GetID()] = true
+
+		params := navigationComponent.AgentParams
+		if params.MaxSpeed == 0 {
+			params.MaxSpeed = e.Kinematic.Speed
+		}
+		agent := s.crowd.AddAgent(e.GetID(), e.Position(), params)
+		agent.Params = params
+		agent.Filter = navigationComponent.QueryFilter
+		agent.Position = e.Position()
+
+		if navigationComponent.PathRequest != navmesh.InvalidPathRequest && navigationComponent.PathStatus != navmesh.PathStatusPending {
+			// the goal was cleared while the request was pending
+			s.releasePathRequest(e)
+		}
+
+		if navigationComponent.State == entity.PathfindingStateTraversing {
+			continue
+		}
+
+		if navigationComponent.PathDirty {
+			navigationComponent.PathDirty = false
+			// requesting before releasing the previous request keeps the search
+			// going if it's between the same polygons
+			request := s.paths.Request(e.Position(), navigationComponent.Goal, navigationComponent.QueryFilter)
+			s.releasePathRequest(e)
+			navigationComponent.PathRequest = request
+			s.pathRequests[e.GetID()] = request
+			navigationComponent.PathStatus = navmesh.PathStatusPending
+		} else if navigationComponent.State == entity.Idle && navigationComponent.PathStatus != navmesh.PathStatusPending && agent.State != crowd.AgentStateIdle {
+			s.crowd.ResetTarget(e.GetID())
+		}
+	}
 
-		if s.app.NavMesh() != nil {
-			position := e.Position()
-
-			if navigationComponent.PathDirty {
-				polyPath := navmesh.FindPath(s.app.NavMesh(), e.Position(), navigationComponent.Goal)
-				straightPath := navmesh.FindStraightPath(s.app.NavMesh().Tiles[0], e.Position(), navigationComponent.Goal, polyPath)
-				navmesh.PATHVERTICES = straightPath
-
-				if len(straightPath) < 2 {
-					navigationComponent.Path = nil
-					navigationComponent.NextTarget = entity.InvalidNavigationTarget
-					navigationComponent.State = entity.Idle
-					continue
-				}
-
-				navigationComponent.Path = straightPath
-				navigationComponent.PathDirty = false
-				navigationComponent.NextTarget = 1
-				navigationComponent.State = entity.PathfindingStatePathing
+	s.paths.Update()
+	for _, e := range entities {
+		s.applyPathResult(e)
+	}
+
+	for _, agent := range s.crowd.Agents() {
+		if !agentIDs[agent.ID] {
+			s.crowd.RemoveAgent(agent.ID)
+		}
+	}
+	for entityID, request := range s.pathRequests {
+		if !agentIDs[entityID] {
+			s.paths.Release(request)
+			delete(s.pathRequests, entityID)
+		}
+	}
+
+	s.crowd.Update(delta)
+
+	for _, e := range entities {
+		navigationComponent := e.NavigationComponent
+		agent := s.crowd.Agent(e.GetID())
+
+		if navigationComponent.State == entity.PathfindingStateTraversing {
+			s.traverseOffMeshConnection(delta, e)
+			continue
+		}
+
+		if navigationComponent.State == entity.PathfindingStatePathing {
+			if agent.Replan {
+				navigationComponent.PathDirty = true
 			}
 
-			if navigationComponent.State == entity.PathfindingStatePathing {
-				path := navigationComponent.Path
-
-				targetIndex := navigationComponent.NextTarget
-				target := navigationComponent.Path[targetIndex]
-
-				var atGoal bool
-				if position.Sub(target).Len() < travelThreshold {
-					position = target
-					if targetIndex == len(path)-1 {
-						navigationComponent.Path = nil
-						navigationComponent.NextTarget = entity.InvalidNavigationTarget
-						atGoal = true
-					} else {
-						targetIndex = (targetIndex + 1) % len(path)
-						navigationComponent.NextTarget = targetIndex
-						target = navigationComponent.Path[targetIndex]
-					}
-				}
-
-				if !atGoal {
-					vecToTarget2D := target.Sub(position)
-					vecToTarget2D[1] = 0
-					dir := vecToTarget2D
-					if dir.LenSqr() > 0 {
-						dir = dir.Normalize()
-						dir = applyNeighborSeparation(e, getNeighbors(e, world), dir)
-						e.Kinematic.MoveIntent = dir
-
-						if !utils.Vec3IsZero(dir) {
-							newRotation := mgl64.QuatBetweenVectors(mgl64.Vec3{0, 0, -1}, dir)
-							e.SetLocalRotation(newRotation)
-						}
-					}
-				} else {
-					navigationComponent.State = entity.Idle
-				}
+			navigationComponent.Path = append([]mgl64.Vec3{e.Position()}, agent.Corners...)
+			navigationComponent.PathConnections = append([]int{-1}, agent.CornerConnections...)
+			navigationComponent.NextTarget = 1
+			navmesh.PATHVERTICES = navigationComponent.Path
+
+			if agent.State == crowd.AgentStateOffMesh {
+				navigationComponent.NextTarget = 2
+				startOffMeshTraversal(e, agent.OffMeshConnection, agent.OffMeshStart, agent.OffMeshEnd)
+				continue
+			} else if agent.State == crowd.AgentStateIdle {
+				clearPath(navigationComponent)
+				navigationComponent.State = entity.Idle
 			}
 		}
+
+		if e.Kinematic.Speed > 0 {
+			e.Kinematic.MoveIntent = agent.Velocity.Mul(1 / e.Kinematic.Speed)
+		}
+
+		if navigationComponent.State == entity.PathfindingStatePathing && agent.Velocity.Len() > minFacingSpeed {
+			newRotation := mgl64.QuatBetweenVectors(mgl64.Vec3{0, 0, -1}, agent.Velocity.Normalize())
+			e.SetLocalRotation(newRotation)
+		}
 	}
 }
 
-func getNeighbors(e *entity.Entity, world system.GameWorld) []*entity.Entity {
-	spatialEntities := world.SpatialPartition().QueryEntities(e.BoundingBox())
-	var entities []*entity.Entity
-	for _, spatialEntity := range spatialEntities {
-		entities = append(entities, world.GetEntityByID(spatialEntity.GetID()))
+// applyPathResult hands the entity's path to the crowd once its request is
+// done
+func (s *NavigationSystem) applyPathResult(e *entity.Entity) {
+	navigationComponent := e.NavigationComponent
+	if navigationComponent.PathStatus != navmesh.PathStatusPending {
+		return
 	}
-	return entities
-}
 
-func applyNeighborSeparation(e *entity.Entity, entities []*entity.Entity, desiredDir mgl64.Vec3) mgl64.Vec3 {
+	polyPath, status := s.paths.Result(navigationComponent.PathRequest)
+	if status == navmesh.PathStatusPending {
+		return
+	}
+	s.releasePathRequest(e)
+	navigationComponent.PathStatus = status
 
-	separation := navigationNeighborSeparation(e, entities)
-	if utils.Vec3IsZero(separation) {
-		return desiredDir
+	if navigationComponent.State == entity.PathfindingStateTraversing {
+		// the path was searched from before the off-mesh connection, search again
+		// once it's crossed
+		navigationComponent.PathDirty = true
+		return
 	}
 
-	dir := desiredDir.Add(separation.Mul(neighborSeparationStrength))
-	if utils.Vec3IsZero(dir) {
-		return desiredDir
+	if status == navmesh.PathStatusFailed {
+		s.crowd.ResetTarget(e.GetID())
+		clearPath(navigationComponent)
+		navigationComponent.State = entity.Idle
+		return
 	}
 
-	return dir.Normalize()
+	s.crowd.SetTarget(e.GetID(), navigationComponent.Goal, polyPath)
+	navigationComponent.State = entity.PathfindingStatePathing
 }
 
-func navigationNeighborSeparation(e *entity.Entity, entities []*entity.Entity) mgl64.Vec3 {
-	position := e.Position()
-	var separation mgl64.Vec3
-	var neighborCount int
-
-	for _, other := range entities {
-		if other == e || other.NavigationComponent == nil || other.Kinematic == nil {
-			continue
-		}
+func (s *NavigationSystem) releasePathRequest(e *entity.Entity) {
+	navigationComponent := e.NavigationComponent
+	if navigationComponent.PathRequest == navmesh.InvalidPathRequest {
+		return
+	}
+	s.paths.Release(navigationComponent.PathRequest)
+	navigationComponent.PathRequest = navmesh.InvalidPathRequest
+	delete(s.pathRequests, e.GetID())
+}
 
-		offset := position.Sub(other.Position())
-		offset[1] = 0
-		distSqr := offset.LenSqr()
-		if distSqr >= neighborSeparationRadius*neighborSeparationRadius {
-			continue
-		}
+func clearPath(navigationComponent *entity.NavigationComponent) {
+	navigationComponent.Path = nil
+	navigationComponent.PathConnections = nil
+	navigationComponent.NextTarget = entity.InvalidNavigationTarget
+}
 
-		var away mgl64.Vec3
-		var weight float64
-		if distSqr < neighborSeparationEpsilon {
-			// pseudorandom separation deterministic on the entity id
-			// this handles an edge case where entities overlap very closely
-			away = stableSeparationDirection(e.ID, other.ID)
-			weight = 1
-		} else {
-			dist := math.Sqrt(distSqr)
-			away = offset.Mul(1 / dist)
-			weight = (neighborSeparationRadius - dist) / neighborSeparationRadius
-		}
+// startOffMeshTraversal moves the entity onto an off-mesh connection, the
+// traversal takes as long as walking the distance between its ends would
+func startOffMeshTraversal(e *entity.Entity, connection int, start, end mgl64.Vec3) {
+	duration := minOffMeshTraversalDuration
+	if e.Kinematic.Speed > 0 {
+		duration = max(end.Sub(start).Len()/e.Kinematic.Speed, minOffMeshTraversalDuration)
+	}
 
-		separation = separation.Add(away.Mul(weight))
-		neighborCount++
+	e.NavigationComponent.State = entity.PathfindingStateTraversing
+	e.NavigationComponent.Traversal = entity.OffMeshTraversal{
+		Connection: connection,
+		Start:      start,
+		End:        end,
+		Duration:   duration,
 	}
 
-	if neighborCount == 0 {
-		return mgl64.Vec3{}
+	facing := end.Sub(start)
+	facing[1] = 0
+	if facing.LenSqr() > 0 {
+		e.SetLocalRotation(mgl64.QuatBetweenVectors(mgl64.Vec3{0, 0, -1}, facing.Normalize()))
 	}
-	return separation.Mul(1 / float64(neighborCount))
 }
 
-func stableSeparationDirection(selfID, otherID int) mgl64.Vec3 {
-	a := selfID
-	b := otherID
-	sign := 1.0
-	if a > b {
-		a, b = b, a
-		sign = -1
+// traverseOffMeshConnection moves the entity along the off-mesh connection it's
+// traversing and hands it back to the crowd once it reaches the end. the
+// position is set directly so velocity from gravity is dropped along the way
+func (s *NavigationSystem) traverseOffMeshConnection(delta time.Duration, e *entity.Entity) {
+	navigationComponent := e.NavigationComponent
+	traversal := &navigationComponent.Traversal
+	traversal.Elapsed += delta.Seconds()
+
+	t := min(traversal.Elapsed/traversal.Duration, 1)
+	entity.SetLocalPosition(e, traversal.Start.Add(traversal.End.Sub(traversal.Start).Mul(t)))
+	e.Kinematic.AccumulatedVelocity = mgl64.Vec3{}
+
+	if t < 1 {
+		return
 	}
 
-	hash := uint32(a)*73856093 ^ uint32(b)*19349663
-	angle := float64(hash&0xffff) / 0x10000 * 2 * math.Pi
-	return mgl64.Vec3{math.Cos(angle) * sign, 0, math.Sin(angle) * sign}
+	s.crowd.CompleteOffMeshConnection(e.GetID())
+	if navigationComponent.NextTarget >= len(navigationComponent.Path)-1 || s.crowd.Agent(e.GetID()).State != crowd.AgentStateMoving {
+		// the goal was cleared while crossing or the connection led to the goal
+		s.crowd.ResetTarget(e.GetID())
+		clearPath(navigationComponent)
+		navigationComponent.State = entity.Idle
+		return
+	}
+	navigationComponent.NextTarget++
+	navigationComponent.State = entity.PathfindingStatePathing
 }
@@ -0,0 +1,113 @@
+package serversystem
+
+import (
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/collision/checks"
+	"github.com/kkevinchou/izzet/internal/collision/collider"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/system"
+)
+
+const (
+	// obstacleRestSpeed is the speed below which a physics object is considered
+	// at rest and carved out of the nav mesh
+	obstacleRestSpeed = 0.1
+	// obstacleMoveThreshold is how far an obstacle has to move from where it was
+	// carved before it's carved again
+	obstacleMoveThreshold = 0.25
+	// obstaclePathPadding pads the bounds of an agent's path when checking if
+	// an obstacle that changed affects it
+	obstaclePathPadding = 1.0
+)
+
+// NavMeshObstacleSystem carves entities with a NavMeshObstacleComponent out of
+// the nav mesh once they come to rest and invalidates the paths of agents that
+// go through the rebuilt area
+type NavMeshObstacleSystem struct {
+	app App
+	// obstacles maps entity ids to the id of their carved obstacle
+	obstacles map[int]int
+}
+
+func NewNavMeshObstacleSystem(app App) *NavMeshObstacleSystem {
+	return &NavMeshObstacleSystem{app: app, obstacles: map[int]int{}}
+}
+
+func (s *NavMeshObstacleSystem) Name() string {
+	return "NavMeshObstacleSystem"
+}
+
+func (s *NavMeshObstacleSystem) Update(delta time.Duration, world system.GameWorld) {
+	obstacles := s.app.NavMeshObstacles()
+
+	for _, e := range world.Entities() {
+		if e.NavMeshObstacleComponent == nil {
+			continue
+		}
+		if e.Physics != nil && (e.Physics.BodyID == 0 || e.Physics.Velocity.Len() > obstacleRestSpeed) {
+			continue
+		}
+
+		obstacle := e.NavMeshObstacle()
+		id, ok := s.obstacles[e.GetID()]
+		if !ok {
+			s.obstacles[e.GetID()] = obstacles.Add(obstacle)
+			continue
+		}
+
+		if carved, _ := obstacles.Get(id); carved.Position.Sub(obstacle.Position).Len() > obstacleMoveThreshold {
+			obstacles.Set(id, obstacle)
+		}
+	}
+
+	for entityID, id := range s.obstacles {
+		if e := world.GetEntityByID(entityID); e != nil && e.NavMeshObstacleComponent != nil {
+			continue
+		}
+		obstacles.Remove(id)
+		delete(s.obstacles, entityID)
+	}
+
+	changed := s.app.RebuildNavMeshObstacles()
+	if len(changed) == 0 {
+		return
+	}
+
+	for _, e := range world.Entities() {
+		navigationComponent := e.NavigationComponent
+		if navigationComponent == nil || navigationComponent.State == entity.Idle {
+			continue
+		}
+		pathBounds := remainingPathBounds(e)
+		for _, bounds := range changed {
+			if checks.BoundingBoxOverlaps(pathBounds, bounds) {
+				navigationComponent.PathDirty = true
+				break
+			}
+		}
+	}
+}
+
+// remainingPathBounds returns the bounds of the part of the entity's path that
+// it hasn't travelled yet
+func remainingPathBounds(e *entity.Entity) collider.BoundingBox {
+	position := e.Position()
+	bounds := collider.BoundingBox{MinVertex: position, MaxVertex: position}
+
+	navigationComponent := e.NavigationComponent
+	if navigationComponent.NextTarget != entity.InvalidNavigationTarget {
+		for _, point := range navigationComponent.Path[navigationComponent.NextTarget:] {
+			for i := range 3 {
+				bounds.MinVertex[i] = min(bounds.MinVertex[i], point[i])
+				bounds.MaxVertex[i] = max(bounds.MaxVertex[i], point[i])
+			}
+		}
+	}
+
+	padding := mgl64.Vec3{obstaclePathPadding, obstaclePathPadding, obstaclePathPadding}
+	bounds.MinVertex = bounds.MinVertex.Sub(padding)
+	bounds.MaxVertex = bounds.MaxVertex.Add(padding)
+	return bounds
+}
@@ -0,0 +1,119 @@
+package serversystem
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/collision"
+	"github.com/kkevinchou/izzet/internal/collision/collider"
+	"github.com/kkevinchou/izzet/internal/perception"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/event"
+	"github.com/kkevinchou/izzet/izzet/system"
+)
+
+type PerceptionSystem struct {
+	app           App
+	noiseConsumer *event.Consumer[event.NoiseEvent]
+	deathConsumer *event.Consumer[event.DeathEvent]
+}
+
+func NewPerceptionSystem(app App) *PerceptionSystem {
+	return &PerceptionSystem{
+		app:           app,
+		noiseConsumer: event.NewConsumer(app.EventsManager().NoiseTopic),
+		deathConsumer: event.NewConsumer(app.EventsManager().DeathTopic),
+	}
+}
+
+func (s *PerceptionSystem) Name() string {
+	return "PerceptionSystem"
+}
+
+// Update records the players each entity with a PerceptionComponent sees or
+// hears in its memory. players are seen if they're in the entity's sight cone
+// and the line to them isn't blocked by another collider. entities that died are
+// forgotten
+func (s *PerceptionSystem) Update(delta time.Duration, world system.GameWorld) {
+	noises := s.noiseConsumer.ReadNewEvents()
+	deaths := s.deathConsumer.ReadNewEvents()
+
+	var players []*entity.Entity
+	for _, e := range world.Entities() {
+		if e.CharacterControllerComponent != nil && !e.Deadge {
+			players = append(players, e)
+		}
+	}
+
+	for _, e := range world.Entities() {
+		component := e.PerceptionComponent
+		if component == nil {
+			continue
+		}
+
+		if component.Memory == nil {
+			component.Memory = perception.NewMemory(time.Duration(component.MemoryDuration * float64(time.Second)))
+		}
+		component.Memory.Update(delta)
+		for _, death := range deaths {
+			component.Memory.Forget(death.EntityID)
+		}
+		if e.Deadge {
+			continue
+		}
+
+		eye := e.Position().Add(mgl64.Vec3{0, component.EyeHeight, 0})
+		forward := e.Rotation().Rotate(mgl64.Vec3{0, 0, -1})
+		fieldOfView := component.FieldOfView * math.Pi / 180
+		for _, p := range players {
+			target := perceptionTarget(p)
+			if !perception.InSightCone(eye, forward, target, fieldOfView, component.SightRange) {
+				continue
+			}
+			if !lineOfSight(world, e, p, eye, target) {
+				continue
+			}
+			component.Memory.Perceive(p.GetID(), p.Position(), perception.SenseSight)
+		}
+
+		for _, noise := range noises {
+			if noise.SourceID == e.GetID() {
+				continue
+			}
+			loudness := perception.Loudness(noise.Position, e.Position(), noise.Loudness, noise.Radius)
+			if loudness > 0 && loudness >= component.HearingThreshold {
+				component.Memory.Perceive(noise.SourceID, noise.Position, perception.SenseHearing)
+			}
+		}
+	}
+}
+
+// perceptionTarget returns the point of the entity that's looked at, the middle
+// of its capsule if it has one
+func perceptionTarget(e *entity.Entity) mgl64.Vec3 {
+	if e.HasCapsuleCollider() {
+		capsule := e.CapsuleCollider()
+		return capsule.Top.Add(capsule.Bottom).Mul(0.5)
+	}
+	return e.Position()
+}
+
+// lineOfSight returns whether the line from the eye to the target point isn't
+// blocked by any collider other than the viewer's and the target's
+func lineOfSight(world system.GameWorld, viewer, target *entity.Entity, eye, point mgl64.Vec3) bool {
+	line := collider.Line{P1: eye, P2: point}
+
+	var blockers []*entity.Entity
+	for _, candidate := range world.SpatialPartition().EntitiesByLineSegment(line) {
+		if candidate.GetID() == viewer.GetID() || candidate.GetID() == target.GetID() {
+			continue
+		}
+		if e := world.GetEntityByID(candidate.GetID()); e != nil && e.Collider != nil {
+			blockers = append(blockers, e)
+		}
+	}
+
+	_, _, hit := collision.ClosestHit(line, blockers)
+	return !hit
+}
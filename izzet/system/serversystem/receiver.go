@@ -2,14 +2,13 @@ package serversystem
 
 import (
 	"fmt"
-	"math/rand"
 	"time"
 
-	"github.com/go-gl/mathgl/mgl64"
 	"github.com/kkevinchou/izzet/izzet/entity"
 	"github.com/kkevinchou/izzet/izzet/event"
 	"github.com/kkevinchou/izzet/izzet/network"
 	"github.com/kkevinchou/izzet/izzet/prefab"
+	"github.com/kkevinchou/izzet/izzet/server/inputbuffer"
 	"github.com/kkevinchou/izzet/izzet/system"
 )
 
@@ -37,7 +36,25 @@ func (s *ReceiverSystem) Update(delta time.Duration, world system.GameWorld) {
 						fmt.Println(fmt.Errorf("failed to deserialize message %w", err))
 						continue
 					}
-					s.app.InputBuffer().PushInput(message.CommandFrame, player.ID, inputMessage.Input)
+					perceivedCommandFrame := inputMessage.AckedGameStateFrame - inputMessage.InterpolationDelay
+					s.app.InputBuffer().PushInput(player.ID, inputbuffer.BufferedInput{
+						Input:                 inputMessage.Input,
+						LocalCommandFrame:     message.CommandFrame,
+						PerceivedCommandFrame: perceivedCommandFrame,
+					})
+					// resent inputs fill in for messages that were lost. the client
+					// was rendering a frame behind for each frame back
+					for i, redundantInput := range inputMessage.RedundantInputs {
+						s.app.InputBuffer().PushInput(player.ID, inputbuffer.BufferedInput{
+							Input:                 redundantInput,
+							LocalCommandFrame:     message.CommandFrame - 1 - i,
+							PerceivedCommandFrame: perceivedCommandFrame - 1 - i,
+						})
+					}
+					// inputs can arrive out of order over udp
+					if inputMessage.AckedGameStateFrame > player.LastAckedGameStateFrame {
+						player.LastAckedGameStateFrame = inputMessage.AckedGameStateFrame
+					}
 				} else if message.MessageType == network.MsgTypePing {
 					pingMessage, err := network.ExtractMessage[network.PingMessage](message)
 					if err != nil {
@@ -85,29 +102,27 @@ func (s *ReceiverSystem) handlePathfindRPC(rpc network.RPCMessage) {
 
 func (s *ReceiverSystem) handleRessurectRPC(world system.GameWorld, rpc network.RPCMessage) {
 	e := world.GetEntityByID(rpc.RessurectRPC.ID)
-	if e == nil {
+	if e == nil || e.HealthComponent == nil || !e.Deadge {
 		return
 	}
-	e.HealthComponent.Amount = 100
-	e.Deadge = false
+	s.app.EventsManager().RespawnTopic.Write(event.RespawnEvent{EntityID: e.GetID()})
 }
 
 func (s *ReceiverSystem) handleCreateEntityRPC(world system.GameWorld, rpc network.RPCMessage) {
-	e := prefab.Instantiate(prefab.PrefabIDVelociraptor, s.app.AssetManager())[0]
+	entityType := entity.EntityType(rpc.CreateEntity.EntityType)
+	if entityType != entity.EntityTypeParasaurolophus {
+		entityType = entity.EntityTypeVelociraptor
+	}
+	e := prefab.Instantiate(prefab.NPCPrefabID(entityType), s.app.AssetManager())[0]
 
 	if rpc.CreateEntity.Patrol {
-		jitterX := rand.Intn(10)
-		jitterZ := rand.Intn(10)
-		entity.SetLocalPosition(e, mgl64.Vec3{float64(jitterX), 20, float64(jitterZ)})
-
-		targetDist := 20
-		jitterTargetX := rand.Intn(targetDist) - 10
-		jitterTargetZ := rand.Intn(targetDist) - 10
-		target := mgl64.Vec3{float64(jitterTargetX), 0, float64(jitterTargetZ)}.Normalize().Mul(float64(targetDist))
-
-		e.AIComponent.PatrolConfig = &entity.PatrolConfig{Points: []mgl64.Vec3{{float64(jitterX), 0, float64(jitterZ)}, target}}
-	} else {
-		e.NavigationComponent = &entity.NavigationComponent{}
+		if route := patrolRoute(world); route != nil {
+			e.AIComponent.PatrolConfig = &entity.PatrolConfig{RouteID: route.GetID()}
+		}
+	}
+	e.NavigationComponent = &entity.NavigationComponent{
+		QueryFilter: prefab.NavigationQueryFilter(entityType),
+		AgentParams: prefab.NavigationAgentParams(entityType),
 	}
 
 	spawnPoint := world.GetSpawnPoint()
@@ -117,3 +132,13 @@ func (s *ReceiverSystem) handleCreateEntityRPC(world system.GameWorld, rpc netwo
 
 	s.app.EventsManager().EntitySpawnTopic.Write(event.EntitySpawnEvent{Entity: e})
 }
+
+// patrolRoute returns the first patrol route in the world, or nil
+func patrolRoute(world system.GameWorld) *entity.Entity {
+	for _, e := range world.Entities() {
+		if e.PatrolRouteComponent != nil {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package serversystem
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/collision/collider"
+	"github.com/kkevinchou/izzet/internal/iztlog"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/network"
+	"github.com/kkevinchou/izzet/izzet/settings"
+	"github.com/kkevinchou/izzet/izzet/system"
+)
+
+// rootEntity returns the top most ancestor of e. children are replicated along
+// with their root rather than entering and leaving relevancy on their own
+func rootEntity(e *entity.Entity) *entity.Entity {
+	for e.Parent != nil {
+		e = e.Parent
+	}
+	return e
+}
+
+// relevancyFocuses are the points that entities are considered relevant around
+func relevancyFocuses(world system.GameWorld, player *network.Player) []mgl64.Vec3 {
+	var focuses []mgl64.Vec3
+	if e := world.GetEntityByID(player.EntityID); e != nil {
+		focuses = append(focuses, e.Position())
+	}
+	if camera := world.GetEntityByID(player.CameraEntityID); camera != nil {
+		focuses = append(focuses, camera.Position())
+	}
+	return focuses
+}
+
+// relevantEntities returns the ids of the root entities that should be replicated
+// to the player. known entities use the larger leave radius so that entities near
+// the boundary don't flicker in and out
+func relevantEntities(world system.GameWorld, player *network.Player, known map[int]bool) map[int]bool {
+	relevant := map[int]bool{}
+	for _, id := range []int{player.EntityID, player.CameraEntityID} {
+		if world.GetEntityByID(id) != nil {
+			relevant[id] = true
+		}
+	}
+
+	sp := world.SpatialPartition()
+	for _, focus := range relevancyFocuses(world, player) {
+		r := settings.RelevancyLeaveRadius
+		bb := collider.BoundingBox{MinVertex: focus.Sub(mgl64.Vec3{r, r, r}), MaxVertex: focus.Add(mgl64.Vec3{r, r, r})}
+
+		// the spatial partition only narrows things down to partitions, distances
+		// are checked against the root so children are relevant along with it
+		for _, spatialEntity := range sp.QueryEntities(bb) {
+			e := world.GetEntityByID(spatialEntity.GetID())
+			if e == nil {
+				continue
+			}
+			root := rootEntity(e)
+			if root.Static {
+				continue
+			}
+
+			radius := settings.RelevancyRadius
+			if known[root.ID] {
+				radius = settings.RelevancyLeaveRadius
+			}
+			if root.Position().Sub(focus).Len() <= radius {
+				relevant[root.ID] = true
+			}
+		}
+	}
+
+	// entities without bounding boxes aren't in the spatial partition so there's
+	// no way to cull them, these are generally cheap things like cameras
+	for _, e := range world.Entities() {
+		if e.Static || e.Parent != nil || e.HasBoundingBox() {
+			continue
+		}
+		relevant[e.ID] = true
+	}
+
+	return relevant
+}
+
+// updateRelevancy notifies the player's client of entities that have entered or
+// left relevancy and returns the full set of relevant entities including children
+func updateRelevancy(world system.GameWorld, player *network.Player, commandFrame int) map[int]bool {
+	if player.RelevantEntities == nil {
+		player.RelevantEntities = map[int]bool{}
+	}
+	relevant := relevantEntities(world, player, player.RelevantEntities)
+
+	var left []int
+	for id := range player.RelevantEntities {
+		if !relevant[id] {
+			left = append(left, id)
+		}
+	}
+	slices.Sort(left)
+	for _, id := range left {
+		delete(player.RelevantEntities, id)
+		if err := player.Client.Send(network.DestroyEntityMessage{EntityID: id}, commandFrame); err != nil {
+			iztlog.ServerLogger.Error("failed to send entity leave", "player id", player.ID, "entity id", id, "error", err)
+		}
+	}
+
+	var entered []int
+	for id := range relevant {
+		if !player.RelevantEntities[id] {
+			entered = append(entered, id)
+		}
+	}
+	slices.Sort(entered)
+	for _, id := range entered {
+		message, err := createEntityMessage(0, world.GetEntityByID(id))
+		if err != nil {
+			iztlog.ServerLogger.Error("failed to serialize entering entity", "entity id", id, "error", err)
+			continue
+		}
+		player.RelevantEntities[id] = true
+		if err := player.Client.Send(message, commandFrame); err != nil {
+			iztlog.ServerLogger.Error("failed to send entity enter", "player id", player.ID, "entity id", id, "error", err)
+		}
+	}
+
+	withChildren := map[int]bool{}
+	for _, e := range world.Entities() {
+		if player.RelevantEntities[rootEntity(e).ID] {
+			withChildren[e.ID] = true
+		}
+	}
+	return withChildren
+}
+
+// replicationPriority is how urgently an entity's state should be sent to a player
+// whose focus is at the given position. other players and entities with animation
+// transitions are favoured and priority falls off with distance
+func replicationPriority(e *entity.Entity, state network.EntityState, focus mgl64.Vec3) float64 {
+	priority := 1.0
+	if e.CharacterControllerComponent != nil {
+		priority = 4
+	} else if e.AIComponent != nil {
+		priority = 2
+	}
+	if len(state.AnimationTransitions) > 0 {
+		priority += 4
+	}
+
+	distance := e.Position().Sub(focus).Len()
+	return priority * settings.RelevancyRadius / (settings.RelevancyRadius + distance)
+}
+
+type replicationCandidate struct {
+	state    network.EntityState
+	size     int
+	priority float64
+	// queued is set for entities the client has no state for that were already
+	// deferred by an earlier update
+	queued bool
+}
+
+// deferredReplication is what a player's game state updates owe it for entities
+// that didn't make the byte budget
+type deferredReplication struct {
+	// priorities are accumulated across updates so deferred entities eventually get through
+	priorities map[int]float64
+	// creates are relevant entities that the client has no state for yet
+	creates map[int]bool
+	// transitions are one shot animation transitions held until the entity is sent
+	transitions map[int][]network.AnimationTransition
+}
+
+func newDeferredReplication() *deferredReplication {
+	return &deferredReplication{
+		priorities:  map[int]float64{},
+		creates:     map[int]bool{},
+		transitions: map[int][]network.AnimationTransition{},
+	}
+}
+
+func (d *deferredReplication) remove(id int) {
+	delete(d.priorities, id)
+	delete(d.creates, id)
+	delete(d.transitions, id)
+}
+
+// prune forgets entities that are no longer relevant
+func (d *deferredReplication) prune(relevant map[int]bool) {
+	maps.DeleteFunc(d.priorities, func(id int, _ float64) bool { return !relevant[id] })
+	maps.DeleteFunc(d.creates, func(id int, _ bool) bool { return !relevant[id] })
+	maps.DeleteFunc(d.transitions, func(id int, _ []network.AnimationTransition) bool { return !relevant[id] })
+}
+
+// selectEntityStates picks which relevant entity states go into the player's next
+// game state update. unchanged states are free, the player's own entity is always
+// sent since the client validates its prediction against it, and everything else
+// is sent in priority order until the byte budget runs out. entities that don't
+// make the cut are held at their baseline state so they're left out of the delta.
+// entities the client has no state for are queued ahead of everything else once
+// deferred, and the animation transitions of deferred entities are carried over
+func selectEntityStates(
+	world system.GameWorld,
+	player *network.Player,
+	relevant map[int]bool,
+	entityStates []network.EntityState,
+	baseline []network.EntityState,
+	deferred *deferredReplication,
+) []network.EntityState {
+	baselineStates := make(map[int]network.EntityState, len(baseline))
+	for _, state := range baseline {
+		baselineStates[state.EntityID] = state
+	}
+
+	var focus mgl64.Vec3
+	if focuses := relevancyFocuses(world, player); len(focuses) > 0 {
+		focus = focuses[0]
+	}
+
+	budget := settings.GameStateUpdateByteBudget
+	var selected []network.EntityState
+	var candidates []replicationCandidate
+
+	for _, state := range entityStates {
+		if !relevant[state.EntityID] {
+			deferred.remove(state.EntityID)
+			continue
+		}
+
+		if carried := deferred.transitions[state.EntityID]; len(carried) > 0 {
+			state.AnimationTransitions = append(slices.Clone(carried), state.AnimationTransitions...)
+		}
+
+		delta := state
+		baselineState, hasBaseline := baselineStates[state.EntityID]
+		if hasBaseline {
+			delta.ChangedFields = network.DiffEntityState(baselineState, state)
+		}
+		if delta.ChangedFields == 0 && len(state.AnimationTransitions) == 0 {
+			selected = append(selected, state)
+			continue
+		}
+
+		size := delta.EncodedSize()
+		if state.EntityID == player.EntityID {
+			budget -= size
+			selected = append(selected, state)
+			deferred.remove(state.EntityID)
+			continue
+		}
+
+		deferred.priorities[state.EntityID] += replicationPriority(world.GetEntityByID(state.EntityID), state, focus)
+		candidates = append(candidates, replicationCandidate{
+			state:    state,
+			size:     size,
+			priority: deferred.priorities[state.EntityID],
+			queued:   !hasBaseline && deferred.creates[state.EntityID],
+		})
+	}
+
+	slices.SortStableFunc(candidates, func(a, b replicationCandidate) int {
+		if a.queued != b.queued {
+			if a.queued {
+				return -1
+			}
+			return 1
+		}
+		if a.priority > b.priority {
+			return -1
+		} else if a.priority < b.priority {
+			return 1
+		}
+		return 0
+	})
+
+	for _, candidate := range candidates {
+		id := candidate.state.EntityID
+		if candidate.size <= budget {
+			budget -= candidate.size
+			selected = append(selected, candidate.state)
+			deferred.remove(id)
+			continue
+		}
+
+		if len(candidate.state.AnimationTransitions) > 0 {
+			deferred.transitions[id] = candidate.state.AnimationTransitions
+		}
+		if baselineState, ok := baselineStates[id]; ok {
+			selected = append(selected, baselineState)
+		} else {
+			deferred.creates[id] = true
+		}
+	}
+
+	deferred.prune(relevant)
+
+	slices.SortFunc(selected, func(a, b network.EntityState) int {
+		return a.EntityID - b.EntityID
+	})
+	return selected
+}
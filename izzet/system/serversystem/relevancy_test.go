@@ -0,0 +1,272 @@
+package serversystem
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/kkevinchou/izzet/internal/collision/collider"
+	"github.com/kkevinchou/izzet/izzet/entity"
+	"github.com/kkevinchou/izzet/izzet/network"
+	"github.com/kkevinchou/izzet/izzet/settings"
+	"github.com/kkevinchou/izzet/izzet/world"
+)
+
+// recordingClient keeps the messages sent to it
+type recordingClient struct {
+	sent []network.Message
+}
+
+func (c *recordingClient) Send(message network.Message, frame int) error {
+	c.sent = append(c.sent, message)
+	return nil
+}
+
+func (c *recordingClient) Recv() (network.MessageTransport, error) {
+	return network.MessageTransport{}, io.EOF
+}
+
+func (c *recordingClient) Close() {}
+
+// created and destroyed return the ids of the entities in the sent create and
+// destroy messages
+func (c *recordingClient) created(t *testing.T) []int {
+	var ids []int
+	for _, message := range c.sent {
+		if create, ok := message.(network.CreateEntityMessage); ok {
+			var e struct{ ID int }
+			if err := json.Unmarshal(create.EntityBytes, &e); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, e.ID)
+		}
+	}
+	return ids
+}
+
+func (c *recordingClient) destroyed() []int {
+	var ids []int
+	for _, message := range c.sent {
+		if destroy, ok := message.(network.DestroyEntityMessage); ok {
+			ids = append(ids, destroy.EntityID)
+		}
+	}
+	return ids
+}
+
+func newCapsuleEntity(id int, position mgl64.Vec3) *entity.Entity {
+	e := entity.InstantiateBaseEntity("capsule", id)
+	capsule := collider.NewCapsule(mgl64.Vec3{0, 1.5, 0}, mgl64.Vec3{0, 0.5, 0}, 0.5)
+	e.Collider = entity.CreateCapsuleColliderComponent(entity.ColliderGroupFlagPlayer, entity.ColliderGroupFlagTerrain|entity.ColliderGroupFlagPlayer, capsule)
+	entity.SetLocalPosition(e, position)
+	return e
+}
+
+func newRelevancyWorld(positions map[int]mgl64.Vec3) *world.GameWorld {
+	w := world.New()
+	for id, position := range positions {
+		w.AddEntity(newCapsuleEntity(id, position))
+	}
+	w.ReindexSpatialEntities()
+	return w
+}
+
+func moveEntity(w *world.GameWorld, id int, position mgl64.Vec3) {
+	entity.SetLocalPosition(w.GetEntityByID(id), position)
+	w.ReindexSpatialEntities()
+}
+
+func TestUpdateRelevancyEnterAndLeave(t *testing.T) {
+	near := mgl64.Vec3{settings.RelevancyRadius - 10, 0, 0}
+	boundary := mgl64.Vec3{(settings.RelevancyRadius + settings.RelevancyLeaveRadius) / 2, 0, 0}
+	far := mgl64.Vec3{settings.RelevancyLeaveRadius + 50, 0, 0}
+
+	w := newRelevancyWorld(map[int]mgl64.Vec3{1: {}, 2: near, 3: boundary})
+	client := &recordingClient{}
+	player := &network.Player{ID: 100, EntityID: 1, CameraEntityID: -1, Client: client}
+
+	relevant := updateRelevancy(w, player, 1)
+	if created := client.created(t); !slices.Equal(created, []int{1, 2}) {
+		t.Fatalf("created %v, want the player's entity and the entity within the relevancy radius", created)
+	}
+	if relevant[3] {
+		t.Fatal("entity past the relevancy radius shouldn't enter")
+	}
+
+	// entities enter within the relevancy radius
+	client.sent = nil
+	moveEntity(w, 3, near)
+	updateRelevancy(w, player, 2)
+	if created := client.created(t); !slices.Equal(created, []int{3}) {
+		t.Fatalf("created %v, want [3]", created)
+	}
+
+	// but only leave past the leave radius
+	client.sent = nil
+	moveEntity(w, 3, boundary)
+	relevant = updateRelevancy(w, player, 3)
+	if len(client.sent) != 0 || !relevant[3] {
+		t.Fatalf("sent %v, want entity 3 to stay relevant between the radii", client.sent)
+	}
+
+	moveEntity(w, 3, far)
+	relevant = updateRelevancy(w, player, 4)
+	if destroyed := client.destroyed(); !slices.Equal(destroyed, []int{3}) {
+		t.Fatalf("destroyed %v, want [3]", destroyed)
+	}
+	if relevant[3] || player.RelevantEntities[3] {
+		t.Fatal("entity 3 should no longer be relevant")
+	}
+}
+
+// changedState is a full entity state that differs from its baselineState
+func changedState(id int) network.EntityState {
+	return network.EntityState{
+		EntityID:            id,
+		ChangedFields:       network.EntityStateFieldAll,
+		Position:            mgl64.Vec3{float64(id), 1, 2},
+		Rotation:            mgl64.QuatRotate(float64(id), mgl64.Vec3{0, 1, 0}),
+		Velocity:            mgl64.Vec3{1, 0, 1},
+		AccumulatedVelocity: mgl64.Vec3{0, -1, 0},
+		Health:              50,
+		MaxHealth:           100,
+	}
+}
+
+func baselineState(id int) network.EntityState {
+	return network.EntityState{EntityID: id, ChangedFields: network.EntityStateFieldAll, Rotation: mgl64.QuatIdent()}
+}
+
+func TestSelectEntityStatesByteBudget(t *testing.T) {
+	const entityCount = 200
+	positions := map[int]mgl64.Vec3{}
+	relevant := map[int]bool{}
+	var current, baseline []network.EntityState
+	for id := 1; id <= entityCount; id++ {
+		positions[id] = mgl64.Vec3{float64(id % 20), 0, float64(id / 20)}
+		relevant[id] = true
+		current = append(current, changedState(id))
+		baseline = append(baseline, baselineState(id))
+	}
+	w := newRelevancyWorld(positions)
+	player := &network.Player{ID: 100, EntityID: entityCount, CameraEntityID: -1}
+	deferred := newDeferredReplication()
+
+	sent := map[int]bool{}
+	for update := 0; len(sent) < entityCount; update++ {
+		if update > entityCount {
+			t.Fatalf("only %d of %d entities were sent", len(sent), entityCount)
+		}
+
+		selected := selectEntityStates(w, player, relevant, current, baseline, deferred)
+		if len(selected) != entityCount {
+			t.Fatalf("selected %d states, want every entity to be present at its new or baseline state", len(selected))
+		}
+
+		var size int
+		var updated []int
+		for _, state := range selected {
+			base := baselineState(state.EntityID)
+			delta := state
+			delta.ChangedFields = network.DiffEntityState(base, state)
+			if delta.ChangedFields == 0 {
+				if !reflect.DeepEqual(state, base) {
+					t.Fatalf("deferred entity %d = %+v, want its baseline state", state.EntityID, state)
+				}
+				continue
+			}
+			if state.Position != changedState(state.EntityID).Position {
+				t.Fatalf("entity %d = %+v, want its current state", state.EntityID, state)
+			}
+			size += delta.EncodedSize()
+			updated = append(updated, state.EntityID)
+		}
+
+		if size > settings.GameStateUpdateByteBudget {
+			t.Fatalf("update %d is %d bytes, over the budget of %d", update, size, settings.GameStateUpdateByteBudget)
+		}
+		if len(updated) == entityCount {
+			t.Fatal("expected the budget to defer some entities")
+		}
+		if !slices.Contains(updated, player.EntityID) {
+			t.Fatalf("update %d deferred the player's entity, it should always be sent", update)
+		}
+		for _, id := range updated {
+			sent[id] = true
+		}
+	}
+}
+
+func TestSelectEntityStatesCarriesDeferredCreatesAndTransitions(t *testing.T) {
+	const entityCount = 200
+	const createdID, animatedID = entityCount + 1, entityCount + 2
+
+	positions := map[int]mgl64.Vec3{}
+	relevant := map[int]bool{}
+	var current, baseline []network.EntityState
+	deferred := newDeferredReplication()
+	for id := 1; id <= animatedID; id++ {
+		positions[id] = mgl64.Vec3{float64(id % 20), 0, float64(id / 20)}
+		relevant[id] = true
+		current = append(current, changedState(id))
+		if id != createdID {
+			baseline = append(baseline, baselineState(id))
+		}
+		// everything else outranks the two entities under test for the first update
+		if id <= entityCount {
+			deferred.priorities[id] = 1000
+		}
+	}
+	w := newRelevancyWorld(positions)
+	player := &network.Player{ID: 100, EntityID: 1, CameraEntityID: -1}
+
+	transitions := []network.AnimationTransition{{SourceState: "idle", DestinationState: "attack", CommandFrame: 10}}
+	withTransitions := slices.Clone(current)
+	withTransitions[animatedID-1].AnimationTransitions = transitions
+
+	find := func(states []network.EntityState, id int) (network.EntityState, bool) {
+		for _, state := range states {
+			if state.EntityID == id {
+				return state, true
+			}
+		}
+		return network.EntityState{}, false
+	}
+
+	selected := selectEntityStates(w, player, relevant, withTransitions, baseline, deferred)
+	if _, ok := find(selected, createdID); ok {
+		t.Fatal("expected the newly relevant entity to be deferred")
+	}
+	if state, ok := find(selected, animatedID); !ok || len(state.AnimationTransitions) != 0 {
+		t.Fatalf("animated entity = %+v, want it held at its baseline", state)
+	}
+
+	// the newly relevant entity goes out ahead of everything else in the next
+	// update and the animation transitions are carried over until the entity is sent
+	selected = selectEntityStates(w, player, relevant, current, baseline, deferred)
+	if state, ok := find(selected, createdID); !ok || !reflect.DeepEqual(state, changedState(createdID)) {
+		t.Fatalf("newly relevant entity = %+v, want it sent in the following update", state)
+	}
+	for update := 0; ; update++ {
+		if update > entityCount {
+			t.Fatal("the animated entity was never sent")
+		}
+		if update > 0 {
+			selected = selectEntityStates(w, player, relevant, current, baseline, deferred)
+		}
+		state, _ := find(selected, animatedID)
+		if state.Position != changedState(animatedID).Position {
+			continue
+		}
+		if !reflect.DeepEqual(state.AnimationTransitions, transitions) {
+			t.Fatalf("animation transitions = %+v, want the deferred transitions %+v", state.AnimationTransitions, transitions)
+		}
+		break
+	}
+	if len(deferred.transitions) != 0 || len(deferred.creates) != 0 {
+		t.Fatalf("expected nothing left deferred, got %+v", deferred)
+	}
+}
@@ -16,6 +16,13 @@ import (
 type ReplicationSystem struct {
 	app                   App
 	destroyEntityConsumer *event.Consumer[event.DestroyEntityEvent]
+
+	// baselines holds the snapshots sent to each player keyed by global command
+	// frame, these are what deltas are computed against once the player acks them
+	baselines map[int]map[int][]network.EntityState
+	// deferred holds what each player is owed for entities that have been
+	// deferred by the byte budget
+	deferred map[int]*deferredReplication
 }
 
 func NewReplicationSystem(app App) *ReplicationSystem {
@@ -23,6 +30,8 @@ func NewReplicationSystem(app App) *ReplicationSystem {
 	return &ReplicationSystem{
 		app:                   app,
 		destroyEntityConsumer: event.NewConsumer(eventsManager.DestroyEntityTopic),
+		baselines:             map[int]map[int][]network.EntityState{},
+		deferred:              map[int]*deferredReplication{},
 	}
 }
 
@@ -44,10 +53,11 @@ func (s *ReplicationSystem) Update(delta time.Duration, world system.GameWorld)
 		}
 
 		entityState := network.EntityState{
-			EntityID: entity.ID,
-			Position: entity.GetLocalPosition(),
-			Rotation: entity.GetLocalRotation(),
-			Deadge:   entity.Deadge,
+			EntityID:      entity.ID,
+			ChangedFields: network.EntityStateFieldAll,
+			Position:      entity.GetLocalPosition(),
+			Rotation:      entity.GetLocalRotation(),
+			Deadge:        entity.Deadge,
 		}
 
 		if entity.Kinematic != nil {
@@ -56,6 +66,10 @@ func (s *ReplicationSystem) Update(delta time.Duration, world system.GameWorld)
 			entityState.Grounded = entity.Kinematic.Grounded
 			entityState.GravityEnabled = entity.Kinematic.GravityEnabled
 		}
+		if entity.HealthComponent != nil {
+			entityState.Health = entity.HealthComponent.Amount
+			entityState.MaxHealth = entity.HealthComponent.Max
+		}
 		if entity.Animation != nil {
 			entityState.AnimationTransitions = convertAnimationTransitions(entity.Animation.AnimationTransitions)
 			entity.Animation.AnimationTransitions = entity.Animation.AnimationTransitions[:0]
@@ -94,9 +108,68 @@ func (s *ReplicationSystem) Update(delta time.Duration, world system.GameWorld)
 		DestroyedEntities:  destroyedEntityIDs,
 	}
 
+	for id := range s.baselines {
+		if _, ok := players[id]; !ok {
+			delete(s.baselines, id)
+			delete(s.deferred, id)
+		}
+	}
+
+	mr := telemetry.ServerRegistry()
 	for _, player := range players {
-		gamestateUpdateMessage.LastInputCommandFrame = player.LastInputLocalCommandFrame
-		player.Client.Send(gamestateUpdateMessage, s.app.CommandFrame())
+		playerBaselines, ok := s.baselines[player.ID]
+		if !ok {
+			playerBaselines = map[int][]network.EntityState{}
+			s.baselines[player.ID] = playerBaselines
+			s.deferred[player.ID] = newDeferredReplication()
+		}
+
+		// a zero ack means the player hasn't applied a snapshot yet
+		ackedBaseline, hasBaseline := playerBaselines[player.LastAckedGameStateFrame]
+		hasBaseline = hasBaseline && player.LastAckedGameStateFrame > 0
+
+		relevant := updateRelevancy(world, player, s.app.CommandFrame())
+		playerEntityStates := selectEntityStates(world, player, relevant, entityStates, ackedBaseline, s.deferred[player.ID])
+
+		message := gamestateUpdateMessage
+		message.EntityStates = playerEntityStates
+		message.LastInputCommandFrame = player.LastInputLocalCommandFrame
+
+		inputStats := s.app.InputBuffer().Stats(player.ID)
+		message.InputFeedback = network.InputFeedback{
+			BufferDepth:      inputStats.Depth,
+			DroppedInputs:    inputStats.DroppedInputs,
+			DuplicatedInputs: inputStats.DuplicatedInputs,
+		}
+
+		if hasBaseline {
+			message = network.DeltaGameStateUpdate(ackedBaseline, player.LastAckedGameStateFrame, message)
+			mr.Inc("gamestate_delta_updates", 1)
+		} else {
+			mr.Inc("gamestate_full_updates", 1)
+		}
+
+		// animation transitions are events rather than state so they're left out of baselines
+		baseline := make([]network.EntityState, len(playerEntityStates))
+		for i, entityState := range playerEntityStates {
+			entityState.AnimationTransitions = nil
+			baseline[i] = entityState
+		}
+		playerBaselines[s.app.CommandFrame()] = baseline
+		pruneBaselines(playerBaselines, player.LastAckedGameStateFrame, s.app.CommandFrame())
+
+		player.Client.Send(message, s.app.CommandFrame())
+	}
+}
+
+// pruneBaselines drops baselines the player can no longer ack, acks only move
+// forward so anything older than the latest ack is dead
+func pruneBaselines(baselines map[int][]network.EntityState, ackedFrame int, commandFrame int) {
+	oldestFrame := max(ackedFrame, commandFrame-settings.MaxGameStateBaselines*settings.NumFramesPerGameStateUpdate)
+	for frame := range baselines {
+		if frame < oldestFrame {
+			delete(baselines, frame)
+		}
 	}
 }
 
@@ -2,12 +2,13 @@ package serversystem
 
 import (
 	"log/slog"
-	"net"
 
+	"github.com/kkevinchou/izzet/internal/collision/collider"
 	"github.com/kkevinchou/izzet/internal/input"
 	"github.com/kkevinchou/izzet/internal/navmesh"
 	"github.com/kkevinchou/izzet/izzet/assets"
 	"github.com/kkevinchou/izzet/izzet/event"
+	"github.com/kkevinchou/izzet/izzet/navmeshbuilder"
 	"github.com/kkevinchou/izzet/izzet/network"
 	"github.com/kkevinchou/izzet/izzet/server/inputbuffer"
 	"github.com/kkevinchou/izzet/izzet/world"
@@ -17,7 +18,7 @@ type App interface {
 	Logger() *slog.Logger
 	AssetManager() *assets.AssetManager
 	GetPlayers() map[int]*network.Player
-	RegisterPlayer(playerID int, connection net.Conn) *network.Player
+	RegisterPlayer(playerID int, client network.IzzetClient) *network.Player
 	InputBuffer() *inputbuffer.InputBuffer
 	CommandFrame() int
 	GetPlayer(playerID int) *network.Player
@@ -28,6 +29,8 @@ type App interface {
 	SystemNames() []string
 	World() *world.GameWorld
 	NavMesh() *navmesh.CompiledNavMesh
+	NavMeshObstacles() *navmeshbuilder.Obstacles
+	RebuildNavMeshObstacles() []collider.BoundingBox
 	ProjectName() string
 	SetPredictionDebugLogging(value bool)
 	PredictionDebugLogging() bool
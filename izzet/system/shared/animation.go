@@ -32,6 +32,12 @@ func animationContext(e *entity.Entity) animationparser.GameContext {
 	if e.AttackComponent != nil {
 		ctx.Attacking = e.AttackComponent.Attacking
 	}
+	if e.AIComponent != nil {
+		ctx.Action = e.AIComponent.Action
+	}
+	if e.NavigationComponent != nil {
+		ctx.Traversing = e.NavigationComponent.State == entity.PathfindingStateTraversing
+	}
 	if e.AimDownSightsComponent != nil && e.AimDownSightsComponent.Active {
 		ctx.AimDownSights = true
 		ctx.AimDownSightsFire = e.AimDownSightsComponent.Fire
@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/kkevinchou/izzet/internal/patrol"
 	"github.com/kkevinchou/izzet/internal/physics"
 	"github.com/kkevinchou/izzet/internal/spatialpartition"
 	"github.com/kkevinchou/izzet/izzet/assets"
@@ -27,6 +28,7 @@ type GameWorld interface {
 	PhysicsWorld() *physics.World
 	AddEntity(*entity.Entity)
 	GetSpawnPoint() *entity.Entity
+	PatrolRoute(id int) (patrol.Route, error)
 }
 
 type App interface {
@@ -1,8 +1,11 @@
 package world
 
 import (
+	"fmt"
 	"slices"
+	"time"
 
+	"github.com/kkevinchou/izzet/internal/patrol"
 	"github.com/kkevinchou/izzet/internal/physics"
 	"github.com/kkevinchou/izzet/internal/spatialpartition"
 	"github.com/kkevinchou/izzet/izzet/entity"
@@ -100,6 +103,19 @@ func (g *GameWorld) ReindexSpatialEntities() {
 	g.spatialPartition.IndexEntities(spatialEntities)
 }
 
+// ReindexEntities updates the spatial partition for only the given entities,
+// for when just a few of them have moved
+func (g *GameWorld) ReindexEntities(entities []*entity.Entity) {
+	spatialEntities := make([]spatialpartition.Entity, 0, len(entities))
+	for _, e := range entities {
+		if !e.HasBoundingBox() {
+			continue
+		}
+		spatialEntities = append(spatialEntities, e)
+	}
+	g.spatialPartition.IndexEntities(spatialEntities)
+}
+
 func (g *GameWorld) SpatialPartition() *spatialpartition.SpatialPartition {
 	return g.spatialPartition
 }
@@ -119,3 +135,38 @@ func (g *GameWorld) GetSpawnPoint() *entity.Entity {
 	}
 	return nil
 }
+
+// PatrolRoute returns the patrol route of the entity with the id by following
+// its chain of waypoints
+func (g *GameWorld) PatrolRoute(id int) (patrol.Route, error) {
+	routeEntity := g.GetEntityByID(id)
+	if routeEntity == nil || routeEntity.PatrolRouteComponent == nil {
+		return patrol.Route{}, fmt.Errorf("entity %d isn't a patrol route", id)
+	}
+
+	route := patrol.Route{Mode: routeEntity.PatrolRouteComponent.Mode}
+	visited := map[int]bool{}
+	for waypointID := routeEntity.PatrolRouteComponent.Start; waypointID != entity.InvalidEntityID; {
+		if visited[waypointID] {
+			return patrol.Route{}, fmt.Errorf("patrol route %d loops back to waypoint %d, set the mode to loop instead", id, waypointID)
+		}
+		visited[waypointID] = true
+
+		e := g.GetEntityByID(waypointID)
+		if e == nil || e.WaypointComponent == nil {
+			return patrol.Route{}, fmt.Errorf("patrol route %d links to entity %d which isn't a waypoint", id, waypointID)
+		}
+		route.Waypoints = append(route.Waypoints, patrol.Waypoint{
+			ID:       waypointID,
+			Position: e.Position(),
+			Wait:     time.Duration(e.WaypointComponent.Wait * float64(time.Second)),
+			Action:   e.WaypointComponent.Action,
+		})
+		waypointID = e.WaypointComponent.Next
+	}
+
+	if len(route.Waypoints) == 0 {
+		return patrol.Route{}, fmt.Errorf("patrol route %d has no waypoints", id)
+	}
+	return route, nil
+}
@@ -2,17 +2,26 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
+	"os/signal"
 	"runtime"
-	"strconv"
-	"strings"
+	"syscall"
+	"time"
 
 	"net/http"
 	_ "net/http/pprof"
 
+	"github.com/kkevinchou/izzet/izzet/assets"
 	"github.com/kkevinchou/izzet/izzet/client"
+	"github.com/kkevinchou/izzet/izzet/launch"
+	"github.com/kkevinchou/izzet/izzet/navmeshbuilder"
+	"github.com/kkevinchou/izzet/izzet/project"
+	"github.com/kkevinchou/izzet/izzet/project/projectpaths"
+	"github.com/kkevinchou/izzet/izzet/server"
 	"github.com/kkevinchou/izzet/izzet/settings"
 )
 
@@ -26,18 +35,16 @@ func init() {
 	runtime.LockOSThread()
 }
 
-const (
-	modeLocal  string = "LOCAL"
-	modeClient string = "CLIENT"
-	modeServer string = "SERVER"
-)
-
 type Game interface {
 	Start()
 }
 
 func main() {
-	mode, logsEnabled := parseCommandLine(os.Args[1:])
+	commandLine, err := launch.ParseCommandLine(os.Args[1:])
+	if err != nil {
+		panic(err)
+	}
+	mode, logsEnabled, projectName := commandLine.Mode, commandLine.LogsEnabled, commandLine.ProjectName
 
 	configFile, err := os.Open("config.json")
 	config := settings.NewConfig()
@@ -77,7 +84,14 @@ func main() {
 			panic(err)
 		}
 		clientApp.Start()
-	} else if mode == "HEADLESS" {
+	} else if mode == launch.ModeHeadless {
+		options, err := launch.ResolveHeadlessOptions(config, projectName)
+		if err != nil {
+			panic(err)
+		}
+		runHeadless(options)
+	} else if mode == launch.ModeBake {
+		bakeNavMesh(projectName)
 	} else if mode == "CLIENT" {
 		clientApp := client.New("shaders", config, logsEnabled)
 		clientApp.Connect()
@@ -85,29 +99,39 @@ func main() {
 	}
 }
 
-func parseCommandLine(args []string) (string, bool) {
-	mode := modeClient
-	logsEnabled := true
-
-	for _, arg := range args {
-		if strings.HasPrefix(arg, "--logs=") {
-			value, err := strconv.ParseBool(strings.TrimPrefix(arg, "--logs="))
-			if err != nil {
-				panic(fmt.Sprintf("unexpected --logs value %q", strings.TrimPrefix(arg, "--logs=")))
-			}
-			logsEnabled = value
-			continue
-		}
+// runHeadless runs a dedicated server for the project until it receives a
+// SIGINT or SIGTERM
+func runHeadless(options launch.HeadlessOptions) {
+	serverApp := server.NewWithFile(options.WorldFilePath, options.ProjectName)
+	serverApp.SetNetworkTransport(options.Transport)
+	serverApp.SetNetworkSimulation(options.NetworkSimulation)
 
-		if strings.HasPrefix(arg, "--") {
-			panic(fmt.Sprintf("unexpected flag %s", arg))
-		}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	launch.RunHeadless(serverApp, signals)
+}
 
-		mode = strings.ToUpper(arg)
-		if mode != modeServer && mode != modeClient && mode != "HEADLESS" {
-			panic(fmt.Sprintf("unexpected mode %s", mode))
-		}
+// bakeNavMesh builds the project's nav mesh and writes it next to the project's
+// world file. the settings of a previous bake are reused, otherwise the defaults
+func bakeNavMesh(projectName string) {
+	assetManager := assets.NewAssetManager(false, slog.Default())
+	world, err := project.LoadWorld(projectName, assetManager)
+	if err != nil {
+		panic(err)
 	}
 
-	return mode, logsEnabled
+	config := navmeshbuilder.DefaultConfig()
+	if previous, err := project.LoadNavMesh(projectName, world); err == nil || errors.Is(err, project.ErrStaleNavMesh) {
+		config = previous.Config
+	}
+
+	start := time.Now()
+	baked := navmeshbuilder.Bake(world, assetManager, config)
+	fmt.Println(time.Since(start), "to build nav mesh")
+
+	filepath := projectpaths.NavMeshFilePath(projectName)
+	if err := navmeshbuilder.Save(filepath, baked); err != nil {
+		panic(err)
+	}
+	fmt.Println("wrote nav mesh to", filepath)
 }